@@ -2,14 +2,25 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jasoncheung94/url-shortener/config"
-	_ "github.com/jasoncheung94/url-shortener/docs" // swagger docs required import
+	"github.com/jasoncheung94/url-shortener/docs" // swagger docs, served under the base URL path
+	"github.com/jasoncheung94/url-shortener/internal/audit"
 	"github.com/jasoncheung94/url-shortener/internal/database"
+	"github.com/jasoncheung94/url-shortener/internal/events"
 	"github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/metadata"
+	"github.com/jasoncheung94/url-shortener/internal/outbox"
+	"github.com/jasoncheung94/url-shortener/internal/ratelimiter"
 	"github.com/jasoncheung94/url-shortener/internal/router"
 	"github.com/jasoncheung94/url-shortener/internal/server"
 	"github.com/jasoncheung94/url-shortener/internal/shortener"
@@ -17,6 +28,7 @@ import (
 	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
 	"github.com/jasoncheung94/url-shortener/internal/validator"
 	_ "github.com/lib/pq" // PostgreSQL driver for database/sql
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
@@ -26,6 +38,14 @@ func main() {
 	validator.SetupValidator()
 	config.Setup()
 
+	grammar := shortener.CodeGrammar{
+		Separators: viper.GetBool("alias_separators"),
+		Unicode:    viper.GetBool("alias_unicode"),
+	}
+	if err := grammar.RegisterValidation(validator.Validate); err != nil {
+		log.Panic("Failed to register alias validation", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -46,9 +66,413 @@ func main() {
 	redis := cache.NewRedis(rdb)
 
 	cachedRepo := repository.NewCache(repo, redis)
-	service := shortener.NewService(cachedRepo)
-	handler := shortener.NewHandler(service)
+
+	var obfuscator *shortener.IDObfuscator
+	if key := viper.GetString("id_obfuscation_key"); key != "" {
+		obfuscator, err = shortener.NewIDObfuscator([]byte(key), viper.GetInt("id_obfuscation_bits"))
+		if err != nil {
+			log.Panic("Invalid ID obfuscation config", err)
+		}
+	}
+
+	strategy := viper.GetString("counter_strategy")
+	if strategy != "redis" && strategy != "snowflake" {
+		log.Panicf("Unknown counter strategy %q", strategy)
+	}
+
+	// Make sure neither redis nor the database counter hands out codes that already exist.
+	if counter, ok := repo.(repository.DurableCounter); ok && strategy == "redis" {
+		counterCtx, cancelCounter := context.WithTimeout(context.Background(), time.Minute)
+		if err := shortener.SeedCounter(counterCtx, repo, counter, obfuscator); err != nil {
+			log.Println("Failed to seed counter", err)
+		}
+		if err := cachedRepo.ReconcileCounter(counterCtx); err != nil {
+			log.Println("Failed to reconcile counter", err)
+		}
+		cancelCounter()
+
+		reconcileCtx, stopReconcile := context.WithCancel(context.Background())
+		go cachedRepo.ReconcileCounterEvery(reconcileCtx, viper.GetDuration("counter_reconcile_interval"))
+		prevCleanup := cleanup
+		cleanup = func() {
+			stopReconcile()
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+
+	baseURL, err := shortener.NewBaseURL(viper.GetString("public_base_url"),
+		strings.Split(viper.GetString("trusted_proxies"), ","))
+	if err != nil {
+		log.Panic("Invalid public base URL config", err)
+	}
+	docs.SwaggerInfo.BasePath = baseURL.Prefix()
+
+	serviceOpts := []shortener.ServiceOption{shortener.WithSelfURL(baseURL)}
+	if viper.GetBool("metadata_fetch") {
+		timeout := viper.GetDuration("metadata_timeout")
+		client := metadata.NewSafeClient(metadata.ClientConfig{
+			Timeout:      timeout,
+			MaxRedirects: viper.GetInt("metadata_max_redirects"),
+		})
+		fetcher := metadata.NewFetcher(client, viper.GetInt64("metadata_max_bytes"))
+		enricher := shortener.NewMetadataEnricher(fetcher, cachedRepo, viper.GetInt("metadata_workers"), timeout)
+		serviceOpts = append(serviceOpts, shortener.WithMetadataEnricher(enricher))
+
+		dbCleanup := cleanup
+		cleanup = func() {
+			enricher.Close() // Stop writing before the database closes.
+			if dbCleanup != nil {
+				dbCleanup()
+			}
+		}
+	}
+
+	bus := events.NewBus()
+	bus.Subscribe(func(_ context.Context, event events.Event) {
+		if event.Type == events.LinkClicked {
+			return // Too many to log.
+		}
+		logger.Logger.Info("link event", "type", event.Type, "shorturl", event.ShortURL, "data", event.Data)
+	})
+
+	if checks, ok := repo.(repository.LinkChecks); ok && viper.GetBool("link_check") {
+		client := metadata.NewSafeClient(metadata.ClientConfig{
+			Timeout:      viper.GetDuration("link_check_timeout"),
+			MaxRedirects: 10,
+		})
+		checker := shortener.NewLinkChecker(cachedRepo, checks, client, bus, shortener.LinkCheckerConfig{
+			Interval:         viper.GetDuration("link_check_interval"),
+			Concurrency:      viper.GetInt("link_check_concurrency"),
+			HostDelay:        viper.GetDuration("link_check_host_delay"),
+			FailureThreshold: viper.GetInt("link_check_failures"),
+		})
+		checkCtx, stopChecks := context.WithCancel(context.Background())
+		checksDone := make(chan struct{})
+		go func() {
+			defer close(checksDone)
+			checker.Run(checkCtx)
+		}()
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			stopChecks()
+			<-checksDone
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+
+	serviceOpts = append(serviceOpts, shortener.WithEventPublisher(bus))
+	var clicks events.Publisher
+	if hooks, ok := repo.(repository.Webhooks); ok && viper.GetBool("webhooks") {
+		client := metadata.NewSafeClient(metadata.ClientConfig{Timeout: viper.GetDuration("webhook_timeout")})
+		webhooks := shortener.NewWebhooks(hooks, client, shortener.WebhookConfig{
+			MaxAttempts: viper.GetInt("webhook_max_attempts"),
+			Backoff:     viper.GetDuration("webhook_backoff"),
+			MaxBackoff:  viper.GetDuration("webhook_max_backoff"),
+			Interval:    viper.GetDuration("webhook_interval"),
+			Concurrency: viper.GetInt("webhook_concurrency"),
+			Lease:       viper.GetDuration("webhook_timeout") + time.Minute,
+		})
+		bus.Subscribe(webhooks.Handle)
+		serviceOpts = append(serviceOpts, shortener.WithWebhooks(webhooks))
+		clicks = bus
+		expiries := shortener.NewExpiryNotifier(cachedRepo, bus, viper.GetDuration("webhook_expiry_interval"))
+
+		hooksCtx, stopHooks := context.WithCancel(context.Background())
+		var hooksDone sync.WaitGroup
+		hooksDone.Add(2)
+		go func() {
+			defer hooksDone.Done()
+			webhooks.Run(hooksCtx)
+		}()
+		go func() {
+			defer hooksDone.Done()
+			expiries.Run(hooksCtx)
+		}()
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			stopHooks() // Queued events are stored before the database closes.
+			hooksDone.Wait()
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+
+	if store, ok := repo.(repository.Outbox); ok && viper.GetBool("outbox") {
+		transport, channel := newOutboxTransport(rdb)
+		relay := outbox.NewRelay(store, transport, outbox.RelayConfig{
+			Interval:  viper.GetDuration("outbox_interval"),
+			Lease:     viper.GetDuration("outbox_lease"),
+			BatchSize: viper.GetInt("outbox_batch_size"),
+			Retention: viper.GetDuration("outbox_retention"),
+		})
+		serviceOpts = append(serviceOpts, shortener.WithOutbox(store))
+
+		outboxCtx, stopOutbox := context.WithCancel(context.Background())
+		var outboxDone sync.WaitGroup
+		outboxDone.Add(1)
+		go func() {
+			defer outboxDone.Done()
+			relay.Run(outboxCtx)
+		}()
+		if channel != nil {
+			// The bus gets each event once, however often the relay sends it.
+			handler := outbox.Idempotent("event-bus", outbox.NewMemoryProcessed(10000), outbox.PublishTo(bus))
+			outboxDone.Add(1)
+			go func() {
+				defer outboxDone.Done()
+				channel.Consume(outboxCtx, handler)
+			}()
+		}
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			stopOutbox() // Before the webhooks stop, they get the events it publishes.
+			outboxDone.Wait()
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+
+	if obfuscator != nil {
+		serviceOpts = append(serviceOpts, shortener.WithIDObfuscator(obfuscator))
+	}
+
+	if strategy == "snowflake" {
+		if obfuscator != nil {
+			log.Panic("ID obfuscation can't be used with snowflake IDs, they don't fit its width")
+		}
+		counter := newSnowflakeCounter(repo)
+		serviceOpts = append(serviceOpts, shortener.WithCounter(counter))
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			counter.Close() // Release the worker ID before the database closes.
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	} else if size := viper.GetInt("counter_block_size"); size > 1 {
+		allocator := shortener.NewCounterAllocator(cachedRepo, size)
+		serviceOpts = append(serviceOpts, shortener.WithCounter(allocator))
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			allocator.Close()
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+	serviceOpts = append(serviceOpts, codeGeneratorOptions()...)
+	aliases := newAliasPolicy()
+	serviceOpts = append(serviceOpts, shortener.WithAliasPolicy(aliases), shortener.WithCodeGrammar(grammar))
+	if domains, ok := repo.(repository.Domains); ok {
+		serviceOpts = append(serviceOpts, shortener.WithDomains(shortener.NewDomains(domains, newResolver())))
+	}
+	if workspaces, ok := repo.(repository.Workspaces); ok {
+		serviceOpts = append(serviceOpts, shortener.WithWorkspaces(shortener.NewWorkspaces(workspaces)))
+	}
+	if history, ok := repo.(repository.History); ok {
+		serviceOpts = append(serviceOpts, shortener.WithHistory(history))
+	}
+	if auditLog, closeAudit := newAuditLog(repo); auditLog != nil {
+		serviceOpts = append(serviceOpts, shortener.WithAuditLog(auditLog))
+
+		prevCleanup := cleanup
+		cleanup = func() {
+			closeAudit()
+			if prevCleanup != nil {
+				prevCleanup()
+			}
+		}
+	}
+
+	service := shortener.NewService(cachedRepo, serviceOpts...)
+	handlerOpts := []shortener.HandlerOption{
+		shortener.WithRedirectStatus(viper.GetInt("redirect_status")),
+		shortener.WithBaseURL(baseURL),
+		shortener.WithAdminKey(viper.GetString("admin_api_key")),
+	}
+	if logo, err := os.ReadFile(viper.GetString("qr_logo_path")); err == nil {
+		handlerOpts = append(handlerOpts, shortener.WithQRLogo(logo))
+	} else {
+		log.Println("QR code logo not loaded", err)
+	}
+	aliasLimiter := ratelimiter.NewIPRateLimiter(viper.GetInt("alias_check_rate"), viper.GetInt("alias_check_burst"),
+		10*time.Minute)
+	handlerOpts = append(handlerOpts, shortener.WithAliasLimiter(aliasLimiter.MiddlewareBy(baseURL.ClientIP)))
+	if clicks != nil {
+		handlerOpts = append(handlerOpts, shortener.WithClickEvents(clicks))
+	}
+	handler := shortener.NewHandler(service, handlerOpts...)
+	aliases.Reserve(router.ReservedNames(handler)...)
 	router := router.New(handler)
 
 	server.Start(router, viper.GetString("port"), cleanup)
 }
+
+// newSnowflakeCounter leases a snowflake worker ID from the database for this instance.
+func newSnowflakeCounter(repo repository.URL) *shortener.SnowflakeCounter {
+	leases, ok := repo.(repository.WorkerLeases)
+	if !ok {
+		log.Panic("Snowflake IDs need a database to lease worker IDs from")
+	}
+	hostname, _ := os.Hostname()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	counter, err := shortener.NewSnowflakeCounter(ctx, leases, shortener.SnowflakeConfig{
+		Owner:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Workers:      viper.GetInt("snowflake_workers"),
+		LeaseTTL:     viper.GetDuration("snowflake_lease_ttl"),
+		Heartbeat:    viper.GetDuration("snowflake_heartbeat"),
+		MaxBackwards: viper.GetDuration("snowflake_max_backwards"),
+	})
+	if err != nil {
+		log.Panic("Failed to lease snowflake worker ID", err)
+	}
+	return counter
+}
+
+// newAliasPolicy returns the custom alias policy from config. Route names are reserved once the
+// routes are known.
+func newAliasPolicy() *shortener.AliasPolicy {
+	var blocklist io.Reader = strings.NewReader(shortener.DefaultBlocklist)
+	if path := viper.GetString("alias_blocklist_path"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Panic("Failed to open alias blocklist", err)
+		}
+		defer file.Close()
+		blocklist = file
+	}
+	words, err := shortener.ReadBlocklist(blocklist)
+	if err != nil {
+		log.Panic("Failed to read alias blocklist", err)
+	}
+
+	return shortener.NewAliasPolicy(shortener.AliasPolicyConfig{
+		Reserved:        strings.Split(viper.GetString("alias_reserved"), ","),
+		Blocklist:       words,
+		CaseInsensitive: viper.GetBool("alias_case_insensitive"),
+		Disjoint:        viper.GetBool("alias_disjoint"),
+	})
+}
+
+// newAuditLog returns the audit log writing to the sinks of AUDIT_SINKS, nil if there are none, and a function
+// closing them. The database comes first when listed, it stores the chain and answers queries.
+func newAuditLog(repo repository.URL) (*audit.Log, func()) {
+	var (
+		sinks []audit.Sink
+		file  *audit.File
+	)
+	for _, name := range strings.Split(viper.GetString("audit_sinks"), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "database":
+			store, ok := repo.(repository.AuditLog)
+			if !ok {
+				log.Panic("The database can't store the audit log")
+			}
+			sinks = append([]audit.Sink{store}, sinks...)
+		case "file":
+			var err error
+			if file, err = audit.OpenFile(viper.GetString("audit_file")); err != nil {
+				log.Panic("Failed to open audit log file", err)
+			}
+			sinks = append(sinks, file)
+		case "stdout":
+			sinks = append(sinks, audit.NewLogger(logger.Logger))
+		default:
+			log.Panicf("Unknown audit sink %q", name)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return audit.New(sinks...), func() {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// newOutboxTransport returns the transport sending outbox messages to OUTBOX_TRANSPORTS, and the channel to
+// consume when the in-process event bus is one of them.
+func newOutboxTransport(rdb *redis.Client) (outbox.Transport, *outbox.Channel) {
+	var (
+		transports []outbox.Transport
+		channel    *outbox.Channel
+	)
+	for _, name := range strings.Split(viper.GetString("outbox_transports"), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "channel":
+			channel = outbox.NewChannel()
+			transports = append(transports, channel)
+		case "redis":
+			stream := outbox.NewRedisStream(rdb, viper.GetString("outbox_stream"), viper.GetInt64("outbox_stream_max_len"))
+			transports = append(transports, stream)
+		default:
+			log.Panicf("Unknown outbox transport %q", name)
+		}
+	}
+	if len(transports) == 0 {
+		log.Panic("OUTBOX needs at least one of OUTBOX_TRANSPORTS")
+	}
+	return outbox.Multi(transports...), channel
+}
+
+// newResolver returns the resolver verifying custom domains, the system one unless DOMAIN_DNS_SERVER is set.
+func newResolver() *net.Resolver {
+	server := viper.GetString("domain_dns_server")
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// codeGeneratorOptions registers every built-in code generator and selects the configured default.
+// Generator metrics are published on /debug/vars, which needs the admin key.
+func codeGeneratorOptions() []shortener.ServiceOption {
+	random, err := shortener.NewRandomGenerator(viper.GetInt("code_random_length"))
+	if err != nil {
+		log.Panic("Invalid random code generator config", err)
+	}
+	hash, err := shortener.NewHashGenerator(viper.GetInt("code_hash_length"))
+	if err != nil {
+		log.Panic("Invalid hash code generator config", err)
+	}
+
+	name := viper.GetString("code_generator")
+	switch name {
+	case shortener.GeneratorCounter, shortener.GeneratorRandom, shortener.GeneratorHash, shortener.GeneratorWords:
+	default:
+		log.Panicf("Unknown code generator %q", name)
+	}
+
+	metrics := shortener.NewGeneratorMetrics()
+	expvar.Publish("code_generators", expvar.Func(func() any { return metrics.Snapshot() }))
+
+	return []shortener.ServiceOption{
+		shortener.WithCodeGenerator(shortener.GeneratorRandom, random),
+		shortener.WithCodeGenerator(shortener.GeneratorHash, hash),
+		shortener.WithCodeGenerator(shortener.GeneratorWords, shortener.NewWordsGenerator()),
+		shortener.WithDefaultCodeGenerator(name),
+		shortener.WithGeneratorMetrics(metrics),
+	}
+}
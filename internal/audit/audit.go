@@ -0,0 +1,226 @@
+// Package audit keeps a tamper evident log of every change made through the API: who made it, from where and
+// what changed. Entries are hash chained and written to pluggable sinks.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+)
+
+// ActorAdmin is the actor of changes made with the admin key.
+const ActorAdmin = "admin"
+
+// Sink receives every entry of the audit log, in order.
+type Sink interface {
+	AppendAudit(ctx context.Context, entry *model.AuditEntry) error
+}
+
+// Source is who made a request and where it came from.
+type Source struct {
+	Actor     string
+	IP        string
+	RequestID string
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context whose changes are recorded as made by the source.
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// WithActor returns a context whose changes are recorded as made by the actor, keeping the rest of its source.
+func WithActor(ctx context.Context, actor string) context.Context {
+	source := SourceFrom(ctx)
+	source.Actor = actor
+	return WithSource(ctx, source)
+}
+
+// SourceFrom returns the source of the context, empty for background jobs.
+func SourceFrom(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
+
+// Change is a change to record. Before and After are the resource before and after it, nil for a resource that
+// was created or removed, and are stored as the JSON fields that differ.
+type Change struct {
+	Action    string // See the model.Audit constants.
+	Resource  string // ID of the resource, e.g. the short URL.
+	Workspace string // Defaults to the workspace the context is scoped to.
+	Before    any
+	After     any
+}
+
+// maxAppendAttempts is how often an entry is chained again after another instance appended one first.
+const maxAppendAttempts = 5
+
+// Log records changes as hash chained entries. The first sink that is a repository.AuditLog stores the chain:
+// the last entry is read from it on the first change and it answers queries. Instances sharing it take turns,
+// an entry another instance appended first is a ConflictError and the change is chained after it. Without
+// one the chain is kept in memory and starts over when the app restarts.
+type Log struct {
+	store repository.AuditLog
+	sinks []Sink
+	now   func() time.Time
+
+	mu     sync.Mutex
+	head   model.AuditEntry // Last entry, zero for an empty log.
+	loaded bool
+}
+
+// New returns a Log writing to the sinks in order.
+func New(sinks ...Sink) *Log {
+	l := &Log{now: time.Now}
+	for _, sink := range sinks {
+		if store, ok := sink.(repository.AuditLog); ok && l.store == nil {
+			l.store = store
+			continue
+		}
+		l.sinks = append(l.sinks, sink)
+	}
+	return l
+}
+
+// Record appends the change to the log as made by the source of the context. Updates that didn't change
+// anything aren't recorded. The entry is in the log once the store has it, an error of another sink is
+// returned after writing to the rest.
+func (l *Log) Record(ctx context.Context, change Change) error {
+	changes, err := Diff(change.Before, change.After)
+	if err != nil {
+		return fmt.Errorf("audit: failed to diff %s of %q: %w", change.Action, change.Resource, err)
+	}
+	if change.Before != nil && change.After != nil && len(changes) == 0 {
+		return nil
+	}
+
+	source := SourceFrom(ctx)
+	entry := model.AuditEntry{
+		Time:      l.now().UTC().Truncate(time.Millisecond), // The precision every sink keeps.
+		Actor:     source.Actor,
+		Workspace: change.Workspace,
+		Action:    change.Action,
+		Resource:  change.Resource,
+		IP:        source.IP,
+		RequestID: source.RequestID,
+		Changes:   changes,
+	}
+	if entry.Workspace == "" {
+		entry.Workspace, _ = tenant.From(ctx)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		if err := l.loadHead(ctx); err != nil {
+			return err
+		}
+		entry.Seq, entry.PrevHash = l.head.Seq+1, l.head.Hash
+		entry.Hash = Hash(entry)
+		if l.store == nil {
+			break
+		}
+		err := l.store.AppendAudit(ctx, &entry)
+		if errors.Is(err, e.ConflictError{}) && attempt < maxAppendAttempts {
+			l.loaded = false // Another instance appended first.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("audit: failed to append %s of %q: %w", change.Action, change.Resource, err)
+		}
+		break
+	}
+	l.head = entry
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := sink.AppendAudit(ctx, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("audit: failed to write entry %d: %w", entry.Seq, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadHead reads the last entry from the store unless it's known.
+func (l *Log) loadHead(ctx context.Context) error {
+	if l.loaded || l.store == nil {
+		return nil
+	}
+	last, err := l.store.LastAudit(ctx)
+	if err != nil {
+		return fmt.Errorf("audit: failed to get last entry: %w", err)
+	}
+	l.head = model.AuditEntry{}
+	if last != nil {
+		l.head = *last
+	}
+	l.loaded = true
+	return nil
+}
+
+// errNotQueryable is returned by the queries of a Log without a store.
+var errNotQueryable = e.NewBadRequestError("the audit log isn't stored anywhere it can be queried")
+
+// List returns the entries passing the filter by Seq.
+func (l *Log) List(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	if l.store == nil {
+		return nil, errNotQueryable
+	}
+	entries, err := l.store.ListAudit(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// verifyPageSize is how many entries Verify reads at a time.
+const verifyPageSize = 500
+
+// Verify checks the hash chain of the whole log and reports the first entry that was changed, or follows a
+// removed one. Entries removed from the end can only be found by comparing Head with a copy kept elsewhere.
+func (l *Log) Verify(ctx context.Context) (*model.AuditVerification, error) {
+	if l.store == nil {
+		return nil, errNotQueryable
+	}
+	result := &model.AuditVerification{Valid: true}
+	var prev model.AuditEntry
+	for {
+		entries, err := l.store.ListAudit(ctx, model.AuditFilter{AfterSeq: prev.Seq, Limit: verifyPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("audit: failed to list entries: %w", err)
+		}
+		for _, entry := range entries {
+			if reason := chainError(prev, entry); reason != "" {
+				result.Valid, result.BrokenAt, result.Reason = false, entry.Seq, reason
+				return result, nil
+			}
+			result.Entries++
+			result.Head = entry.Hash
+			prev = entry
+		}
+		if len(entries) < verifyPageSize {
+			return result, nil
+		}
+	}
+}
+
+// chainError returns why the entry doesn't follow prev in the chain, "" if it does.
+func chainError(prev, entry model.AuditEntry) string {
+	switch {
+	case entry.Seq != prev.Seq+1:
+		return fmt.Sprintf("entries %d to %d are missing", prev.Seq+1, entry.Seq-1)
+	case entry.PrevHash != prev.Hash:
+		return fmt.Sprintf("previous hash doesn't match entry %d", prev.Seq)
+	case Hash(entry) != entry.Hash:
+		return "hash doesn't match the entry, it was changed"
+	}
+	return ""
+}
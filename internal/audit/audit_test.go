@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type link struct {
+	URL  string   `json:"url"`
+	Tags []string `json:"tags,omitempty"`
+	Hits int      `json:"hits"`
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	changes, err := Diff(&link{URL: "https://a.com", Tags: []string{"x"}, Hits: 1}, link{URL: "https://b.com", Hits: 1})
+	require.NoError(t, err)
+	assert.Equal(t, model.AuditChanges{
+		"url":  {Before: json.RawMessage(`"https://a.com"`), After: json.RawMessage(`"https://b.com"`)},
+		"tags": {Before: json.RawMessage(`["x"]`)},
+	}, changes)
+
+	changes, err = Diff(nil, link{URL: "https://a.com"})
+	require.NoError(t, err)
+	assert.Len(t, changes, 2, "every field of a created resource")
+
+	changes, err = Diff((*link)(nil), (*link)(nil))
+	require.NoError(t, err)
+	assert.Nil(t, changes)
+
+	_, err = Diff("not an object", nil)
+	assert.Error(t, err)
+}
+
+func TestLog_Record(t *testing.T) {
+	t.Parallel()
+	store := repository.NewInMemory()
+	var logged bytes.Buffer
+	log := New(store, NewLogger(slog.New(slog.NewTextHandler(&logged, nil))))
+
+	ctx := WithActor(WithSource(tenant.With(context.Background(), "acme"), Source{IP: "203.0.113.9",
+		RequestID: "req-1"}), "alice")
+	require.NoError(t, log.Record(ctx, Change{Action: model.AuditURLCreate, Resource: "abc",
+		After: link{URL: "https://a.com"}}))
+	require.NoError(t, log.Record(ctx, Change{Action: model.AuditURLUpdate, Resource: "abc",
+		Before: link{URL: "https://a.com"}, After: link{URL: "https://a.com"}}), "nothing changed")
+	require.NoError(t, log.Record(context.Background(), Change{Action: model.AuditURLUpdate, Resource: "abc",
+		Workspace: "acme", Before: link{URL: "https://a.com"}, After: link{URL: "https://b.com"}}))
+
+	entries, err := log.List(context.Background(), model.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	first, second := entries[0], entries[1]
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Equal(t, "alice", first.Actor)
+	assert.Equal(t, "acme", first.Workspace)
+	assert.Equal(t, "203.0.113.9", first.IP)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, Hash(first), first.Hash)
+	assert.Equal(t, int64(2), second.Seq)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Empty(t, second.Actor, "background changes have no actor")
+	assert.Equal(t, json.RawMessage(`"https://b.com"`), second.Changes["url"].After)
+
+	assert.Contains(t, logged.String(), "action=url.create")
+	assert.Contains(t, logged.String(), "hash="+second.Hash)
+
+	verification, err := log.Verify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &model.AuditVerification{Valid: true, Entries: 2, Head: second.Hash}, verification)
+}
+
+func TestLog_SharedStore(t *testing.T) {
+	t.Parallel()
+	store := repository.NewInMemory()
+	a, b := New(store), New(store)
+	ctx := context.Background()
+	change := Change{Action: model.AuditKeyCreate, Resource: "key", After: map[string]string{"name": "ci"}}
+
+	require.NoError(t, b.Record(ctx, change))
+	require.NoError(t, a.Record(ctx, change))
+	require.NoError(t, b.Record(ctx, change), "b chains after a's entry")
+
+	verification, err := a.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, int64(3), verification.Entries)
+}
+
+func TestLog_VerifyTampering(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	source := repository.NewInMemory()
+	log := New(source)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, log.Record(ctx, Change{Action: model.AuditMemberCreate, Resource: name,
+			After: map[string]string{"id": name}}))
+	}
+	entries, err := log.List(ctx, model.AuditFilter{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(entries []model.AuditEntry) []model.AuditEntry
+		broken int64
+	}{
+		{name: "changed", broken: 2, tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			entries[1].Actor = "mallory"
+			return entries
+		}},
+		{name: "rehashed", broken: 3, tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			entries[1].Time = entries[1].Time.Add(time.Hour)
+			entries[1].Hash = Hash(entries[1])
+			return entries
+		}},
+		{name: "removed", broken: 3, tamper: func(entries []model.AuditEntry) []model.AuditEntry {
+			return append(entries[:1], entries[2])
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			store := repository.NewInMemory()
+			for _, entry := range tt.tamper(append([]model.AuditEntry{}, entries...)) {
+				require.NoError(t, store.AppendAudit(ctx, &entry))
+			}
+			verification, err := New(store).Verify(ctx)
+			require.NoError(t, err)
+			assert.False(t, verification.Valid)
+			assert.Equal(t, tt.broken, verification.BrokenAt)
+			assert.NotEmpty(t, verification.Reason)
+		})
+	}
+}
+
+func TestLog_WithoutStore(t *testing.T) {
+	t.Parallel()
+	var logged bytes.Buffer
+	log := New(NewLogger(slog.New(slog.NewJSONHandler(&logged, nil))))
+	require.NoError(t, log.Record(context.Background(), Change{Action: model.AuditDomainCreate, Resource: "sho.rt",
+		After: map[string]string{"name": "sho.rt"}}))
+	assert.Contains(t, logged.String(), `"seq":1`)
+
+	_, err := log.List(context.Background(), model.AuditFilter{})
+	assert.Error(t, err)
+	_, err = log.Verify(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// maxLineSize is the longest entry a File reads.
+const maxLineSize = 1 << 20
+
+// File appends entries to a JSON lines file, one entry per line. It's a repository.AuditLog too, queries read
+// the whole file, so on its own it keeps the chain across restarts of a single instance.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+	last int64 // Seq of the last entry in the file.
+}
+
+var _ repository.AuditLog = &File{}
+
+// OpenFile opens or creates the JSON lines file at path for appending.
+func OpenFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to open file: %w", err)
+	}
+	f := &File{file: file}
+	last, err := f.LastAudit(context.Background())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if last != nil {
+		f.last = last.Seq
+	}
+	return f, nil
+}
+
+// AppendAudit writes the entry as a line and syncs it to disk. Entries must come in order, one with a Seq the
+// file already has is a ConflictError.
+func (f *File) AppendAudit(_ context.Context, entry *model.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audit: failed to encode entry: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry.Seq <= f.last {
+		return e.NewConflictError("audit entry %d already exists", entry.Seq)
+	}
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit: failed to write entry: %w", err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("audit: failed to sync file: %w", err)
+	}
+	f.last = entry.Seq
+	return nil
+}
+
+// LastAudit returns the last entry of the file, nil if it's empty.
+func (f *File) LastAudit(_ context.Context) (*model.AuditEntry, error) {
+	var last *model.AuditEntry
+	err := f.scan(func(entry model.AuditEntry) bool {
+		last = &entry
+		return true
+	})
+	return last, err
+}
+
+// ListAudit returns the entries of the file passing the filter, in the order they were written.
+func (f *File) ListAudit(_ context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	entries := []model.AuditEntry{}
+	err := f.scan(func(entry model.AuditEntry) bool {
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+		return filter.Limit <= 0 || len(entries) < filter.Limit
+	})
+	return entries, err
+}
+
+// scan reads the entries of the file from the start until fn returns false.
+func (f *File) scan(fn func(entry model.AuditEntry) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reader := io.NewSectionReader(f.file, 0, 1<<62) // Reads from the start without moving the append offset.
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		var entry model.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("audit: line %d of the file isn't an entry: %w", line, err)
+		}
+		if !fn(entry) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("audit: failed to read file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
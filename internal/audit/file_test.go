@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	file, err := OpenFile(path)
+	require.NoError(t, err)
+	log := New(file)
+	require.NoError(t, log.Record(ctx, Change{Action: model.AuditURLCreate, Resource: "abc",
+		After: map[string]any{"originalURL": "https://a.com", "redirectType": 301}}))
+	require.NoError(t, file.Close())
+
+	// Reopened, the chain carries on from the last line.
+	file, err = OpenFile(path)
+	require.NoError(t, err)
+	defer file.Close()
+	log = New(file)
+	require.NoError(t, log.Record(ctx, Change{Action: model.AuditURLUpdate, Resource: "abc",
+		Before: map[string]any{"redirectType": 301}, After: map[string]any{"redirectType": 308}}))
+	assert.Error(t, file.AppendAudit(ctx, &model.AuditEntry{Seq: 2}), "seq already written")
+
+	entries, err := file.ListAudit(ctx, model.AuditFilter{Action: model.AuditURLUpdate})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].Seq)
+
+	verification, err := log.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, verification.Reason)
+	assert.Equal(t, int64(2), verification.Entries)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"), "one entry per line")
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// Hash returns the hex SHA-256 of the entry without its own Hash, which covers PrevHash and so every entry
+// before it. Values are hashed in a canonical JSON form, so entries hash the same after a round trip through
+// a database that reformats JSON.
+func Hash(entry model.AuditEntry) string {
+	var changes map[string][2]any
+	if len(entry.Changes) > 0 {
+		changes = make(map[string][2]any, len(entry.Changes))
+		for name, change := range entry.Changes {
+			changes[name] = [2]any{decode(change.Before), decode(change.After)}
+		}
+	}
+	// Map keys are sorted by encoding/json, struct fields keep their order.
+	payload, _ := json.Marshal(struct {
+		Seq       int64
+		Time      string
+		Actor     string
+		Workspace string
+		Action    string
+		Resource  string
+		IP        string
+		RequestID string
+		Changes   map[string][2]any
+		PrevHash  string
+	}{
+		Seq:       entry.Seq,
+		Time:      entry.Time.UTC().Format(time.RFC3339Nano),
+		Actor:     entry.Actor,
+		Workspace: entry.Workspace,
+		Action:    entry.Action,
+		Resource:  entry.Resource,
+		IP:        entry.IP,
+		RequestID: entry.RequestID,
+		Changes:   changes,
+		PrevHash:  entry.PrevHash,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// decode returns the value of the JSON, nil if there's none.
+func decode(raw json.RawMessage) any {
+	var value any
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &value)
+	}
+	return value
+}
+
+// Diff returns the fields of before and after whose JSON differs, with both values. Either may be nil, for a
+// resource that was created or removed; otherwise they must encode to JSON objects.
+func Diff(before, after any) (model.AuditChanges, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := model.AuditChanges{}
+	for name, value := range from {
+		if !bytes.Equal(value, to[name]) {
+			changes[name] = model.AuditChange{Before: value, After: to[name]}
+		}
+	}
+	for name, value := range to {
+		if _, ok := from[name]; !ok {
+			changes[name] = model.AuditChange{After: value}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
+
+// fields returns the fields of the value's JSON object in canonical form, nil for null.
+func fields(value any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, errors.New("value isn't a JSON object")
+	}
+	for name, raw := range object {
+		if object[name], err = json.Marshal(decode(raw)); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// Logger writes entries to a slog logger, e.g. the app's logger on stdout for a log collector to ship.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// NewLogger returns a sink logging every entry to logger.
+func NewLogger(logger *slog.Logger) *Logger {
+	return &Logger{logger: logger}
+}
+
+// AppendAudit logs the entry at info level.
+func (l *Logger) AppendAudit(ctx context.Context, entry *model.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	l.logger.InfoContext(ctx, "audit",
+		"seq", entry.Seq,
+		"time", entry.Time,
+		"actor", entry.Actor,
+		"workspace", entry.Workspace,
+		"action", entry.Action,
+		"resource", entry.Resource,
+		"ip", entry.IP,
+		"request_id", entry.RequestID,
+		"changes", string(changes),
+		"prev_hash", entry.PrevHash,
+		"hash", entry.Hash,
+	)
+	return nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS utm,
+    DROP COLUMN IF EXISTS forward_query;
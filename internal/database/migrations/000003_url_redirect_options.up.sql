@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS utm JSONB,                                 -- UTM template appended on redirect (optional)
+    ADD COLUMN IF NOT EXISTS forward_query VARCHAR(10) NOT NULL DEFAULT ''; -- Query forwarding mode: none, merge or override
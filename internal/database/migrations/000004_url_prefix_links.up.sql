@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS prefix BOOLEAN NOT NULL DEFAULT FALSE; -- Forward /{short_url}/path to original_url + /path
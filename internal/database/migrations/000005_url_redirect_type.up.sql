@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NOT NULL DEFAULT 0; -- 301, 302, 307 or 308. 0 uses the server default.
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS title VARCHAR(200) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS image VARCHAR(2048) NOT NULL DEFAULT '';
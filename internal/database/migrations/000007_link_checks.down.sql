@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS link_checks;
+
+DROP INDEX IF EXISTS idx_urls_broken;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS broken;
@@ -0,0 +1,15 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS broken BOOLEAN NOT NULL DEFAULT FALSE; -- Set by the link checker after repeated failures.
+
+CREATE INDEX IF NOT EXISTS idx_urls_broken ON urls (broken) WHERE broken;
+
+CREATE TABLE IF NOT EXISTS link_checks (
+    id BIGSERIAL PRIMARY KEY,
+    short_url VARCHAR(255) NOT NULL REFERENCES urls (short_url) ON DELETE CASCADE,
+    status_code SMALLINT NOT NULL DEFAULT 0, -- 0 when no response was received.
+    error TEXT NOT NULL DEFAULT '',
+    ok BOOLEAN NOT NULL,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_checks_short_url ON link_checks (short_url, checked_at DESC);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS worker_leases (
+    worker_id INTEGER PRIMARY KEY, -- Snowflake worker ID, unique among running instances.
+    owner TEXT NOT NULL, -- Instance holding the lease.
+    expires_at TIMESTAMPTZ NOT NULL, -- Renewed by heartbeats, free to take once passed.
+    last_time TIMESTAMPTZ -- Time of the latest ID generated with this worker ID.
+);
@@ -0,0 +1,5 @@
+ALTER TABLE link_checks
+    ALTER COLUMN short_url TYPE VARCHAR(255);
+
+ALTER TABLE urls
+    ALTER COLUMN short_url TYPE VARCHAR(255);
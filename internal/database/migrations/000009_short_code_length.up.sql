@@ -0,0 +1,7 @@
+-- Size short codes to the code grammar: shortener.MaxCodeLength characters, aliases shortener.MaxAliasLength.
+ALTER TABLE urls
+    ALTER COLUMN short_url TYPE VARCHAR(20),
+    ALTER COLUMN custom_url TYPE VARCHAR(20);
+
+ALTER TABLE link_checks
+    ALTER COLUMN short_url TYPE VARCHAR(20);
@@ -0,0 +1,19 @@
+-- Links on custom domains can't keep their codes once codes are unique across all domains again.
+DELETE FROM urls WHERE domain <> '';
+
+DROP INDEX IF EXISTS idx_link_checks_short_url;
+CREATE INDEX IF NOT EXISTS idx_link_checks_short_url ON link_checks (short_url, checked_at DESC);
+
+ALTER TABLE link_checks
+    DROP CONSTRAINT IF EXISTS link_checks_url_fkey;
+
+ALTER TABLE urls
+    DROP CONSTRAINT IF EXISTS unique_domain_short_url,
+    ADD CONSTRAINT unique_short_url UNIQUE (short_url),
+    DROP COLUMN IF EXISTS domain;
+
+ALTER TABLE link_checks
+    DROP COLUMN IF EXISTS domain,
+    ADD CONSTRAINT link_checks_short_url_fkey FOREIGN KEY (short_url) REFERENCES urls (short_url) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS domains;
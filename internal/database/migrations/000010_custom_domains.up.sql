@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS domains (
+    name VARCHAR(253) PRIMARY KEY, -- Lower case, IDNA ASCII form.
+    owner TEXT NOT NULL,
+    token TEXT NOT NULL, -- Expected in the verification TXT record.
+    verified_at TIMESTAMPTZ, -- NULL until the TXT record was found.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_owner ON domains (owner, name);
+
+-- Short codes are unique per domain, '' is the default host.
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS domain VARCHAR(253) NOT NULL DEFAULT '';
+
+ALTER TABLE link_checks
+    DROP CONSTRAINT IF EXISTS link_checks_short_url_fkey,
+    ADD COLUMN IF NOT EXISTS domain VARCHAR(253) NOT NULL DEFAULT '';
+
+ALTER TABLE urls
+    DROP CONSTRAINT IF EXISTS unique_short_url,
+    ADD CONSTRAINT unique_domain_short_url UNIQUE (domain, short_url);
+
+ALTER TABLE link_checks
+    ADD CONSTRAINT link_checks_url_fkey FOREIGN KEY (domain, short_url)
+        REFERENCES urls (domain, short_url) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_link_checks_short_url;
+CREATE INDEX IF NOT EXISTS idx_link_checks_short_url ON link_checks (domain, short_url, checked_at DESC);
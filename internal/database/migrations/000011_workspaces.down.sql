@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_urls_tenant_created;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+    id VARCHAR(63) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    link_quota INT NOT NULL DEFAULT 0, -- 0 is no limit.
+    monthly_quota INT NOT NULL DEFAULT 0, -- Links created per calendar month (UTC), 0 is no limit.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(32) PRIMARY KEY,
+    workspace_id VARCHAR(63) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    hash CHAR(64) NOT NULL, -- Hex SHA-256 of the key, the key itself isn't stored.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_workspace ON api_keys (workspace_id, created_at);
+
+-- Links belong to a workspace, '' is the default one.
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_urls_tenant_created ON urls (tenant_id, created_at);
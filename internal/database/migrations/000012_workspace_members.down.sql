@@ -0,0 +1,4 @@
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS member_id;
+
+DROP TABLE IF EXISTS workspace_members;
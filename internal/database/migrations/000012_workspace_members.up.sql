@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id VARCHAR(63) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    member_id VARCHAR(254) NOT NULL, -- User name or email.
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, member_id)
+);
+
+-- Keys act with the role of their member, '' for keys created before members existed.
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS member_id VARCHAR(254) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_urls_tenant_folder;
+DROP INDEX IF EXISTS idx_urls_tags;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS folder,
+    DROP COLUMN IF EXISTS notes;
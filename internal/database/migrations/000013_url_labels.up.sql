@@ -0,0 +1,8 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}', -- Lower case and sorted.
+    ADD COLUMN IF NOT EXISTS folder VARCHAR(100) NOT NULL DEFAULT '', -- '' is no folder.
+    ADD COLUMN IF NOT EXISTS notes VARCHAR(2000) NOT NULL DEFAULT '';
+
+-- Links with all of the tags are found with tags @> ARRAY[...].
+CREATE INDEX IF NOT EXISTS idx_urls_tags ON urls USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_urls_tenant_folder ON urls (tenant_id, folder);
@@ -0,0 +1,21 @@
+-- Append-only history of the destination, expiry and redirect rules of links.
+CREATE TABLE IF NOT EXISTS url_versions (
+    domain VARCHAR(253) NOT NULL DEFAULT '',
+    short_url VARCHAR(20) NOT NULL,
+    version INT NOT NULL, -- 1 is the link as it was created.
+    original_url TEXT NOT NULL,
+    expiration_date TIMESTAMP,
+    utm JSONB,
+    forward_query VARCHAR(10) NOT NULL DEFAULT '',
+    prefix BOOLEAN NOT NULL DEFAULT FALSE,
+    redirect_type SMALLINT NOT NULL DEFAULT 0,
+    actor VARCHAR(254) NOT NULL DEFAULT '', -- Member who made the change, '' if unknown.
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'rollback')),
+    rollback_of INT NOT NULL DEFAULT 0, -- Version a rollback restored.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (domain, short_url, version),
+    FOREIGN KEY (domain, short_url) REFERENCES urls (domain, short_url) ON DELETE CASCADE
+);
+
+-- Versions are never changed.
+CREATE OR REPLACE RULE url_versions_no_update AS ON UPDATE TO url_versions DO INSTEAD NOTHING;
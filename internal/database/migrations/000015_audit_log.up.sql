@@ -0,0 +1,23 @@
+-- Append-only, hash chained log of every change made through the API.
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq BIGINT PRIMARY KEY, -- Position in the hash chain, from 1.
+    time TIMESTAMPTZ NOT NULL,
+    actor VARCHAR(254) NOT NULL DEFAULT '', -- Member ID, 'admin' for the admin key, '' without an API key.
+    workspace_id VARCHAR(63) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    resource VARCHAR(300) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    changes JSONB, -- Changed fields with their value before and after.
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '', -- Hash of the entry before, '' for the first one.
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_workspace ON audit_log (workspace_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (resource, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log (time);
+
+-- Entries are never changed or removed.
+CREATE OR REPLACE RULE audit_log_no_update AS ON UPDATE TO audit_log DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_log_no_delete AS ON DELETE TO audit_log DO INSTEAD NOTHING;
@@ -0,0 +1,30 @@
+-- Subscriptions of workspaces to link events.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(32) PRIMARY KEY,
+    workspace_id VARCHAR(63) NOT NULL DEFAULT '', -- '' is the default workspace.
+    url VARCHAR(2048) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}', -- Event types sent, every type if empty.
+    secret VARCHAR(100) NOT NULL, -- Signs the payloads, so it's kept as is.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_workspace ON webhooks (workspace_id, created_at);
+
+-- Queue of events to send to each webhook, kept as the delivery log.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(32) PRIMARY KEY, -- Derived from the webhook and event IDs.
+    webhook_id VARCHAR(32) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    workspace_id VARCHAR(63) NOT NULL DEFAULT '',
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INT NOT NULL DEFAULT 0, -- Since it was queued or redelivered.
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    log JSONB, -- Latest attempts with their status code, error and response.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at DESC);
@@ -0,0 +1,15 @@
+-- Events written in the transaction of the change they record, published by the outbox relay.
+CREATE TABLE IF NOT EXISTS outbox (
+    seq BIGSERIAL PRIMARY KEY, -- Publication order.
+    id VARCHAR(64) NOT NULL UNIQUE, -- The event ID, consumers skip the IDs they already processed.
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_key VARCHAR(512) NOT NULL, -- The link the event is about.
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox (seq) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published ON outbox (published_at) WHERE published_at IS NOT NULL;
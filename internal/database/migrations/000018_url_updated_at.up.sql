@@ -0,0 +1,2 @@
+-- NULL for links that never changed since they were created.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- '' for custom aliases and links created before the generator was stored.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS generator VARCHAR(50) NOT NULL DEFAULT '';
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -25,19 +26,7 @@ func InitMongo(ctx context.Context, uri string) (*mongo.Client, error) {
 		return nil, err
 	}
 
-	databases, err := client.ListDatabases(ctx, bson.D{{Key: "name", Value: "url_shortener_db"}})
-	if err != nil {
-		l.Logger.Warn("failed to list mongodb databases")
-	}
-
-	// Check if database is already created. Return early otherwise create.
-	l.Logger.Info("Checking if mongodb databases are setup")
-	for _, db := range databases.Databases {
-		if db.Name == "url_shortener_db" {
-			return client, nil
-		}
-	}
-
+	// Index and validator changes are picked up by existing databases too, every step is idempotent.
 	l.Logger.Info("Setting up indexes and database for url shortener")
 	// Create/Use Database.
 	db := client.Database("url_shortener_db")
@@ -46,6 +35,14 @@ func InitMongo(ctx context.Context, uri string) (*mongo.Client, error) {
 	// Use the collection.
 	collection := db.Collection("urls")
 	createIndexes(ctx, collection)
+	createLinkCheckIndexes(ctx, db.Collection("link_checks"))
+	createVersionIndexes(ctx, db.Collection("url_versions"))
+	createAuditIndexes(ctx, db.Collection("audit_log"))
+	createDomainIndexes(ctx, db.Collection("domains"))
+	createAPIKeyIndexes(ctx, db.Collection("api_keys"))
+	createMemberIndexes(ctx, db.Collection("workspace_members"))
+	createWebhookIndexes(ctx, db.Collection("webhooks"), db.Collection("webhook_deliveries"))
+	createOutboxIndexes(ctx, db.Collection("outbox"))
 
 	return client, nil
 }
@@ -63,20 +60,96 @@ func createCollectionWithValidation(ctx context.Context, db *mongo.Database) {
 				},
 				"short_url": bson.M{
 					"bsonType":    "string",
+					"maxLength":   shortener.MaxCodeLength,
 					"description": "must be a string and is required",
 				},
+				"domain": bson.M{
+					"bsonType":    "string",
+					"maxLength":   253,
+					"description": "optional custom domain, missing for the default host",
+				},
+				"tenant_id": bson.M{
+					"bsonType":    "string",
+					"maxLength":   63,
+					"description": "optional workspace, missing for the default workspace",
+				},
 				"custom_url": bson.M{
 					"bsonType":    []string{"string", "null"},
+					"maxLength":   shortener.MaxAliasLength,
 					"description": "optional string",
 				},
 				"expiration_date": bson.M{
 					"bsonType":    bson.A{"date", "null"}, // example with bson.A
 					"description": "optional expiration date",
 				},
+				"utm": bson.M{
+					"bsonType":    bson.A{"object", "null"},
+					"description": "optional utm template appended on redirect",
+				},
+				"forward_query": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"description": "optional query forwarding mode: none, merge or override",
+				},
+				"prefix": bson.M{
+					"bsonType":    bson.A{"bool", "null"},
+					"description": "optional flag to forward path suffixes to the original url",
+				},
+				"redirect_type": bson.M{
+					"bsonType":    bson.A{"int", "null"},
+					"description": "optional redirect status: 301, 302, 307 or 308",
+				},
+				"title": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   200,
+					"description": "optional open graph title",
+				},
+				"description": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   500,
+					"description": "optional open graph description",
+				},
+				"image": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   2048,
+					"description": "optional open graph image url",
+				},
+				"broken": bson.M{
+					"bsonType":    bson.A{"bool", "null"},
+					"description": "set by the link checker after repeated failures",
+				},
+				"tags": bson.M{
+					"bsonType":    bson.A{"array", "null"},
+					"maxItems":    shortener.MaxTags,
+					"uniqueItems": true,
+					"items": bson.M{
+						"bsonType":  "string",
+						"maxLength": shortener.MaxTagLength,
+					},
+					"description": "optional lower case tags, sorted",
+				},
+				"folder": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   100,
+					"description": "optional folder or campaign",
+				},
+				"notes": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   2000,
+					"description": "optional free text notes",
+				},
+				"generator": bson.M{
+					"bsonType":    bson.A{"string", "null"},
+					"maxLength":   50,
+					"description": "optional code generator of the link",
+				},
 				"created_at": bson.M{
 					"bsonType":    "date",
 					"description": "must be a date and is required",
 				},
+				"updated_at": bson.M{
+					"bsonType":    bson.A{"date", "null"},
+					"description": "optional date of the last change",
+				},
 			},
 		},
 	}
@@ -85,7 +158,12 @@ func createCollectionWithValidation(ctx context.Context, db *mongo.Database) {
 	err := db.CreateCollection(ctx, "urls", opts)
 	if err != nil {
 		if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Code == 48 {
-			fmt.Println("Collection already exists. Skipping creation.")
+			// Existing collections get the fields added since they were created.
+			err := db.RunCommand(ctx, bson.D{{Key: "collMod", Value: "urls"}, {Key: "validator", Value: validator}}).Err()
+			if err != nil {
+				log.Fatal("updating collection validator:", err)
+			}
+			fmt.Println("Collection already exists. Validator updated.")
 		} else {
 			log.Fatal("creating collection:", err)
 		}
@@ -95,9 +173,59 @@ func createCollectionWithValidation(ctx context.Context, db *mongo.Database) {
 }
 
 func createIndexes(ctx context.Context, collection *mongo.Collection) {
-	// Create index on short_url.
+	// Short codes are unique per domain. Links on the default host have no domain, indexed as null.
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "short_url", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+
+	// Databases created before domains have a unique index on short_url alone, which would keep the same code
+	// from being used on two domains. It's dropped once the index above covers uniqueness.
+	if _, err := collection.Indexes().DropOne(ctx, "short_url_1"); err != nil {
+		if cmdErr, ok := err.(mongo.CommandError); !ok || cmdErr.Code != 27 { // IndexNotFound
+			log.Fatal("Dropping index", err)
+		}
+	}
+
+	// Quota checks count a workspace's links, in total and since the start of the month.
+	indexModel = mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}},
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		log.Fatal("Creating index", err)
+	}
+
+	// Listing a workspace's links by tag or folder. Tags is an array, so the first index is multikey.
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "folder", Value: 1}}},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createLinkCheckIndexes(ctx context.Context, collection *mongo.Collection) {
+	// Latest checks of a link first.
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "domain", Value: 1}, {Key: "short_url", Value: 1}, {Key: "checked_at", Value: -1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createVersionIndexes(ctx context.Context, collection *mongo.Collection) {
+	// A link has each version once, listed in order.
 	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: "short_url", Value: 1}},
+		Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "short_url", Value: 1}, {Key: "version", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	}
 
@@ -106,3 +234,84 @@ func createIndexes(ctx context.Context, collection *mongo.Collection) {
 		log.Fatal("Creating index", err)
 	}
 }
+
+func createAuditIndexes(ctx context.Context, collection *mongo.Collection) {
+	// Entries are keyed by seq, these list an actor's, workspace's or resource's entries in order.
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "resource", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "time", Value: 1}}},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createDomainIndexes(ctx context.Context, collection *mongo.Collection) {
+	// Domains of an owner by name.
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "owner", Value: 1}, {Key: "_id", Value: 1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createAPIKeyIndexes(ctx context.Context, collection *mongo.Collection) {
+	// Keys of a workspace, oldest first.
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: 1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createMemberIndexes(ctx context.Context, collection *mongo.Collection) {
+	// A user is a member of a workspace once, listed by ID.
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "member_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createWebhookIndexes(ctx context.Context, webhooks, deliveries *mongo.Collection) {
+	// Webhooks of a workspace, oldest first.
+	_, err := webhooks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+
+	// Pending deliveries by when they're due, and the latest deliveries of a webhook.
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+	if _, err := deliveries.Indexes().CreateMany(ctx, indexModels); err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
+
+func createOutboxIndexes(ctx context.Context, collection *mongo.Collection) {
+	// Unpublished messages, which have no published_at, oldest first. Also finds the published ones to purge.
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		log.Fatal("Creating index", err)
+	}
+}
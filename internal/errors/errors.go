@@ -116,6 +116,29 @@ func (e ForbiddenError) Is(target error) bool {
 	return ok
 }
 
+// UnauthorizedError represents a missing or invalid credentials error (401).
+type UnauthorizedError struct {
+	message string
+}
+
+// Error returns the error as a string.
+func (e UnauthorizedError) Error() string {
+	return e.message
+}
+
+// NewUnauthorizedError returns a new unauthorized error.
+func NewUnauthorizedError(message string, args ...any) UnauthorizedError {
+	return UnauthorizedError{
+		message: fmt.Sprintf(message, args...),
+	}
+}
+
+// Is checks if err is the same as target.
+func (e UnauthorizedError) Is(target error) bool {
+	_, ok := target.(UnauthorizedError)
+	return ok
+}
+
 // ConflictError represents a resource conflict error (409).
 type ConflictError struct {
 	message string
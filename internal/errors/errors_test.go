@@ -92,6 +92,12 @@ func TestErrorTypes_IsMethod(t *testing.T) {
 			targetErr:     ForbiddenError{},
 			expectedMatch: true,
 		},
+		{
+			name:          "UnauthorizedError match",
+			err:           NewUnauthorizedError("unauthorized"),
+			targetErr:     UnauthorizedError{},
+			expectedMatch: true,
+		},
 		{
 			name:          "ConflictError match",
 			err:           NewConflictError("conflict"),
@@ -0,0 +1,97 @@
+// Package events publishes things that happen to links so other parts of the system can react.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Type names an event.
+type Type string
+
+const (
+	// LinkCreated is published when a link is created.
+	LinkCreated Type = "link.created"
+	// LinkUpdated is published when a link is changed or rolled back.
+	LinkUpdated Type = "link.updated"
+	// LinkDeleted is published when a link is deleted.
+	LinkDeleted Type = "link.deleted"
+	// LinkExpired is published once when the expiration date of a link passes.
+	LinkExpired Type = "link.expired"
+	// LinkClicked is published when a visitor is redirected by a link.
+	LinkClicked Type = "link.clicked"
+	// LinkBroken is published when a link's destination fails repeated health checks.
+	LinkBroken Type = "link.broken"
+	// LinkRecovered is published when the destination of a broken link works again.
+	LinkRecovered Type = "link.recovered"
+)
+
+// Types are every event type, in the order they're documented.
+var Types = []Type{LinkCreated, LinkUpdated, LinkDeleted, LinkExpired, LinkClicked, LinkBroken, LinkRecovered}
+
+// Event is something that happened to a link.
+type Event struct {
+	ID         string         `json:"id"` // Unique per event, the same when an event is published again.
+	Type       Type           `json:"type"`
+	ShortURL   string         `json:"shortURL"`
+	Domain     string         `json:"domain,omitempty"`    // Custom domain of the link, empty for the default host.
+	Workspace  string         `json:"workspace,omitempty"` // Workspace of the link, empty for the default one.
+	OccurredAt time.Time      `json:"occurredAt"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// NewID returns a random event ID.
+func NewID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id) // Never fails, see crypto/rand.Read.
+	return hex.EncodeToString(id)
+}
+
+// Handler reacts to an event.
+type Handler func(ctx context.Context, event Event)
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// Bus is an in-process Publisher. Subscribers are called in order on the publishing goroutine.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+var _ Publisher = &Bus{}
+
+// NewBus returns an empty Bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds a handler called for every event.
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish calls every subscriber with the event, after filling in a missing ID and time.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if event.ID == "" {
+		event.ID = NewID()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+	bus := NewBus()
+	bus.Publish(context.Background(), Event{Type: LinkBroken}) // No subscribers.
+
+	var received []string
+	bus.Subscribe(func(_ context.Context, e Event) { received = append(received, "first:"+e.ShortURL) })
+	bus.Subscribe(func(_ context.Context, e Event) { received = append(received, "second:"+e.ShortURL) })
+
+	bus.Publish(context.Background(), Event{Type: LinkBroken, ShortURL: "abc"})
+	assert.Equal(t, []string{"first:abc", "second:abc"}, received)
+}
+
+func TestBus_FillsIDAndTime(t *testing.T) {
+	t.Parallel()
+	bus := NewBus()
+	var received []Event
+	bus.Subscribe(func(_ context.Context, e Event) { received = append(received, e) })
+
+	bus.Publish(context.Background(), Event{Type: LinkClicked})
+	bus.Publish(context.Background(), Event{Type: LinkClicked})
+	occurred := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	bus.Publish(context.Background(), Event{ID: "expired-abc", Type: LinkExpired, OccurredAt: occurred})
+
+	assert.Len(t, received[0].ID, 32)
+	assert.NotEqual(t, received[0].ID, received[1].ID)
+	assert.False(t, received[0].OccurredAt.IsZero())
+	assert.Equal(t, "expired-abc", received[2].ID)
+	assert.Equal(t, occurred, received[2].OccurredAt)
+}
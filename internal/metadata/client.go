@@ -0,0 +1,103 @@
+// Package metadata fetches the title, description and preview image of destination pages.
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a request would connect to an address that isn't publicly routable.
+var ErrBlockedAddress = errors.New("metadata: blocked address")
+
+// blockedPrefixes are special purpose ranges not covered by the netip.Addr helpers.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network.
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT.
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments.
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation.
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking.
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation.
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation.
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, includes broadcast.
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64 can reach IPv4 private ranges.
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64.
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation.
+	netip.MustParsePrefix("2002::/16"),       // 6to4 can embed any IPv4 address.
+}
+
+// IsPublicAddr reports whether the address is publicly routable.
+// Loopback, private, link-local, multicast and other special purpose ranges are not.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientConfig limits what the client is allowed to do.
+type ClientConfig struct {
+	Timeout      time.Duration // Whole request including redirects and reading the body.
+	MaxRedirects int
+}
+
+// NewSafeClient returns an HTTP client for fetching user supplied URLs.
+// The address is checked after DNS resolution on every connection, so redirects and DNS rebinding
+// can't reach loopback or internal services. Proxies are ignored as they would hide the real address.
+func NewSafeClient(cfg ClientConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: checkAddress,
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+	}
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return fmt.Errorf("metadata: stopped after %d redirects", cfg.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("metadata: redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// checkAddress runs before each connection with the resolved IP address.
+func checkAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
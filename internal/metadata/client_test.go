@@ -0,0 +1,81 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // Cloud metadata endpoint.
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, IsPublicAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestSafeClient_BlocksLoopback(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer server.Close()
+
+	client := NewSafeClient(ClientConfig{Timeout: time.Second, MaxRedirects: 3})
+	_, err := NewFetcher(client, 1024).Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+
+	// localhost resolves to the loopback address, the check runs after DNS resolution.
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	_, err = NewFetcher(client, 1024).Fetch(context.Background(), "http://localhost:"+u.Port())
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+}
+
+func TestSafeClient_CheckRedirect(t *testing.T) {
+	t.Parallel()
+	client := NewSafeClient(ClientConfig{Timeout: time.Second, MaxRedirects: 2})
+	req := func(rawURL string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		return r
+	}
+	first := req("https://example.com/")
+
+	assert.NoError(t, client.CheckRedirect(req("https://example.com/a"), []*http.Request{first}))
+	assert.NoError(t, client.CheckRedirect(req("https://example.com/b"), []*http.Request{first, first}))
+	assert.Error(t, client.CheckRedirect(req("https://example.com/c"), []*http.Request{first, first, first}))
+	assert.Error(t, client.CheckRedirect(req("ftp://example.com/"), []*http.Request{first}))
+}
@@ -0,0 +1,168 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// userAgent identifies the fetcher to the destination site.
+const userAgent = "url-shortener-metadata/1.0 (+https://github.com/jasoncheung94/url-shortener)"
+
+// Metadata is the preview information found in the head of a page.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string // Absolute http(s) URL.
+}
+
+// Fetcher downloads pages and extracts their metadata.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewFetcher returns a Fetcher reading at most maxBytes of each page.
+// Use NewSafeClient in production, tests can pass any client.
+func NewFetcher(client *http.Client, maxBytes int64) *Fetcher {
+	return &Fetcher{client: client, maxBytes: maxBytes}
+}
+
+// Fetch downloads the page and returns its metadata.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return Metadata{}, fmt.Errorf("metadata: unsupported scheme %q", target.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Metadata{}, fmt.Errorf("metadata: unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, fmt.Errorf("metadata: unsupported content type %q", mediaType)
+	}
+
+	return Parse(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL), nil
+}
+
+// Parse reads the head of an HTML document. Open Graph and Twitter card tags win over the plain
+// title and description. Relative image URLs are resolved against base.
+func Parse(r io.Reader, base *url.URL) Metadata {
+	var (
+		tokenizer                  = html.NewTokenizer(r)
+		title, description         string
+		ogTitle, ogDesc, ogImage   string
+		inTitle, seenTitle, inHead = false, false, true
+	)
+
+	for inHead {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break // EOF, a truncated body or invalid markup: use what was found.
+		}
+		name, _ := tokenizer.TagName()
+		tag := string(name)
+
+		switch {
+		case tt == html.TextToken && inTitle:
+			title += string(tokenizer.Text())
+		case tt == html.EndTagToken && tag == "title":
+			inTitle = false
+		case tt == html.EndTagToken && tag == "head", tt == html.StartTagToken && tag == "body":
+			inHead = false
+		case tt == html.StartTagToken && tag == "title":
+			inTitle, seenTitle = !seenTitle, true
+		case (tt == html.StartTagToken || tt == html.SelfClosingTagToken) && tag == "meta":
+			key, content := metaAttributes(tokenizer)
+			switch key {
+			case "og:title", "twitter:title":
+				ogTitle = firstNonEmpty(ogTitle, content)
+			case "description":
+				description = firstNonEmpty(description, content)
+			case "og:description", "twitter:description":
+				ogDesc = firstNonEmpty(ogDesc, content)
+			case "og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src":
+				ogImage = firstNonEmpty(ogImage, content)
+			}
+		}
+	}
+
+	return Metadata{
+		Title:       clean(firstNonEmpty(ogTitle, title)),
+		Description: clean(firstNonEmpty(ogDesc, description)),
+		Image:       resolveImage(strings.TrimSpace(ogImage), base),
+	}
+}
+
+// metaAttributes returns the lowercase property or name of a meta tag and its content.
+func metaAttributes(tokenizer *html.Tokenizer) (string, string) {
+	var key, content string
+	for {
+		name, value, more := tokenizer.TagAttr()
+		switch string(name) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(string(value)))
+			}
+		case "content":
+			content = string(value)
+		}
+		if !more {
+			return key, content
+		}
+	}
+}
+
+func resolveImage(image string, base *url.URL) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" || ref.Host == "" {
+		return ""
+	}
+	return ref.String()
+}
+
+// clean collapses whitespace and drops invalid UTF-8 from pages in other encodings.
+func clean(s string) string {
+	return strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
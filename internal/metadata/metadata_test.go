@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	base, err := url.Parse("https://example.com/blog/post")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		page     string
+		expected Metadata
+	}{
+		{
+			name:     "Plain title and description",
+			page:     `<html><head><title> Hello &amp;   world </title><meta name="description" content="A page"></head>`,
+			expected: Metadata{Title: "Hello & world", Description: "A page"},
+		},
+		{
+			name: "Open Graph wins",
+			page: `<head><title>Plain</title><meta name="description" content="Plain description">
+				<meta property="og:title" content="OG title"><meta property="og:description" content="OG description">
+				<meta property="og:image" content="https://cdn.example.com/og.png"></head>`,
+			expected: Metadata{Title: "OG title", Description: "OG description", Image: "https://cdn.example.com/og.png"},
+		},
+		{
+			name:     "Twitter card and relative image",
+			page:     `<head><meta name="twitter:title" content="Tweet"/><meta name="twitter:image" content="../img/a.png"/>`,
+			expected: Metadata{Title: "Tweet", Image: "https://example.com/img/a.png"},
+		},
+		{
+			name:     "First title only",
+			page:     `<head><title>First</title><title>Second</title></head>`,
+			expected: Metadata{Title: "First"},
+		},
+		{
+			name:     "Body is ignored",
+			page:     `<head></head><body><title>Not this</title><meta property="og:title" content="Nor this"></body>`,
+			expected: Metadata{},
+		},
+		{
+			name:     "Non http image is dropped",
+			page:     `<head><meta property="og:image" content="javascript:alert(1)"></head>`,
+			expected: Metadata{},
+		},
+		{
+			name:     "Truncated document",
+			page:     `<head><title>Cut off`,
+			expected: Metadata{Title: "Cut off"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, Parse(strings.NewReader(tt.page), base))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, userAgent, r.UserAgent())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<head><title>Page</title><meta property="og:image" content="/og.png"></head>`))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><title>` + strings.Repeat("a", 100) + `</title></head>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The safe client blocks loopback, so tests inject the server's own client.
+	fetcher := NewFetcher(server.Client(), 1024)
+
+	meta, err := fetcher.Fetch(context.Background(), server.URL+"/page")
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Title: "Page", Image: server.URL + "/og.png"}, meta)
+
+	meta, err = fetcher.Fetch(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/og.png", meta.Image, "image is resolved against the final URL")
+
+	meta, err = NewFetcher(server.Client(), 64).Fetch(context.Background(), server.URL+"/large")
+	require.NoError(t, err)
+	assert.Len(t, meta.Title, 64-len("<head><title>"), "body is cut at maxBytes")
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/json")
+	assert.Error(t, err)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+
+	_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+	assert.Error(t, err)
+}
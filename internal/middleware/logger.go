@@ -14,6 +14,7 @@ func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 				"method", r.Method,
 				"path", r.URL.Path,
 				"user_agent", r.UserAgent(),
+				"request_id", RequestIDFrom(r.Context()),
 			)
 			next.ServeHTTP(w, r)
 		})
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Parallel()
+	var got string
+	handler := RequestID(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = RequestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "client id", header: "abc-123", keep: true},
+		{name: "missing", header: ""},
+		{name: "invalid", header: "bad id\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(RequestIDHeader, tt.header)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got == "" || rr.Header().Get(RequestIDHeader) != got {
+			t.Errorf("%s: expected the response header %q to match the context id %q", tt.name,
+				rr.Header().Get(RequestIDHeader), got)
+		}
+		if (got == tt.header) != tt.keep {
+			t.Errorf("%s: expected keeping %q to be %v, got id %q", tt.name, tt.header, tt.keep, got)
+		}
+	}
+}
@@ -41,6 +41,20 @@ func (m *MockRedisInterface) EXPECT() *MockRedisInterfaceMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockRedisInterface) Delete(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRedisInterfaceMockRecorder) Delete(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRedisInterface)(nil).Delete), ctx, key)
+}
+
 // Get mocks base method.
 func (m *MockRedisInterface) Get(ctx context.Context, key string, dest any) error {
 	m.ctrl.T.Helper()
@@ -70,6 +84,21 @@ func (mr *MockRedisInterfaceMockRecorder) Increment(ctx, key any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Increment", reflect.TypeOf((*MockRedisInterface)(nil).Increment), ctx, key)
 }
 
+// IncrementBy mocks base method.
+func (m *MockRedisInterface) IncrementBy(ctx context.Context, key string, value int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementBy", ctx, key, value)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementBy indicates an expected call of IncrementBy.
+func (mr *MockRedisInterfaceMockRecorder) IncrementBy(ctx, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementBy", reflect.TypeOf((*MockRedisInterface)(nil).IncrementBy), ctx, key, value)
+}
+
 // Set mocks base method.
 func (m *MockRedisInterface) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
 	m.ctrl.T.Helper()
@@ -83,3 +112,18 @@ func (mr *MockRedisInterfaceMockRecorder) Set(ctx, key, value, ttl any) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRedisInterface)(nil).Set), ctx, key, value, ttl)
 }
+
+// SetMax mocks base method.
+func (m *MockRedisInterface) SetMax(ctx context.Context, key string, value int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMax", ctx, key, value)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetMax indicates an expected call of SetMax.
+func (mr *MockRedisInterfaceMockRecorder) SetMax(ctx, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMax", reflect.TypeOf((*MockRedisInterface)(nil).SetMax), ctx, key, value)
+}
@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/jasoncheung94/url-shortener/internal/shortener/model"
 	gomock "go.uber.org/mock/gomock"
@@ -41,19 +42,48 @@ func (m *MockURL) EXPECT() *MockURLMockRecorder {
 	return m.recorder
 }
 
+// DeleteURL mocks base method.
+func (m *MockURL) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteURL", ctx, domain, shortURL)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteURL indicates an expected call of DeleteURL.
+func (mr *MockURLMockRecorder) DeleteURL(ctx, domain, shortURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockURL)(nil).DeleteURL), ctx, domain, shortURL)
+}
+
+// ExistingURLs mocks base method.
+func (m *MockURL) ExistingURLs(ctx context.Context, domain string, shortURLs []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExistingURLs", ctx, domain, shortURLs)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExistingURLs indicates an expected call of ExistingURLs.
+func (mr *MockURLMockRecorder) ExistingURLs(ctx, domain, shortURLs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistingURLs", reflect.TypeOf((*MockURL)(nil).ExistingURLs), ctx, domain, shortURLs)
+}
+
 // GetURL mocks base method.
-func (m *MockURL) GetURL(ctx context.Context, shortURL string) (*model.URL, error) {
+func (m *MockURL) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", ctx, shortURL)
+	ret := m.ctrl.Call(m, "GetURL", ctx, domain, shortURL)
 	ret0, _ := ret[0].(*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetURL indicates an expected call of GetURL.
-func (mr *MockURLMockRecorder) GetURL(ctx, shortURL any) *gomock.Call {
+func (mr *MockURLMockRecorder) GetURL(ctx, domain, shortURL any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockURL)(nil).GetURL), ctx, shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockURL)(nil).GetURL), ctx, domain, shortURL)
 }
 
 // IncrementCounter mocks base method.
@@ -71,6 +101,36 @@ func (mr *MockURLMockRecorder) IncrementCounter() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementCounter", reflect.TypeOf((*MockURL)(nil).IncrementCounter))
 }
 
+// ListTags mocks base method.
+func (m *MockURL) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", ctx)
+	ret0, _ := ret[0].([]model.TagCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockURLMockRecorder) ListTags(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockURL)(nil).ListTags), ctx)
+}
+
+// ListURLs mocks base method.
+func (m *MockURL) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, filter)
+	ret0, _ := ret[0].([]model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockURLMockRecorder) ListURLs(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockURL)(nil).ListURLs), ctx, filter)
+}
+
 // SaveURL mocks base method.
 func (m *MockURL) SaveURL(ctx context.Context, data *model.URL) error {
 	m.ctrl.T.Helper()
@@ -84,3 +144,925 @@ func (mr *MockURLMockRecorder) SaveURL(ctx, data any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockURL)(nil).SaveURL), ctx, data)
 }
+
+// UpdateURL mocks base method.
+func (m *MockURL) UpdateURL(ctx context.Context, data *model.URL) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockURLMockRecorder) UpdateURL(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockURL)(nil).UpdateURL), ctx, data)
+}
+
+// MockLinkChecks is a mock of LinkChecks interface.
+type MockLinkChecks struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkChecksMockRecorder
+	isgomock struct{}
+}
+
+// MockLinkChecksMockRecorder is the mock recorder for MockLinkChecks.
+type MockLinkChecksMockRecorder struct {
+	mock *MockLinkChecks
+}
+
+// NewMockLinkChecks creates a new mock instance.
+func NewMockLinkChecks(ctrl *gomock.Controller) *MockLinkChecks {
+	mock := &MockLinkChecks{ctrl: ctrl}
+	mock.recorder = &MockLinkChecksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkChecks) EXPECT() *MockLinkChecksMockRecorder {
+	return m.recorder
+}
+
+// ListLinkChecks mocks base method.
+func (m *MockLinkChecks) ListLinkChecks(ctx context.Context, domain, shortURL string, limit int) ([]model.LinkCheck, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLinkChecks", ctx, domain, shortURL, limit)
+	ret0, _ := ret[0].([]model.LinkCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLinkChecks indicates an expected call of ListLinkChecks.
+func (mr *MockLinkChecksMockRecorder) ListLinkChecks(ctx, domain, shortURL, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLinkChecks", reflect.TypeOf((*MockLinkChecks)(nil).ListLinkChecks), ctx, domain, shortURL, limit)
+}
+
+// SaveLinkCheck mocks base method.
+func (m *MockLinkChecks) SaveLinkCheck(ctx context.Context, check *model.LinkCheck) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinkCheck", ctx, check)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLinkCheck indicates an expected call of SaveLinkCheck.
+func (mr *MockLinkChecksMockRecorder) SaveLinkCheck(ctx, check any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinkCheck", reflect.TypeOf((*MockLinkChecks)(nil).SaveLinkCheck), ctx, check)
+}
+
+// MockHistory is a mock of History interface.
+type MockHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryMockRecorder
+	isgomock struct{}
+}
+
+// MockHistoryMockRecorder is the mock recorder for MockHistory.
+type MockHistoryMockRecorder struct {
+	mock *MockHistory
+}
+
+// NewMockHistory creates a new mock instance.
+func NewMockHistory(ctrl *gomock.Controller) *MockHistory {
+	mock := &MockHistory{ctrl: ctrl}
+	mock.recorder = &MockHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistory) EXPECT() *MockHistoryMockRecorder {
+	return m.recorder
+}
+
+// ListVersions mocks base method.
+func (m *MockHistory) ListVersions(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVersions", ctx, domain, shortURL)
+	ret0, _ := ret[0].([]model.URLVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVersions indicates an expected call of ListVersions.
+func (mr *MockHistoryMockRecorder) ListVersions(ctx, domain, shortURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVersions", reflect.TypeOf((*MockHistory)(nil).ListVersions), ctx, domain, shortURL)
+}
+
+// SaveVersion mocks base method.
+func (m *MockHistory) SaveVersion(ctx context.Context, version *model.URLVersion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveVersion", ctx, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveVersion indicates an expected call of SaveVersion.
+func (mr *MockHistoryMockRecorder) SaveVersion(ctx, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveVersion", reflect.TypeOf((*MockHistory)(nil).SaveVersion), ctx, version)
+}
+
+// MockAuditLog is a mock of AuditLog interface.
+type MockAuditLog struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditLogMockRecorder is the mock recorder for MockAuditLog.
+type MockAuditLogMockRecorder struct {
+	mock *MockAuditLog
+}
+
+// NewMockAuditLog creates a new mock instance.
+func NewMockAuditLog(ctrl *gomock.Controller) *MockAuditLog {
+	mock := &MockAuditLog{ctrl: ctrl}
+	mock.recorder = &MockAuditLogMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLog) EXPECT() *MockAuditLogMockRecorder {
+	return m.recorder
+}
+
+// AppendAudit mocks base method.
+func (m *MockAuditLog) AppendAudit(ctx context.Context, entry *model.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendAudit", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AppendAudit indicates an expected call of AppendAudit.
+func (mr *MockAuditLogMockRecorder) AppendAudit(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAudit", reflect.TypeOf((*MockAuditLog)(nil).AppendAudit), ctx, entry)
+}
+
+// LastAudit mocks base method.
+func (m *MockAuditLog) LastAudit(ctx context.Context) (*model.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastAudit", ctx)
+	ret0, _ := ret[0].(*model.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastAudit indicates an expected call of LastAudit.
+func (mr *MockAuditLogMockRecorder) LastAudit(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastAudit", reflect.TypeOf((*MockAuditLog)(nil).LastAudit), ctx)
+}
+
+// ListAudit mocks base method.
+func (m *MockAuditLog) ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAudit", ctx, filter)
+	ret0, _ := ret[0].([]model.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAudit indicates an expected call of ListAudit.
+func (mr *MockAuditLogMockRecorder) ListAudit(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAudit", reflect.TypeOf((*MockAuditLog)(nil).ListAudit), ctx, filter)
+}
+
+// MockWebhooks is a mock of Webhooks interface.
+type MockWebhooks struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhooksMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhooksMockRecorder is the mock recorder for MockWebhooks.
+type MockWebhooksMockRecorder struct {
+	mock *MockWebhooks
+}
+
+// NewMockWebhooks creates a new mock instance.
+func NewMockWebhooks(ctrl *gomock.Controller) *MockWebhooks {
+	mock := &MockWebhooks{ctrl: ctrl}
+	mock.recorder = &MockWebhooksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhooks) EXPECT() *MockWebhooksMockRecorder {
+	return m.recorder
+}
+
+// ClaimDeliveries mocks base method.
+func (m *MockWebhooks) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDeliveries", ctx, now, lease, limit)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDeliveries indicates an expected call of ClaimDeliveries.
+func (mr *MockWebhooksMockRecorder) ClaimDeliveries(ctx, now, lease, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDeliveries", reflect.TypeOf((*MockWebhooks)(nil).ClaimDeliveries), ctx, now, lease, limit)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhooks) DeleteWebhook(ctx context.Context, workspace, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhooksMockRecorder) DeleteWebhook(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhooks)(nil).DeleteWebhook), ctx, workspace, id)
+}
+
+// GetDelivery mocks base method.
+func (m *MockWebhooks) GetDelivery(ctx context.Context, webhook, id string) (*model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelivery", ctx, webhook, id)
+	ret0, _ := ret[0].(*model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelivery indicates an expected call of GetDelivery.
+func (mr *MockWebhooksMockRecorder) GetDelivery(ctx, webhook, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockWebhooks)(nil).GetDelivery), ctx, webhook, id)
+}
+
+// GetWebhook mocks base method.
+func (m *MockWebhooks) GetWebhook(ctx context.Context, workspace, id string) (*model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", ctx, workspace, id)
+	ret0, _ := ret[0].(*model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockWebhooksMockRecorder) GetWebhook(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockWebhooks)(nil).GetWebhook), ctx, workspace, id)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhooks) ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", ctx, webhook, limit)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhooksMockRecorder) ListDeliveries(ctx, webhook, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhooks)(nil).ListDeliveries), ctx, webhook, limit)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockWebhooks) ListWebhooks(ctx context.Context, workspace string) ([]model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx, workspace)
+	ret0, _ := ret[0].([]model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockWebhooksMockRecorder) ListWebhooks(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockWebhooks)(nil).ListWebhooks), ctx, workspace)
+}
+
+// SaveDelivery mocks base method.
+func (m *MockWebhooks) SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDelivery indicates an expected call of SaveDelivery.
+func (mr *MockWebhooksMockRecorder) SaveDelivery(ctx, delivery any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDelivery", reflect.TypeOf((*MockWebhooks)(nil).SaveDelivery), ctx, delivery)
+}
+
+// SaveWebhook mocks base method.
+func (m *MockWebhooks) SaveWebhook(ctx context.Context, hook *model.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebhook", ctx, hook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebhook indicates an expected call of SaveWebhook.
+func (mr *MockWebhooksMockRecorder) SaveWebhook(ctx, hook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebhook", reflect.TypeOf((*MockWebhooks)(nil).SaveWebhook), ctx, hook)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockWebhooks) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockWebhooksMockRecorder) UpdateDelivery(ctx, delivery any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockWebhooks)(nil).UpdateDelivery), ctx, delivery)
+}
+
+// MockOutbox is a mock of Outbox interface.
+type MockOutbox struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxMockRecorder
+	isgomock struct{}
+}
+
+// MockOutboxMockRecorder is the mock recorder for MockOutbox.
+type MockOutboxMockRecorder struct {
+	mock *MockOutbox
+}
+
+// NewMockOutbox creates a new mock instance.
+func NewMockOutbox(ctrl *gomock.Controller) *MockOutbox {
+	mock := &MockOutbox{ctrl: ctrl}
+	mock.recorder = &MockOutboxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutbox) EXPECT() *MockOutboxMockRecorder {
+	return m.recorder
+}
+
+// ClaimOutbox mocks base method.
+func (m *MockOutbox) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutbox", ctx, now, lease, limit)
+	ret0, _ := ret[0].([]model.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutbox indicates an expected call of ClaimOutbox.
+func (mr *MockOutboxMockRecorder) ClaimOutbox(ctx, now, lease, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutbox", reflect.TypeOf((*MockOutbox)(nil).ClaimOutbox), ctx, now, lease, limit)
+}
+
+// MarkPublished mocks base method.
+func (m *MockOutbox) MarkPublished(ctx context.Context, ids []string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPublished", ctx, ids, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkPublished indicates an expected call of MarkPublished.
+func (mr *MockOutboxMockRecorder) MarkPublished(ctx, ids, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPublished", reflect.TypeOf((*MockOutbox)(nil).MarkPublished), ctx, ids, at)
+}
+
+// PurgeOutbox mocks base method.
+func (m *MockOutbox) PurgeOutbox(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeOutbox", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeOutbox indicates an expected call of PurgeOutbox.
+func (mr *MockOutboxMockRecorder) PurgeOutbox(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeOutbox", reflect.TypeOf((*MockOutbox)(nil).PurgeOutbox), ctx, before)
+}
+
+// SaveURLWithEvent mocks base method.
+func (m *MockOutbox) SaveURLWithEvent(ctx context.Context, data *model.URL, event func(*model.URL) (*model.OutboxMessage, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveURLWithEvent", ctx, data, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveURLWithEvent indicates an expected call of SaveURLWithEvent.
+func (mr *MockOutboxMockRecorder) SaveURLWithEvent(ctx, data, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURLWithEvent", reflect.TypeOf((*MockOutbox)(nil).SaveURLWithEvent), ctx, data, event)
+}
+
+// MockDomains is a mock of Domains interface.
+type MockDomains struct {
+	ctrl     *gomock.Controller
+	recorder *MockDomainsMockRecorder
+	isgomock struct{}
+}
+
+// MockDomainsMockRecorder is the mock recorder for MockDomains.
+type MockDomainsMockRecorder struct {
+	mock *MockDomains
+}
+
+// NewMockDomains creates a new mock instance.
+func NewMockDomains(ctrl *gomock.Controller) *MockDomains {
+	mock := &MockDomains{ctrl: ctrl}
+	mock.recorder = &MockDomainsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDomains) EXPECT() *MockDomainsMockRecorder {
+	return m.recorder
+}
+
+// GetDomain mocks base method.
+func (m *MockDomains) GetDomain(ctx context.Context, name string) (*model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDomain", ctx, name)
+	ret0, _ := ret[0].(*model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDomain indicates an expected call of GetDomain.
+func (mr *MockDomainsMockRecorder) GetDomain(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDomain", reflect.TypeOf((*MockDomains)(nil).GetDomain), ctx, name)
+}
+
+// ListDomains mocks base method.
+func (m *MockDomains) ListDomains(ctx context.Context, owner string) ([]model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDomains", ctx, owner)
+	ret0, _ := ret[0].([]model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDomains indicates an expected call of ListDomains.
+func (mr *MockDomainsMockRecorder) ListDomains(ctx, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDomains", reflect.TypeOf((*MockDomains)(nil).ListDomains), ctx, owner)
+}
+
+// ReplacePendingDomain mocks base method.
+func (m *MockDomains) ReplacePendingDomain(ctx context.Context, domain *model.Domain, registeredBefore time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplacePendingDomain", ctx, domain, registeredBefore)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplacePendingDomain indicates an expected call of ReplacePendingDomain.
+func (mr *MockDomainsMockRecorder) ReplacePendingDomain(ctx, domain, registeredBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplacePendingDomain", reflect.TypeOf((*MockDomains)(nil).ReplacePendingDomain), ctx, domain, registeredBefore)
+}
+
+// SaveDomain mocks base method.
+func (m *MockDomains) SaveDomain(ctx context.Context, domain *model.Domain) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDomain", ctx, domain)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDomain indicates an expected call of SaveDomain.
+func (mr *MockDomainsMockRecorder) SaveDomain(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDomain", reflect.TypeOf((*MockDomains)(nil).SaveDomain), ctx, domain)
+}
+
+// UpdateDomain mocks base method.
+func (m *MockDomains) UpdateDomain(ctx context.Context, domain *model.Domain) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDomain", ctx, domain)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDomain indicates an expected call of UpdateDomain.
+func (mr *MockDomainsMockRecorder) UpdateDomain(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDomain", reflect.TypeOf((*MockDomains)(nil).UpdateDomain), ctx, domain)
+}
+
+// MockWorkspaces is a mock of Workspaces interface.
+type MockWorkspaces struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkspacesMockRecorder
+	isgomock struct{}
+}
+
+// MockWorkspacesMockRecorder is the mock recorder for MockWorkspaces.
+type MockWorkspacesMockRecorder struct {
+	mock *MockWorkspaces
+}
+
+// NewMockWorkspaces creates a new mock instance.
+func NewMockWorkspaces(ctrl *gomock.Controller) *MockWorkspaces {
+	mock := &MockWorkspaces{ctrl: ctrl}
+	mock.recorder = &MockWorkspacesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkspaces) EXPECT() *MockWorkspacesMockRecorder {
+	return m.recorder
+}
+
+// CountURLs mocks base method.
+func (m *MockWorkspaces) CountURLs(ctx context.Context, workspace string, since time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLs", ctx, workspace, since)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLs indicates an expected call of CountURLs.
+func (mr *MockWorkspacesMockRecorder) CountURLs(ctx, workspace, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLs", reflect.TypeOf((*MockWorkspaces)(nil).CountURLs), ctx, workspace, since)
+}
+
+// DeleteAPIKey mocks base method.
+func (m *MockWorkspaces) DeleteAPIKey(ctx context.Context, workspace, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockWorkspacesMockRecorder) DeleteAPIKey(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockWorkspaces)(nil).DeleteAPIKey), ctx, workspace, id)
+}
+
+// DeleteMember mocks base method.
+func (m *MockWorkspaces) DeleteMember(ctx context.Context, workspace, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMember", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMember indicates an expected call of DeleteMember.
+func (mr *MockWorkspacesMockRecorder) DeleteMember(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMember", reflect.TypeOf((*MockWorkspaces)(nil).DeleteMember), ctx, workspace, id)
+}
+
+// GetAPIKey mocks base method.
+func (m *MockWorkspaces) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKey", ctx, id)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKey indicates an expected call of GetAPIKey.
+func (mr *MockWorkspacesMockRecorder) GetAPIKey(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKey", reflect.TypeOf((*MockWorkspaces)(nil).GetAPIKey), ctx, id)
+}
+
+// GetMember mocks base method.
+func (m *MockWorkspaces) GetMember(ctx context.Context, workspace, id string) (*model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMember", ctx, workspace, id)
+	ret0, _ := ret[0].(*model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMember indicates an expected call of GetMember.
+func (mr *MockWorkspacesMockRecorder) GetMember(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMember", reflect.TypeOf((*MockWorkspaces)(nil).GetMember), ctx, workspace, id)
+}
+
+// GetWorkspace mocks base method.
+func (m *MockWorkspaces) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkspace", ctx, id)
+	ret0, _ := ret[0].(*model.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkspace indicates an expected call of GetWorkspace.
+func (mr *MockWorkspacesMockRecorder) GetWorkspace(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkspace", reflect.TypeOf((*MockWorkspaces)(nil).GetWorkspace), ctx, id)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockWorkspaces) ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, workspace)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockWorkspacesMockRecorder) ListAPIKeys(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockWorkspaces)(nil).ListAPIKeys), ctx, workspace)
+}
+
+// ListMembers mocks base method.
+func (m *MockWorkspaces) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMembers", ctx, workspace)
+	ret0, _ := ret[0].([]model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMembers indicates an expected call of ListMembers.
+func (mr *MockWorkspacesMockRecorder) ListMembers(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMembers", reflect.TypeOf((*MockWorkspaces)(nil).ListMembers), ctx, workspace)
+}
+
+// LockWorkspace mocks base method.
+func (m *MockWorkspaces) LockWorkspace(ctx context.Context, workspace string, ttl time.Duration) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockWorkspace", ctx, workspace, ttl)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockWorkspace indicates an expected call of LockWorkspace.
+func (mr *MockWorkspacesMockRecorder) LockWorkspace(ctx, workspace, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWorkspace", reflect.TypeOf((*MockWorkspaces)(nil).LockWorkspace), ctx, workspace, ttl)
+}
+
+// SaveAPIKey mocks base method.
+func (m *MockWorkspaces) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAPIKey indicates an expected call of SaveAPIKey.
+func (mr *MockWorkspacesMockRecorder) SaveAPIKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAPIKey", reflect.TypeOf((*MockWorkspaces)(nil).SaveAPIKey), ctx, key)
+}
+
+// SaveMember mocks base method.
+func (m *MockWorkspaces) SaveMember(ctx context.Context, member *model.Member) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMember", ctx, member)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMember indicates an expected call of SaveMember.
+func (mr *MockWorkspacesMockRecorder) SaveMember(ctx, member any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMember", reflect.TypeOf((*MockWorkspaces)(nil).SaveMember), ctx, member)
+}
+
+// SaveWorkspace mocks base method.
+func (m *MockWorkspaces) SaveWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWorkspace", ctx, workspace)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWorkspace indicates an expected call of SaveWorkspace.
+func (mr *MockWorkspacesMockRecorder) SaveWorkspace(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWorkspace", reflect.TypeOf((*MockWorkspaces)(nil).SaveWorkspace), ctx, workspace)
+}
+
+// UpdateMember mocks base method.
+func (m *MockWorkspaces) UpdateMember(ctx context.Context, member *model.Member) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMember", ctx, member)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMember indicates an expected call of UpdateMember.
+func (mr *MockWorkspacesMockRecorder) UpdateMember(ctx, member any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMember", reflect.TypeOf((*MockWorkspaces)(nil).UpdateMember), ctx, member)
+}
+
+// UpdateWorkspace mocks base method.
+func (m *MockWorkspaces) UpdateWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWorkspace", ctx, workspace)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWorkspace indicates an expected call of UpdateWorkspace.
+func (mr *MockWorkspacesMockRecorder) UpdateWorkspace(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWorkspace", reflect.TypeOf((*MockWorkspaces)(nil).UpdateWorkspace), ctx, workspace)
+}
+
+// MockCounterBlocks is a mock of CounterBlocks interface.
+type MockCounterBlocks struct {
+	ctrl     *gomock.Controller
+	recorder *MockCounterBlocksMockRecorder
+	isgomock struct{}
+}
+
+// MockCounterBlocksMockRecorder is the mock recorder for MockCounterBlocks.
+type MockCounterBlocksMockRecorder struct {
+	mock *MockCounterBlocks
+}
+
+// NewMockCounterBlocks creates a new mock instance.
+func NewMockCounterBlocks(ctrl *gomock.Controller) *MockCounterBlocks {
+	mock := &MockCounterBlocks{ctrl: ctrl}
+	mock.recorder = &MockCounterBlocksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCounterBlocks) EXPECT() *MockCounterBlocksMockRecorder {
+	return m.recorder
+}
+
+// ReserveCounters mocks base method.
+func (m *MockCounterBlocks) ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveCounters", ctx, n)
+	ret0, _ := ret[0].([]model.CounterRange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveCounters indicates an expected call of ReserveCounters.
+func (mr *MockCounterBlocksMockRecorder) ReserveCounters(ctx, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveCounters", reflect.TypeOf((*MockCounterBlocks)(nil).ReserveCounters), ctx, n)
+}
+
+// MockDurableCounter is a mock of DurableCounter interface.
+type MockDurableCounter struct {
+	ctrl     *gomock.Controller
+	recorder *MockDurableCounterMockRecorder
+	isgomock struct{}
+}
+
+// MockDurableCounterMockRecorder is the mock recorder for MockDurableCounter.
+type MockDurableCounterMockRecorder struct {
+	mock *MockDurableCounter
+}
+
+// NewMockDurableCounter creates a new mock instance.
+func NewMockDurableCounter(ctrl *gomock.Controller) *MockDurableCounter {
+	mock := &MockDurableCounter{ctrl: ctrl}
+	mock.recorder = &MockDurableCounterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDurableCounter) EXPECT() *MockDurableCounterMockRecorder {
+	return m.recorder
+}
+
+// AdvanceCounter mocks base method.
+func (m *MockDurableCounter) AdvanceCounter(ctx context.Context, value uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceCounter", ctx, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdvanceCounter indicates an expected call of AdvanceCounter.
+func (mr *MockDurableCounterMockRecorder) AdvanceCounter(ctx, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceCounter", reflect.TypeOf((*MockDurableCounter)(nil).AdvanceCounter), ctx, value)
+}
+
+// CurrentCounter mocks base method.
+func (m *MockDurableCounter) CurrentCounter(ctx context.Context) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentCounter", ctx)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentCounter indicates an expected call of CurrentCounter.
+func (mr *MockDurableCounterMockRecorder) CurrentCounter(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentCounter", reflect.TypeOf((*MockDurableCounter)(nil).CurrentCounter), ctx)
+}
+
+// ReserveCounters mocks base method.
+func (m *MockDurableCounter) ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveCounters", ctx, n)
+	ret0, _ := ret[0].([]model.CounterRange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveCounters indicates an expected call of ReserveCounters.
+func (mr *MockDurableCounterMockRecorder) ReserveCounters(ctx, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveCounters", reflect.TypeOf((*MockDurableCounter)(nil).ReserveCounters), ctx, n)
+}
+
+// MockWorkerLeases is a mock of WorkerLeases interface.
+type MockWorkerLeases struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkerLeasesMockRecorder
+	isgomock struct{}
+}
+
+// MockWorkerLeasesMockRecorder is the mock recorder for MockWorkerLeases.
+type MockWorkerLeasesMockRecorder struct {
+	mock *MockWorkerLeases
+}
+
+// NewMockWorkerLeases creates a new mock instance.
+func NewMockWorkerLeases(ctrl *gomock.Controller) *MockWorkerLeases {
+	mock := &MockWorkerLeases{ctrl: ctrl}
+	mock.recorder = &MockWorkerLeasesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkerLeases) EXPECT() *MockWorkerLeasesMockRecorder {
+	return m.recorder
+}
+
+// AcquireWorker mocks base method.
+func (m *MockWorkerLeases) AcquireWorker(ctx context.Context, owner string, workers int, ttl time.Duration) (*model.WorkerLease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireWorker", ctx, owner, workers, ttl)
+	ret0, _ := ret[0].(*model.WorkerLease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireWorker indicates an expected call of AcquireWorker.
+func (mr *MockWorkerLeasesMockRecorder) AcquireWorker(ctx, owner, workers, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireWorker", reflect.TypeOf((*MockWorkerLeases)(nil).AcquireWorker), ctx, owner, workers, ttl)
+}
+
+// ReleaseWorker mocks base method.
+func (m *MockWorkerLeases) ReleaseWorker(ctx context.Context, lease *model.WorkerLease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseWorker", ctx, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseWorker indicates an expected call of ReleaseWorker.
+func (mr *MockWorkerLeasesMockRecorder) ReleaseWorker(ctx, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseWorker", reflect.TypeOf((*MockWorkerLeases)(nil).ReleaseWorker), ctx, lease)
+}
+
+// RenewWorker mocks base method.
+func (m *MockWorkerLeases) RenewWorker(ctx context.Context, lease *model.WorkerLease, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewWorker", ctx, lease, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewWorker indicates an expected call of RenewWorker.
+func (mr *MockWorkerLeasesMockRecorder) RenewWorker(ctx, lease, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewWorker", reflect.TypeOf((*MockWorkerLeases)(nil).RenewWorker), ctx, lease, ttl)
+}
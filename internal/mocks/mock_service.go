@@ -41,19 +41,374 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// AddMember mocks base method.
+func (m *MockService) AddMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMember", ctx, member)
+	ret0, _ := ret[0].(*model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMember indicates an expected call of AddMember.
+func (mr *MockServiceMockRecorder) AddMember(ctx, member any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMember", reflect.TypeOf((*MockService)(nil).AddMember), ctx, member)
+}
+
+// AliasAvailability mocks base method.
+func (m *MockService) AliasAvailability(ctx context.Context, domain, alias string) (*model.AliasAvailability, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AliasAvailability", ctx, domain, alias)
+	ret0, _ := ret[0].(*model.AliasAvailability)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AliasAvailability indicates an expected call of AliasAvailability.
+func (mr *MockServiceMockRecorder) AliasAvailability(ctx, domain, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AliasAvailability", reflect.TypeOf((*MockService)(nil).AliasAvailability), ctx, domain, alias)
+}
+
+// AuditLog mocks base method.
+func (m *MockService) AuditLog(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLog", ctx, filter)
+	ret0, _ := ret[0].([]model.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditLog indicates an expected call of AuditLog.
+func (mr *MockServiceMockRecorder) AuditLog(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLog", reflect.TypeOf((*MockService)(nil).AuditLog), ctx, filter)
+}
+
+// Authenticate mocks base method.
+func (m *MockService) Authenticate(ctx context.Context, key string) (*model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, key)
+	ret0, _ := ret[0].(*model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockServiceMockRecorder) Authenticate(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockService)(nil).Authenticate), ctx, key)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockService) CreateAPIKey(ctx context.Context, workspace, member, name string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, workspace, member, name)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockServiceMockRecorder) CreateAPIKey(ctx, workspace, member, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockService)(nil).CreateAPIKey), ctx, workspace, member, name)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockService) CreateWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, hook)
+	ret0, _ := ret[0].(*model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockServiceMockRecorder) CreateWebhook(ctx, hook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockService)(nil).CreateWebhook), ctx, hook)
+}
+
+// CreateWorkspace mocks base method.
+func (m *MockService) CreateWorkspace(ctx context.Context, workspace *model.Workspace, owner string) (*model.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWorkspace", ctx, workspace, owner)
+	ret0, _ := ret[0].(*model.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWorkspace indicates an expected call of CreateWorkspace.
+func (mr *MockServiceMockRecorder) CreateWorkspace(ctx, workspace, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWorkspace", reflect.TypeOf((*MockService)(nil).CreateWorkspace), ctx, workspace, owner)
+}
+
+// DeleteAPIKey mocks base method.
+func (m *MockService) DeleteAPIKey(ctx context.Context, workspace, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockServiceMockRecorder) DeleteAPIKey(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockService)(nil).DeleteAPIKey), ctx, workspace, id)
+}
+
+// DeleteURL mocks base method.
+func (m *MockService) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteURL", ctx, domain, shortURL)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteURL indicates an expected call of DeleteURL.
+func (mr *MockServiceMockRecorder) DeleteURL(ctx, domain, shortURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockService)(nil).DeleteURL), ctx, domain, shortURL)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockService) DeleteWebhook(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockServiceMockRecorder) DeleteWebhook(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockService)(nil).DeleteWebhook), ctx, id)
+}
+
+// DomainForHost mocks base method.
+func (m *MockService) DomainForHost(ctx context.Context, host string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DomainForHost", ctx, host)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// DomainForHost indicates an expected call of DomainForHost.
+func (mr *MockServiceMockRecorder) DomainForHost(ctx, host any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DomainForHost", reflect.TypeOf((*MockService)(nil).DomainForHost), ctx, host)
+}
+
 // GetURL mocks base method.
-func (m *MockService) GetURL(ctx context.Context, shortURL string) (*model.URL, error) {
+func (m *MockService) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", ctx, shortURL)
+	ret := m.ctrl.Call(m, "GetURL", ctx, domain, shortURL)
 	ret0, _ := ret[0].(*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetURL indicates an expected call of GetURL.
-func (mr *MockServiceMockRecorder) GetURL(ctx, shortURL any) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetURL(ctx, domain, shortURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockService)(nil).GetURL), ctx, domain, shortURL)
+}
+
+// GetWorkspace mocks base method.
+func (m *MockService) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkspace", ctx, id)
+	ret0, _ := ret[0].(*model.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkspace indicates an expected call of GetWorkspace.
+func (mr *MockServiceMockRecorder) GetWorkspace(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkspace", reflect.TypeOf((*MockService)(nil).GetWorkspace), ctx, id)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockService) ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, workspace)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockServiceMockRecorder) ListAPIKeys(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockService)(nil).ListAPIKeys), ctx, workspace)
+}
+
+// ListDomains mocks base method.
+func (m *MockService) ListDomains(ctx context.Context, owner string) ([]model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDomains", ctx, owner)
+	ret0, _ := ret[0].([]model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDomains indicates an expected call of ListDomains.
+func (mr *MockServiceMockRecorder) ListDomains(ctx, owner any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockService)(nil).GetURL), ctx, shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDomains", reflect.TypeOf((*MockService)(nil).ListDomains), ctx, owner)
+}
+
+// ListMembers mocks base method.
+func (m *MockService) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMembers", ctx, workspace)
+	ret0, _ := ret[0].([]model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMembers indicates an expected call of ListMembers.
+func (mr *MockServiceMockRecorder) ListMembers(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMembers", reflect.TypeOf((*MockService)(nil).ListMembers), ctx, workspace)
+}
+
+// ListTags mocks base method.
+func (m *MockService) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", ctx)
+	ret0, _ := ret[0].([]model.TagCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockServiceMockRecorder) ListTags(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockService)(nil).ListTags), ctx)
+}
+
+// ListURLs mocks base method.
+func (m *MockService) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, filter)
+	ret0, _ := ret[0].([]model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockServiceMockRecorder) ListURLs(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockService)(nil).ListURLs), ctx, filter)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockService) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockServiceMockRecorder) ListWebhooks(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockService)(nil).ListWebhooks), ctx)
+}
+
+// MergeTags mocks base method.
+func (m *MockService) MergeTags(ctx context.Context, tags []string, into string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeTags", ctx, tags, into)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeTags indicates an expected call of MergeTags.
+func (mr *MockServiceMockRecorder) MergeTags(ctx, tags, into any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeTags", reflect.TypeOf((*MockService)(nil).MergeTags), ctx, tags, into)
+}
+
+// RedeliverWebhook mocks base method.
+func (m *MockService) RedeliverWebhook(ctx context.Context, id, delivery string) (*model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeliverWebhook", ctx, id, delivery)
+	ret0, _ := ret[0].(*model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeliverWebhook indicates an expected call of RedeliverWebhook.
+func (mr *MockServiceMockRecorder) RedeliverWebhook(ctx, id, delivery any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeliverWebhook", reflect.TypeOf((*MockService)(nil).RedeliverWebhook), ctx, id, delivery)
+}
+
+// RegisterDomain mocks base method.
+func (m *MockService) RegisterDomain(ctx context.Context, name, owner string) (*model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterDomain", ctx, name, owner)
+	ret0, _ := ret[0].(*model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterDomain indicates an expected call of RegisterDomain.
+func (mr *MockServiceMockRecorder) RegisterDomain(ctx, name, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterDomain", reflect.TypeOf((*MockService)(nil).RegisterDomain), ctx, name, owner)
+}
+
+// RemoveMember mocks base method.
+func (m *MockService) RemoveMember(ctx context.Context, workspace, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockServiceMockRecorder) RemoveMember(ctx, workspace, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockService)(nil).RemoveMember), ctx, workspace, id)
+}
+
+// RenameTag mocks base method.
+func (m *MockService) RenameTag(ctx context.Context, tag, to string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameTag", ctx, tag, to)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameTag indicates an expected call of RenameTag.
+func (mr *MockServiceMockRecorder) RenameTag(ctx, tag, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameTag", reflect.TypeOf((*MockService)(nil).RenameTag), ctx, tag, to)
+}
+
+// RollbackURL mocks base method.
+func (m *MockService) RollbackURL(ctx context.Context, domain, shortURL string, version int) (*model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackURL", ctx, domain, shortURL, version)
+	ret0, _ := ret[0].(*model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RollbackURL indicates an expected call of RollbackURL.
+func (mr *MockServiceMockRecorder) RollbackURL(ctx, domain, shortURL, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackURL", reflect.TypeOf((*MockService)(nil).RollbackURL), ctx, domain, shortURL, version)
 }
 
 // SaveURL mocks base method.
@@ -70,3 +425,123 @@ func (mr *MockServiceMockRecorder) SaveURL(ctx, data any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockService)(nil).SaveURL), ctx, data)
 }
+
+// URLHistory mocks base method.
+func (m *MockService) URLHistory(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "URLHistory", ctx, domain, shortURL)
+	ret0, _ := ret[0].([]model.URLVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// URLHistory indicates an expected call of URLHistory.
+func (mr *MockServiceMockRecorder) URLHistory(ctx, domain, shortURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URLHistory", reflect.TypeOf((*MockService)(nil).URLHistory), ctx, domain, shortURL)
+}
+
+// UpdateMember mocks base method.
+func (m *MockService) UpdateMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMember", ctx, member)
+	ret0, _ := ret[0].(*model.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMember indicates an expected call of UpdateMember.
+func (mr *MockServiceMockRecorder) UpdateMember(ctx, member any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMember", reflect.TypeOf((*MockService)(nil).UpdateMember), ctx, member)
+}
+
+// UpdateURL mocks base method.
+func (m *MockService) UpdateURL(ctx context.Context, domain, shortURL string, update model.URLUpdate) (*model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, domain, shortURL, update)
+	ret0, _ := ret[0].(*model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockServiceMockRecorder) UpdateURL(ctx, domain, shortURL, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockService)(nil).UpdateURL), ctx, domain, shortURL, update)
+}
+
+// UpdateWorkspace mocks base method.
+func (m *MockService) UpdateWorkspace(ctx context.Context, workspace *model.Workspace) (*model.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWorkspace", ctx, workspace)
+	ret0, _ := ret[0].(*model.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWorkspace indicates an expected call of UpdateWorkspace.
+func (mr *MockServiceMockRecorder) UpdateWorkspace(ctx, workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWorkspace", reflect.TypeOf((*MockService)(nil).UpdateWorkspace), ctx, workspace)
+}
+
+// VerifyAuditLog mocks base method.
+func (m *MockService) VerifyAuditLog(ctx context.Context) (*model.AuditVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAuditLog", ctx)
+	ret0, _ := ret[0].(*model.AuditVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAuditLog indicates an expected call of VerifyAuditLog.
+func (mr *MockServiceMockRecorder) VerifyAuditLog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAuditLog", reflect.TypeOf((*MockService)(nil).VerifyAuditLog), ctx)
+}
+
+// VerifyDomain mocks base method.
+func (m *MockService) VerifyDomain(ctx context.Context, name string) (*model.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyDomain", ctx, name)
+	ret0, _ := ret[0].(*model.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyDomain indicates an expected call of VerifyDomain.
+func (mr *MockServiceMockRecorder) VerifyDomain(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDomain", reflect.TypeOf((*MockService)(nil).VerifyDomain), ctx, name)
+}
+
+// WebhookDeliveries mocks base method.
+func (m *MockService) WebhookDeliveries(ctx context.Context, id string, limit int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WebhookDeliveries", ctx, id, limit)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WebhookDeliveries indicates an expected call of WebhookDeliveries.
+func (mr *MockServiceMockRecorder) WebhookDeliveries(ctx, id, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WebhookDeliveries", reflect.TypeOf((*MockService)(nil).WebhookDeliveries), ctx, id, limit)
+}
+
+// WorkspaceUsage mocks base method.
+func (m *MockService) WorkspaceUsage(ctx context.Context, id string) (*model.WorkspaceUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WorkspaceUsage", ctx, id)
+	ret0, _ := ret[0].(*model.WorkspaceUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WorkspaceUsage indicates an expected call of WorkspaceUsage.
+func (mr *MockServiceMockRecorder) WorkspaceUsage(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WorkspaceUsage", reflect.TypeOf((*MockService)(nil).WorkspaceUsage), ctx, id)
+}
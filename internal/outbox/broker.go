@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// NATSPublisher publishes to NATS JetStream, e.g. a small adapter over a jetstream.JetStream. msgID is sent as
+// the Nats-Msg-Id header: JetStream drops a message whose ID it saw within its duplicate window.
+type NATSPublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, msgID string) error
+}
+
+// NATS is a transport publishing each message to the subject of its type under a prefix, e.g.
+// links.link.created, with the message ID as the JetStream message ID.
+type NATS struct {
+	publisher NATSPublisher
+	prefix    string
+}
+
+var _ Transport = &NATS{}
+
+// NewNATS returns a transport publishing with publisher under the subject prefix.
+func NewNATS(publisher NATSPublisher, prefix string) *NATS {
+	return &NATS{publisher: publisher, prefix: prefix}
+}
+
+// Send publishes the payload of the message.
+func (n *NATS) Send(ctx context.Context, msg model.OutboxMessage) error {
+	return n.publisher.Publish(ctx, n.prefix+"."+msg.Type, msg.Payload, msg.ID)
+}
+
+// KafkaMessage is a record written to a Kafka topic.
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaWriter writes records to Kafka, e.g. a small adapter over a kafka-go Writer. Writes should be
+// acknowledged by the in-sync replicas, a record that may be lost is sent again only if the write fails.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// Kafka is a transport writing each message to a topic, keyed by the link so its events stay in order on one
+// partition. The message ID and type are the id and type headers.
+type Kafka struct {
+	writer KafkaWriter
+	topic  string
+}
+
+var _ Transport = &Kafka{}
+
+// NewKafka returns a transport writing to topic with writer.
+func NewKafka(writer KafkaWriter, topic string) *Kafka {
+	return &Kafka{writer: writer, topic: topic}
+}
+
+// Send writes the payload of the message.
+func (k *Kafka) Send(ctx context.Context, msg model.OutboxMessage) error {
+	return k.writer.WriteMessages(ctx, KafkaMessage{
+		Topic:   k.topic,
+		Key:     []byte(msg.Key),
+		Value:   msg.Payload,
+		Headers: map[string]string{"id": msg.ID, "type": msg.Type},
+	})
+}
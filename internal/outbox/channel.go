@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// delivery is a message sent over a Channel with where its handler's result goes.
+type delivery struct {
+	msg  model.OutboxMessage
+	done chan error
+}
+
+// Channel is an in-process transport. Send hands the message to the goroutine running Consume and waits for
+// the handler, so a message is only published once it was handled.
+type Channel struct {
+	deliveries chan delivery
+}
+
+var _ Transport = &Channel{}
+
+// NewChannel returns a Channel, messages are sent once Consume runs.
+func NewChannel() *Channel {
+	return &Channel{deliveries: make(chan delivery)}
+}
+
+// Send waits for the consumer to handle the message and returns its error.
+func (c *Channel) Send(ctx context.Context, msg model.OutboxMessage) error {
+	d := delivery{msg: msg, done: make(chan error, 1)}
+	select {
+	case c.deliveries <- d:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-d.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Consume calls handler with every message sent until ctx is done.
+func (c *Channel) Consume(ctx context.Context, handler Handler) {
+	for {
+		select {
+		case d := <-c.deliveries:
+			d.done <- handler(ctx, d.msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
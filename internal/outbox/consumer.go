@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// Processed remembers the messages each consumer handled, by consumer name and message ID.
+type Processed interface {
+	// Seen reports whether the consumer handled the message.
+	Seen(ctx context.Context, consumer, id string) (bool, error)
+	// Mark records that the consumer handled the message.
+	Mark(ctx context.Context, consumer, id string) error
+}
+
+// Idempotent wraps the handler of a consumer so messages it already handled are skipped. Messages are sent at
+// least once, so each consumer needs a name of its own: the same message is handled once per name.
+// A consumer stopping between handling a message and marking it handles it again.
+func Idempotent(consumer string, processed Processed, handler Handler) Handler {
+	return func(ctx context.Context, msg model.OutboxMessage) error {
+		seen, err := processed.Seen(ctx, consumer, msg.ID)
+		if err != nil {
+			return fmt.Errorf("outbox: failed to check %q processed %q: %w", consumer, msg.ID, err)
+		}
+		if seen {
+			return nil
+		}
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		if err := processed.Mark(ctx, consumer, msg.ID); err != nil {
+			return fmt.Errorf("outbox: failed to mark %q processed %q: %w", consumer, msg.ID, err)
+		}
+		return nil
+	}
+}
+
+// PublishTo returns a handler publishing the event of each message, e.g. to the in-process events.Bus.
+func PublishTo(publisher events.Publisher) Handler {
+	return func(ctx context.Context, msg model.OutboxMessage) error {
+		var event events.Event
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return fmt.Errorf("outbox: failed to decode %s %q: %w", msg.Type, msg.ID, err)
+		}
+		publisher.Publish(ctx, event)
+		return nil
+	}
+}
+
+// MemoryProcessed keeps the latest processed message IDs in memory, for consumers in the relay's process.
+type MemoryProcessed struct {
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	order    []string // Oldest first, to forget when full.
+	capacity int
+}
+
+var _ Processed = &MemoryProcessed{}
+
+// NewMemoryProcessed returns a MemoryProcessed remembering up to capacity IDs of all consumers together.
+func NewMemoryProcessed(capacity int) *MemoryProcessed {
+	return &MemoryProcessed{seen: make(map[string]struct{}), capacity: max(capacity, 1)}
+}
+
+// Seen reports whether the ID is among the latest marked.
+func (p *MemoryProcessed) Seen(_ context.Context, consumer, id string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.seen[consumer+"\x00"+id]
+	return ok, nil
+}
+
+// Mark remembers the ID, forgetting the oldest one when full.
+func (p *MemoryProcessed) Mark(_ context.Context, consumer, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := consumer + "\x00" + id
+	if _, ok := p.seen[key]; ok {
+		return nil
+	}
+	if len(p.order) == p.capacity {
+		delete(p.seen, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.seen[key] = struct{}{}
+	p.order = append(p.order, key)
+	return nil
+}
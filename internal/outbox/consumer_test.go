@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotent(t *testing.T) {
+	t.Parallel()
+	processed := NewMemoryProcessed(10)
+	calls := map[string]int{}
+	fail := true
+	handler := func(name string) Handler {
+		return func(_ context.Context, msg model.OutboxMessage) error {
+			calls[name+"/"+msg.ID]++
+			if msg.ID == "flaky" && fail {
+				return errors.New("try again")
+			}
+			return nil
+		}
+	}
+	search := Idempotent("search", processed, handler("search"))
+	billing := Idempotent("billing", processed, handler("billing"))
+
+	for range 3 {
+		require.NoError(t, search(context.Background(), model.OutboxMessage{ID: "m1"}))
+	}
+	require.NoError(t, billing(context.Background(), model.OutboxMessage{ID: "m1"}))
+	assert.Equal(t, 1, calls["search/m1"], "handled once per consumer")
+	assert.Equal(t, 1, calls["billing/m1"], "consumers are independent")
+
+	require.Error(t, search(context.Background(), model.OutboxMessage{ID: "flaky"}))
+	fail = false
+	require.NoError(t, search(context.Background(), model.OutboxMessage{ID: "flaky"}))
+	require.NoError(t, search(context.Background(), model.OutboxMessage{ID: "flaky"}))
+	assert.Equal(t, 2, calls["search/flaky"], "failures aren't marked")
+}
+
+func TestMemoryProcessed_Capacity(t *testing.T) {
+	t.Parallel()
+	processed := NewMemoryProcessed(2)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "b", "c"} {
+		require.NoError(t, processed.Mark(ctx, "search", id))
+	}
+
+	seen, _ := processed.Seen(ctx, "search", "a")
+	assert.False(t, seen, "the oldest is forgotten")
+	for _, id := range []string{"b", "c"} {
+		seen, _ = processed.Seen(ctx, "search", id)
+		assert.True(t, seen, id)
+	}
+}
+
+func TestRedisProcessed(t *testing.T) {
+	t.Parallel()
+	client, mock := redismock.NewClientMock()
+	mock.ExpectExists("outbox:processed:search:m1").SetVal(0)
+	mock.ExpectSet("outbox:processed:search:m1", 1, 48*time.Hour).SetVal("OK")
+	mock.ExpectExists("outbox:processed:search:m1").SetVal(1)
+
+	processed := NewRedisProcessed(client, 48*time.Hour)
+	seen, err := processed.Seen(context.Background(), "search", "m1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+	require.NoError(t, processed.Mark(context.Background(), "search", "m1"))
+	seen, err = processed.Seen(context.Background(), "search", "m1")
+	require.NoError(t, err)
+	assert.True(t, seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishTo(t *testing.T) {
+	t.Parallel()
+	bus := events.NewBus()
+	var received []events.Event
+	bus.Subscribe(func(_ context.Context, event events.Event) { received = append(received, event) })
+	handler := PublishTo(bus)
+
+	err := handler(context.Background(), model.OutboxMessage{ID: "m1",
+		Payload: []byte(`{"id":"m1","type":"link.created","shortURL":"abc"}`)})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, "m1", received[0].ID, "the bus keeps the event ID")
+	assert.Equal(t, events.LinkCreated, received[0].Type)
+
+	err = handler(context.Background(), model.OutboxMessage{ID: "m2", Payload: []byte(`not json`)})
+	assert.ErrorContains(t, err, `"m2"`)
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// NATSMessage is a message published to FakeNATS.
+type NATSMessage struct {
+	Subject string
+	Data    []byte
+	MsgID   string
+}
+
+// FakeNATS is an in-memory NATSPublisher for local runs and tests. Like JetStream, it drops a message whose
+// ID it already has. Publish returns Err when set.
+type FakeNATS struct {
+	mu       sync.Mutex
+	messages []NATSMessage
+	Err      error
+}
+
+var _ NATSPublisher = &FakeNATS{}
+
+// Publish stores the message unless its ID was published before.
+func (f *FakeNATS) Publish(_ context.Context, subject string, data []byte, msgID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	if slices.ContainsFunc(f.messages, func(m NATSMessage) bool { return m.MsgID == msgID }) {
+		return nil
+	}
+	f.messages = append(f.messages, NATSMessage{Subject: subject, Data: slices.Clone(data), MsgID: msgID})
+	return nil
+}
+
+// Messages returns the messages published, oldest first.
+func (f *FakeNATS) Messages() []NATSMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.messages)
+}
+
+// FakeKafka is an in-memory KafkaWriter for local runs and tests. It keeps every record written, duplicates
+// too, like a topic. WriteMessages returns Err when set.
+type FakeKafka struct {
+	mu       sync.Mutex
+	messages []KafkaMessage
+	Err      error
+}
+
+var _ KafkaWriter = &FakeKafka{}
+
+// WriteMessages appends the records.
+func (f *FakeKafka) WriteMessages(_ context.Context, msgs ...KafkaMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+// Messages returns the records written, oldest first.
+func (f *FakeKafka) Messages() []KafkaMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.messages)
+}
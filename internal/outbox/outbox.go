@@ -0,0 +1,172 @@
+// Package outbox publishes events stored in the transaction of the change they record, so a change is never
+// saved without its event or the other way around. A Relay reads the stored messages and sends them to pluggable
+// transports at least once, consumers skip the messages they already processed by their ID.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// Transport sends a message to its consumers. The message is sent again if Send fails, or if the relay stops
+// before recording that it was sent.
+type Transport interface {
+	Send(ctx context.Context, msg model.OutboxMessage) error
+}
+
+// Handler processes a message received from a transport.
+type Handler func(ctx context.Context, msg model.OutboxMessage) error
+
+// multi sends to several transports.
+type multi []Transport
+
+// Multi returns a transport sending every message to each transport in order. A message is sent to all of them
+// again if one fails.
+func Multi(transports ...Transport) Transport {
+	if len(transports) == 1 {
+		return transports[0]
+	}
+	return multi(transports)
+}
+
+// Send sends the message to each transport, stopping at the first error.
+func (m multi) Send(ctx context.Context, msg model.OutboxMessage) error {
+	for _, transport := range m {
+		if err := transport.Send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelayConfig tunes a Relay, zero fields take the defaults.
+type RelayConfig struct {
+	Interval    time.Duration // Between checks for new messages, 1s by default.
+	Lease       time.Duration // Before a message claimed by a relay that didn't send it is sent again, 30s by default.
+	SendTimeout time.Duration // For each send, 10s by default.
+	BatchSize   int           // Messages claimed at once, 100 by default.
+	Retention   time.Duration // How long published messages are kept, 24h by default.
+}
+
+const (
+	defaultRelayInterval = time.Second
+	defaultLease         = 30 * time.Second
+	defaultSendTimeout   = 10 * time.Second
+	defaultBatchSize     = 100
+	defaultRetention     = 24 * time.Hour
+	purgeInterval        = time.Hour
+)
+
+// Relay publishes the unpublished messages of the outbox, oldest first. Relays of several instances share the
+// work, each message is claimed by one of them for the lease. A message that can't be sent stops the batch, so
+// the messages after it aren't sent before it; they're all claimed again once the lease is over.
+type Relay struct {
+	store     repository.Outbox
+	transport Transport
+	cfg       RelayConfig
+	now       func() time.Time
+	lastPurge time.Time
+}
+
+// NewRelay returns a Relay publishing the messages of store with transport.
+func NewRelay(store repository.Outbox, transport Transport, cfg RelayConfig) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRelayInterval
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultLease
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = defaultSendTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+	return &Relay{store: store, transport: transport, cfg: cfg, now: time.Now}
+}
+
+// Run publishes the pending messages every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.PublishPending(ctx); err != nil && ctx.Err() == nil {
+			l.Logger.Error("outbox relay round failed", "error", err)
+		}
+		if err := r.purge(ctx); err != nil && ctx.Err() == nil {
+			l.Logger.Error("outbox purge failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending claims and sends batches of messages until none are left or a send fails.
+func (r *Relay) PublishPending(ctx context.Context) error {
+	for {
+		messages, err := r.store.ClaimOutbox(ctx, r.now(), r.cfg.Lease, r.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("outbox: failed to claim messages: %w", err)
+		}
+
+		sent := make([]string, 0, len(messages))
+		var sendErr error
+		for _, msg := range messages {
+			if sendErr = r.send(ctx, msg); sendErr != nil {
+				break
+			}
+			sent = append(sent, msg.ID)
+		}
+		if len(sent) > 0 {
+			// Recorded even while stopping, the sent messages would be sent again otherwise.
+			markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			err := r.store.MarkPublished(markCtx, sent, r.now())
+			cancel()
+			if err != nil {
+				return fmt.Errorf("outbox: failed to mark %d messages published: %w", len(sent), err)
+			}
+		}
+		if sendErr != nil || len(messages) < r.cfg.BatchSize {
+			return sendErr
+		}
+	}
+}
+
+// send sends the message with the send timeout.
+func (r *Relay) send(ctx context.Context, msg model.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.SendTimeout)
+	defer cancel()
+	if err := r.transport.Send(ctx, msg); err != nil {
+		return fmt.Errorf("outbox: failed to send %s %q (attempt %d): %w", msg.Type, msg.ID, msg.Attempts, err)
+	}
+	return nil
+}
+
+// purge deletes the messages published before the retention, at most once per purge interval.
+func (r *Relay) purge(ctx context.Context) error {
+	now := r.now()
+	if now.Sub(r.lastPurge) < purgeInterval {
+		return nil
+	}
+	r.lastPurge = now
+	n, err := r.store.PurgeOutbox(ctx, now.Add(-r.cfg.Retention))
+	if err != nil {
+		return fmt.Errorf("outbox: failed to purge published messages: %w", err)
+	}
+	if n > 0 {
+		l.Logger.Info("purged published outbox messages", "count", n)
+	}
+	return nil
+}
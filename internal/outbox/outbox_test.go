@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a transport recording the IDs it was sent, failing the ones in fail.
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+	fail map[string]error
+}
+
+func (r *recorder) Send(_ context.Context, msg model.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.fail[msg.ID]; err != nil {
+		return err
+	}
+	r.sent = append(r.sent, msg.ID)
+	return nil
+}
+
+func (r *recorder) Sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sent...)
+}
+
+// saveLinks saves n links with their messages m1, m2, ... due at now.
+func saveLinks(t *testing.T, repo *repository.InMemoryRepo, n int, now time.Time) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		err := repo.SaveURLWithEvent(context.Background(), &model.URL{ShortURL: fmt.Sprint("s", i)},
+			func(data *model.URL) (*model.OutboxMessage, error) {
+				return &model.OutboxMessage{ID: fmt.Sprint("m", i), Type: "link.created", Key: data.ShortURL,
+					NextAttemptAt: now, CreatedAt: now}, nil
+			})
+		require.NoError(t, err)
+	}
+}
+
+func TestRelay_PublishPending(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	saveLinks(t, repo, 5, now)
+
+	transport := &recorder{}
+	relay := NewRelay(repo, transport, RelayConfig{BatchSize: 2})
+	relay.now = func() time.Time { return now }
+
+	require.NoError(t, relay.PublishPending(context.Background()))
+	assert.Equal(t, []string{"m1", "m2", "m3", "m4", "m5"}, transport.Sent(), "every batch, in order")
+
+	require.NoError(t, relay.PublishPending(context.Background()))
+	assert.Len(t, transport.Sent(), 5, "published messages aren't sent again")
+}
+
+func TestRelay_RetriesAfterLease(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	saveLinks(t, repo, 3, now)
+
+	transport := &recorder{fail: map[string]error{"m2": errors.New("broker down")}}
+	relay := NewRelay(repo, transport, RelayConfig{Lease: time.Minute})
+	relay.now = func() time.Time { return now }
+
+	err := relay.PublishPending(context.Background())
+	require.ErrorContains(t, err, "broker down")
+	assert.Equal(t, []string{"m1"}, transport.Sent(), "the messages after a failure wait for it")
+
+	delete(transport.fail, "m2")
+	require.NoError(t, relay.PublishPending(context.Background()))
+	assert.Equal(t, []string{"m1"}, transport.Sent(), "claimed messages wait for the lease")
+
+	relay.now = func() time.Time { return now.Add(time.Minute) }
+	require.NoError(t, relay.PublishPending(context.Background()))
+	assert.Equal(t, []string{"m1", "m2", "m3"}, transport.Sent())
+}
+
+// failingMark is an outbox that fails to record published messages.
+type failingMark struct {
+	*repository.InMemoryRepo
+}
+
+func (failingMark) MarkPublished(context.Context, []string, time.Time) error {
+	return errors.New("database down")
+}
+
+func TestRelay_AtLeastOnce(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	saveLinks(t, repo, 1, now)
+
+	transport := &recorder{}
+	relay := NewRelay(failingMark{repo}, transport, RelayConfig{Lease: time.Minute})
+	relay.now = func() time.Time { return now }
+	require.ErrorContains(t, relay.PublishPending(context.Background()), "database down")
+
+	relay = NewRelay(repo, transport, RelayConfig{Lease: time.Minute})
+	relay.now = func() time.Time { return now.Add(time.Minute) }
+	require.NoError(t, relay.PublishPending(context.Background()))
+	assert.Equal(t, []string{"m1", "m1"}, transport.Sent(), "sent again, consumers skip it by ID")
+}
+
+func TestRelay_Purge(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	saveLinks(t, repo, 2, now)
+	require.NoError(t, repo.MarkPublished(context.Background(), []string{"m1"}, now))
+
+	relay := NewRelay(repo, &recorder{}, RelayConfig{Retention: time.Hour})
+	relay.now = func() time.Time { return now.Add(2 * time.Hour) }
+	require.NoError(t, relay.purge(context.Background()))
+
+	claimed, err := repo.ClaimOutbox(context.Background(), now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "unpublished messages are kept")
+	n, err := repo.PurgeOutbox(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n, "the published message was purged")
+}
+
+func TestMulti(t *testing.T) {
+	t.Parallel()
+	first, second := &recorder{}, &recorder{fail: map[string]error{"m2": errors.New("down")}}
+	transport := Multi(first, second)
+
+	require.NoError(t, transport.Send(context.Background(), model.OutboxMessage{ID: "m1"}))
+	require.Error(t, transport.Send(context.Background(), model.OutboxMessage{ID: "m2"}))
+	assert.Equal(t, []string{"m1", "m2"}, first.Sent())
+	assert.Equal(t, []string{"m1"}, second.Sent())
+	assert.Same(t, first, Multi(first), "a single transport isn't wrapped")
+}
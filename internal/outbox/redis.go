@@ -0,0 +1,154 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStream is a transport appending messages to a Redis stream. The stream keeps the message ID in the id
+// field, consumer groups reading it skip the IDs they already processed, see Consume.
+type RedisStream struct {
+	client *redis.Client
+	stream string
+	maxLen int64 // Entries kept, about, 0 keeps them all.
+}
+
+var _ Transport = &RedisStream{}
+
+// NewRedisStream returns a transport appending to stream, trimmed to about maxLen entries.
+func NewRedisStream(client *redis.Client, stream string, maxLen int64) *RedisStream {
+	return &RedisStream{client: client, stream: stream, maxLen: maxLen}
+}
+
+// Send appends the message to the stream.
+func (s *RedisStream) Send(ctx context.Context, msg model.OutboxMessage) error {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: []any{"id", msg.ID, "type", msg.Type, "key", msg.Key, "payload", string(msg.Payload)},
+	}).Err()
+}
+
+const (
+	// redisBlock is how long a read waits for new entries, Consume checks if it should stop in between.
+	redisBlock = 5 * time.Second
+	// redisRetryDelay is how long a consumer waits before handling the entries that failed again.
+	redisRetryDelay = 5 * time.Second
+)
+
+// Consume reads the stream as the consumer of the group, created if needed, until ctx is done. Entries are
+// acknowledged once handler succeeds, the ones that failed are read again after a delay. Wrap the handler with
+// Idempotent: the relay can append a message more than once.
+func (s *RedisStream) Consume(ctx context.Context, group, consumer string, handler Handler) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("outbox: failed to create group %q of %q: %w", group, s.stream, err)
+	}
+
+	pending := true // Entries read before a restart and not acknowledged come first.
+	for ctx.Err() == nil {
+		id, block := ">", redisBlock
+		if pending {
+			id, block = "0", -1 // No BLOCK, the entries are already there.
+		}
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{s.stream, id},
+			Count:    defaultBatchSize,
+			Block:    block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("outbox: failed to read %q: %w", s.stream, err)
+		}
+
+		var entries []redis.XMessage
+		if len(streams) > 0 {
+			entries = streams[0].Messages
+		}
+		failed := s.handle(ctx, group, entries, handler)
+		pending = failed > 0
+		if pending {
+			select {
+			case <-ctx.Done():
+			case <-time.After(redisRetryDelay):
+			}
+		}
+	}
+	return nil
+}
+
+// handle calls handler with the entries and acknowledges the ones it handled, it returns how many failed.
+func (s *RedisStream) handle(ctx context.Context, group string, entries []redis.XMessage, handler Handler) int {
+	failed := 0
+	for _, entry := range entries {
+		if len(entry.Values) == 0 { // Trimmed from the stream while pending.
+			s.ack(ctx, group, entry.ID)
+			continue
+		}
+		msg := model.OutboxMessage{
+			ID:      fmt.Sprint(entry.Values["id"]),
+			Type:    fmt.Sprint(entry.Values["type"]),
+			Key:     fmt.Sprint(entry.Values["key"]),
+			Payload: []byte(fmt.Sprint(entry.Values["payload"])),
+		}
+		if err := handler(ctx, msg); err != nil {
+			l.Logger.Error("outbox stream handler failed", "stream", s.stream, "entry", entry.ID, "error", err)
+			failed++
+			continue
+		}
+		s.ack(ctx, group, entry.ID)
+	}
+	return failed
+}
+
+// ack acknowledges the entry, one that isn't is read again when the consumer restarts.
+func (s *RedisStream) ack(ctx context.Context, group, id string) {
+	if err := s.client.XAck(ctx, s.stream, group, id).Err(); err != nil {
+		l.Logger.Error("failed to acknowledge outbox stream entry", "stream", s.stream, "entry", id, "error", err)
+	}
+}
+
+// RedisProcessed keeps the processed message IDs in Redis for ttl, for consumers on several instances.
+type RedisProcessed struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+var _ Processed = &RedisProcessed{}
+
+// NewRedisProcessed returns a RedisProcessed remembering IDs for ttl, which should be longer than the outbox
+// keeps published messages.
+func NewRedisProcessed(client *redis.Client, ttl time.Duration) *RedisProcessed {
+	return &RedisProcessed{client: client, ttl: ttl}
+}
+
+// processedKey is the key marking the message processed by the consumer.
+func processedKey(consumer, id string) string {
+	return "outbox:processed:" + consumer + ":" + id
+}
+
+// Seen reports whether the key of the message exists.
+func (p *RedisProcessed) Seen(ctx context.Context, consumer, id string) (bool, error) {
+	n, err := p.client.Exists(ctx, processedKey(consumer, id)).Result()
+	return n > 0, err
+}
+
+// Mark sets the key of the message.
+func (p *RedisProcessed) Mark(ctx context.Context, consumer, id string) error {
+	return p.client.Set(ctx, processedKey(consumer, id), 1, p.ttl).Err()
+}
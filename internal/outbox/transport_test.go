@@ -0,0 +1,123 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = model.OutboxMessage{ID: "m1", Type: "link.created", Key: "go.acme.com/abc",
+	Payload: []byte(`{"id":"m1"}`)}
+
+func TestChannel(t *testing.T) {
+	t.Parallel()
+	channel := NewChannel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout, cancelTimeout := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelTimeout()
+	assert.ErrorIs(t, channel.Send(timeout, testMessage), context.DeadlineExceeded, "nobody consumes")
+
+	var received []string
+	go channel.Consume(ctx, func(_ context.Context, msg model.OutboxMessage) error {
+		received = append(received, msg.ID)
+		if msg.ID == "bad" {
+			return errors.New("can't handle")
+		}
+		return nil
+	})
+	require.NoError(t, channel.Send(ctx, testMessage))
+	assert.ErrorContains(t, channel.Send(ctx, model.OutboxMessage{ID: "bad"}), "can't handle",
+		"the sender gets the handler's error")
+	assert.Equal(t, []string{"m1", "bad"}, received)
+}
+
+func TestRedisStream(t *testing.T) {
+	t.Parallel()
+	client, mock := redismock.NewClientMock()
+	mock.ExpectXAdd(&redis.XAddArgs{
+		Stream: "events",
+		MaxLen: 1000,
+		Approx: true,
+		Values: []any{"id", "m1", "type", "link.created", "key", "go.acme.com/abc", "payload", `{"id":"m1"}`},
+	}).SetVal("1-0")
+	mock.ExpectXAdd(&redis.XAddArgs{
+		Stream: "events",
+		MaxLen: 1000,
+		Approx: true,
+		Values: []any{"id", "m1", "type", "link.created", "key", "go.acme.com/abc", "payload", `{"id":"m1"}`},
+	}).SetErr(errors.New("redis down"))
+
+	stream := NewRedisStream(client, "events", 1000)
+	require.NoError(t, stream.Send(context.Background(), testMessage))
+	assert.ErrorContains(t, stream.Send(context.Background(), testMessage), "redis down")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisStream_Handle(t *testing.T) {
+	t.Parallel()
+	client, mock := redismock.NewClientMock()
+	mock.ExpectXAck("events", "search", "1-0").SetVal(1)
+	mock.ExpectXAck("events", "search", "3-0").SetVal(1)
+
+	var received []model.OutboxMessage
+	failed := NewRedisStream(client, "events", 0).handle(context.Background(), "search", []redis.XMessage{
+		{ID: "1-0", Values: map[string]any{"id": "m1", "type": "link.created", "key": "abc", "payload": `{}`}},
+		{ID: "2-0", Values: map[string]any{"id": "bad"}},
+		{ID: "3-0"}, // Trimmed while pending.
+	}, func(_ context.Context, msg model.OutboxMessage) error {
+		received = append(received, msg)
+		if msg.ID == "bad" {
+			return errors.New("can't handle")
+		}
+		return nil
+	})
+
+	assert.Equal(t, 1, failed)
+	require.Len(t, received, 2)
+	assert.Equal(t, model.OutboxMessage{ID: "m1", Type: "link.created", Key: "abc", Payload: []byte(`{}`)},
+		received[0])
+	assert.NoError(t, mock.ExpectationsWereMet(), "the failed entry isn't acknowledged")
+}
+
+func TestNATS(t *testing.T) {
+	t.Parallel()
+	fake := &FakeNATS{}
+	transport := NewNATS(fake, "links")
+
+	require.NoError(t, transport.Send(context.Background(), testMessage))
+	require.NoError(t, transport.Send(context.Background(), testMessage))
+	assert.Equal(t, []NATSMessage{{Subject: "links.link.created", Data: []byte(`{"id":"m1"}`), MsgID: "m1"}},
+		fake.Messages(), "the duplicate is dropped")
+
+	fake.Err = errors.New("no responders")
+	assert.ErrorContains(t, transport.Send(context.Background(), testMessage), "no responders")
+}
+
+func TestKafka(t *testing.T) {
+	t.Parallel()
+	fake := &FakeKafka{}
+	transport := NewKafka(fake, "link-events")
+
+	require.NoError(t, transport.Send(context.Background(), testMessage))
+	require.NoError(t, transport.Send(context.Background(), testMessage))
+	messages := fake.Messages()
+	require.Len(t, messages, 2, "topics keep duplicates, consumers skip them")
+	assert.Equal(t, KafkaMessage{
+		Topic:   "link-events",
+		Key:     []byte("go.acme.com/abc"),
+		Value:   []byte(`{"id":"m1"}`),
+		Headers: map[string]string{"id": "m1", "type": "link.created"},
+	}, messages[0])
+
+	fake.Err = errors.New("leader not available")
+	assert.ErrorContains(t, transport.Send(context.Background(), testMessage), "leader not available")
+}
@@ -0,0 +1,116 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode_Capacity(t *testing.T) {
+	t.Parallel()
+	// Byte mode capacities from the QR code specification.
+	tests := []struct {
+		version  int
+		level    Level
+		capacity int
+	}{
+		{1, Low, 17}, {1, Medium, 14}, {1, Quartile, 11}, {1, High, 7},
+		{10, Low, 271}, {10, Medium, 213}, {10, Quartile, 151}, {10, High, 119},
+		{40, Low, 2953}, {40, Medium, 2331}, {40, Quartile, 1663}, {40, High, 1273},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d-%s", tt.version, tt.level), func(t *testing.T) {
+			t.Parallel()
+			code, err := Encode(strings.Repeat("a", tt.capacity), tt.level)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, code.Version)
+			assert.Equal(t, tt.version*4+17, code.Size)
+
+			code, err = Encode(strings.Repeat("a", tt.capacity+1), tt.level)
+			if tt.version == maxVersion {
+				assert.ErrorIs(t, err, ErrTooLong)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.version+1, code.Version)
+		})
+	}
+}
+
+func TestEncode_FinderPatterns(t *testing.T) {
+	t.Parallel()
+	code, err := Encode("http://localhost:8080/abc", Medium)
+	require.NoError(t, err)
+
+	for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+		for dy := range 7 {
+			for dx := range 7 {
+				ring := max(abs(dx-3), abs(dy-3))
+				assert.Equal(t, ring != 2, code.At(corner[0]+dx, corner[1]+dy), "corner %v at %d,%d", corner, dx, dy)
+			}
+		}
+	}
+	assert.False(t, code.At(-1, 0))
+	assert.False(t, code.At(code.Size, 0))
+}
+
+func TestEncode_Deterministic(t *testing.T) {
+	t.Parallel()
+	a, err := Encode("http://localhost:8080/abc", High)
+	require.NoError(t, err)
+	b, err := Encode("http://localhost:8080/abc", High)
+	require.NoError(t, err)
+	assert.Equal(t, a.modules, b.modules)
+	assert.Equal(t, a.Mask, b.Mask)
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"L", "m", "Q", "h"} {
+		level, err := ParseLevel(s)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(s), level.String())
+	}
+	_, err := ParseLevel("X")
+	assert.Error(t, err)
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	code, err := Encode("http://localhost:8080/abc", High)
+	require.NoError(t, err)
+	opts := RenderOptions{Size: 256, Foreground: color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}}
+
+	data, err := code.PNG(opts)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	width := (code.Size + 2*QuietZone) * (256 / (code.Size + 2*QuietZone))
+	assert.Equal(t, width, img.Bounds().Dx())
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b}, "quiet zone is light")
+
+	svg, err := code.SVG(opts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(svg), "<svg "))
+	assert.Contains(t, string(svg), `fill="#112233"`)
+	assert.NotContains(t, string(svg), "<image")
+
+	logo, err := LoadLogo(data)
+	require.NoError(t, err)
+	opts.Logo = logo
+	svg, err = code.SVG(opts)
+	require.NoError(t, err)
+	assert.Contains(t, string(svg), `<image `)
+
+	withLogo, err := code.PNG(opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, withLogo)
+}
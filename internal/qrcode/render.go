@@ -0,0 +1,151 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// QuietZone is the light border around the code in modules, required by the spec.
+const QuietZone = 4
+
+// logoRatio is the share of the code width covered by the logo.
+// Small enough for High level error correction to recover the hidden modules.
+const logoRatio = 0.2
+
+// RenderOptions controls how a code is drawn.
+type RenderOptions struct {
+	Size       int         // Width and height in pixels, rounded down to a whole number of pixels per module.
+	Foreground color.Color // Dark modules, defaults to black.
+	Background color.Color // Light modules and quiet zone, defaults to white.
+	Logo       image.Image // Optional logo drawn over the centre. Use the High level so the code stays readable.
+}
+
+func (o RenderOptions) colors() (color.Color, color.Color) {
+	fg, bg := o.Foreground, o.Background
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	return fg, bg
+}
+
+// scale returns the number of pixels per module.
+func (c *Code) scale(size int) int {
+	return max(1, size/(c.Size+2*QuietZone))
+}
+
+// Image draws the code as an image.
+func (c *Code) Image(opts RenderOptions) image.Image {
+	fg, bg := opts.colors()
+	scale := c.scale(opts.Size)
+	width := (c.Size + 2*QuietZone) * scale
+
+	img := image.NewRGBA(image.Rect(0, 0, width, width))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	dark := image.NewUniform(fg)
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				px, py := (x+QuietZone)*scale, (y+QuietZone)*scale
+				draw.Draw(img, image.Rect(px, py, px+scale, py+scale), dark, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if opts.Logo != nil {
+		box := c.logoBox(scale)
+		pad := scale // Keep a border so the logo doesn't touch the modules.
+		draw.Draw(img, box.Inset(-pad), image.NewUniform(bg), image.Point{}, draw.Src)
+		draw.Draw(img, box, resize(opts.Logo, box.Dx(), box.Dy()), image.Point{}, draw.Over)
+	}
+	return img
+}
+
+// PNG draws the code as a PNG image.
+func (c *Code) PNG(opts RenderOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(opts)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG draws the code as an SVG document using one path for all dark modules.
+func (c *Code) SVG(opts RenderOptions) ([]byte, error) {
+	fg, bg := opts.colors()
+	width := c.Size + 2*QuietZone
+	pixels := c.scale(opts.Size) * width
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" `+
+		`viewBox="0 0 %d %d" shape-rendering="crispEdges">`, pixels, pixels, width, width)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hex(bg))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hex(fg))
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo != nil {
+		logo, err := encodeLogo(opts.Logo)
+		if err != nil {
+			return nil, err
+		}
+		box := c.logoBox(1)
+		// Keep a module of border so the logo doesn't touch the dark modules.
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+			box.Min.X-1, box.Min.Y-1, box.Dx()+2, box.Dy()+2, hex(bg))
+		fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+			box.Min.X, box.Min.Y, box.Dx(), box.Dy(), logo)
+	}
+	buf.WriteString("</svg>")
+	return buf.Bytes(), nil
+}
+
+// logoBox returns the centred square covered by the logo, in pixels for the given scale.
+func (c *Code) logoBox(scale int) image.Rectangle {
+	modules := max(1, int(float64(c.Size)*logoRatio))
+	start := (QuietZone + (c.Size-modules)/2) * scale
+	return image.Rect(start, start, start+modules*scale, start+modules*scale)
+}
+
+func encodeLogo(logo image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, logo); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// LoadLogo decodes a PNG logo.
+func LoadLogo(data []byte) (image.Image, error) {
+	return png.Decode(bytes.NewReader(data))
+}
+
+// resize scales the image to width x height using nearest neighbour, which is enough for small logos.
+func resize(src image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	b := src.Bounds()
+	for y := range height {
+		for x := range width {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/width, b.Min.Y+y*b.Dy()/height))
+		}
+	}
+	return dst
+}
+
+func hex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
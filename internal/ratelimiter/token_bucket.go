@@ -36,12 +36,13 @@ func NewTokenBucket(rate int, capacity int) *TokenBucket {
 func (tb *TokenBucket) refill() {
 	now := time.Now()
 	elapsed := now.Sub(tb.lastRefill).Seconds()
-	tb.lastRefill = now
 
 	// Calculate how many tokens to add based on elapsed time and rate
 	newTokens := int(elapsed * float64(tb.rate))
 	if newTokens > 0 {
 		tb.tokens = min(tb.capacity, tb.tokens+newTokens)
+		// Only move on once a token was added, otherwise calls closer together than 1/rate never refill.
+		tb.lastRefill = now
 	}
 }
 
@@ -126,17 +127,24 @@ func (l *IPRateLimiter) cleanupOldEntries() {
 
 // Middleware applies per-IP rate limiting to incoming requests.
 func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getIP(r)
-		limiter := l.GetLimiter(ip)
+	return l.MiddlewareBy(getIP)(next)
+}
 
-		if !limiter.Allow() {
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
-			return
-		}
+// MiddlewareBy applies rate limiting per client IP, as returned by clientIP. Use it with a clientIP that only
+// honours X-Forwarded-For from trusted proxies, or clients can pick a new bucket on every request.
+func (l *IPRateLimiter) MiddlewareBy(clientIP func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := l.GetLimiter(clientIP(r))
 
-		next.ServeHTTP(w, r)
-	})
+			if !limiter.Allow() {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // getIP tries to extract a user IP, accounting for proxies.
@@ -14,6 +14,59 @@ import (
 // New return the http handler with routes init and middleware.
 func New(handler *shortener.Handler) http.Handler {
 	mux := http.NewServeMux()
+	routes(mux, handler)
+
+	// middleware chain.
+	rateLimiter := rate.NewLimiter(2, 5) // Small rate limit for my app! :D
+	middlewareMux := middleware.Chain(mount(mux, handler.BaseURL().Prefix()),
+		middleware.Logger(l.Logger), // Logs every request
+		middleware.Recovery,
+		middleware.RateLimiter(rateLimiter),
+		handler.Source,       // Client IP and request ID for the audit log.
+		middleware.RequestID, // Outermost, so every log line has the request ID.
+	)
+
+	return middlewareMux
+}
+
+// mount serves the routes under the path prefix of the base URL, e.g. /links/shorten for /links. Paths without
+// it are served too, so proxies may strip the prefix or pass it on.
+func mount(mux http.Handler, prefix string) http.Handler {
+	if prefix == "" {
+		return mux
+	}
+	root := http.NewServeMux()
+	root.Handle(prefix+"/", http.StripPrefix(prefix, mux)) // Also redirects the prefix to prefix/.
+	root.Handle("/", mux)
+	return root
+}
+
+// ReservedNames returns the first path segment of every route, e.g. health and swagger.
+// Links can't use them as short codes since the routes would shadow the link.
+func ReservedNames(handler *shortener.Handler) []string {
+	var recorder patternRecorder
+	routes(&recorder, handler)
+	if prefix := handler.BaseURL().Prefix(); prefix != "" {
+		recorder.patterns = append(recorder.patterns, prefix+"/") // The mount point shadows links too.
+	}
+	return shortener.RouteNames(recorder.patterns)
+}
+
+// patternRecorder records the patterns of the routes registered on it.
+type patternRecorder struct {
+	patterns []string
+}
+
+func (r *patternRecorder) Handle(pattern string, _ http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+func (r *patternRecorder) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+// routes registers every route of the app on mux.
+func routes(mux shortener.Mux, handler *shortener.Handler) {
 	// Setup handler routes.
 	handler.Routes(mux)
 
@@ -27,15 +80,6 @@ func New(handler *shortener.Handler) http.Handler {
 		panic("something went wrong!") // This simulates a panic
 	})
 
-	mux.Handle("/swagger/", httpSwagger.WrapHandler)
-
-	// middleware chain.
-	rateLimiter := rate.NewLimiter(2, 5) // Small rate limit for my app! :D
-	middlewareMux := middleware.Chain(mux,
-		middleware.Logger(l.Logger), // Logs every request
-		middleware.Recovery,
-		middleware.RateLimiter(rateLimiter),
-	)
-
-	return middlewareMux
+	// Method is required so it doesn't conflict with the GET /{shorturl}/{path...} prefix link route.
+	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
 }
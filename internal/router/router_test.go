@@ -35,3 +35,54 @@ func TestRouter(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, rec.Code)      // or whatever it should return
 	require.Contains(t, rec.Body.String(), "Internal Server Error") // or actual response
 }
+
+func TestReservedNames(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	names := ReservedNames(shortener.NewHandler(mocks.NewMockService(ctrl)))
+	for _, name := range []string{"health", "panic", "debug", "swagger", "preview", "urls", "shorten", "webhooks"} {
+		require.Contains(t, names, name)
+	}
+	require.NotContains(t, names, "")
+}
+
+func TestRouter_SubPath(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	base, err := shortener.NewBaseURL("https://example.com/links/", nil)
+	require.NoError(t, err)
+	handler := shortener.NewHandler(mocks.NewMockService(ctrl), shortener.WithBaseURL(base))
+	router := New(handler)
+
+	// Proxies may pass the prefix on or strip it.
+	for _, path := range []string{"/links/health", "/health"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/links", nil))
+	require.Equal(t, "/links/", rec.Header().Get("Location"))
+
+	require.Contains(t, ReservedNames(handler), "links")
+}
+
+func TestRouter_DebugVars(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	router := New(shortener.NewHandler(mocks.NewMockService(ctrl), shortener.WithAdminKey("admin-secret")))
+
+	// The command line and memory stats aren't public.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "memstats")
+}
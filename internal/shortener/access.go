@@ -0,0 +1,77 @@
+package shortener
+
+import (
+	"context"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+)
+
+// Permission is an action on a workspace that needs a minimum role.
+type Permission string
+
+// Permissions checked by the service.
+const (
+	PermissionViewLinks     Permission = "view links and stats"
+	PermissionEditLinks     Permission = "create, update and delete links"
+	PermissionManageDomains Permission = "manage domains"
+	PermissionManageMembers Permission = "manage members"
+	PermissionManageKeys    Permission = "manage api keys"
+	PermissionManageHooks   Permission = "manage webhooks"
+)
+
+// minimumRoles is the lowest role with each permission.
+var minimumRoles = map[Permission]model.Role{
+	PermissionViewLinks:     model.RoleViewer,
+	PermissionEditLinks:     model.RoleEditor,
+	PermissionManageDomains: model.RoleAdmin,
+	PermissionManageMembers: model.RoleAdmin,
+	PermissionManageKeys:    model.RoleAdmin,
+	PermissionManageHooks:   model.RoleAdmin,
+}
+
+// Can reports whether the role has the permission.
+func Can(role model.Role, permission Permission) bool {
+	minimum, ok := minimumRoles[permission]
+	return ok && role.Rank() >= minimum.Rank()
+}
+
+type memberKey struct{}
+
+// withMember returns a context acting as the member, whose role authorize checks.
+func withMember(ctx context.Context, member *model.Member) context.Context {
+	return context.WithValue(ctx, memberKey{}, member)
+}
+
+// memberFrom returns the member the context acts as. Contexts of the admin key, the default workspace and
+// background jobs have none and may do anything.
+func memberFrom(ctx context.Context) (*model.Member, bool) {
+	member, ok := ctx.Value(memberKey{}).(*model.Member)
+	return member, ok
+}
+
+type adminKey struct{}
+
+// withAdmin returns a context acting with the admin key.
+func withAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// anonymous reports whether the context is a request without a key: it acts for the default workspace with
+// neither a member nor the admin key.
+func anonymous(ctx context.Context) bool {
+	workspace, scoped := tenant.From(ctx)
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	_, member := memberFrom(ctx)
+	return scoped && workspace == tenant.Default && !admin && !member
+}
+
+// authorize returns a ForbiddenError if the context's member doesn't have the permission.
+func authorize(ctx context.Context, permission Permission) error {
+	member, ok := memberFrom(ctx)
+	if !ok || Can(member.Role, permission) {
+		return nil
+	}
+	return e.NewForbiddenError("%s %q can't %s", member.Role, member.ID, permission)
+}
@@ -0,0 +1,259 @@
+package shortener
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCan(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		role    model.Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{model.RoleOwner, []Permission{PermissionManageMembers, PermissionManageKeys, PermissionEditLinks}, nil},
+		{model.RoleAdmin, []Permission{PermissionManageMembers, PermissionManageDomains, PermissionViewLinks}, nil},
+		{model.RoleEditor, []Permission{PermissionEditLinks, PermissionViewLinks},
+			[]Permission{PermissionManageMembers, PermissionManageKeys, PermissionManageDomains}},
+		{model.RoleViewer, []Permission{PermissionViewLinks}, []Permission{PermissionEditLinks, PermissionManageKeys}},
+		{"guest", nil, []Permission{PermissionViewLinks}},
+	}
+	for _, tt := range tests {
+		for _, permission := range tt.allowed {
+			assert.True(t, Can(tt.role, permission), "%s can %s", tt.role, permission)
+		}
+		for _, permission := range tt.denied {
+			assert.False(t, Can(tt.role, permission), "%s can't %s", tt.role, permission)
+		}
+	}
+	assert.False(t, Can(model.RoleOwner, "delete the internet"), "unknown permissions are denied")
+}
+
+func TestWorkspaces_Members(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)))
+	admin := tenant.With(context.Background(), "acme")
+	_, err := service.CreateWorkspace(admin, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+
+	as := func(id string, role model.Role) context.Context {
+		return withMember(admin, &model.Member{Workspace: "acme", ID: id, Role: role})
+	}
+	add := func(ctx context.Context, id string, role model.Role) error {
+		_, err := service.AddMember(ctx, &model.Member{Workspace: "acme", ID: id, Role: role})
+		return err
+	}
+
+	assert.NoError(t, add(admin, "bob", model.RoleAdmin))
+	assert.NoError(t, add(as("bob", model.RoleAdmin), "eve", model.RoleEditor))
+	assert.NoError(t, add(as("bob", model.RoleAdmin), "vic", model.RoleViewer))
+	assert.ErrorIs(t, add(as("bob", model.RoleAdmin), "eve", model.RoleViewer), e.ConflictError{})
+	assert.ErrorIs(t, add(as("bob", model.RoleAdmin), "mal", model.RoleOwner), e.ForbiddenError{})
+	assert.ErrorIs(t, add(as("eve", model.RoleEditor), "mal", model.RoleViewer), e.ForbiddenError{})
+	assert.ErrorIs(t, add(admin, "mal", "superuser"), e.BadRequestError{})
+	assert.ErrorIs(t, add(admin, "mal/../x", model.RoleViewer), e.BadRequestError{})
+
+	_, err = service.UpdateMember(as("bob", model.RoleAdmin), &model.Member{Workspace: "acme", ID: "ann",
+		Role: model.RoleViewer})
+	assert.ErrorIs(t, err, e.ForbiddenError{}, "only owners manage owners")
+	_, err = service.UpdateMember(as("ann", model.RoleOwner), &model.Member{Workspace: "acme", ID: "ann",
+		Role: model.RoleAdmin})
+	assert.ErrorIs(t, err, e.ConflictError{}, "the last owner stays")
+	assert.ErrorIs(t, service.RemoveMember(admin, "acme", "ann"), e.ConflictError{})
+	updated, err := service.UpdateMember(as("ann", model.RoleOwner), &model.Member{Workspace: "acme", ID: "bob",
+		Role: model.RoleOwner})
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleOwner, updated.Role)
+	assert.NoError(t, service.RemoveMember(as("bob", model.RoleOwner), "acme", "ann"), "another owner is left")
+
+	_, err = service.CreateAPIKey(as("eve", model.RoleEditor), "acme", "", "ci")
+	assert.ErrorIs(t, err, e.ForbiddenError{}, "editors don't manage keys")
+	_, err = service.CreateAPIKey(admin, "acme", "", "ci")
+	assert.ErrorIs(t, err, e.BadRequestError{}, "the admin key has to name the member")
+	_, err = service.CreateAPIKey(admin, "acme", "nobody", "ci")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	key, err := service.CreateAPIKey(admin, "acme", "vic", "ci")
+	require.NoError(t, err)
+	member, err := service.Authenticate(admin, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleViewer, member.Role)
+
+	require.NoError(t, service.RemoveMember(as("bob", model.RoleOwner), "acme", "vic"))
+	_, err = service.Authenticate(admin, key.Key)
+	assert.ErrorIs(t, err, e.UnauthorizedError{}, "keys of removed members stop working")
+
+	members, err := service.ListMembers(as("eve", model.RoleEditor), "acme")
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, "bob", members[0].ID)
+}
+
+func TestWorkspaces_KeyEscalation(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	workspaces := NewWorkspaces(repo)
+	ctx := tenant.With(context.Background(), "acme")
+	_, err := workspaces.Create(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	_, err = workspaces.AddMember(ctx, &model.Member{Workspace: "acme", ID: "bob", Role: model.RoleAdmin})
+	require.NoError(t, err)
+
+	bob := withMember(ctx, &model.Member{Workspace: "acme", ID: "bob", Role: model.RoleAdmin})
+	_, err = workspaces.CreateKey(bob, "acme", "ann", "")
+	assert.ErrorIs(t, err, e.ForbiddenError{}, "admins can't get an owner's key")
+	key, err := workspaces.CreateKey(bob, "acme", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", key.Member)
+
+	legacy := &model.APIKey{ID: "0123456789abcdef", Workspace: "acme"}
+	legacy.Key = apiKeyPrefix + legacy.ID + "_secret"
+	legacy.Hash = hashAPIKey(legacy.Key)
+	require.NoError(t, repo.SaveAPIKey(ctx, legacy))
+	member, err := workspaces.Authenticate(ctx, legacy.Key)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, member.Role, "keys without a member act as admins")
+}
+
+func TestRBACHandlers(t *testing.T) {
+	t.Parallel()
+	domains, repo, _ := newTestDomains()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithDomains(domains))
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey("admin-secret")).Routes(mux)
+
+	ctx := tenant.With(context.Background(), "acme")
+	_, err := service.CreateWorkspace(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	keys := map[model.Role]string{}
+	for _, role := range []model.Role{model.RoleAdmin, model.RoleEditor, model.RoleViewer} {
+		_, err := service.AddMember(ctx, &model.Member{Workspace: "acme", ID: string(role) + "1", Role: role})
+		require.NoError(t, err)
+		key, err := service.CreateAPIKey(ctx, "acme", string(role)+"1", "")
+		require.NoError(t, err)
+		keys[role] = key.Key
+	}
+
+	shorten := func() *http.Request {
+		return makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"})
+	}
+	addMember := func(role model.Role) *http.Request {
+		return makeJSONRequest(http.MethodPost, "/workspaces/acme/members", model.Member{ID: "new-" + string(role),
+			Role: role})
+	}
+	for _, tt := range []struct {
+		req    *http.Request
+		role   model.Role
+		status int
+	}{
+		{httptest.NewRequest(http.MethodGet, "/urls", nil), model.RoleViewer, http.StatusOK},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme/usage", nil), model.RoleViewer, http.StatusOK},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme/members", nil), model.RoleViewer, http.StatusOK},
+		{shorten(), model.RoleViewer, http.StatusForbidden},
+		{shorten(), model.RoleEditor, http.StatusCreated},
+		{makeJSONRequest(http.MethodPost, "/domains", model.Domain{Name: "go.acme.com"}), model.RoleEditor,
+			http.StatusForbidden},
+		{addMember(model.RoleViewer), model.RoleEditor, http.StatusForbidden},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme/keys", nil), model.RoleEditor, http.StatusForbidden},
+		{addMember(model.RoleEditor), model.RoleAdmin, http.StatusCreated},
+		{addMember(model.RoleOwner), model.RoleAdmin, http.StatusForbidden},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme/keys", nil), model.RoleAdmin, http.StatusOK},
+		{httptest.NewRequest(http.MethodDelete, "/urls/missing", nil), model.RoleViewer, http.StatusForbidden},
+		{httptest.NewRequest(http.MethodDelete, "/urls/missing", nil), model.RoleEditor, http.StatusNotFound},
+		{makeJSONRequest(http.MethodPatch, "/workspaces/acme/members/viewer1", model.Member{Role: model.RoleEditor}),
+			model.RoleAdmin, http.StatusOK},
+		{httptest.NewRequest(http.MethodDelete, "/workspaces/acme/members/ann", nil), model.RoleAdmin,
+			http.StatusForbidden},
+		{httptest.NewRequest(http.MethodDelete, "/workspaces/acme/members/missing", nil), model.RoleAdmin,
+			http.StatusNotFound},
+	} {
+		tt.req.Header.Set("Authorization", "Bearer "+keys[tt.role])
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tt.req)
+		assert.Equal(t, tt.status, rr.Code, "%s %s as %s: %s", tt.req.Method, tt.req.URL, tt.role, rr.Body)
+	}
+}
+
+func TestAnonymousWrites(t *testing.T) {
+	t.Parallel()
+	domains, repo, _ := newTestDomains()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithHistory(repo), WithDomains(domains))
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey(testAdminKey)).Routes(mux)
+
+	verifiedAt := time.Now()
+	require.NoError(t, repo.SaveDomain(context.Background(), &model.Domain{Name: "go.bank.example.com",
+		Owner: "bank", VerifiedAt: &verifiedAt}))
+	_, err := service.SaveURL(tenant.With(context.Background(), tenant.Default),
+		&model.URL{OriginalURL: "https://mybank.example.com", CustomURL: ptr.Of("mybank")})
+	require.NoError(t, err)
+	ctx := tenant.With(context.Background(), "acme")
+	_, err = service.CreateWorkspace(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	key, err := service.CreateAPIKey(ctx, "acme", "ann", "")
+	require.NoError(t, err)
+
+	hijack := func() *http.Request {
+		return makeJSONRequest(http.MethodPatch, "/urls/mybank",
+			model.URLUpdate{OriginalURL: ptr.Of("https://evil.example.net")})
+	}
+	onDomain := func() *http.Request {
+		return makeJSONRequest(http.MethodPost, "/shorten",
+			model.URL{OriginalURL: "https://evil.example.net", Domain: "go.bank.example.com"})
+	}
+	withKey := func(req *http.Request) *http.Request {
+		req.Header.Set("Authorization", "Bearer "+key.Key)
+		return req
+	}
+	for _, tt := range []struct {
+		req    *http.Request
+		status int
+	}{
+		{hijack(), http.StatusUnauthorized},
+		{httptest.NewRequest(http.MethodPost, "/urls/mybank/rollback/1", nil), http.StatusUnauthorized},
+		{httptest.NewRequest(http.MethodDelete, "/urls/mybank", nil), http.StatusUnauthorized},
+		{makeJSONRequest(http.MethodPatch, "/urls/mybank", model.URLUpdate{Tags: &model.Tags{"spam"}}),
+			http.StatusUnauthorized},
+		{makeJSONRequest(http.MethodPost, "/tags/bank/rename", map[string]string{"to": "spam"}),
+			http.StatusUnauthorized},
+		{makeJSONRequest(http.MethodPost, "/tags/merge", model.TagMerge{Tags: []string{"bank"}, Into: "spam"}),
+			http.StatusUnauthorized},
+		{makeJSONRequest(http.MethodPost, "/webhooks", model.Webhook{URL: "https://hooks.evil.example.net"}),
+			http.StatusUnauthorized},
+		{httptest.NewRequest(http.MethodGet, "/webhooks", nil), http.StatusUnauthorized},
+		{makeJSONRequest(http.MethodPost, "/domains", model.Domain{Name: "go.evil.example.net", Owner: "eve"}),
+			http.StatusUnauthorized},
+		{httptest.NewRequest(http.MethodPost, "/domains/go.bank.example.com/verify", nil), http.StatusUnauthorized},
+		{onDomain(), http.StatusBadRequest},
+		{withKey(onDomain()), http.StatusBadRequest}, // A domain of another workspace.
+		{withKey(httptest.NewRequest(http.MethodDelete, "/urls/mybank", nil)), http.StatusNotFound},
+		{withKey(hijack()), http.StatusNotFound}, // A link of another workspace.
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tt.req)
+		assert.Equal(t, tt.status, rr.Code, "%s %s: %s", tt.req.Method, tt.req.URL, rr.Body)
+	}
+
+	stored, err := service.GetURL(context.Background(), "", "mybank")
+	require.NoError(t, err)
+	assert.Equal(t, "https://mybank.example.com", stored.OriginalURL)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, asAdmin(hijack()))
+	assert.Equal(t, http.StatusOK, rr.Code, "the admin key manages links created without a key: %s", rr.Body)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, asAdmin(onDomain()))
+	assert.Equal(t, http.StatusCreated, rr.Code, "the admin key uses default workspace domains: %s", rr.Body)
+}
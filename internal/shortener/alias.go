@@ -0,0 +1,214 @@
+package shortener
+
+import (
+	"bufio"
+	_ "embed" // Default blocklist.
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Reasons a custom alias is refused.
+var (
+	ErrAliasReserved  = errors.New("alias is reserved")
+	ErrAliasBlocked   = errors.New("alias contains a blocked word")
+	ErrAliasNamespace = errors.New("alias looks like a generated code, use lower case or add a letter")
+)
+
+// maxAliasSuggestions is how many free alternatives are suggested for an alias that isn't available.
+const maxAliasSuggestions = 3
+
+// DefaultBlocklist is the built-in list of words aliases can't contain, one per line.
+//
+//go:embed alias_blocklist.txt
+var DefaultBlocklist string
+
+// AliasPolicyConfig configures which custom aliases are accepted.
+type AliasPolicyConfig struct {
+	Reserved        []string // Names that can't be aliases, e.g. the first segment of every route.
+	Blocklist       []string // Words no alias may contain, matched ignoring case and digits used as letters.
+	CaseInsensitive bool     // Aliases are stored in lower case so MyLink and mylink are the same alias.
+	Disjoint        bool     // Aliases and counter codes never overlap, see InAliasNamespace.
+}
+
+// AliasPolicy decides which custom aliases can be claimed.
+//
+// With Disjoint set, aliases must be in the alias namespace, see InAliasNamespace. The counter generator
+// skips every value whose code is in that namespace, so an alias can never be taken by a later counter
+// value. Codes from the other generators still go through the usual collision retry.
+type AliasPolicy struct {
+	mu              sync.RWMutex
+	reserved        map[string]bool
+	blocklist       []string
+	caseInsensitive bool
+	disjoint        bool
+}
+
+// NewAliasPolicy returns a policy for the config.
+func NewAliasPolicy(cfg AliasPolicyConfig) *AliasPolicy {
+	p := &AliasPolicy{
+		reserved:        make(map[string]bool),
+		caseInsensitive: cfg.CaseInsensitive,
+		disjoint:        cfg.Disjoint,
+	}
+	p.Reserve(cfg.Reserved...)
+	for _, word := range cfg.Blocklist {
+		if word = deLeet(strings.ToLower(strings.TrimSpace(word))); word != "" {
+			p.blocklist = append(p.blocklist, word)
+		}
+	}
+	return p
+}
+
+// Reserve adds names that can't be used as aliases.
+func (p *AliasPolicy) Reserve(names ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			p.reserved[name] = true
+		}
+	}
+}
+
+// IsReserved reports whether the code is a reserved name, ignoring case.
+func (p *AliasPolicy) IsReserved(code string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.reserved[strings.ToLower(code)]
+}
+
+// CaseInsensitive reports whether aliases are matched ignoring case.
+func (p *AliasPolicy) CaseInsensitive() bool {
+	return p.caseInsensitive
+}
+
+// Check returns the alias as it's stored, lower case when the policy is case insensitive, or why it
+// can't be used: ErrAliasReserved, ErrAliasBlocked or ErrAliasNamespace.
+func (p *AliasPolicy) Check(alias string) (string, error) {
+	if p.caseInsensitive {
+		alias = strings.ToLower(alias)
+	}
+	if p.IsReserved(alias) {
+		return "", ErrAliasReserved
+	}
+	if p.isBlocked(alias) {
+		return "", ErrAliasBlocked
+	}
+	if p.disjoint && !InAliasNamespace(alias) {
+		return "", ErrAliasNamespace
+	}
+	return alias, nil
+}
+
+// skipCode reports whether the counter generator must not hand out the code.
+func (p *AliasPolicy) skipCode(code string) bool {
+	return p.disjoint && InAliasNamespace(code) || p.IsReserved(code)
+}
+
+func (p *AliasPolicy) isBlocked(alias string) bool {
+	normalized := deLeet(strings.ToLower(alias))
+	for _, word := range p.blocklist {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// InAliasNamespace reports whether the code has a character that's not base62, such as - or an emoji, or
+// only has lower case letters and digits with at least one letter. Custom aliases must be in it when the
+// policy is disjoint and counter codes never are.
+func InAliasNamespace(code string) bool {
+	hasLetter := false
+	for _, c := range code {
+		switch {
+		case c >= 'a' && c <= 'z':
+			hasLetter = true
+		case c >= '0' && c <= '9':
+		case c >= 'A' && c <= 'Z':
+			return !isGeneratedCode(code) // Upper case is only in the namespace next to non-base62 characters.
+		default:
+			return true
+		}
+	}
+	return hasLetter
+}
+
+// leetReplacer maps digits commonly used as letters so "sh1t" matches "shit", and drops separators so
+// "s-h-i-t" does too.
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b",
+	"-", "", "_", "")
+
+func deLeet(s string) string {
+	return leetReplacer.Replace(s)
+}
+
+// aliasCandidates returns alternatives to the alias in order of preference: numbered versions, a few
+// common prefixes and suffixes, then random numbers. The alias is shortened to keep them valid.
+func aliasCandidates(alias string) []string {
+	var candidates []string
+	runes := []rune(alias)
+	add := func(prefix, suffix string) {
+		base := string(runes[:min(len(runes), MaxAliasLength-len(prefix)-len(suffix))])
+		if candidate := prefix + base + suffix; utf8.RuneCountInString(candidate) >= MinAliasLength {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	for n := 2; n <= 9; n++ {
+		add("", strconv.Itoa(n))
+	}
+	for _, prefix := range []string{"my", "get", "go"} {
+		add(prefix, "")
+	}
+	for _, suffix := range []string{"hq", "app", "now"} {
+		add("", suffix)
+	}
+	for range 3 {
+		add("", strconv.Itoa(10+rand.IntN(990))) //nolint:gosec // Suggestions don't need to be unpredictable.
+	}
+	return candidates
+}
+
+// ReadBlocklist reads one word per line, skipping blank lines and lines starting with #.
+func ReadBlocklist(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// RouteNames returns the literal first path segment of each ServeMux pattern, e.g. "health" for
+// "GET /health" and "preview" for "GET /preview/{shorturl}". Patterns starting with a wildcard have none.
+func RouteNames(patterns []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		// Drop the method, then the host: "GET example.com/path" -> "/path".
+		if _, rest, ok := strings.Cut(pattern, " "); ok {
+			pattern = strings.TrimSpace(rest)
+		}
+		i := strings.Index(pattern, "/")
+		if i < 0 {
+			continue
+		}
+		segment, _, _ := strings.Cut(pattern[i+1:], "/")
+		if segment == "" || strings.HasPrefix(segment, "{") || seen[segment] {
+			continue
+		}
+		seen[segment] = true
+		names = append(names, segment)
+	}
+	return names
+}
@@ -0,0 +1,170 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasPolicy_Check(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{
+		Reserved:  []string{"health", "Swagger", " "},
+		Blocklist: []string{"badword", "# not a comment here", ""},
+		Disjoint:  true,
+	})
+
+	tests := []struct {
+		alias    string
+		expected string
+		err      error
+	}{
+		{alias: "mylink", expected: "mylink"},
+		{alias: "promo2025", expected: "promo2025"},
+		{alias: "health", err: ErrAliasReserved},
+		{alias: "HEALTH", err: ErrAliasReserved},
+		{alias: "swagger", err: ErrAliasReserved},
+		{alias: "healthy", expected: "healthy"},
+		{alias: "mybadword", err: ErrAliasBlocked},
+		{alias: "b4dw0rd", err: ErrAliasBlocked},
+		{alias: "MyLink", err: ErrAliasNamespace},
+		{alias: "12345", err: ErrAliasNamespace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			t.Parallel()
+			alias, err := policy.Check(tt.alias)
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, alias)
+		})
+	}
+}
+
+func TestAliasPolicy_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{CaseInsensitive: true, Disjoint: true})
+
+	alias, err := policy.Check("MyLink")
+	require.NoError(t, err)
+	assert.Equal(t, "mylink", alias)
+	assert.True(t, policy.CaseInsensitive())
+
+	// Without disjoint namespaces any case is accepted as is.
+	policy = NewAliasPolicy(AliasPolicyConfig{})
+	alias, err = policy.Check("MyLink")
+	require.NoError(t, err)
+	assert.Equal(t, "MyLink", alias)
+}
+
+func TestMixedCaseAliases(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	ctx := context.Background()
+
+	// The default policy isn't disjoint, so mixed case aliases keep working.
+	service := NewService(repo, WithAliasPolicy(NewAliasPolicy(AliasPolicyConfig{})))
+	code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of("MyLink")})
+	require.NoError(t, err)
+	assert.Equal(t, "MyLink", code)
+
+	// Opting in refuses new ones, but the links that have them still resolve.
+	service = NewService(repo, WithAliasPolicy(NewAliasPolicy(AliasPolicyConfig{Disjoint: true})))
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of("NewLink")})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	data, err := service.GetURL(ctx, "", "MyLink")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", data.OriginalURL)
+}
+
+func TestAliasPolicy_Reserve(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{})
+	assert.False(t, policy.IsReserved("urls"))
+
+	policy.Reserve("urls")
+	assert.True(t, policy.IsReserved("urls"))
+	assert.True(t, policy.IsReserved("URLS"))
+}
+
+func TestInAliasNamespace(t *testing.T) {
+	t.Parallel()
+	assert.True(t, InAliasNamespace("abc"))
+	assert.True(t, InAliasNamespace("a1"))
+	assert.False(t, InAliasNamespace("aBc"))
+	assert.False(t, InAliasNamespace("123"))
+	assert.False(t, InAliasNamespace(""))
+	assert.False(t, InAliasNamespace("MyLink"))
+	// Codes with characters base62 doesn't have can never be generated.
+	assert.True(t, InAliasNamespace("my-link"))
+	assert.True(t, InAliasNamespace("My-Link"))
+	assert.True(t, InAliasNamespace("Café"))
+}
+
+func TestCounterGenerator_SkipsAliasNamespace(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{Disjoint: true})
+	counter := &fakeCounter{next: 9}
+	generator := NewCounterGenerator(counter, nil)
+	generator.skip = policy.skipCode
+
+	var codes []string
+	for range 3 {
+		code, err := generator.Generate(t.Context(), nil, 0)
+		require.NoError(t, err)
+		codes = append(codes, code)
+	}
+	// 10 to 35 are a to z, 36 is A.
+	assert.Equal(t, []string{"A", "B", "C"}, codes)
+	assert.Equal(t, 29, counter.calls)
+}
+
+func TestReadBlocklist(t *testing.T) {
+	t.Parallel()
+	words, err := ReadBlocklist(strings.NewReader("# comment\nfoo\n\n  bar  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, words)
+
+	words, err = ReadBlocklist(strings.NewReader(DefaultBlocklist))
+	require.NoError(t, err)
+	assert.NotEmpty(t, words)
+}
+
+func TestRouteNames(t *testing.T) {
+	t.Parallel()
+	names := RouteNames([]string{
+		"/",
+		"GET /favicon.ico",
+		"GET /preview/{shorturl}",
+		"/{shorturl}",
+		"/{shorturl}/{path...}",
+		"GET /urls",
+		"POST /shorten",
+		"GET /debug/vars",
+		"GET /swagger/",
+		"GET example.com/admin/",
+		"GET /urls/{id}",
+	})
+	assert.Equal(t, []string{"favicon.ico", "preview", "urls", "shorten", "debug", "swagger", "admin"}, names)
+}
+
+func TestAliasCandidates(t *testing.T) {
+	t.Parallel()
+	candidates := aliasCandidates("mylink")
+	assert.Equal(t, []string{"mylink2", "mylink3"}, candidates[:2])
+	assert.Contains(t, candidates, "getmylink")
+	assert.Contains(t, candidates, "mylinkhq")
+
+	for _, candidate := range aliasCandidates(strings.Repeat("a", MaxAliasLength)) {
+		assert.NoError(t, CodeGrammar{}.ValidateAlias(candidate), candidate)
+	}
+	for _, candidate := range aliasCandidates(strings.Repeat("é", MaxAliasLength)) {
+		assert.NoError(t, CodeGrammar{Unicode: true}.ValidateAlias(candidate), candidate)
+	}
+}
@@ -0,0 +1,160 @@
+package shortener
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/audit"
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/middleware"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// record adds the change to the audit log. The change was already made, so an entry that can't be recorded is
+// logged instead of failing the request.
+func (s *shortenerService) record(ctx context.Context, change audit.Change) {
+	if s.auditLog == nil {
+		return
+	}
+	if err := s.auditLog.Record(ctx, change); err != nil {
+		l.Logger.Error("failed to record audit entry", "action", change.Action, "resource", change.Resource,
+			"error", err)
+	}
+}
+
+// linkResource is the audit log resource of a link: the short URL, prefixed by its custom domain.
+func linkResource(domain, shortURL string) string {
+	if domain == "" {
+		return shortURL
+	}
+	return domain + "/" + shortURL
+}
+
+// snapshot copies the link as it is before a change.
+func snapshot(data *model.URL) *model.URL {
+	before := *data
+	before.Tags = slices.Clone(data.Tags)
+	return &before
+}
+
+// Source records the client address and request ID of requests for the audit log. The address is the one
+// the trusted proxies forwarded, see BaseURL.ClientIP.
+func (h *Handler) Source(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := audit.SourceFrom(r.Context())
+		source.IP = h.baseURL.ClientIP(r)
+		source.RequestID = middleware.RequestIDFrom(r.Context())
+		next.ServeHTTP(w, r.WithContext(audit.WithSource(r.Context(), source)))
+	})
+}
+
+// auditList is a page of the audit log.
+type auditList struct {
+	Entries []model.AuditEntry `json:"entries"`
+	Next    int64              `json:"next,omitempty"` // Pass as after for the next page, 0 on the last one.
+}
+
+// AuditLog lists the audit log.
+// @Summary List the audit log
+// @Description Lists the changes made through the API, oldest first: who made them, from which IP, in which
+// @Description request and the fields that changed with their value before and after. Page through the log with
+// @Description after. Needs the admin key.
+// @Tags Audit
+// @Produce json
+// @Param Authorization header string true "Bearer {admin key}"
+// @Param actor query string false "Only changes of the member, admin for the admin key"
+// @Param workspace query string false "Only changes in the workspace"
+// @Param action query string false "Only changes of the action, e.g. url.update or key.delete"
+// @Param resource query string false "Only changes of the resource, e.g. a short URL or key ID"
+// @Param from query string false "Only changes at or after the time (RFC 3339)"
+// @Param to query string false "Only changes before the time (RFC 3339)"
+// @Param after query int false "Only entries after the seq, the next of the previous page" default(0)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} auditList
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /audit [get]
+func (h *Handler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := model.AuditFilter{
+		Actor:     q.Get("actor"),
+		Workspace: q.Get("workspace"),
+		Action:    q.Get("action"),
+		Resource:  q.Get("resource"),
+	}
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := q.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid query",
+					param.name+" must be an RFC 3339 time, e.g. 2025-01-31T12:00:00Z"))
+				return
+			}
+			*param.dest = &t
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		after, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 0 {
+			e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid query",
+				"after must be a positive number"))
+			return
+		}
+		filter.AfterSeq = after
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid query",
+				"limit must be a positive number"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	entries, err := h.service.AuditLog(ctx, filter)
+	if writeServiceError(w, err) {
+		return
+	}
+	list := auditList{Entries: entries}
+	if len(entries) > 0 && len(entries) == min(cmp.Or(filter.Limit, DefaultListLimit), MaxListLimit) {
+		list.Next = entries[len(entries)-1].Seq
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// VerifyAuditLog checks the hash chain of the audit log.
+// @Summary Verify the audit log
+// @Description Recomputes the hash chain of the whole audit log and reports the first entry that was changed
+// @Description or follows a removed one. Compare head with a copy kept elsewhere to detect removed trailing
+// @Description entries. Needs the admin key.
+// @Tags Audit
+// @Produce json
+// @Param Authorization header string true "Bearer {admin key}"
+// @Success 200 {object} model.AuditVerification
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /audit/verify [get]
+func (h *Handler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), time.Minute)
+	defer cancel()
+
+	verification, err := h.service.VerifyAuditLog(ctx)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, verification)
+}
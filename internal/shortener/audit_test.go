@@ -0,0 +1,153 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasoncheung94/url-shortener/internal/audit"
+	"github.com/jasoncheung94/url-shortener/internal/middleware"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortenerService_AuditLog(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithHistory(repo),
+		WithAuditLog(audit.New(repo)))
+	admin := audit.WithActor(context.Background(), audit.ActorAdmin)
+
+	_, err := service.CreateWorkspace(admin, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	key, err := service.CreateAPIKey(tenant.With(admin, "acme"), "acme", "ann", "ci")
+	require.NoError(t, err)
+
+	ctx := audit.WithSource(tenant.With(context.Background(), "acme"), audit.Source{Actor: "ann",
+		IP: "203.0.113.9", RequestID: "req-1"})
+	shortURL, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://a.com", Tags: model.Tags{"old"}})
+	require.NoError(t, err)
+	_, err = service.UpdateURL(ctx, "", shortURL, model.URLUpdate{OriginalURL: ptr.Of("https://b.com")})
+	require.NoError(t, err)
+	_, err = service.UpdateURL(ctx, "", shortURL, model.URLUpdate{OriginalURL: ptr.Of("https://b.com")})
+	require.NoError(t, err, "nothing changed")
+	_, err = service.RenameTag(ctx, "old", "new")
+	require.NoError(t, err)
+	_, err = service.UpdateMember(ctx, &model.Member{Workspace: "acme", ID: "ann", Role: model.RoleOwner})
+	require.NoError(t, err, "nothing changed")
+	require.NoError(t, service.DeleteAPIKey(ctx, "acme", key.ID))
+
+	entries, err := service.AuditLog(context.Background(), model.AuditFilter{})
+	require.NoError(t, err)
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{model.AuditWorkspaceCreate, model.AuditMemberCreate, model.AuditKeyCreate,
+		model.AuditURLCreate, model.AuditURLUpdate, model.AuditURLUpdate, model.AuditKeyDelete}, actions)
+
+	keyCreated := entries[2]
+	assert.Equal(t, audit.ActorAdmin, keyCreated.Actor)
+	assert.Equal(t, "acme", keyCreated.Workspace)
+	assert.NotContains(t, keyCreated.Changes, "key", "the secret isn't logged")
+
+	updated := entries[4]
+	assert.Equal(t, shortURL, updated.Resource)
+	assert.Equal(t, "ann", updated.Actor)
+	assert.Equal(t, "203.0.113.9", updated.IP)
+	assert.Equal(t, "req-1", updated.RequestID)
+	assert.Equal(t, model.AuditChange{Before: json.RawMessage(`"https://a.com"`),
+		After: json.RawMessage(`"https://b.com"`)}, updated.Changes["originalURL"])
+	assert.NotNil(t, updated.Changes["updatedAt"].After)
+	assert.Len(t, updated.Changes, 2)
+	assert.Equal(t, json.RawMessage(`["new"]`), entries[5].Changes["tags"].After)
+
+	revoked := entries[6]
+	assert.Equal(t, json.RawMessage(`"ci"`), revoked.Changes["name"].Before)
+	assert.Nil(t, revoked.Changes["name"].After)
+
+	filtered, err := service.AuditLog(context.Background(), model.AuditFilter{Resource: shortURL, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, model.AuditURLCreate, filtered[0].Action)
+
+	verification, err := service.VerifyAuditLog(context.Background())
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, int64(7), verification.Entries)
+}
+
+func TestAuditHandlers(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithAuditLog(audit.New(repo)))
+	base, err := NewBaseURL(DefaultBaseURL, []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	handler := NewHandler(service, WithAdminKey("admin-secret"), WithBaseURL(base))
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+	server := middleware.Chain(mux, handler.Source, middleware.RequestID)
+
+	serve := func(req *http.Request, token string) *httptest.ResponseRecorder {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	req := makeJSONRequest(http.MethodPost, "/workspaces", workspaceRequest{Workspace: model.Workspace{ID: "acme"},
+		Owner: "ann"})
+	req.RemoteAddr = "10.1.2.3:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	req.Header.Set(middleware.RequestIDHeader, "create-acme")
+	rr := serve(req, "admin-secret")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created workspaceResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+
+	rr = serve(makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"}),
+		created.APIKey.Key)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	requestID := rr.Header().Get(middleware.RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+
+	for _, tt := range []struct {
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{"/audit", created.APIKey.Key, http.StatusForbidden, ""},
+		{"/audit", "", http.StatusForbidden, ""},
+		{"/audit?from=yesterday", "admin-secret", http.StatusBadRequest, "RFC 3339"},
+		{"/audit?after=-1", "admin-secret", http.StatusBadRequest, "after"},
+		{"/audit?actor=admin&action=workspace.create", "admin-secret", http.StatusOK,
+			`"ip":"198.51.100.7","requestID":"create-acme"`},
+		{"/audit?actor=ann", "admin-secret", http.StatusOK, `"action":"url.create"`},
+		{"/audit?actor=ann", "admin-secret", http.StatusOK, `"requestID":"` + requestID + `"`},
+		{"/audit?limit=1", "admin-secret", http.StatusOK, `"next":1`},
+		{"/audit?from=2999-01-01T00:00:00Z", "admin-secret", http.StatusOK, `{"entries":[]}`},
+		{"/audit/verify", "admin-secret", http.StatusOK, `"valid":true,"entries":4`},
+		{"/audit/verify", created.APIKey.Key, http.StatusForbidden, ""},
+	} {
+		rr := serve(httptest.NewRequest(http.MethodGet, tt.path, nil), tt.token)
+		assert.Equal(t, tt.status, rr.Code, tt.path)
+		assert.Contains(t, rr.Body.String(), tt.body, tt.path)
+	}
+
+	disabled := http.NewServeMux()
+	NewHandler(NewService(repo), WithAdminKey("admin-secret")).Routes(disabled)
+	req = httptest.NewRequest(http.MethodGet, "/audit", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr = httptest.NewRecorder()
+	disabled.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "no audit log without sinks")
+}
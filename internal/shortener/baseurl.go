@@ -0,0 +1,206 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultBaseURL is the public address short links are served from unless configured.
+const DefaultBaseURL = "http://localhost:8080/"
+
+// defaultBaseURL is used by handlers and services created without a base URL.
+var defaultBaseURL = mustBaseURL(DefaultBaseURL)
+
+// BaseURL is the public address of the app: scheme, host and the path it's mounted on. Behind a trusted
+// proxy the scheme and host the client used are taken from X-Forwarded-Proto and X-Forwarded-Host.
+type BaseURL struct {
+	scheme  string
+	host    string
+	prefix  string // Path the app is mounted on without the trailing slash, "" at the root.
+	trusted prefixes
+}
+
+// NewBaseURL parses the public base URL, e.g. https://sho.rt/ or https://example.com/links/, and the
+// addresses or CIDR ranges of the proxies whose forwarded headers are trusted.
+func NewBaseURL(rawURL string, trustedProxies []string) (*BaseURL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("base url: must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return nil, errors.New("base url: must contain a host")
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("base url: must only have a scheme, host and path")
+	}
+
+	base := &BaseURL{scheme: u.Scheme, host: strings.ToLower(u.Host)}
+	if p := path.Clean("/" + u.Path); p != "/" {
+		if strings.ContainsAny(p, "{} ") {
+			return nil, errors.New("base url: path can't contain braces or spaces")
+		}
+		base.prefix = p
+	}
+
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("trusted proxy %q: must be an IP address or CIDR range", proxy)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		base.trusted = append(base.trusted, prefix.Masked())
+	}
+	return base, nil
+}
+
+func mustBaseURL(rawURL string) *BaseURL {
+	base, err := NewBaseURL(rawURL, nil)
+	if err != nil {
+		panic(err)
+	}
+	return base
+}
+
+// String returns the configured base URL, always ending with a slash.
+func (b *BaseURL) String() string {
+	return b.scheme + "://" + b.host + b.prefix + "/"
+}
+
+// Prefix returns the path the app is mounted on without the trailing slash, "" at the root.
+func (b *BaseURL) Prefix() string {
+	return b.prefix
+}
+
+// For returns the base URL the client of the request used: the configured one, or the forwarded scheme and
+// host when the request came through a trusted proxy.
+func (b *BaseURL) For(r *http.Request) string {
+	if !b.trustedProxy(r) {
+		return b.String()
+	}
+	scheme, host := b.scheme, b.host
+	if proto := strings.ToLower(forwarded(r, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	if fwd := forwardedHost(r); fwd != "" {
+		host = fwd
+	}
+	return scheme + "://" + host + b.prefix + "/"
+}
+
+// Host returns the Host the client of the request used, forwarded by a trusted proxy or the request's own.
+func (b *BaseURL) Host(r *http.Request) string {
+	if b.trustedProxy(r) {
+		if host := forwardedHost(r); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+// Link returns the public URL of the short code on the domain, the one the request was made to if empty.
+func (b *BaseURL) Link(r *http.Request, domain, shortURL string) string {
+	if domain == "" {
+		return b.For(r) + url.PathEscape(shortURL)
+	}
+	return "https://" + domain + b.prefix + "/" + url.PathEscape(shortURL)
+}
+
+// Contains reports whether the URL points at the app, so a link to it would redirect to another short link.
+func (b *BaseURL) Contains(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Host, b.host) {
+		return false
+	}
+	return b.mounted(u.Path)
+}
+
+// mounted reports whether the path is one the app serves, i.e. under the prefix.
+func (b *BaseURL) mounted(p string) bool {
+	return b.prefix == "" || p == b.prefix || strings.HasPrefix(p, b.prefix+"/")
+}
+
+// ClientIP returns the address of the client of the request. Behind trusted proxies it's the last address of
+// X-Forwarded-For that isn't a trusted proxy, the ones before it could be made up by the client.
+func (b *BaseURL) ClientIP(r *http.Request) string {
+	addr, ok := remoteAddr(r)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !b.trusted.contains(addr) {
+		return addr.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !b.trusted.contains(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+// trustedProxy reports whether the request came directly from a trusted proxy.
+func (b *BaseURL) trustedProxy(r *http.Request) bool {
+	addr, ok := remoteAddr(r)
+	return ok && b.trusted.contains(addr)
+}
+
+// remoteAddr returns the address the request came from directly.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// prefixes are the address ranges of trusted proxies.
+type prefixes []netip.Prefix
+
+func (p prefixes) contains(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded returns the first value of a forwarded header, the one set by the proxy closest to the client.
+func forwarded(r *http.Request, header string) string {
+	value, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(value)
+}
+
+// forwardedHost returns the X-Forwarded-Host of the request, "" if missing or not a plain host[:port].
+func forwardedHost(r *http.Request) string {
+	host := forwarded(r, "X-Forwarded-Host")
+	if u, err := url.Parse("//" + host); err != nil || u.Host != host || u.User != nil || u.Path != "" {
+		return ""
+	}
+	return host
+}
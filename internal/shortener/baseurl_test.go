@@ -0,0 +1,181 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewBaseURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input  string
+		want   string
+		prefix string
+		err    bool
+	}{
+		{input: "http://localhost:8080", want: "http://localhost:8080/"},
+		{input: "https://Sho.rt/", want: "https://sho.rt/"},
+		{input: "https://example.com/links", want: "https://example.com/links/", prefix: "/links"},
+		{input: "https://example.com//a/../links/", want: "https://example.com/links/", prefix: "/links"},
+		{input: "ftp://example.com/", err: true},
+		{input: "/links/", err: true},
+		{input: "https://example.com/?a=b", err: true},
+		{input: "https://user@example.com/", err: true},
+		{input: "https://example.com/{code}/", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			base, err := NewBaseURL(tt.input, nil)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, base.String())
+			assert.Equal(t, tt.prefix, base.Prefix())
+		})
+	}
+
+	_, err := NewBaseURL(DefaultBaseURL, []string{"10.0.0.0/8", " 192.168.1.1 ", "", "::1"})
+	assert.NoError(t, err)
+	_, err = NewBaseURL(DefaultBaseURL, []string{"proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestBaseURL_ForwardedHeaders(t *testing.T) {
+	t.Parallel()
+	base, err := NewBaseURL("http://localhost:8080/links/", []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	request := func(remoteAddr string, headers map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/links/abc", nil)
+		r.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+	forwarded := map[string]string{"X-Forwarded-Host": "sho.rt, inner.proxy", "X-Forwarded-Proto": "https"}
+
+	trusted := request("10.1.2.3:4000", forwarded)
+	assert.Equal(t, "https://sho.rt/links/", base.For(trusted))
+	assert.Equal(t, "sho.rt", base.Host(trusted))
+	assert.Equal(t, "https://sho.rt/links/abc", base.Link(trusted, "", "abc"))
+	assert.Equal(t, "https://go.acme.com/links/abc", base.Link(trusted, "go.acme.com", "abc"))
+
+	untrusted := request("203.0.113.9:4000", forwarded)
+	assert.Equal(t, "http://localhost:8080/links/", base.For(untrusted))
+	assert.Equal(t, "example.com", base.Host(untrusted))
+
+	mapped := request("[::ffff:10.0.0.1]:4000", map[string]string{"X-Forwarded-Proto": "gopher"})
+	assert.Equal(t, "http://localhost:8080/links/", base.For(mapped), "invalid proto and missing host are ignored")
+
+	spoofed := request("10.1.2.3:4000", map[string]string{"X-Forwarded-Host": "evil.com/phish?"})
+	assert.Equal(t, "http://localhost:8080/links/", base.For(spoofed))
+	assert.Equal(t, "example.com", base.Host(spoofed))
+}
+
+func TestBaseURL_ClientIP(t *testing.T) {
+	t.Parallel()
+	base, err := NewBaseURL(DefaultBaseURL, []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{remoteAddr: "203.0.113.9:4000", want: "203.0.113.9"},
+		{remoteAddr: "203.0.113.9:4000", forwarded: "198.51.100.1", want: "203.0.113.9"},
+		{remoteAddr: "10.1.2.3:4000", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{remoteAddr: "10.1.2.3:4000", forwarded: "1.2.3.4, 198.51.100.1, 10.0.0.7", want: "198.51.100.1"},
+		{remoteAddr: "10.1.2.3:4000", forwarded: "10.0.0.8, 10.0.0.7", want: "10.0.0.8"},
+		{remoteAddr: "10.1.2.3:4000", forwarded: "not-an-ip, 10.0.0.7", want: "10.0.0.7"},
+		{remoteAddr: "10.1.2.3:4000", want: "10.1.2.3"},
+		{remoteAddr: "[::ffff:203.0.113.9]:4000", want: "203.0.113.9"},
+		{remoteAddr: "pipe", want: "pipe"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		assert.Equal(t, tt.want, base.ClientIP(r), "%s forwarded for %q", tt.remoteAddr, tt.forwarded)
+	}
+}
+
+func TestBaseURL_Contains(t *testing.T) {
+	t.Parallel()
+	root, err := NewBaseURL("http://localhost:8080/", nil)
+	require.NoError(t, err)
+	assert.True(t, root.Contains("http://localhost:8080/Lu8S545"))
+	assert.True(t, root.Contains("https://LOCALHOST:8080"))
+	assert.False(t, root.Contains("http://localhost:9090/abc"))
+	assert.False(t, root.Contains("https://example.com/abc"))
+
+	mounted, err := NewBaseURL("https://example.com/links/", nil)
+	require.NoError(t, err)
+	assert.True(t, mounted.Contains("https://example.com/links/abc"))
+	assert.True(t, mounted.Contains("https://example.com/links"))
+	assert.False(t, mounted.Contains("https://example.com/linksabc"))
+	assert.False(t, mounted.Contains("https://example.com/blog/post"))
+}
+
+func TestShortenerService_SelfReferencing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	domains, repo, _ := newTestDomains()
+	now := time.Now()
+	require.NoError(t, repo.SaveDomain(ctx, &model.Domain{Name: "go.acme.com", Owner: "acme", VerifiedAt: &now}))
+	base, err := NewBaseURL("https://example.com/links/", nil)
+	require.NoError(t, err)
+	service := NewService(repo, WithSelfURL(base), WithDomains(domains))
+
+	for _, rawURL := range []string{"https://example.com/links/abc", "https://go.acme.com/links/abc"} {
+		_, err := service.SaveURL(ctx, &model.URL{OriginalURL: rawURL, CustomURL: ptr.Of("mylink")})
+		assert.EqualError(t, err, "invalid url", rawURL)
+	}
+	for _, rawURL := range []string{"https://example.com/blog", "http://localhost:8080/abc"} {
+		_, err := service.SaveURL(ctx, &model.URL{OriginalURL: rawURL})
+		assert.NoError(t, err, rawURL)
+	}
+
+	_, err = NewService(repository.NewInMemory()).SaveURL(ctx, &model.URL{OriginalURL: "http://localhost:8080/abc"})
+	assert.EqualError(t, err, "invalid url", "the default base url is rejected without config")
+}
+
+func TestShortenURL_BaseURL(t *testing.T) {
+	t.Parallel()
+	base, err := NewBaseURL("https://example.com/links/", []string{"127.0.0.1"})
+	require.NoError(t, err)
+	mockService := newMockService(t)
+	mockService.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return("abc", nil).Times(2)
+	handler := NewHandler(mockService, WithBaseURL(base))
+
+	rr := httptest.NewRecorder()
+	handler.ShortenURL(rr, makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"}))
+	var res model.URL
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.Equal(t, "https://example.com/links/abc", res.ShortURL)
+
+	req := makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"})
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("X-Forwarded-Host", "sho.rt")
+	rr = httptest.NewRecorder()
+	handler.ShortenURL(rr, req)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.Equal(t, "https://sho.rt/links/abc", res.ShortURL)
+}
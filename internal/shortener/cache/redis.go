@@ -15,6 +15,9 @@ type RedisInterface interface {
 	Set(ctx context.Context, key string, value any, ttl time.Duration) error
 	Get(ctx context.Context, key string, dest any) error
 	Increment(ctx context.Context, key string) (int64, error)
+	IncrementBy(ctx context.Context, key string, value int64) (int64, error)
+	SetMax(ctx context.Context, key string, value int64) (int64, error)
+	Delete(ctx context.Context, key string) error
 }
 
 // RedisCache represents the redis client.
@@ -63,3 +66,29 @@ func (r *RedisCache) Increment(ctx context.Context, key string) (int64, error) {
 	val, err := r.client.Incr(ctx, key).Result()
 	return val, err
 }
+
+// IncrementBy increases the counter value for a given key by value and returns the updated value.
+func (r *RedisCache) IncrementBy(ctx context.Context, key string, value int64) (int64, error) {
+	return r.client.IncrBy(ctx, key, value).Result()
+}
+
+// setMaxScript raises the integer at KEYS[1] to ARGV[1] if it's lower, in one atomic step.
+var setMaxScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local value = tonumber(ARGV[1])
+if current < value then
+	redis.call('SET', KEYS[1], ARGV[1])
+	return value
+end
+return current
+`)
+
+// SetMax sets the counter at key to value unless it's already higher, and returns the resulting value.
+func (r *RedisCache) SetMax(ctx context.Context, key string, value int64) (int64, error) {
+	return setMaxScript.Run(ctx, r.client, []string{key}, value).Int64()
+}
+
+// Delete removes the key from Redis. Deleting a missing key isn't an error.
+func (r *RedisCache) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, key).Err()
+}
@@ -205,3 +205,50 @@ func TestRedisCache_Increment(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCache_IncrementBy(t *testing.T) {
+	t.Parallel()
+	db, mock := redismock.NewClientMock()
+	cache := NewRedis(db)
+
+	mock.ExpectIncrBy("counter", 1000).SetVal(3000)
+	mock.ExpectIncrBy("counter", 1000).SetErr(errors.New("redis failure"))
+
+	val, err := cache.IncrementBy(context.Background(), "counter", 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3000), val)
+
+	_, err = cache.IncrementBy(context.Background(), "counter", 1000)
+	assert.EqualError(t, err, "redis failure")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisCache_SetMax(t *testing.T) {
+	t.Parallel()
+	db, mock := redismock.NewClientMock()
+	cache := NewRedis(db)
+
+	mock.ExpectEvalSha(setMaxScript.Hash(), []string{"counter"}, int64(100)).SetVal(int64(250))
+	mock.ExpectEvalSha(setMaxScript.Hash(), []string{"counter"}, int64(100)).SetErr(errors.New("redis failure"))
+
+	val, err := cache.SetMax(context.Background(), "counter", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), val)
+
+	_, err = cache.SetMax(context.Background(), "counter", 100)
+	assert.EqualError(t, err, "redis failure")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisCache_Delete(t *testing.T) {
+	t.Parallel()
+	db, mock := redismock.NewClientMock()
+	cache := NewRedis(db)
+
+	mock.ExpectDel("shorturl:abc").SetVal(1)
+	mock.ExpectDel("shorturl:abc").SetErr(errors.New("redis failure"))
+
+	assert.NoError(t, cache.Delete(context.Background(), "shorturl:abc"))
+	assert.Error(t, cache.Delete(context.Background(), "shorturl:abc"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,146 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// reserveTimeout limits how long a block reservation may take.
+const reserveTimeout = 5 * time.Second
+
+// ErrCounterClosed is returned by a CounterAllocator after Close.
+var ErrCounterClosed = errors.New("shortener: counter allocator closed")
+
+// CounterAllocator hands out counter values from blocks reserved in one round trip, so creating
+// links doesn't wait on Redis or the database for every value. Values are unique across instances
+// but only increase per instance, and unused values are lost on shutdown, leaving gaps.
+type CounterAllocator struct {
+	source repository.CounterBlocks
+	size   int
+
+	mu     sync.Mutex
+	ranges []model.CounterRange // Reserved and not yet handed out, in order.
+	closed bool
+}
+
+var _ Counter = &CounterAllocator{}
+
+// NewCounterAllocator returns a CounterAllocator reserving size values at a time.
+func NewCounterAllocator(source repository.CounterBlocks, size int) *CounterAllocator {
+	return &CounterAllocator{source: source, size: max(1, size)}
+}
+
+// IncrementCounter returns the next reserved value, reserving a new block when the current one is used up.
+func (a *CounterAllocator) IncrementCounter() (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return 0, ErrCounterClosed
+	}
+
+	if len(a.ranges) == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), reserveTimeout)
+		defer cancel()
+		ranges, err := a.source.ReserveCounters(ctx, a.size)
+		if err != nil {
+			return 0, err
+		}
+		if len(ranges) == 0 {
+			return 0, errors.New("shortener: no counters reserved")
+		}
+		a.ranges = ranges
+	}
+
+	current := &a.ranges[0]
+	value := current.First
+	if current.First == current.Last {
+		a.ranges = a.ranges[1:]
+	} else {
+		current.First++
+	}
+	return value, nil
+}
+
+// Close abandons the values that weren't handed out. They can't be returned to a shared counter,
+// so they're never used. Later calls to IncrementCounter fail.
+func (a *CounterAllocator) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+
+	var unused uint64
+	for _, r := range a.ranges {
+		unused += r.Len()
+	}
+	a.ranges = nil
+	if unused > 0 {
+		l.Logger.Info("abandoned reserved counters", "count", unused)
+	}
+}
+
+// SeedCounter starts a durable counter that doesn't exist yet after the highest code made by the
+// counter generator, so it won't hand out codes that are taken. Codes are decoded with the obfuscator
+// if one is set; custom aliases, codes of other generators and codes that don't decode to a counter
+// value are skipped.
+func SeedCounter(ctx context.Context, urls repository.URL, counter repository.DurableCounter,
+	obfuscator *IDObfuscator) error {
+	current, err := counter.CurrentCounter(ctx)
+	if err != nil || current > 0 {
+		return err
+	}
+
+	const batchSize = 1000
+	var highest uint64
+	for offset := 0; ; offset += batchSize {
+		links, err := urls.ListURLs(ctx, model.URLFilter{Limit: batchSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if value, ok := counterValue(link, obfuscator); ok {
+				highest = max(highest, value)
+			}
+		}
+		if len(links) < batchSize {
+			break
+		}
+	}
+
+	if highest == 0 {
+		return nil
+	}
+	l.Logger.Info("seeding counter from existing short urls", "value", highest)
+	return counter.AdvanceCounter(ctx, highest)
+}
+
+// counterValue returns the counter value a link's code was generated from. Links stored before their
+// generator was have none, their codes are decoded unless they look like ones of the words generator.
+func counterValue(link model.URL, obfuscator *IDObfuscator) (uint64, bool) {
+	switch {
+	case link.CustomURL != nil && *link.CustomURL != "", len(link.ShortURL) > MaxGeneratedLength:
+		return 0, false
+	case link.Generator == "" && isWordsCode(link.ShortURL):
+		return 0, false
+	case link.Generator != "" && link.Generator != GeneratorCounter:
+		return 0, false
+	}
+	value, err := DecodeBase62(link.ShortURL)
+	if err != nil {
+		return 0, false
+	}
+	if obfuscator != nil {
+		if value, err = obfuscator.Decode(value); err != nil {
+			return 0, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,243 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCounter is a shared counter that takes latency per round trip, like Redis INCR/INCRBY.
+type fakeCounter struct {
+	mu      sync.Mutex
+	next    uint64
+	calls   int
+	latency time.Duration
+	ranges  func(first uint64, n int) []model.CounterRange // Defaults to one contiguous range.
+	err     error
+}
+
+func (f *fakeCounter) IncrementCounter() (uint64, error) {
+	ranges, err := f.ReserveCounters(context.Background(), 1)
+	if err != nil {
+		return 0, err
+	}
+	return ranges[0].First, nil
+}
+
+func (f *fakeCounter) ReserveCounters(_ context.Context, n int) ([]model.CounterRange, error) {
+	time.Sleep(f.latency)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	first := f.next + 1
+	f.next += uint64(n)
+	if f.ranges != nil {
+		return f.ranges(first, n), nil
+	}
+	return []model.CounterRange{{First: first, Last: f.next}}, nil
+}
+
+var _ repository.CounterBlocks = &fakeCounter{}
+
+func TestCounterAllocator(t *testing.T) {
+	t.Parallel()
+	source := &fakeCounter{}
+	allocator := NewCounterAllocator(source, 3)
+
+	for want := uint64(1); want <= 7; want++ {
+		got, err := allocator.IncrementCounter()
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.Equal(t, 3, source.calls)
+}
+
+func TestCounterAllocatorSplitRanges(t *testing.T) {
+	t.Parallel()
+	// Values reserved by other instances in between, e.g. concurrent Postgres nextval calls.
+	source := &fakeCounter{ranges: func(first uint64, _ int) []model.CounterRange {
+		return []model.CounterRange{{First: first, Last: first}, {First: first + 5, Last: first + 6}}
+	}}
+	allocator := NewCounterAllocator(source, 3)
+
+	var got []uint64
+	for range 3 {
+		value, err := allocator.IncrementCounter()
+		require.NoError(t, err)
+		got = append(got, value)
+	}
+	assert.Equal(t, []uint64{1, 6, 7}, got)
+	assert.Equal(t, 1, source.calls)
+}
+
+func TestCounterAllocatorConcurrent(t *testing.T) {
+	t.Parallel()
+	source := &fakeCounter{}
+	allocator := NewCounterAllocator(source, 10)
+
+	var (
+		mu   sync.Mutex
+		seen = map[uint64]bool{}
+		wg   sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				value, err := allocator.IncrementCounter()
+				assert.NoError(t, err)
+				mu.Lock()
+				assert.False(t, seen[value], "duplicate value %d", value)
+				seen[value] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seen, 800)
+	assert.Equal(t, 80, source.calls)
+}
+
+func TestCounterAllocatorError(t *testing.T) {
+	t.Parallel()
+	source := &fakeCounter{err: errors.New("redis down")}
+	allocator := NewCounterAllocator(source, 10)
+
+	_, err := allocator.IncrementCounter()
+	assert.EqualError(t, err, "redis down")
+}
+
+func TestCounterAllocatorClose(t *testing.T) {
+	t.Parallel()
+	source := &fakeCounter{}
+	allocator := NewCounterAllocator(source, 10)
+
+	value, err := allocator.IncrementCounter()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), value)
+
+	allocator.Close()
+	allocator.Close()
+	_, err = allocator.IncrementCounter()
+	require.ErrorIs(t, err, ErrCounterClosed)
+
+	// The rest of the block is abandoned, the next instance continues after it.
+	next, err := NewCounterAllocator(source, 10).IncrementCounter()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), next)
+}
+
+// durableCounter is an in-memory repository.DurableCounter.
+type durableCounter struct {
+	fakeCounter
+}
+
+func (d *durableCounter) CurrentCounter(_ context.Context) (uint64, error) {
+	return d.next, nil
+}
+
+func (d *durableCounter) AdvanceCounter(_ context.Context, value uint64) error {
+	d.next = max(d.next, value)
+	return nil
+}
+
+func TestSeedCounter(t *testing.T) {
+	t.Parallel()
+	obfuscator, err := NewIDObfuscator([]byte("secret"), 36)
+	require.NoError(t, err)
+	obfuscated, err := obfuscator.Encode(300)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		codes      []string
+		custom     []string
+		generated  map[string]string // Codes stored with their generator.
+		current    uint64
+		obfuscator *IDObfuscator
+		expected   uint64
+	}{
+		{name: "Highest counter code", codes: []string{"1", "1C", "a"}, expected: 100},
+		{name: "Custom aliases skipped", codes: []string{"1C"}, custom: []string{"zzzzzz"}, expected: 100},
+		{name: "Invalid codes skipped", codes: []string{"1C", "bad-code", "zzzzzzzzzzz"}, expected: 100},
+		{name: "Other generators skipped", codes: []string{"1C"}, generated: map[string]string{
+			"CalmOwl42": GeneratorWords, "Zq81xKp": GeneratorRandom, "1D": GeneratorCounter}, expected: 101},
+		{name: "Words codes without a generator skipped", codes: []string{"1C", "CalmOwl42", "NewYak07"},
+			expected: 100},
+		{name: "Existing counter kept", codes: []string{"1C"}, current: 5, expected: 5},
+		{name: "Empty", expected: 0},
+		{
+			name:       "Obfuscated",
+			codes:      []string{EncodeBase62(obfuscated), "zzzzzzzz"},
+			obfuscator: obfuscator,
+			expected:   300,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			repo := repository.NewInMemory()
+			for _, code := range tt.codes {
+				require.NoError(t, repo.SaveURL(context.Background(), &model.URL{ShortURL: code}))
+			}
+			for code, generator := range tt.generated {
+				require.NoError(t, repo.SaveURL(context.Background(), &model.URL{ShortURL: code, Generator: generator}))
+			}
+			for _, code := range tt.custom {
+				require.NoError(t, repo.SaveURL(context.Background(), &model.URL{ShortURL: code, CustomURL: &code}))
+			}
+			counter := &durableCounter{fakeCounter{next: tt.current}}
+
+			require.NoError(t, SeedCounter(context.Background(), repo, counter, tt.obfuscator))
+			assert.Equal(t, tt.expected, counter.next)
+		})
+	}
+}
+
+// redisLatency is a typical round trip to Redis in the same data centre.
+const redisLatency = 200 * time.Microsecond
+
+// BenchmarkCounterPerLink is the old behaviour: one round trip per link.
+func BenchmarkCounterPerLink(b *testing.B) {
+	source := &fakeCounter{latency: redisLatency}
+	for b.Loop() {
+		if _, err := source.IncrementCounter(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCounterAllocator reserves 1000 values per round trip.
+func BenchmarkCounterAllocator(b *testing.B) {
+	allocator := NewCounterAllocator(&fakeCounter{latency: redisLatency}, 1000)
+	for b.Loop() {
+		if _, err := allocator.IncrementCounter(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCounterAllocatorParallel(b *testing.B) {
+	allocator := NewCounterAllocator(&fakeCounter{latency: redisLatency}, 1000)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := allocator.IncrementCounter(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
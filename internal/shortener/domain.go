@@ -0,0 +1,382 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"golang.org/x/net/idna"
+)
+
+// Domain verification record: a TXT record at DomainVerificationPrefix.{domain} holding
+// DomainVerificationValue followed by the domain's token.
+const (
+	DomainVerificationPrefix = "_url-shortener"
+	DomainVerificationValue  = "url-shortener-verification="
+)
+
+// Resolve remembers whether a host is a verified domain for domainCacheTTL. Host headers are chosen by
+// clients, so the cache is emptied once it holds maxCachedHosts.
+const (
+	domainCacheTTL = time.Minute
+	maxCachedHosts = 10000
+)
+
+// Unverified registrations are kept for pendingDomainTTL, after that anyone can register the domain again. That
+// way registering a domain someone else controls only keeps it from them until then.
+const pendingDomainTTL = 72 * time.Hour
+
+// Reasons a custom domain can't be used.
+var (
+	ErrDomainName       = errors.New("must be a domain name such as go.example.com")
+	ErrDomainUnverified = errors.New("domain isn't verified")
+)
+
+// domainProfile maps domains like a browser does and rejects labels DNS can't hold.
+var domainProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// Resolver looks up DNS TXT records. *net.Resolver implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Domains registers custom domains, verifies their owners control them through a DNS TXT record and
+// tells which verified domain a request was made to.
+type Domains struct {
+	repo     repository.Domains
+	resolver Resolver
+	now      func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]domainCacheEntry
+}
+
+type domainCacheEntry struct {
+	verified bool
+	expires  time.Time
+}
+
+// NewDomains returns Domains stored in repo and verified with resolver, e.g. net.DefaultResolver.
+func NewDomains(repo repository.Domains, resolver Resolver) *Domains {
+	return &Domains{
+		repo:     repo,
+		resolver: resolver,
+		now:      time.Now,
+		hosts:    make(map[string]domainCacheEntry),
+	}
+}
+
+// NormalizeDomain returns the domain or Host header in the form it's stored in: lower case IDNA ASCII,
+// without port or trailing dot. IP addresses and names without a dot aren't domains.
+func NormalizeDomain(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	name = strings.TrimSuffix(name, ".")
+	if net.ParseIP(name) != nil || !strings.Contains(name, ".") {
+		return "", ErrDomainName
+	}
+
+	ascii, err := domainProfile.ToASCII(name)
+	if err != nil {
+		return "", ErrDomainName
+	}
+	return ascii, nil
+}
+
+// Record returns the TXT record that proves control of the domain.
+func (d *Domains) Record(domain *model.Domain) *model.DNSRecord {
+	return &model.DNSRecord{
+		Type:  "TXT",
+		Name:  DomainVerificationPrefix + "." + domain.Name,
+		Value: DomainVerificationValue + domain.Token,
+	}
+}
+
+// Register adds an unverified domain for the owner, replacing a registration that wasn't verified within
+// pendingDomainTTL. The returned domain has the record to create.
+func (d *Domains) Register(ctx context.Context, name, owner string) (*model.Domain, error) {
+	if anonymous(ctx) {
+		return nil, e.NewUnauthorizedError("registering a domain needs an api key")
+	}
+	normalized, err := NormalizeDomain(name)
+	if err != nil {
+		return nil, e.NewBadRequestError("domain %q: %s", name, err)
+	}
+	owner, err = workspaceOwner(ctx, strings.TrimSpace(owner))
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" {
+		return nil, e.NewBadRequestError("domain %q needs an owner", normalized)
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	domain := &model.Domain{
+		Name:      normalized,
+		Owner:     owner,
+		Token:     hex.EncodeToString(token),
+		CreatedAt: d.now().UTC(),
+	}
+	err = d.repo.SaveDomain(ctx, domain)
+	if errors.Is(err, e.ConflictError{}) {
+		if d.repo.ReplacePendingDomain(ctx, domain, domain.CreatedAt.Add(-pendingDomainTTL)) == nil {
+			d.forget(normalized)
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	domain.Record = d.Record(domain)
+	return domain, nil
+}
+
+// Verify looks up the domain's TXT record and marks the domain verified when it holds the token.
+func (d *Domains) Verify(ctx context.Context, name string) (*model.Domain, error) {
+	normalized, err := NormalizeDomain(name)
+	if err != nil {
+		return nil, e.NewBadRequestError("domain %q: %s", name, err)
+	}
+	domain, err := d.repo.GetDomain(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if !ownedBy(ctx, domain) {
+		return nil, e.NewNotFoundError("domain %q not found", normalized)
+	}
+	if domain.Verified() {
+		return domain, nil
+	}
+
+	record := d.Record(domain)
+	values, err := d.resolver.LookupTXT(ctx, record.Name)
+	if err != nil {
+		l.Logger.Info("domain verification lookup failed", "domain", normalized, "error", err)
+	}
+	if !slices.ContainsFunc(values, func(v string) bool { return strings.TrimSpace(v) == record.Value }) {
+		return nil, e.NewBadRequestError("%s: no TXT record %s with value %s found", ErrDomainUnverified,
+			record.Name, record.Value)
+	}
+
+	verifiedAt := d.now().UTC()
+	domain.VerifiedAt = &verifiedAt
+	if err := d.repo.UpdateDomain(ctx, domain); err != nil {
+		return nil, err
+	}
+	d.forget(normalized)
+	return domain, nil
+}
+
+// List returns the owner's domains, unverified ones with the record to create.
+func (d *Domains) List(ctx context.Context, owner string) ([]model.Domain, error) {
+	owner, err := workspaceOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := d.repo.ListDomains(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	for i := range domains {
+		if !domains[i].Verified() {
+			domains[i].Record = d.Record(&domains[i])
+		}
+	}
+	return domains, nil
+}
+
+// Verified returns the stored name of the domain, or a BadRequestError if it isn't registered and verified.
+func (d *Domains) Verified(ctx context.Context, name string) (string, error) {
+	normalized, err := NormalizeDomain(name)
+	if err != nil {
+		return "", e.NewBadRequestError("domain %q: %s", name, err)
+	}
+	domain, err := d.repo.GetDomain(ctx, normalized)
+	switch {
+	case errors.Is(err, e.NotFoundError{}), err == nil && !ownedBy(ctx, domain):
+		return "", e.NewBadRequestError("domain %q isn't registered", normalized)
+	case err != nil:
+		return "", err
+	case !domain.Verified():
+		return "", e.NewBadRequestError("domain %q: %s", normalized, ErrDomainUnverified)
+	}
+	return normalized, nil
+}
+
+// workspaceOwner returns the owner of domains in the context's workspace. Outside the default workspace
+// domains belong to the workspace itself.
+func workspaceOwner(ctx context.Context, owner string) (string, error) {
+	workspace, scoped := tenant.From(ctx)
+	if !scoped || workspace == tenant.Default {
+		return owner, nil
+	}
+	if owner != "" && owner != workspace {
+		return "", e.NewBadRequestError("domains of workspace %q are owned by the workspace", workspace)
+	}
+	return workspace, nil
+}
+
+// ownedBy reports whether the domain can be used in the context's workspace. Domains of the default workspace
+// need the admin key, requests without a key can't use any.
+func ownedBy(ctx context.Context, domain *model.Domain) bool {
+	workspace, scoped := tenant.From(ctx)
+	return !scoped || workspace == tenant.Default && !anonymous(ctx) || domain.Owner == workspace
+}
+
+// Resolve returns the verified domain a request with the Host header was made to, or "" for the default
+// host and anything else. Answers are cached for a minute since every redirect asks.
+func (d *Domains) Resolve(ctx context.Context, host string) string {
+	name, err := NormalizeDomain(host)
+	if err != nil {
+		return ""
+	}
+
+	now := d.now()
+	d.mu.Lock()
+	entry, ok := d.hosts[name]
+	d.mu.Unlock()
+	if !ok || now.After(entry.expires) {
+		domain, err := d.repo.GetDomain(ctx, name)
+		if err != nil && !errors.Is(err, e.NotFoundError{}) {
+			l.Logger.Error("failed to resolve domain", "host", name, "error", err)
+			return ""
+		}
+		entry = domainCacheEntry{verified: err == nil && domain.Verified(), expires: now.Add(domainCacheTTL)}
+		d.mu.Lock()
+		if len(d.hosts) >= maxCachedHosts {
+			clear(d.hosts)
+		}
+		d.hosts[name] = entry
+		d.mu.Unlock()
+	}
+
+	if !entry.verified {
+		return ""
+	}
+	return name
+}
+
+// forget drops the cached answer for the domain so Resolve sees its new state.
+func (d *Domains) forget(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.hosts, name)
+}
+
+// RegisterDomain registers a custom domain to serve short links from.
+// @Summary Register a custom domain
+// @Description Registers the domain for the owner. Short links can use it once it's verified: create the
+// @Description returned TXT record, then call the verify endpoint. Codes are unique per domain.
+// @Description Needs an admin's API key, the domain then belongs to the key's workspace, or the admin key.
+// @Description A registration that isn't verified within 72 hours can be replaced by registering the domain again.
+// @Tags Domains
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key}, or the admin key"
+// @Param requestBody body model.Domain true "Domain name and owner"
+// @Success 201 {object} model.Domain
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /domains [post]
+func (h *Handler) RegisterDomain(w http.ResponseWriter, r *http.Request) {
+	var request model.Domain
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	domain, err := h.service.RegisterDomain(ctx, request.Name, request.Owner)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, domain)
+}
+
+// VerifyDomain checks the verification TXT record of a domain.
+// @Summary Verify a custom domain
+// @Description Looks up the domain's TXT record. Once it holds the value returned on registration the domain
+// @Description is verified and requests to it are routed to its links.
+// @Tags Domains
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the domain's workspace, or the admin key"
+// @Param domain path string true "Domain name"
+// @Success 200 {object} model.Domain
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /domains/{domain}/verify [post]
+func (h *Handler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 10*time.Second)
+	defer cancel()
+
+	domain, err := h.service.VerifyDomain(ctx, r.PathValue("domain"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, domain)
+}
+
+// ListDomains lists the custom domains of an owner.
+// @Summary List custom domains
+// @Description Lists the owner's domains by name. Unverified domains include the TXT record to create.
+// @Tags Domains
+// @Produce json
+// @Param Authorization header string false "Bearer {api key}, lists the domains of the key's workspace"
+// @Param owner query string false "Owner of the domains, required without an api key"
+// @Success 200 {array} model.Domain
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /domains [get]
+func (h *Handler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	owner := r.URL.Query().Get("owner")
+	if workspace, _ := tenant.From(r.Context()); owner == "" && workspace == tenant.Default {
+		e.WriteJSONError(w, http.StatusBadRequest,
+			e.NewErrorResponse(http.StatusBadRequest, "invalid query", "owner is required"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	domains, err := h.service.ListDomains(ctx, owner)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+// writeJSON writes the value as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
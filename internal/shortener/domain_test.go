@@ -0,0 +1,311 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/mocks"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeResolver serves TXT records from a map.
+type fakeResolver struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if values, ok := r.records[name]; ok {
+		return values, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) set(name string, values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[name] = values
+}
+
+func newTestDomains() (*Domains, *repository.InMemoryRepo, *fakeResolver) {
+	repo := repository.NewInMemory()
+	resolver := &fakeResolver{records: make(map[string][]string)}
+	return NewDomains(repo, resolver), repo, resolver
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected string
+		err      error
+	}{
+		{input: "go.acme.com", expected: "go.acme.com"},
+		{input: "Go.ACME.com.", expected: "go.acme.com"},
+		{input: "go.acme.com:8443", expected: "go.acme.com"},
+		{input: "bücher.example", expected: "xn--bcher-kva.example"},
+		{input: "localhost", err: ErrDomainName},
+		{input: "localhost:8080", err: ErrDomainName},
+		{input: "127.0.0.1", err: ErrDomainName},
+		{input: "[::1]:8080", err: ErrDomainName},
+		{input: "go acme.com", err: ErrDomainName},
+		{input: strings.Repeat("a", 64) + ".com", err: ErrDomainName},
+		{input: "", err: ErrDomainName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			domain, err := NormalizeDomain(tt.input)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, domain)
+		})
+	}
+}
+
+func TestDomains_RegisterAndVerify(t *testing.T) {
+	t.Parallel()
+	domains, _, resolver := newTestDomains()
+	ctx := context.Background()
+
+	domain, err := domains.Register(ctx, "Go.Acme.com", "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", domain.Name)
+	assert.False(t, domain.Verified())
+	require.NotNil(t, domain.Record)
+	assert.Equal(t, "_url-shortener.go.acme.com", domain.Record.Name)
+	assert.Equal(t, DomainVerificationValue+domain.Token, domain.Record.Value)
+
+	_, err = domains.Register(ctx, "go.acme.com", "someone-else")
+	assert.ErrorIs(t, err, e.ConflictError{})
+	_, err = domains.Register(ctx, "go.example.com", " ")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	// Not verified until the TXT record holds the token.
+	_, err = domains.Verify(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	resolver.set(domain.Record.Name, "v=spf1 -all", DomainVerificationValue+"wrong")
+	_, err = domains.Verify(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = domains.Verified(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	resolver.set(domain.Record.Name, "v=spf1 -all", domain.Record.Value)
+	verified, err := domains.Verify(ctx, "go.acme.com")
+	require.NoError(t, err)
+	assert.True(t, verified.Verified())
+	name, err := domains.Verified(ctx, "GO.acme.com")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", name)
+
+	_, err = domains.Verify(ctx, "unknown.acme.com")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	_, err = domains.Verified(ctx, "unknown.acme.com")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	list, err := domains.List(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Nil(t, list[0].Record, "verified domains don't need the record")
+}
+
+func TestDomains_Resolve(t *testing.T) {
+	t.Parallel()
+	domains, repo, _ := newTestDomains()
+	ctx := context.Background()
+	now := time.Now()
+	domains.now = func() time.Time { return now }
+
+	verifiedAt := now
+	require.NoError(t, repo.SaveDomain(ctx, &model.Domain{Name: "go.acme.com", Owner: "acme", VerifiedAt: &verifiedAt}))
+	require.NoError(t, repo.SaveDomain(ctx, &model.Domain{Name: "pending.acme.com", Owner: "acme"}))
+
+	assert.Equal(t, "go.acme.com", domains.Resolve(ctx, "GO.acme.com:443"))
+	assert.Empty(t, domains.Resolve(ctx, "pending.acme.com"))
+	assert.Empty(t, domains.Resolve(ctx, "localhost:8080"))
+	assert.Empty(t, domains.Resolve(ctx, "unknown.acme.com"))
+
+	// Cached answers are used until they expire.
+	pending, err := repo.GetDomain(ctx, "pending.acme.com")
+	require.NoError(t, err)
+	pending.VerifiedAt = &verifiedAt
+	require.NoError(t, repo.UpdateDomain(ctx, pending))
+	assert.Empty(t, domains.Resolve(ctx, "pending.acme.com"))
+	now = now.Add(domainCacheTTL + time.Second)
+	assert.Equal(t, "pending.acme.com", domains.Resolve(ctx, "pending.acme.com"))
+}
+
+func TestDomains_PendingExpires(t *testing.T) {
+	t.Parallel()
+	domains, _, resolver := newTestDomains()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	domains.now = func() time.Time { return now }
+
+	squatted, err := domains.Register(ctx, "go.acme.com", "squatter")
+	require.NoError(t, err)
+	_, err = domains.Register(ctx, "go.acme.com", "acme")
+	assert.ErrorIs(t, err, e.ConflictError{}, "pending registrations are kept for a while")
+
+	now = now.Add(pendingDomainTTL + time.Second)
+	domain, err := domains.Register(ctx, "go.acme.com", "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", domain.Owner)
+	resolver.set(domain.Record.Name, squatted.Record.Value)
+	_, err = domains.Verify(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, e.BadRequestError{}, "the old token no longer verifies")
+	resolver.set(domain.Record.Name, domain.Record.Value)
+	_, err = domains.Verify(ctx, "go.acme.com")
+	require.NoError(t, err)
+
+	now = now.Add(2 * pendingDomainTTL)
+	_, err = domains.Register(ctx, "go.acme.com", "squatter")
+	assert.ErrorIs(t, err, e.ConflictError{}, "verified domains are never replaced")
+}
+
+func TestShortenerService_Domains(t *testing.T) {
+	t.Parallel()
+	domains, repo, resolver := newTestDomains()
+	service := NewService(repo, WithDomains(domains))
+	ctx := context.Background()
+
+	domain, err := service.RegisterDomain(ctx, "go.acme.com", "acme")
+	require.NoError(t, err)
+	link := &model.URL{OriginalURL: "https://example.com", Domain: "go.acme.com"}
+	_, err = service.SaveURL(ctx, link)
+	assert.ErrorIs(t, err, e.BadRequestError{}, "unverified domains can't have links")
+
+	resolver.set(domain.Record.Name, domain.Record.Value)
+	_, err = service.VerifyDomain(ctx, "go.acme.com")
+	require.NoError(t, err)
+
+	// The same alias on the default host and on the domain are different links.
+	for _, data := range []*model.URL{
+		{OriginalURL: "https://example.com/default", CustomURL: ptr.Of("spring")},
+		{OriginalURL: "https://example.com/acme", CustomURL: ptr.Of("spring"), Domain: "Go.Acme.com"},
+	} {
+		_, err = service.SaveURL(ctx, data)
+		require.NoError(t, err)
+	}
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of("spring"),
+		Domain: "go.acme.com"})
+	assert.ErrorIs(t, err, e.ConflictError{})
+
+	data, err := service.GetURL(ctx, "", "spring")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/default", data.OriginalURL)
+	data, err = service.GetURL(ctx, service.DomainForHost(ctx, "go.acme.com"), "spring")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme", data.OriginalURL)
+	assert.Equal(t, "go.acme.com", data.Domain)
+
+	availability, err := service.AliasAvailability(ctx, "go.acme.com", "spring")
+	require.NoError(t, err)
+	assert.Equal(t, model.AliasTaken, availability.Status)
+	availability, err = service.AliasAvailability(ctx, "go.acme.com", "summer")
+	require.NoError(t, err)
+	assert.Equal(t, model.AliasAvailable, availability.Status)
+}
+
+func TestShortenerService_WithoutDomains(t *testing.T) {
+	t.Parallel()
+	service := NewService(mocks.NewMockURL(gomock.NewController(t)))
+	ctx := context.Background()
+
+	_, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", Domain: "go.acme.com"})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = service.RegisterDomain(ctx, "go.acme.com", "acme")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	assert.Empty(t, service.DomainForHost(ctx, "go.acme.com"))
+}
+
+func TestDomainHandlers(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	mux := http.NewServeMux()
+	NewHandler(mockService, WithAdminKey(testAdminKey)).Routes(mux)
+
+	registered := &model.Domain{Name: "go.acme.com", Owner: "acme",
+		Record: &model.DNSRecord{Type: "TXT", Name: "_url-shortener.go.acme.com", Value: "token"}}
+	mockService.EXPECT().RegisterDomain(gomock.Any(), "go.acme.com", "acme").Return(registered, nil)
+	mockService.EXPECT().RegisterDomain(gomock.Any(), "go.acme.com", "acme").
+		Return(nil, e.NewConflictError("already registered"))
+	mockService.EXPECT().VerifyDomain(gomock.Any(), "go.acme.com").Return(nil, e.NewBadRequestError("no record"))
+	mockService.EXPECT().ListDomains(gomock.Any(), "acme").Return([]model.Domain{*registered}, nil)
+
+	for _, tt := range []struct {
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{makeJSONRequest(http.MethodPost, "/domains", map[string]string{"name": "go.acme.com", "owner": "acme"}),
+			http.StatusUnauthorized, "api key"},
+		{asAdmin(makeJSONRequest(http.MethodPost, "/domains", map[string]string{"name": "go.acme.com",
+			"owner": "acme"})), http.StatusCreated, `"verificationRecord":{"type":"TXT","name":"_url-shortener.go.acme.com"`},
+		{asAdmin(makeJSONRequest(http.MethodPost, "/domains", map[string]string{"name": "go.acme.com",
+			"owner": "acme"})), http.StatusConflict, "already registered"},
+		{httptest.NewRequest(http.MethodPost, "/domains/go.acme.com/verify", nil), http.StatusUnauthorized, "api key"},
+		{asAdmin(httptest.NewRequest(http.MethodPost, "/domains/go.acme.com/verify", nil)), http.StatusBadRequest,
+			"no record"},
+		{httptest.NewRequest(http.MethodGet, "/domains?owner=acme", nil), http.StatusOK, `"name":"go.acme.com"`},
+		{httptest.NewRequest(http.MethodGet, "/domains", nil), http.StatusBadRequest, "owner is required"},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tt.req)
+		assert.Equal(t, tt.status, rr.Code, tt.req.URL)
+		assert.Contains(t, rr.Body.String(), tt.body, tt.req.URL)
+	}
+}
+
+func TestRedirectURL_CustomDomain(t *testing.T) {
+	t.Parallel()
+	mockService := mocks.NewMockService(gomock.NewController(t))
+	mux := http.NewServeMux()
+	NewHandler(mockService).Routes(mux)
+
+	mockService.EXPECT().DomainForHost(gomock.Any(), "go.acme.com").Return("go.acme.com").Times(2)
+	mockService.EXPECT().GetURL(gomock.Any(), "go.acme.com", "spring").
+		Return(&model.URL{ShortURL: "spring", Domain: "go.acme.com", OriginalURL: "https://acme.com/sale"}, nil).Times(2)
+
+	req := httptest.NewRequest(http.MethodGet, "http://go.acme.com/spring", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://acme.com/sale", rr.Header().Get("Location"))
+
+	// QR codes encode the link on its own domain.
+	req = httptest.NewRequest(http.MethodGet, "http://go.acme.com/qr/spring?format=svg", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestShortenURL_CustomDomain(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	handler := NewHandler(mockService)
+	mockService.EXPECT().SaveURL(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.URL) (string,
+		error) {
+		assert.Equal(t, "go.acme.com", data.Domain)
+		return "spring", nil
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ShortenURL(rr, makeJSONRequest(http.MethodPost, "/shorten",
+		map[string]string{"originalURL": "https://acme.com/sale", "domain": "go.acme.com"}))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"shortURL":"https://go.acme.com/spring"`)
+}
@@ -0,0 +1,89 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// ExpiryNotifier publishes an events.LinkExpired event when the expiration date of a link passes.
+type ExpiryNotifier struct {
+	urls      repository.URL
+	events    events.Publisher
+	interval  time.Duration
+	batchSize int
+	now       func() time.Time
+	since     time.Time // Links that expired up to then were published.
+}
+
+// NewExpiryNotifier returns an ExpiryNotifier looking for expired links every interval. Links that expired
+// before it started aren't published.
+func NewExpiryNotifier(urls repository.URL, publisher events.Publisher, interval time.Duration) *ExpiryNotifier {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &ExpiryNotifier{urls: urls, events: publisher, interval: interval, batchSize: 100, now: time.Now,
+		since: time.Now()}
+}
+
+// Run publishes the links that expired every interval until the context is cancelled.
+func (n *ExpiryNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := n.Notify(ctx); err != nil && ctx.Err() == nil {
+			l.Logger.Error("expired link round failed", "error", err)
+		}
+	}
+}
+
+// Notify publishes the links that expired since the last call. An expiry has the same event ID on every
+// instance, so webhooks get it once however many instances run.
+func (n *ExpiryNotifier) Notify(ctx context.Context) error {
+	now := n.now()
+	for offset := 0; ; offset += n.batchSize {
+		links, err := n.urls.ListURLs(ctx, model.URLFilter{Limit: n.batchSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			expiry := link.ExpirationDate
+			if expiry == nil || !expiry.After(n.since) || expiry.After(now) {
+				continue
+			}
+			n.events.Publish(ctx, events.Event{
+				ID:         expiryEventID(&link),
+				Type:       events.LinkExpired,
+				ShortURL:   link.ShortURL,
+				Domain:     link.Domain,
+				Workspace:  link.Workspace,
+				OccurredAt: expiry.UTC(),
+				Data:       map[string]any{"link": snapshot(&link)},
+			})
+		}
+
+		if len(links) < n.batchSize {
+			n.since = now
+			return nil
+		}
+	}
+}
+
+// expiryEventID is the ID of the expiry of the link at its expiration date.
+func expiryEventID(link *model.URL) string {
+	sum := sha256.Sum256([]byte(link.Domain + "/" + link.ShortURL + "/" + link.ExpirationDate.UTC().String()))
+	return hex.EncodeToString(sum[:16])
+}
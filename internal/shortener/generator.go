@@ -0,0 +1,282 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// Names of the built-in code generators.
+const (
+	GeneratorCounter = "counter"
+	GeneratorRandom  = "random"
+	GeneratorHash    = "hash"
+	GeneratorWords   = "words"
+)
+
+// ErrGeneratorExhausted is returned when a generator has no more codes to try for a link.
+var ErrGeneratorExhausted = errors.New("shortener: no more codes to try")
+
+// CodeGenerator produces short codes for links created without a custom alias.
+type CodeGenerator interface {
+	// Generate returns the code to try for the link. attempt is 0 for the first try and goes up by one
+	// each time the previous code was already taken.
+	Generate(ctx context.Context, data *model.URL, attempt int) (string, error)
+}
+
+// Counter hands out unique, increasing IDs.
+type Counter interface {
+	IncrementCounter() (uint64, error)
+}
+
+// maxCounterSkips bounds how many counter values in a row the generator skips.
+const maxCounterSkips = 1000
+
+// CounterGenerator base62 encodes the next value of the counter, optionally obfuscated first.
+type CounterGenerator struct {
+	counter    Counter
+	obfuscator *IDObfuscator
+	skip       func(code string) bool // Codes never handed out, e.g. ones in the alias namespace.
+}
+
+// NewCounterGenerator returns a CounterGenerator. obfuscator may be nil for sequential codes.
+func NewCounterGenerator(counter Counter, obfuscator *IDObfuscator) *CounterGenerator {
+	return &CounterGenerator{counter: counter, obfuscator: obfuscator}
+}
+
+// Generate returns the code of the next counter value. Retries take a new value.
+func (g *CounterGenerator) Generate(_ context.Context, _ *model.URL, _ int) (string, error) {
+	for range maxCounterSkips {
+		id, err := g.counter.IncrementCounter()
+		if err != nil {
+			return "", err
+		}
+		if g.obfuscator != nil {
+			if id, err = g.obfuscator.Encode(id); err != nil {
+				return "", fmt.Errorf("failed to obfuscate counter: %w", err)
+			}
+		}
+		if code := EncodeBase62(id); g.skip == nil || !g.skip(code) {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("shortener: skipped %d counter values in a row", maxCounterSkips)
+}
+
+// RandomGenerator returns random base62 codes of a fixed length.
+type RandomGenerator struct {
+	length int
+	rand   io.Reader
+}
+
+// NewRandomGenerator returns a RandomGenerator of codes with length characters, between 1 and 10.
+func NewRandomGenerator(length int) (*RandomGenerator, error) {
+	if length < 1 || length > MaxGeneratedLength {
+		return nil, fmt.Errorf("shortener: random code length must be between 1 and %d, got %d",
+			MaxGeneratedLength, length)
+	}
+	return &RandomGenerator{length: length, rand: rand.Reader}, nil
+}
+
+// Generate returns a new random code on every attempt.
+func (g *RandomGenerator) Generate(_ context.Context, _ *model.URL, _ int) (string, error) {
+	code := make([]byte, g.length)
+	for i := range code {
+		n, err := rand.Int(g.rand, big.NewInt(int64(len(base62Chars))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = base62Chars[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// HashGenerator derives the code from the SHA-256 of the destination, so the same URL gets the same
+// code on every deployment. A collision salts the hash and extends the code by one character.
+type HashGenerator struct {
+	length int
+	rand   io.Reader
+}
+
+// NewHashGenerator returns a HashGenerator starting with codes of length characters, between 1 and 10.
+func NewHashGenerator(length int) (*HashGenerator, error) {
+	if length < 1 || length > MaxGeneratedLength {
+		return nil, fmt.Errorf("shortener: hash code length must be between 1 and %d, got %d",
+			MaxGeneratedLength, length)
+	}
+	return &HashGenerator{length: length, rand: rand.Reader}, nil
+}
+
+// Generate returns the first length base62 characters of the hash of the URL. Retries hash the URL with a
+// random salt and take one more character each, up to 10: once a URL was shortened its code and every
+// longer prefix of the same hash would always be taken.
+func (g *HashGenerator) Generate(_ context.Context, data *model.URL, attempt int) (string, error) {
+	input := []byte(data.OriginalURL)
+	if attempt > 0 {
+		salt := make([]byte, 8)
+		if _, err := io.ReadFull(g.rand, salt); err != nil {
+			return "", err
+		}
+		input = append(input, salt...)
+	}
+
+	sum := sha256.Sum256(input)
+	digest := encodeBase62Bytes(sum[:])
+	return digest[:min(g.length+attempt, MaxGeneratedLength)], nil
+}
+
+// encodeBase62Bytes encodes a big-endian number of any size to base62, left padded so equal
+// length inputs give equal length outputs. big.Int uses the same alphabet as base62Chars.
+func encodeBase62Bytes(b []byte) string {
+	// Characters needed for the largest value of len(b) bytes: ceil(8*len(b) / log2(62)).
+	width := (len(b)*8*1000 + 5953) / 5954
+	encoded := new(big.Int).SetBytes(b).Text(62)
+	if len(encoded) < width {
+		encoded = strings.Repeat("0", width-len(encoded)) + encoded
+	}
+	return encoded
+}
+
+// wordAdjectives and wordNouns have at most 4 letters so two words and two digits fit in 10 characters.
+var (
+	wordAdjectives = []string{
+		"Able", "Bold", "Busy", "Calm", "Cool", "Cozy", "Deep", "Fair",
+		"Fast", "Fine", "Fond", "Free", "Glad", "Gold", "Good", "Gray",
+		"Hazy", "Keen", "Kind", "Late", "Lazy", "Loud", "Lush", "Mild",
+		"Neat", "New", "Nice", "Odd", "Pink", "Pure", "Avid", "Rare",
+		"Red", "Rich", "Ripe", "Safe", "Shy", "Slim", "Soft", "Sly",
+		"Tall", "Tidy", "Tiny", "True", "Vast", "Warm", "Wise", "Wild",
+	}
+	wordNouns = []string{
+		"Ant", "Bear", "Bee", "Bird", "Boat", "Cat", "Clam", "Crab",
+		"Deer", "Dog", "Dove", "Duck", "Eel", "Elk", "Fern", "Fish",
+		"Fox", "Frog", "Goat", "Hare", "Hawk", "Kite", "Lake", "Lamb",
+		"Leaf", "Lion", "Lynx", "Mole", "Moon", "Moth", "Newt", "Oak",
+		"Owl", "Pine", "Pond", "Puma", "Rain", "Rose", "Seal", "Star",
+		"Swan", "Tuna", "Toad", "Tree", "Wave", "Wren", "Wolf", "Yak",
+	}
+)
+
+// WordsGenerator returns easy to read and say codes such as "CalmOwl42".
+type WordsGenerator struct {
+	rand io.Reader
+}
+
+// NewWordsGenerator returns a WordsGenerator.
+func NewWordsGenerator() *WordsGenerator {
+	return &WordsGenerator{rand: rand.Reader}
+}
+
+// Generate returns a random adjective, noun and two digit number.
+func (g *WordsGenerator) Generate(_ context.Context, _ *model.URL, _ int) (string, error) {
+	pick := func(n int) (int, error) {
+		v, err := rand.Int(g.rand, big.NewInt(int64(n)))
+		if err != nil {
+			return 0, err
+		}
+		return int(v.Int64()), nil
+	}
+
+	adjective, err := pick(len(wordAdjectives))
+	if err != nil {
+		return "", err
+	}
+	noun, err := pick(len(wordNouns))
+	if err != nil {
+		return "", err
+	}
+	number, err := pick(100)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s%02d", wordAdjectives[adjective], wordNouns[noun], number), nil
+}
+
+// isWordsCode reports whether the code is made of an adjective, a noun and two digits like the codes of
+// WordsGenerator.
+func isWordsCode(code string) bool {
+	for _, adjective := range wordAdjectives {
+		rest, ok := strings.CutPrefix(code, adjective)
+		if !ok {
+			continue
+		}
+		for _, noun := range wordNouns {
+			digits, ok := strings.CutPrefix(rest, noun)
+			if ok && len(digits) == 2 && '0' <= digits[0] && digits[0] <= '9' && '0' <= digits[1] && digits[1] <= '9' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GeneratorMetrics counts the codes each generator produced and how many were already taken.
+type GeneratorMetrics struct {
+	mu    sync.RWMutex
+	stats map[string]*generatorCounters
+}
+
+type generatorCounters struct {
+	attempts   atomic.Uint64
+	collisions atomic.Uint64
+	failures   atomic.Uint64
+}
+
+// GeneratorStats is a snapshot of the metrics of one generator.
+type GeneratorStats struct {
+	Attempts      uint64  `json:"attempts"`      // Codes generated.
+	Collisions    uint64  `json:"collisions"`    // Codes that were already taken.
+	Failures      uint64  `json:"failures"`      // Links that couldn't get a code.
+	CollisionRate float64 `json:"collisionRate"` // Collisions / Attempts.
+}
+
+// NewGeneratorMetrics returns empty metrics.
+func NewGeneratorMetrics() *GeneratorMetrics {
+	return &GeneratorMetrics{stats: make(map[string]*generatorCounters)}
+}
+
+func (m *GeneratorMetrics) counters(name string) *generatorCounters {
+	m.mu.RLock()
+	c, ok := m.stats[name]
+	m.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok = m.stats[name]; !ok {
+		c = &generatorCounters{}
+		m.stats[name] = c
+	}
+	return c
+}
+
+// Snapshot returns the current metrics by generator name.
+func (m *GeneratorMetrics) Snapshot() map[string]GeneratorStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[string]GeneratorStats, len(m.stats))
+	for name, c := range m.stats {
+		stats := GeneratorStats{
+			Attempts:   c.attempts.Load(),
+			Collisions: c.collisions.Load(),
+			Failures:   c.failures.Load(),
+		}
+		if stats.Attempts > 0 {
+			stats.CollisionRate = float64(stats.Collisions) / float64(stats.Attempts)
+		}
+		snapshot[name] = stats
+	}
+	return snapshot
+}
@@ -0,0 +1,153 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/jasoncheung94/url-shortener/internal/mocks"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCounterGenerator(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	gomock.InOrder(
+		mockRepo.EXPECT().IncrementCounter().Return(uint64(100), nil),
+		mockRepo.EXPECT().IncrementCounter().Return(uint64(101), nil),
+	)
+
+	generator := NewCounterGenerator(mockRepo, nil)
+	code, err := generator.Generate(context.Background(), &model.URL{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "1C", code)
+
+	code, err = generator.Generate(context.Background(), &model.URL{}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "1D", code)
+}
+
+func TestRandomGenerator(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRandomGenerator(0)
+	assert.Error(t, err)
+	_, err = NewRandomGenerator(11)
+	assert.Error(t, err)
+
+	generator, err := NewRandomGenerator(7)
+	require.NoError(t, err)
+	seen := map[string]bool{}
+	for range 1000 {
+		code, err := generator.Generate(context.Background(), &model.URL{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, code, 7)
+		assert.True(t, isGeneratedCode(code), code)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestHashGenerator(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHashGenerator(0)
+	assert.Error(t, err)
+
+	generator, err := NewHashGenerator(6)
+	require.NoError(t, err)
+	data := &model.URL{OriginalURL: "https://example.com"}
+
+	first, err := generator.Generate(context.Background(), data, 0)
+	require.NoError(t, err)
+	again, err := generator.Generate(context.Background(), data, 0)
+	require.NoError(t, err)
+	assert.Len(t, first, 6)
+	assert.Equal(t, first, again, "same URL gives the same code")
+
+	extended, err := generator.Generate(context.Background(), data, 2)
+	require.NoError(t, err)
+	assert.Len(t, extended, 8)
+	assert.NotEqual(t, first, extended[:6], "collisions salt the hash")
+
+	other, err := generator.Generate(context.Background(), &model.URL{OriginalURL: "https://example.org"}, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+
+	longest, err := generator.Generate(context.Background(), data, 5)
+	require.NoError(t, err)
+	assert.Len(t, longest, MaxGeneratedLength)
+}
+
+func TestHashGenerator_SameURL(t *testing.T) {
+	t.Parallel()
+	generator, err := NewHashGenerator(6)
+	require.NoError(t, err)
+	service := NewService(repository.NewInMemory(),
+		WithCodeGenerator(GeneratorHash, generator), WithDefaultCodeGenerator(GeneratorHash))
+
+	// Every shortening after the first collides with the unsalted code, and must still find a free one.
+	seen := map[string]bool{}
+	for range 10 {
+		code, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com"})
+		require.NoError(t, err)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestEncodeBase62Bytes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "00", encodeBase62Bytes([]byte{0}))
+	assert.Equal(t, "47", encodeBase62Bytes([]byte{255}))
+
+	sum := sha256.Sum256([]byte("https://example.com"))
+	encoded := encodeBase62Bytes(sum[:])
+	assert.Len(t, encoded, 43)
+	decoded, ok := new(big.Int).SetString(encoded, 62)
+	require.True(t, ok)
+	assert.Equal(t, sum[:], decoded.FillBytes(make([]byte, 32)))
+}
+
+func TestWordsGenerator(t *testing.T) {
+	t.Parallel()
+
+	for _, words := range [][]string{wordAdjectives, wordNouns} {
+		seen := map[string]bool{}
+		for _, word := range words {
+			assert.LessOrEqual(t, len(word), 4, word)
+			assert.False(t, seen[word], "duplicate word %s", word)
+			seen[word] = true
+		}
+	}
+
+	generator := NewWordsGenerator()
+	for range 100 {
+		code, err := generator.Generate(context.Background(), &model.URL{}, 0)
+		require.NoError(t, err)
+		assert.True(t, isGeneratedCode(code), code)
+	}
+}
+
+func TestGeneratorMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewGeneratorMetrics()
+	assert.Empty(t, metrics.Snapshot())
+
+	counters := metrics.counters(GeneratorRandom)
+	counters.attempts.Add(4)
+	counters.collisions.Add(1)
+	metrics.counters(GeneratorRandom).failures.Add(1)
+
+	assert.Equal(t, map[string]GeneratorStats{
+		GeneratorRandom: {Attempts: 4, Collisions: 1, Failures: 1, CollisionRate: 0.25},
+	}, metrics.Snapshot())
+}
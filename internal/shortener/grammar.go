@@ -0,0 +1,133 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Short code lengths in characters (Unicode code points, like Postgres VARCHAR). The short_url and
+// custom_url columns and the Mongo schema are sized to MaxCodeLength.
+const (
+	MinAliasLength     = 3
+	MaxAliasLength     = 20
+	MaxGeneratedLength = 10 // Base62 codes of the built-in generators, enough for 59 bit IDs.
+	MaxCodeLength      = max(MaxAliasLength, MaxGeneratedLength)
+)
+
+// AliasValidationTag is the validator tag checking custom aliases against the grammar, see RegisterValidation.
+const AliasValidationTag = "alias"
+
+// Reasons a code doesn't match the grammar.
+var (
+	ErrCodeLength    = fmt.Errorf("must be %d to %d characters", MinAliasLength, MaxAliasLength)
+	ErrCodeCharacter = errors.New("contains a character that isn't allowed")
+	ErrCodeSeparator = errors.New("- and _ can only be between other characters")
+)
+
+// CodeGrammar is the syntax of short codes, shared by request validation, the service, the redirect
+// handlers and the database schema so a code accepted in one place works everywhere.
+//
+// Generated codes are always 1 to MaxGeneratedLength base62 characters. Custom aliases are
+// MinAliasLength to MaxAliasLength ASCII letters and digits, optionally with - and _ between them
+// (Separators) and letters, digits and emoji of any script (Unicode). Unicode aliases are NFC
+// normalised so the same text typed differently is the same alias.
+//
+// Turning an option off makes existing aliases using it unreachable.
+type CodeGrammar struct {
+	Separators bool
+	Unicode    bool
+}
+
+// ValidateAlias returns why the alias can't be a custom alias: ErrCodeLength, ErrCodeCharacter or
+// ErrCodeSeparator. It expects a normalised alias, see Normalize.
+func (g CodeGrammar) ValidateAlias(alias string) error {
+	if !utf8.ValidString(alias) {
+		return ErrCodeCharacter
+	}
+	if n := utf8.RuneCountInString(alias); n < MinAliasLength || n > MaxAliasLength {
+		return ErrCodeLength
+	}
+
+	var prev rune
+	for i, r := range alias {
+		switch {
+		case isBase62(r):
+		case r == '-' || r == '_':
+			if !g.Separators {
+				return ErrCodeCharacter
+			}
+			if i == 0 || i == len(alias)-1 || prev == '-' || prev == '_' {
+				return ErrCodeSeparator
+			}
+		case g.Unicode && isUnicodeAliasRune(r, i == 0):
+		default:
+			return ErrCodeCharacter
+		}
+		prev = r
+	}
+	return nil
+}
+
+// ValidCode reports whether the code can be a short code, generated or custom. Lookups of anything
+// else are rejected before they reach the database.
+func (g CodeGrammar) ValidCode(code string) bool {
+	return isGeneratedCode(code) || g.ValidateAlias(code) == nil
+}
+
+// Normalize returns the code in the form it's stored in: NFC for Unicode aliases, unchanged otherwise.
+func (g CodeGrammar) Normalize(code string) string {
+	if !g.Unicode {
+		return code
+	}
+	return norm.NFC.String(code)
+}
+
+// RegisterValidation registers the AliasValidationTag validator tag checking aliases against g.
+func (g CodeGrammar) RegisterValidation(v *validator.Validate) error {
+	return v.RegisterValidation(AliasValidationTag, func(fl validator.FieldLevel) bool {
+		return g.ValidateAlias(g.Normalize(fl.Field().String())) == nil
+	})
+}
+
+// isGeneratedCode reports whether the code looks like one of the built-in generators' codes.
+func isGeneratedCode(code string) bool {
+	if len(code) == 0 || len(code) > MaxGeneratedLength {
+		return false
+	}
+	for _, r := range code {
+		if !isBase62(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBase62(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// Joiners and modifiers that build up emoji sequences.
+const (
+	zeroWidthJoiner   = '\u200d'
+	emojiPresentation = '\ufe0f'
+)
+
+// isUnicodeAliasRune allows non-ASCII letters, digits and emoji. Marks, joiners and modifiers only follow
+// another character. Spaces, punctuation, control and other invisible characters are never allowed.
+func isUnicodeAliasRune(r rune, first bool) bool {
+	if r <= unicode.MaxASCII {
+		return false
+	}
+	switch {
+	case unicode.IsLetter(r), unicode.Is(unicode.Nd, r), unicode.Is(unicode.So, r):
+		return true
+	case unicode.IsMark(r), unicode.Is(unicode.Sk, r), r == zeroWidthJoiner, r == emojiPresentation:
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package shortener
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestCodeGrammar_ValidateAlias(t *testing.T) {
+	t.Parallel()
+	ascii := CodeGrammar{}
+	separators := CodeGrammar{Separators: true}
+	unicode := CodeGrammar{Separators: true, Unicode: true}
+
+	tests := []struct {
+		alias   string
+		grammar CodeGrammar
+		err     error
+	}{
+		{alias: "abc", grammar: ascii},
+		{alias: "MyLink2025", grammar: ascii},
+		{alias: strings.Repeat("a", MaxAliasLength), grammar: ascii},
+		{alias: strings.Repeat("a", MaxAliasLength+1), grammar: ascii, err: ErrCodeLength},
+		{alias: "ab", grammar: ascii, err: ErrCodeLength},
+		{alias: "my-link", grammar: ascii, err: ErrCodeCharacter},
+		{alias: "my-link", grammar: separators},
+		{alias: "my_link-2", grammar: separators},
+		{alias: "-mylink", grammar: separators, err: ErrCodeSeparator},
+		{alias: "mylink_", grammar: separators, err: ErrCodeSeparator},
+		{alias: "my--link", grammar: separators, err: ErrCodeSeparator},
+		{alias: "my link", grammar: separators, err: ErrCodeCharacter},
+		{alias: "my/link", grammar: unicode, err: ErrCodeCharacter},
+		{alias: "my.link", grammar: unicode, err: ErrCodeCharacter},
+		{alias: "caf\u00e9", grammar: ascii, err: ErrCodeCharacter},
+		{alias: "caf\u00e9", grammar: unicode},
+		{alias: "東京タワー", grammar: unicode},
+		{alias: "🚀🚀🚀", grammar: unicode},
+		{alias: "hi\U0001F44B\U0001F3FD", grammar: unicode},                        // Waving hand with a skin tone modifier.
+		{alias: "fam\U0001F468\u200d\U0001F469\u200d\U0001F467", grammar: unicode}, // Zero width joiners.
+		{alias: "\u0301abc", grammar: unicode, err: ErrCodeCharacter},              // Leading combining mark.
+		{alias: "ab\u200bc", grammar: unicode, err: ErrCodeCharacter},              // Zero width space.
+		{alias: "ab\u202ec", grammar: unicode, err: ErrCodeCharacter},              // Right to left override.
+		{alias: "a^bc", grammar: unicode, err: ErrCodeCharacter},
+		{alias: "ab\xffc", grammar: unicode, err: ErrCodeCharacter},
+		{alias: strings.Repeat("é", MaxAliasLength), grammar: unicode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			t.Parallel()
+			assert.ErrorIs(t, tt.grammar.ValidateAlias(tt.alias), tt.err)
+		})
+	}
+}
+
+func TestCodeGrammar_ValidCode(t *testing.T) {
+	t.Parallel()
+	grammar := CodeGrammar{}
+	assert.True(t, grammar.ValidCode("a"), "generated codes can be shorter than aliases")
+	assert.True(t, grammar.ValidCode("Zz9"))
+	assert.True(t, grammar.ValidCode(strings.Repeat("a", 15)), "aliases can be longer than generated codes")
+	assert.False(t, grammar.ValidCode(""))
+	assert.False(t, grammar.ValidCode("my-link"))
+	assert.True(t, CodeGrammar{Separators: true}.ValidCode("my-link"))
+}
+
+func TestCodeGrammar_Normalize(t *testing.T) {
+	t.Parallel()
+	decomposed := "cafe\u0301"
+	assert.Equal(t, decomposed, CodeGrammar{}.Normalize(decomposed))
+	assert.Equal(t, "caf\u00e9", CodeGrammar{Unicode: true}.Normalize(decomposed))
+}
+
+// TestMigrationsMatchCodeGrammar checks the latest size of the short code columns in the migrations.
+func TestMigrationsMatchCodeGrammar(t *testing.T) {
+	t.Parallel()
+	files, err := filepath.Glob("../database/migrations/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	slices.Sort(files)
+
+	column := regexp.MustCompile(`(short_url|custom_url)\s+(?:TYPE\s+)?VARCHAR\((\d+)\)`)
+	sizes := map[string]int{}
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		require.NoError(t, err)
+		for _, match := range column.FindAllStringSubmatch(string(sql), -1) {
+			sizes[match[1]], err = strconv.Atoi(match[2])
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, MaxCodeLength, sizes["short_url"])
+	assert.Equal(t, MaxAliasLength, sizes["custom_url"])
+}
+
+func FuzzCodeGrammar(f *testing.F) {
+	seeds := []string{"abc", "my-link", "caf\u00e9", "cafe\u0301", "🚀🚀🚀", "a/b", "-ab", "", "\u0301ab"}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	grammars := []CodeGrammar{{}, {Separators: true}, {Unicode: true}, {Separators: true, Unicode: true}}
+
+	f.Fuzz(func(t *testing.T, code string) {
+		for _, grammar := range grammars {
+			normalized := grammar.Normalize(code)
+			if grammar.Normalize(normalized) != normalized {
+				t.Fatalf("%+v: normalising %q isn't stable", grammar, code)
+			}
+			if !grammar.ValidCode(normalized) {
+				continue
+			}
+
+			// Valid codes fit the columns and are one path segment that survives escaping.
+			if n := utf8.RuneCountInString(normalized); n > MaxCodeLength {
+				t.Fatalf("%+v: %q has %d characters", grammar, normalized, n)
+			}
+			if strings.ContainsAny(normalized, "/?#%. \t\n") {
+				t.Fatalf("%+v: %q has a reserved URL character", grammar, normalized)
+			}
+			if unescaped, err := url.PathUnescape(url.PathEscape(normalized)); err != nil || unescaped != normalized {
+				t.Fatalf("%+v: %q doesn't round trip as a path segment", grammar, normalized)
+			}
+			if grammar.Unicode && !norm.NFC.IsNormalString(normalized) {
+				t.Fatalf("%+v: %q isn't NFC", grammar, normalized)
+			}
+		}
+
+		// Every grammar accepts what the stricter ones accept.
+		if grammars[0].ValidateAlias(code) == nil {
+			for _, grammar := range grammars[1:] {
+				if err := grammar.ValidateAlias(code); err != nil {
+					t.Fatalf("%+v rejects %q: %v", grammar, code, err)
+				}
+			}
+		}
+	})
+}
+
+// FuzzDisjointNamespace checks that a counter code the generator hands out is never a valid alias.
+func FuzzDisjointNamespace(f *testing.F) {
+	for _, seed := range []uint64{1, 10, 35, 36, 62, 3843, 1 << 40} {
+		f.Add(seed)
+	}
+	policy := NewAliasPolicy(AliasPolicyConfig{Disjoint: true, CaseInsensitive: true})
+
+	f.Fuzz(func(t *testing.T, counter uint64) {
+		code := EncodeBase62(counter)
+		if policy.skipCode(code) {
+			return
+		}
+		if alias, err := policy.Check(code); err == nil && alias == code && (CodeGrammar{}).ValidateAlias(code) == nil {
+			t.Fatalf("counter %d gives %q which is also a valid alias", counter, code)
+		}
+	})
+}
@@ -4,41 +4,160 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
+	"html/template"
+	"image"
 	"net/http"
-	"text/template"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
 	v "github.com/jasoncheung94/url-shortener/internal/validator"
 )
 
+// permanentRedirectMaxAge is how long browsers may cache a permanent redirect. Links can be edited, so it's
+// short and shared caches don't keep it at all: changes reach visitors within minutes.
+const permanentRedirectMaxAge = 5 * time.Minute
+
 // Handler represents the methods for handling CRUD.
 type Handler struct {
-	service Service
+	service        Service
+	redirectStatus int
+	qrLogo         image.Image
+	qrLogoTag      string // Hash of the logo so QR code ETags change with it.
+	aliasLimiter   func(http.Handler) http.Handler
+	baseURL        *BaseURL
+	adminKey       string // Bearer token allowed to manage every workspace, none if empty.
+	clicks         events.Publisher
+}
+
+// HandlerOption configures optional Handler settings.
+type HandlerOption func(*Handler)
+
+// WithRedirectStatus sets the redirect status used for links without their own redirect type.
+// Anything other than 301, 302, 307 or 308 is ignored.
+func WithRedirectStatus(status int) HandlerOption {
+	return func(h *Handler) {
+		if isRedirectStatus(status) {
+			h.redirectStatus = status
+		}
+	}
+}
+
+// WithAliasLimiter rate limits alias availability checks, e.g. per BaseURL.ClientIP with
+// ratelimiter.IPRateLimiter, so they can't be used to enumerate aliases quickly.
+func WithAliasLimiter(limiter func(http.Handler) http.Handler) HandlerOption {
+	return func(h *Handler) {
+		h.aliasLimiter = limiter
+	}
+}
+
+// WithBaseURL sets the public base URL short links and the HTML page use. Defaults to DefaultBaseURL.
+func WithBaseURL(base *BaseURL) HandlerOption {
+	return func(h *Handler) {
+		h.baseURL = base
+	}
+}
+
+// WithAdminKey sets the key that creates workspaces and can act for any of them. Without one workspaces
+// can't be created over HTTP.
+func WithAdminKey(key string) HandlerOption {
+	return func(h *Handler) {
+		h.adminKey = key
+	}
+}
+
+// WithClickEvents publishes an events.LinkClicked event for every visitor redirected by a link. HEAD requests
+// and crawlers unfurling the link aren't clicks.
+func WithClickEvents(publisher events.Publisher) HandlerOption {
+	return func(h *Handler) {
+		h.clicks = publisher
+	}
 }
 
 // NewHandler returns instance of Handler.
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, opts ...HandlerOption) *Handler {
+	h := &Handler{service: service, redirectStatus: http.StatusFound, baseURL: defaultBaseURL}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// BaseURL returns the public base URL of the handler, its prefix is the path the routes are mounted on.
+func (h *Handler) BaseURL() *BaseURL {
+	return h.baseURL
+}
+
+// Mux is where routes are registered, an *http.ServeMux or something recording the patterns.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
 }
 
 // Routes setup routes for shortener.
-func (h *Handler) Routes(mux *http.ServeMux) {
+func (h *Handler) Routes(mux Mux) {
 	// GET
-	mux.HandleFunc("/", HomeHandler)
+	mux.HandleFunc("/", h.HomeHandler)
 	mux.HandleFunc("GET /favicon.ico", FaviconHandler)
-	mux.HandleFunc("GET /{shorturl}", h.RedirectURL)
 	mux.HandleFunc("GET /preview/{shorturl}", h.PreviewURL)
+	mux.HandleFunc("GET /qr/{shorturl}", h.QRCode)
+
+	// Any method: 307/308 links preserve the method and body, the handler rejects the rest.
+	mux.HandleFunc("/{shorturl}", h.RedirectURL)
+	mux.HandleFunc("/{shorturl}/{path...}", h.RedirectPrefixURL)
+
+	mux.HandleFunc("GET /urls", h.scoped(h.ListURLs))
+	mux.HandleFunc("GET /urls/{shorturl}/history", h.scoped(h.URLHistory))
+	mux.HandleFunc("GET /tags", h.scoped(h.ListTags))
+
+	var availability http.Handler = http.HandlerFunc(h.AliasAvailability)
+	if h.aliasLimiter != nil {
+		availability = h.aliasLimiter(availability)
+	}
+	mux.Handle("GET /aliases/{alias}/availability", availability)
+	mux.HandleFunc("GET /domains", h.scoped(h.ListDomains))
+	mux.HandleFunc("GET /workspaces/{workspace}", h.workspaceAccess(h.GetWorkspace))
+	mux.HandleFunc("GET /workspaces/{workspace}/usage", h.workspaceAccess(h.WorkspaceUsage))
+	mux.HandleFunc("GET /workspaces/{workspace}/keys", h.workspaceAccess(h.ListAPIKeys))
+	mux.HandleFunc("GET /workspaces/{workspace}/members", h.workspaceAccess(h.ListMembers))
+	mux.HandleFunc("GET /audit", h.adminOnly(h.AuditLog))
+	mux.HandleFunc("GET /audit/verify", h.adminOnly(h.VerifyAuditLog))
+	mux.HandleFunc("GET /debug/vars", h.adminOnly(expvar.Handler().ServeHTTP)) // Command line, memory and generators.
+	mux.HandleFunc("GET /webhooks", h.scopedOrAdmin(h.ListWebhooks))
+	mux.HandleFunc("GET /webhooks/{webhook}/deliveries", h.scopedOrAdmin(h.ListWebhookDeliveries))
 
 	// POST
-	mux.HandleFunc("POST /shorten", h.ShortenURL)
+	mux.HandleFunc("POST /shorten", h.scoped(h.ShortenURL))
+	mux.HandleFunc("POST /domains", h.scopedOrAdmin(h.RegisterDomain))
+	mux.HandleFunc("POST /domains/{domain}/verify", h.scopedOrAdmin(h.VerifyDomain))
+	mux.HandleFunc("POST /urls/{shorturl}/rollback/{version}", h.scopedOrAdmin(h.RollbackURL))
+	mux.HandleFunc("POST /tags/{tag}/rename", h.scopedOrAdmin(h.RenameTag))
+	mux.HandleFunc("POST /tags/merge", h.scopedOrAdmin(h.MergeTags))
+	mux.HandleFunc("POST /workspaces", h.adminOnly(h.CreateWorkspace))
+	mux.HandleFunc("POST /workspaces/{workspace}/keys", h.workspaceAccess(h.CreateAPIKey))
+	mux.HandleFunc("POST /workspaces/{workspace}/members", h.workspaceAccess(h.AddMember))
+	mux.HandleFunc("POST /webhooks", h.scopedOrAdmin(h.CreateWebhook))
+	mux.HandleFunc("POST /webhooks/{webhook}/deliveries/{delivery}/redeliver", h.scopedOrAdmin(h.RedeliverWebhook))
+
+	// PATCH, DELETE
+	mux.HandleFunc("PATCH /urls/{shorturl}", h.scopedOrAdmin(h.UpdateURL))
+	mux.HandleFunc("DELETE /urls/{shorturl}", h.scopedOrAdmin(h.DeleteURL))
+	mux.HandleFunc("PATCH /workspaces/{workspace}", h.adminOnly(h.UpdateWorkspace))
+	mux.HandleFunc("PATCH /workspaces/{workspace}/members/{member}", h.workspaceAccess(h.UpdateMember))
+	mux.HandleFunc("DELETE /workspaces/{workspace}/keys/{key}", h.workspaceAccess(h.DeleteAPIKey))
+	mux.HandleFunc("DELETE /workspaces/{workspace}/members/{member}", h.workspaceAccess(h.RemoveMember))
+	mux.HandleFunc("DELETE /webhooks/{webhook}", h.scopedOrAdmin(h.DeleteWebhook))
 }
 
 // HomeHandler serves the HTML page
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" { // handle path that isn't registered!
 		e.NewErrorResponse(http.StatusNotFound, "404 page not found", "404 page not found")
 		return
@@ -49,7 +168,7 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error loading page", http.StatusInternalServerError)
 		return
 	}
-	err = tmpl.Execute(w, nil)
+	err = tmpl.Execute(w, map[string]string{"BasePath": h.baseURL.Prefix() + "/"})
 	if err != nil {
 		http.Error(w, "Error loading page", http.StatusInternalServerError)
 		return
@@ -65,12 +184,17 @@ func FaviconHandler(w http.ResponseWriter, r *http.Request) {
 // ShortenURL creates a short URL
 // @Summary Shortens a URL
 // @Description Accepts a long URL, a custom alias, and an optional expiration date, and returns a shortened version
+// @Description Links on a custom domain need an API key of the domain's workspace, or the admin key for domains
+// @Description of the default workspace.
 // @Tags URL Shortener
 // @Accept json
 // @Produce json
+// @Param Authorization header string false "Bearer {api key} or the admin key, the link then belongs to its workspace"
 // @Param requestBody body model.URL true "Request body containing URL, custom alias, and expiration date"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string "The role can't create links or the workspace reached a quota"
 // @Failure 500 {object} map[string]string
 // @Router /shorten [post]
 func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
@@ -101,12 +225,24 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 10*time.Second)
 	defer cancel()
 	data := &model.URL{
 		OriginalURL:    requestData.OriginalURL,
 		CustomURL:      requestData.CustomURL,
 		ExpirationDate: requestData.ExpirationDate,
+		UTM:            requestData.UTM,
+		ForwardQuery:   requestData.ForwardQuery,
+		Prefix:         requestData.Prefix,
+		RedirectType:   requestData.RedirectType,
+		Title:          requestData.Title,
+		Description:    requestData.Description,
+		Image:          requestData.Image,
+		Generator:      requestData.Generator,
+		Domain:         requestData.Domain,
+		Tags:           requestData.Tags,
+		Folder:         requestData.Folder,
+		Notes:          requestData.Notes,
 	}
 
 	shortKey, err := h.service.SaveURL(ctx, data)
@@ -114,14 +250,19 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	case errors.Is(err, e.ConflictError{}):
 		e.WriteJSONError(w, http.StatusConflict, e.NewErrorResponse(http.StatusConflict, "conflict", err.Error()))
 		return
+	case errors.Is(err, e.BadRequestError{}):
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "bad request", err.Error()))
+		return
+	case errors.Is(err, e.ForbiddenError{}):
+		e.WriteJSONError(w, http.StatusForbidden, e.NewErrorResponse(http.StatusForbidden, "forbidden", err.Error()))
+		return
 	case err != nil:
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Build response
-	shortURL := "http://localhost:8080/" + shortKey
-	data.ShortURL = shortURL
+	data.ShortURL = h.baseURL.Link(r, data.Domain, shortKey)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	// Encode the data as JSON
@@ -133,33 +274,59 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 // RedirectURL redirects a shortened URL to the original
 // @Summary Redirects to the original URL
-// @Description Finds the original URL from the shortened key and redirects
+// @Description Finds the original URL from the shortened key and redirects.
+// @Description The link's UTM template and, if enabled, the request query string are added to the destination.
 // @Tags URL Shortener
 // @Param shorturl path string true "Shortened URL key"
+// @Description Links use their redirectType (301, 302, 307 or 308) or the server default.
+// @Description Known crawler bots get an HTML page with the link's Open Graph tags instead, if it has any.
+// @Description Methods other than GET and HEAD are only accepted by 307 and 308 links.
+// @Description Requests to a verified custom domain find the link with the code on that domain.
+// @Success 200 {string} string "Open Graph page for crawler bots"
+// @Success 301
 // @Success 302
+// @Success 307
+// @Success 308
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 405 {object} map[string]string
 // @Router /{shorturl} [get]
+// @Router /{shorturl} [post]
 func (h *Handler) RedirectURL(w http.ResponseWriter, r *http.Request) {
+	h.redirect(w, r, "", false)
+}
+
+// RedirectPrefixURL redirects a prefix link, appending the rest of the path to the original URL.
+// @Summary Redirects a prefix link with a path suffix
+// @Description Only links created with prefix enabled accept a path suffix, e.g. /{shorturl}/docs/page
+// @Description redirects to the original URL + /docs/page. Other links return 404.
+// @Tags URL Shortener
+// @Param shorturl path string true "Shortened URL key"
+// @Param path path string true "Path appended to the original URL"
+// @Success 301
+// @Success 302
+// @Success 307
+// @Success 308
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 405 {object} map[string]string
+// @Router /{shorturl}/{path} [get]
+// @Router /{shorturl}/{path} [post]
+func (h *Handler) RedirectPrefixURL(w http.ResponseWriter, r *http.Request) {
+	h.redirect(w, r, r.PathValue("path"), true)
+}
+
+func (h *Handler) redirect(w http.ResponseWriter, r *http.Request, suffix string, hasSuffix bool) {
 	shortURL := r.PathValue("shorturl")
-	if shortURL == "" || !isValidShortURL(shortURL) {
-		e.WriteJSONError(w, http.StatusBadRequest, e.ErrorResponse{
-			Errors: []e.Error{
-				{
-					Status: http.StatusBadRequest,
-					Title:  "URL is not valid",
-					Detail: "URL should be formatted correctly",
-				},
-			},
-		})
-		return
-	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	data, err := h.service.GetURL(ctx, shortURL)
+	data, err := h.service.GetURL(ctx, h.service.DomainForHost(ctx, h.baseURL.Host(r)), shortURL)
 	switch {
+	case errors.Is(err, e.BadRequestError{}):
+		writeInvalidShortURL(w)
+		return
 	case errors.As(err, &e.NotFoundError{}), errors.Is(err, e.NotFoundError{}): // example of both.
 		e.WriteJSONError(w, http.StatusNotFound, e.NewErrorResponse(http.StatusNotFound, "url not found", err.Error()))
 		return
@@ -168,18 +335,134 @@ func (h *Handler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Cache-Control", "no-store")
-	http.Redirect(w, r, data.OriginalURL, http.StatusFound)
+	status := h.redirectStatusFor(data)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead &&
+		status != http.StatusTemporaryRedirect && status != http.StatusPermanentRedirect {
+		w.Header().Set("Allow", "GET, HEAD")
+		e.WriteJSONError(w, http.StatusMethodNotAllowed, e.NewErrorResponse(http.StatusMethodNotAllowed,
+			"method not allowed", "only 307 and 308 links accept methods other than GET"))
+		return
+	}
+
+	if hasSuffix {
+		if !data.Prefix {
+			e.WriteJSONError(w, http.StatusNotFound,
+				e.NewErrorResponse(http.StatusNotFound, "url not found", "short url doesn't accept a path"))
+			return
+		}
+		joined, err := JoinPrefixPath(data.OriginalURL, suffix)
+		if err != nil {
+			e.WriteJSONError(w, http.StatusBadRequest,
+				e.NewErrorResponse(http.StatusBadRequest, "URL is not valid", err.Error()))
+			return
+		}
+		withSuffix := *data
+		withSuffix.OriginalURL = joined
+		data = &withSuffix
+	}
+
+	target, err := BuildRedirectURL(data, r.URL.Query())
+	if err != nil {
+		l.Logger.Error("failed to build redirect url", "shorturl", shortURL, "error", err)
+		target = data.OriginalURL
+	}
+
+	// Crawlers unfurling the link get the link's own preview, people still get redirected.
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && hasPreview(data) {
+		w.Header().Add("Vary", "User-Agent")
+		if isCrawler(r.UserAgent()) {
+			writeOpenGraph(w, data, h.baseURL.Link(r, data.Domain, data.ShortURL), target)
+			return
+		}
+	}
+
+	if h.clicks != nil && r.Method != http.MethodHead {
+		h.clicks.Publish(context.WithoutCancel(r.Context()), clickEvent(r, data, target))
+	}
+	w.Header().Set("Cache-Control", redirectCacheControl(status, data.ExpirationDate))
+	http.Redirect(w, r, target, status)
+}
+
+// writeInvalidShortURL responds to a short code that doesn't match the code grammar.
+func writeInvalidShortURL(w http.ResponseWriter) {
+	e.WriteJSONError(w, http.StatusBadRequest, e.ErrorResponse{
+		Errors: []e.Error{
+			{
+				Status: http.StatusBadRequest,
+				Title:  "URL is not valid",
+				Detail: "URL should be formatted correctly",
+			},
+		},
+	})
+}
+
+// writeServiceError writes the response for an error of the service and reports whether there was one.
+func writeServiceError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, e.BadRequestError{}):
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "bad request", err.Error()))
+	case errors.Is(err, e.UnauthorizedError{}):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		e.WriteJSONError(w, http.StatusUnauthorized,
+			e.NewErrorResponse(http.StatusUnauthorized, "unauthorized", err.Error()))
+	case errors.Is(err, e.ForbiddenError{}):
+		e.WriteJSONError(w, http.StatusForbidden, e.NewErrorResponse(http.StatusForbidden, "forbidden", err.Error()))
+	case errors.Is(err, e.NotFoundError{}):
+		e.WriteJSONError(w, http.StatusNotFound, e.NewErrorResponse(http.StatusNotFound, "not found", err.Error()))
+	case errors.Is(err, e.ConflictError{}):
+		e.WriteJSONError(w, http.StatusConflict, e.NewErrorResponse(http.StatusConflict, "conflict", err.Error()))
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return true
+}
+
+// redirectStatusFor returns the link's own redirect status or the server default.
+func (h *Handler) redirectStatusFor(data *model.URL) int {
+	if isRedirectStatus(data.RedirectType) {
+		return data.RedirectType
+	}
+	return h.redirectStatus
+}
+
+// redirectCacheControl lets browsers cache permanent redirects for permanentRedirectMaxAge, but never past the
+// link's expiration date.
+// Temporary redirects are never cached so every hit reaches the server.
+func redirectCacheControl(status int, expirationDate *time.Time) string {
+	if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+		return "no-store"
+	}
+
+	maxAge := permanentRedirectMaxAge
+	if expirationDate != nil {
+		maxAge = min(maxAge, time.Until(*expirationDate))
+	}
+	if maxAge <= 0 {
+		return "no-store"
+	}
+	return fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+}
+
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
 }
 
 // PreviewURL retrieves the original URL and related data for a given short URL.
 // @Summary Preview a short URL
 // @Description Returns information about a short URL, such as the original URL and metadata.
+// @Description Revalidated with the ETag and Last-Modified of the link's last change.
 // @Tags URL Shortener
 // @Accept json
 // @Produce json
 // @Param shorturl path string true "Short URL code"
 // @Success 200 {object} model.URL
+// @Success 304
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {string} string
@@ -189,21 +472,12 @@ func (h *Handler) PreviewURL(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	shortURL := r.PathValue("shorturl")
-	if shortURL == "" || !isValidShortURL(shortURL) {
-		e.WriteJSONError(w, http.StatusBadRequest, e.ErrorResponse{
-			Errors: []e.Error{
-				{
-					Status: http.StatusBadRequest,
-					Title:  "URL is not valid",
-					Detail: "URL should be formatted correctly",
-				},
-			},
-		})
-		return
-	}
 
-	data, err := h.service.GetURL(ctx, shortURL)
+	data, err := h.service.GetURL(ctx, h.service.DomainForHost(ctx, h.baseURL.Host(r)), shortURL)
 	switch {
+	case errors.Is(err, e.BadRequestError{}):
+		writeInvalidShortURL(w)
+		return
 	case errors.As(err, &e.NotFoundError{}), errors.Is(err, e.NotFoundError{}): // example of both.
 		e.WriteJSONError(w, http.StatusNotFound, e.NewErrorResponse(http.StatusNotFound, "url not found", err.Error()))
 		return
@@ -212,9 +486,16 @@ func (h *Handler) PreviewURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lastModified := data.CreatedAt.UTC().Format(http.TimeFormat)
-	w.Header().Set("Last-Modified", lastModified)           // App doesn't allow edit. Use created at date for http cache.
-	w.Header().Set("Cache-Control", "public, max-age=3600") // http cache for 1 hour
+	// Links can be edited, so caches revalidate every time and get a 304 while the link is unchanged.
+	modified := data.LastModified()
+	etag := fmt.Sprintf(`"%x"`, modified.UnixNano())
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	// Encode the data as JSON
@@ -232,3 +513,181 @@ func (h *Handler) PreviewURL(w http.ResponseWriter, r *http.Request) {
 // 	}
 // 	fmt.Println(string(jsonData))
 // }
+
+// ListURLs lists short URLs, optionally only the broken ones or the ones with tags or in a folder.
+// @Summary List short URLs
+// @Description Lists short URLs, oldest first. broken=true returns the links the link checker flagged because
+// @Description their destination failed repeated health checks. Repeated tag parameters return links with all
+// @Description of the tags, folder= returns links without a folder.
+// @Tags URL Shortener
+// @Produce json
+// @Param Authorization header string false "Bearer {api key}, lists the links of the key's workspace"
+// @Param broken query bool false "Only broken (true) or only working (false) links"
+// @Param tag query []string false "Only links with all of the tags" collectionFormat(multi)
+// @Param folder query string false "Only links in the folder"
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Param offset query int false "Number of links to skip" default(0)
+// @Success 200 {object} model.URLList
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /urls [get]
+func (h *Handler) ListURLs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var filter model.URLFilter
+	if v := q.Get("broken"); v != "" {
+		broken, err := strconv.ParseBool(v)
+		if err != nil {
+			e.WriteJSONError(w, http.StatusBadRequest,
+				e.NewErrorResponse(http.StatusBadRequest, "invalid query", "broken must be true or false"))
+			return
+		}
+		filter.Broken = &broken
+	}
+	if q.Has("folder") {
+		folder := strings.TrimSpace(q.Get("folder"))
+		filter.Folder = &folder
+	}
+	if tags := q["tag"]; len(tags) > 0 {
+		normalized, err := NormalizeTags(tags)
+		if writeServiceError(w, err) {
+			return
+		}
+		filter.Tags = normalized
+	}
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if v := q.Get(param.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid query",
+					param.name+" must be a positive number"))
+				return
+			}
+			*param.dest = n
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	urls, err := h.service.ListURLs(ctx, filter)
+	if writeServiceError(w, err) {
+		return
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.URLList{
+		URLs:   urls,
+		Limit:  min(limit, MaxListLimit),
+		Offset: filter.Offset,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// UpdateURL changes a link.
+// @Summary Update a short URL
+// @Description Changes the destination, expiry, redirect rules, tags, folder or notes of a link. Fields that are
+// @Description left out stay as they are, tags replaces all tags and [] removes them. Changes to the destination,
+// @Description expiry or redirect rules add a version to the link's history. Needs an editor's API key, or the
+// @Description admin key for links created without one.
+// @Tags URL Shortener
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the link's workspace, or the admin key"
+// @Param shorturl path string true "Shortened URL key"
+// @Param domain query string false "Custom domain of the link, the default host if empty"
+// @Param requestBody body model.URLUpdate true "Changes"
+// @Success 200 {object} model.URL
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "The link was changed at the same time"
+// @Failure 500 {string} string
+// @Router /urls/{shorturl} [patch]
+func (h *Handler) UpdateURL(w http.ResponseWriter, r *http.Request) {
+	var update model.URLUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	data, err := h.service.UpdateURL(ctx, r.URL.Query().Get("domain"), r.PathValue("shorturl"), update)
+	if writeServiceError(w, err) {
+		return
+	}
+	data.ShortURL = h.baseURL.Link(r, data.Domain, data.ShortURL)
+	writeJSON(w, http.StatusOK, data)
+}
+
+// DeleteURL removes a link.
+// @Summary Delete a short URL
+// @Description Removes the link with its version history and health checks. The short URL stops redirecting
+// @Description and can be claimed again. Needs an editor's API key, or the admin key for links created without
+// @Description one.
+// @Tags URL Shortener
+// @Param Authorization header string true "Bearer {api key} of the link's workspace, or the admin key"
+// @Param shorturl path string true "Shortened URL key"
+// @Param domain query string false "Custom domain of the link, the default host if empty"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /urls/{shorturl} [delete]
+func (h *Handler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	if writeServiceError(w, h.service.DeleteURL(ctx, r.URL.Query().Get("domain"), r.PathValue("shorturl"))) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AliasAvailability tells whether a custom alias can still be claimed.
+// @Summary Check if a custom alias is available
+// @Description Returns available, reserved (a route name or a blocked word) or taken. Aliases that aren't
+// @Description available come with up to 3 free suggestions. Rate limited per client.
+// @Tags URL Shortener
+// @Produce json
+// @Param alias path string true "Custom alias"
+// @Param domain query string false "Custom domain the alias would be on, the default host if empty"
+// @Success 200 {object} model.AliasAvailability
+// @Failure 400 {object} map[string]string
+// @Failure 429 {string} string
+// @Failure 500 {string} string
+// @Router /aliases/{alias}/availability [get]
+func (h *Handler) AliasAvailability(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	availability, err := h.service.AliasAvailability(ctx, r.URL.Query().Get("domain"), r.PathValue("alias"))
+	switch {
+	case errors.Is(err, e.BadRequestError{}):
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "bad request", err.Error()))
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(availability); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -5,9 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/mocks"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/ratelimiter"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -16,11 +22,18 @@ import (
 func TestRoutes(t *testing.T) {
 	t.Parallel()
 	mux := http.NewServeMux()
-	service := mocks.NewMockService(gomock.NewController(t))
+	service := newMockService(t)
 	handler := NewHandler(service) // use a mock or dummy service
 	handler.Routes(mux)
 }
 
+// newMockService returns a mock service serving requests from the default host.
+func newMockService(t *testing.T) *mocks.MockService {
+	service := mocks.NewMockService(gomock.NewController(t))
+	service.EXPECT().DomainForHost(gomock.Any(), gomock.Any()).Return("").AnyTimes()
+	return service
+}
+
 func makeJSONRequest(method, path string, payload any) *http.Request {
 	body, _ := json.Marshal(payload)
 	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
@@ -28,9 +41,18 @@ func makeJSONRequest(method, path string, payload any) *http.Request {
 	return req
 }
 
+// testAdminKey is the admin key of test handlers that edit default workspace links, which needs a key.
+const testAdminKey = "admin-secret"
+
+// asAdmin sends the request with testAdminKey.
+func asAdmin(req *http.Request) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	return req
+}
+
 func TestShortenURL(t *testing.T) {
 	t.Parallel()
-	mockService := mocks.NewMockService(gomock.NewController(t))
+	mockService := newMockService(t)
 	handler := NewHandler(mockService)
 	mockService.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return("test", nil)
 
@@ -51,7 +73,7 @@ func TestShortenURL(t *testing.T) {
 
 func TestRedirectURL(t *testing.T) {
 	t.Parallel()
-	mockService := mocks.NewMockService(gomock.NewController(t))
+	mockService := newMockService(t)
 	handler := NewHandler(mockService)
 	data := model.URL{
 		ShortURL:    "1",
@@ -60,7 +82,7 @@ func TestRedirectURL(t *testing.T) {
 	// Set up the router
 	mux := http.NewServeMux()
 	handler.Routes(mux) // This registers the route handlers in mux.
-	mockService.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(&data, nil)
+	mockService.EXPECT().GetURL(gomock.Any(), "", gomock.Any()).Return(&data, nil)
 	// Create a GET request to the redirect route
 	req := httptest.NewRequest(http.MethodGet, "/1234", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -77,16 +99,18 @@ func TestRedirectURL(t *testing.T) {
 
 func TestPreviewURL(t *testing.T) {
 	t.Parallel()
-	mockService := mocks.NewMockService(gomock.NewController(t))
+	mockService := newMockService(t)
 	handler := NewHandler(mockService)
 	data := model.URL{
 		ShortURL:    "1",
 		OriginalURL: "http://localhost:8080/google",
+		CreatedAt:   time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		UpdatedAt:   ptr.Of(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)),
 	}
 	// Set up the router
 	mux := http.NewServeMux()
 	handler.Routes(mux) // This registers the route handlers in mux.
-	mockService.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(&data, nil)
+	mockService.EXPECT().GetURL(gomock.Any(), "", gomock.Any()).Return(&data, nil).Times(2)
 	// Create a GET request to the redirect route
 	req := httptest.NewRequest(http.MethodGet, "/preview/1234", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -101,4 +125,258 @@ func TestPreviewURL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, res.OriginalURL, "http://localhost:8080/")
 	assert.Contains(t, res.ShortURL, "1")
+	assert.Equal(t, "Sat, 01 Mar 2025 12:00:00 GMT", rr.Header().Get("Last-Modified"), "edits are newer")
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+
+	// Unchanged links are revalidated with their ETag.
+	req = httptest.NewRequest(http.MethodGet, "/preview/1234", nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestRedirectURL_ForwardQuery(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	handler := NewHandler(mockService)
+	data := model.URL{
+		ShortURL:     "abc",
+		OriginalURL:  "https://example.com/landing#pricing",
+		UTM:          &model.UTM{Source: "poster"},
+		ForwardQuery: model.ForwardQueryMerge,
+	}
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+	mockService.EXPECT().GetURL(gomock.Any(), "", "abc").Return(&data, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/abc?ref=email", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://example.com/landing?ref=email&utm_source=poster#pricing", rr.Header().Get("Location"))
+}
+
+func TestRedirectPrefixURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		path             string
+		data             model.URL
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "Prefix link forwards the path",
+			path:             "/docs/guide/install?ref=email",
+			data:             model.URL{ShortURL: "docs", OriginalURL: "https://example.com/v2", Prefix: true},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/v2/guide/install",
+		},
+		{
+			name:             "Prefix link forwards a qr suffix",
+			path:             "/docs/qr",
+			data:             model.URL{ShortURL: "docs", OriginalURL: "https://example.com/v2", Prefix: true},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/v2/qr",
+		},
+		{
+			name:           "Regular link rejects a path",
+			path:           "/docs/guide",
+			data:           model.URL{ShortURL: "docs", OriginalURL: "https://example.com/v2"},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := newMockService(t)
+			handler := NewHandler(mockService)
+			mux := http.NewServeMux()
+			handler.Routes(mux)
+			mockService.EXPECT().GetURL(gomock.Any(), "", "docs").Return(&tt.data, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectURL_RedirectType(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		method               string
+		redirectType         int
+		expirationDate       *time.Time
+		opts                 []HandlerOption
+		expectedStatus       int
+		expectedCacheControl string
+	}{
+		{name: "Server default", method: http.MethodGet, expectedStatus: http.StatusFound,
+			expectedCacheControl: "no-store"},
+		{name: "Configured server default", method: http.MethodGet, opts: []HandlerOption{WithRedirectStatus(301)},
+			expectedStatus: http.StatusMovedPermanently, expectedCacheControl: "private, max-age=300"},
+		{name: "Invalid server default is ignored", method: http.MethodGet, opts: []HandlerOption{WithRedirectStatus(200)},
+			expectedStatus: http.StatusFound, expectedCacheControl: "no-store"},
+		{name: "Permanent link is cacheable", method: http.MethodGet, redirectType: 308,
+			expectedStatus: http.StatusPermanentRedirect, expectedCacheControl: "private, max-age=300"},
+		{name: "Permanent link is cached until expiry at most", method: http.MethodGet, redirectType: 301,
+			expirationDate:       ptr.Of(time.Now().Add(2*time.Minute + 30*time.Second)),
+			expectedStatus:       http.StatusMovedPermanently,
+			expectedCacheControl: "private, max-age=14"}, // Prefix match, a few seconds may pass before the redirect.
+		{name: "Temporary link is not cached", method: http.MethodGet, redirectType: 307,
+			expectedStatus: http.StatusTemporaryRedirect, expectedCacheControl: "no-store"},
+		{name: "POST allowed for 307", method: http.MethodPost, redirectType: 307,
+			expectedStatus: http.StatusTemporaryRedirect, expectedCacheControl: "no-store"},
+		{name: "POST allowed for 308", method: http.MethodPost, redirectType: 308,
+			expectedStatus: http.StatusPermanentRedirect, expectedCacheControl: "private, max-age=300"},
+		{name: "POST rejected for 302", method: http.MethodPost, redirectType: 302,
+			expectedStatus: http.StatusMethodNotAllowed},
+		{name: "PUT rejected for 301", method: http.MethodPut, redirectType: 301,
+			expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := newMockService(t)
+			handler := NewHandler(mockService, tt.opts...)
+			mux := http.NewServeMux()
+			handler.Routes(mux)
+			mockService.EXPECT().GetURL(gomock.Any(), "", "abc").Return(&model.URL{
+				ShortURL:       "abc",
+				OriginalURL:    "https://example.com/api",
+				RedirectType:   tt.redirectType,
+				ExpirationDate: tt.expirationDate,
+			}, nil)
+
+			req := httptest.NewRequest(tt.method, "/abc", bytes.NewBufferString(`{"a":1}`))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusMethodNotAllowed {
+				assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+				return
+			}
+			assert.Equal(t, "https://example.com/api", rr.Header().Get("Location"))
+			assert.True(t, strings.HasPrefix(rr.Header().Get("Cache-Control"), tt.expectedCacheControl),
+				"unexpected Cache-Control %q", rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		query          string
+		expectedFilter *model.URLFilter
+		expectedStatus int
+	}{
+		{name: "All links", query: "", expectedFilter: &model.URLFilter{}, expectedStatus: http.StatusOK},
+		{name: "Broken links page", query: "?broken=true&limit=5&offset=10",
+			expectedFilter: &model.URLFilter{Broken: ptr.Of(true), Limit: 5, Offset: 10}, expectedStatus: http.StatusOK},
+		{name: "Invalid broken", query: "?broken=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid limit", query: "?limit=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := newMockService(t)
+			handler := NewHandler(mockService)
+			mux := http.NewServeMux()
+			handler.Routes(mux)
+			if tt.expectedFilter != nil {
+				mockService.EXPECT().ListURLs(gomock.Any(), *tt.expectedFilter).
+					Return([]model.URL{{ShortURL: "abc", Broken: true}}, nil)
+			}
+
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/urls"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var res model.URLList
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+			assert.Equal(t, []model.URL{{ShortURL: "abc", Broken: true}}, res.URLs)
+		})
+	}
+}
+
+func TestAliasAvailability(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	limited := 0
+	handler := NewHandler(mockService, WithAliasLimiter(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limited++; limited > 2 {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}))
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+
+	expected := &model.AliasAvailability{Alias: "mylink", Status: model.AliasTaken, Suggestions: []string{"mylink2"}}
+	mockService.EXPECT().AliasAvailability(gomock.Any(), "", "mylink").Return(expected, nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/aliases/mylink/availability", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res model.AliasAvailability
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.Equal(t, *expected, res)
+
+	mockService.EXPECT().AliasAvailability(gomock.Any(), "", "x").Return(nil, e.NewBadRequestError("too short"))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/aliases/x/availability", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/aliases/mylink/availability", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestAliasAvailability_ForwardedFor(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	base, err := NewBaseURL(DefaultBaseURL, []string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+	limiter := ratelimiter.NewIPRateLimiter(1, 2, time.Minute)
+	handler := NewHandler(mockService, WithBaseURL(base), WithAliasLimiter(limiter.MiddlewareBy(base.ClientIP)))
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+	mockService.EXPECT().AliasAvailability(gomock.Any(), "", "mylink").
+		Return(&model.AliasAvailability{Alias: "mylink", Status: model.AliasAvailable}, nil).Times(4)
+
+	check := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/aliases/mylink/availability", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// A client that isn't a trusted proxy can't get a new bucket with a made up X-Forwarded-For.
+	for i, code := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		assert.Equal(t, code, check("203.0.113.5:1234", "198.51.100."+strconv.Itoa(i)), i)
+	}
+
+	// Behind a trusted proxy every forwarded client has its own bucket.
+	assert.Equal(t, http.StatusOK, check("10.0.0.1:1234", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, check("10.0.0.1:1234", "198.51.100.2"))
 }
@@ -0,0 +1,120 @@
+package shortener
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// versionOf returns the destination, expiry and redirect rules of the link as a version.
+func versionOf(data *model.URL) model.URLVersion {
+	version := model.URLVersion{
+		Domain:         data.Domain,
+		ShortURL:       data.ShortURL,
+		OriginalURL:    data.OriginalURL,
+		ExpirationDate: data.ExpirationDate,
+		ForwardQuery:   data.ForwardQuery,
+		Prefix:         data.Prefix,
+		RedirectType:   data.RedirectType,
+	}
+	if data.UTM != nil && *data.UTM != (model.UTM{}) {
+		utm := *data.UTM
+		version.UTM = &utm
+	}
+	return version
+}
+
+// firstVersion is the link as it was created, for links that never changed.
+func firstVersion(data *model.URL) model.URLVersion {
+	version := versionOf(data)
+	version.Version, version.Action, version.CreatedAt = 1, model.VersionCreate, data.CreatedAt
+	return version
+}
+
+// sameVersion reports whether the versions have the same destination, expiry and redirect rules.
+func sameVersion(a, b model.URLVersion) bool {
+	sameExpiry := a.ExpirationDate == nil && b.ExpirationDate == nil ||
+		a.ExpirationDate != nil && b.ExpirationDate != nil && a.ExpirationDate.Equal(*b.ExpirationDate)
+	sameUTM := a.UTM == nil && b.UTM == nil || a.UTM != nil && b.UTM != nil && *a.UTM == *b.UTM
+	return sameExpiry && sameUTM && a.OriginalURL == b.OriginalURL && a.ForwardQuery == b.ForwardQuery &&
+		a.Prefix == b.Prefix && a.RedirectType == b.RedirectType
+}
+
+// restoreVersion sets the destination, expiry and redirect rules of the link to the version's.
+func restoreVersion(data *model.URL, version model.URLVersion) {
+	data.OriginalURL = version.OriginalURL
+	data.ExpirationDate = version.ExpirationDate
+	data.UTM = version.UTM
+	data.ForwardQuery = version.ForwardQuery
+	data.Prefix = version.Prefix
+	data.RedirectType = version.RedirectType
+}
+
+// URLHistory lists the versions of a link.
+// @Summary Version history of a short URL
+// @Description Lists every version of the link's destination, expiry and redirect rules, oldest first, with the
+// @Description member who made the change. Version 1 is the link as it was created.
+// @Tags URL Shortener
+// @Produce json
+// @Param Authorization header string false "Bearer {api key}, for a link of the key's workspace"
+// @Param shorturl path string true "Shortened URL key"
+// @Param domain query string false "Custom domain of the link, the default host if empty"
+// @Success 200 {array} model.URLVersion
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /urls/{shorturl}/history [get]
+func (h *Handler) URLHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	versions, err := h.service.URLHistory(ctx, r.URL.Query().Get("domain"), r.PathValue("shorturl"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// RollbackURL restores an older version of a link.
+// @Summary Roll back a short URL
+// @Description Restores the destination, expiry and redirect rules of the version. The rollback is added to the
+// @Description history as a new version, nothing is removed. Tags, folder and notes aren't versioned. Needs an
+// @Description editor's API key, or the admin key for links created without one.
+// @Tags URL Shortener
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the link's workspace, or the admin key"
+// @Param shorturl path string true "Shortened URL key"
+// @Param version path int true "Version to restore"
+// @Param domain query string false "Custom domain of the link, the default host if empty"
+// @Success 200 {object} model.URL
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /urls/{shorturl}/rollback/{version} [post]
+func (h *Handler) RollbackURL(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid version",
+			"version must be a positive number"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	data, err := h.service.RollbackURL(ctx, r.URL.Query().Get("domain"), r.PathValue("shorturl"), version)
+	if writeServiceError(w, err) {
+		return
+	}
+	data.ShortURL = h.baseURL.Link(r, data.Domain, data.ShortURL)
+	writeJSON(w, http.StatusOK, data)
+}
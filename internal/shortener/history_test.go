@@ -0,0 +1,192 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/mocks"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestShortenerService_History(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithHistory(repo))
+	ctx := tenant.With(context.Background(), "acme")
+	_, err := service.CreateWorkspace(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	eve := withMember(ctx, &model.Member{Workspace: "acme", ID: "eve", Role: model.RoleEditor})
+	vic := withMember(ctx, &model.Member{Workspace: "acme", ID: "vic", Role: model.RoleViewer})
+
+	code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com/v1"})
+	require.NoError(t, err)
+	versions, err := service.URLHistory(vic, "", code)
+	require.NoError(t, err)
+	require.Len(t, versions, 1, "a link that never changed is its first version")
+	assert.Equal(t, model.VersionCreate, versions[0].Action)
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated, err := service.UpdateURL(eve, "", code, model.URLUpdate{OriginalURL: ptr.Of("https://example.com/v2"),
+		RedirectType: ptr.Of(301)})
+	require.NoError(t, err)
+	require.NotNil(t, updated.UpdatedAt)
+	assert.True(t, updated.LastModified().After(updated.CreatedAt), "edits move Last-Modified")
+	_, err = service.UpdateURL(eve, "", code, model.URLUpdate{Tags: &model.Tags{"launch"}})
+	require.NoError(t, err)
+	_, err = service.UpdateURL(ctx, "", code, model.URLUpdate{ExpirationDate: &expires,
+		UTM: &model.UTM{Source: "newsletter"}})
+	require.NoError(t, err)
+
+	versions, err = service.URLHistory(ctx, "", code)
+	require.NoError(t, err)
+	require.Len(t, versions, 3, "labels aren't versioned")
+	assert.Equal(t, []int{1, 2, 3}, []int{versions[0].Version, versions[1].Version, versions[2].Version})
+	assert.Equal(t, "https://example.com/v1", versions[0].OriginalURL)
+	assert.Empty(t, versions[0].Actor)
+	assert.Equal(t, "eve", versions[1].Actor)
+	assert.Equal(t, 301, versions[1].RedirectType)
+	assert.Equal(t, model.VersionUpdate, versions[2].Action)
+	assert.Equal(t, &expires, versions[2].ExpirationDate)
+
+	_, err = service.RollbackURL(vic, "", code, 1)
+	assert.ErrorIs(t, err, e.ForbiddenError{})
+	_, err = service.RollbackURL(eve, "", code, 9)
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	data, err := service.RollbackURL(eve, "", code, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/v1", data.OriginalURL)
+	assert.Nil(t, data.ExpirationDate)
+	assert.Nil(t, data.UTM)
+	assert.Zero(t, data.RedirectType)
+	assert.Equal(t, model.Tags{"launch"}, data.Tags, "labels stay")
+
+	stored, err := service.GetURL(ctx, "", code)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/v1", stored.OriginalURL)
+	versions, err = service.URLHistory(ctx, "", code)
+	require.NoError(t, err)
+	require.Len(t, versions, 4)
+	assert.Equal(t, model.VersionRollback, versions[3].Action)
+	assert.Equal(t, 1, versions[3].RollbackOf)
+	assert.Equal(t, "eve", versions[3].Actor)
+
+	_, err = service.RollbackURL(eve, "", code, 4)
+	require.NoError(t, err)
+	versions, err = service.URLHistory(ctx, "", code)
+	require.NoError(t, err)
+	assert.Len(t, versions, 4, "rolling back to the current version changes nothing")
+
+	_, err = service.UpdateURL(eve, "", code, model.URLUpdate{OriginalURL: ptr.Of("ftp://example.com")})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = service.UpdateURL(eve, "", code, model.URLUpdate{RedirectType: ptr.Of(303)})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = service.URLHistory(tenant.With(context.Background(), "other"), "", code)
+	assert.ErrorIs(t, err, e.NotFoundError{}, "other workspaces can't see the history")
+
+	_, err = NewService(repo).URLHistory(ctx, "", code)
+	assert.ErrorIs(t, err, e.BadRequestError{}, "history needs WithHistory")
+}
+
+func TestShortenerService_HistoryConflict(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	history := mocks.NewMockHistory(gomock.NewController(t))
+	service := NewService(repo, WithHistory(history))
+	ctx := context.Background()
+	code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com/v1"})
+	require.NoError(t, err)
+
+	history.EXPECT().ListVersions(gomock.Any(), "", code).Return([]model.URLVersion{{Version: 1}}, nil)
+	history.EXPECT().SaveVersion(gomock.Any(), gomock.Any()).Return(e.NewConflictError("version 2 exists"))
+
+	_, err = service.UpdateURL(ctx, "", code, model.URLUpdate{OriginalURL: ptr.Of("https://example.com/v2")})
+	assert.ErrorIs(t, err, e.ConflictError{})
+	stored, err := service.GetURL(ctx, "", code)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/v1", stored.OriginalURL, "the link isn't changed without its version")
+}
+
+func TestShortenerService_RollbackDropsCache(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	redis := mocks.NewMockRedisInterface(gomock.NewController(t))
+	service := NewService(repository.NewCache(repo, redis), WithHistory(repo))
+	ctx := context.Background()
+
+	redis.EXPECT().Set(gomock.Any(), "shorturl:abc", gomock.Any(), gomock.Any()).Return(nil)
+	redis.EXPECT().Get(gomock.Any(), "shorturl:abc", gomock.Any()).Return(e.NewNotFoundError("miss")).AnyTimes()
+	redis.EXPECT().Set(gomock.Any(), "shorturl:abc", gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	redis.EXPECT().Delete(gomock.Any(), "shorturl:abc").Return(nil).Times(2)
+
+	_, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com/v1", CustomURL: ptr.Of("abc")})
+	require.NoError(t, err)
+	_, err = service.UpdateURL(ctx, "", "abc", model.URLUpdate{OriginalURL: ptr.Of("https://example.com/v2")})
+	require.NoError(t, err)
+	_, err = service.RollbackURL(ctx, "", "abc", 1)
+	require.NoError(t, err)
+}
+
+func TestHistoryHandlers(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithHistory(repo))
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey(testAdminKey)).Routes(mux)
+	_, err := service.SaveURL(tenant.With(context.Background(), tenant.Default),
+		&model.URL{OriginalURL: "https://example.com/v1", CustomURL: ptr.Of("abc")})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{makeJSONRequest(http.MethodPatch, "/urls/abc", model.URLUpdate{OriginalURL: ptr.Of("https://evil.example.net")}),
+			http.StatusUnauthorized, "api key"},
+		{httptest.NewRequest(http.MethodPost, "/urls/abc/rollback/1", nil), http.StatusUnauthorized, "api key"},
+		{asAdmin(makeJSONRequest(http.MethodPatch, "/urls/abc", model.URLUpdate{
+			OriginalURL: ptr.Of("https://example.com/v2"), ForwardQuery: ptr.Of(model.ForwardQueryMerge)})),
+			http.StatusOK, `"originalURL":"https://example.com/v2"`},
+		{asAdmin(makeJSONRequest(http.MethodPatch, "/urls/abc",
+			model.URLUpdate{ForwardQuery: ptr.Of(model.ForwardQuery("x"))})), http.StatusBadRequest, "forward query"},
+		{httptest.NewRequest(http.MethodGet, "/urls/abc/history", nil), http.StatusOK, `"version":2`},
+		{httptest.NewRequest(http.MethodGet, "/urls/missing/history", nil), http.StatusNotFound, ""},
+		{asAdmin(httptest.NewRequest(http.MethodPost, "/urls/abc/rollback/0", nil)), http.StatusBadRequest, "version"},
+		{asAdmin(httptest.NewRequest(http.MethodPost, "/urls/abc/rollback/7", nil)), http.StatusNotFound, ""},
+		{asAdmin(httptest.NewRequest(http.MethodPost, "/urls/abc/rollback/1", nil)), http.StatusOK,
+			`"originalURL":"https://example.com/v1"`},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tt.req)
+		assert.Equal(t, tt.status, rr.Code, "%s %s: %s", tt.req.Method, tt.req.URL, rr.Body)
+		assert.Contains(t, rr.Body.String(), tt.body, tt.req.URL)
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/urls/abc/history", nil))
+	var versions []model.URLVersion
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&versions))
+	require.Len(t, versions, 3)
+	assert.Equal(t, model.VersionRollback, versions[2].Action)
+	assert.Equal(t, model.ForwardQuery(""), versions[2].ForwardQuery)
+
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, asAdmin(httptest.NewRequest(http.MethodDelete, "/urls/abc", nil)))
+		assert.Equal(t, status, rr.Code, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/urls/abc/history", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "deleted with its history")
+}
@@ -0,0 +1,257 @@
+package shortener
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// linkCheckUserAgent identifies the checker to the destination site.
+const linkCheckUserAgent = "url-shortener-linkcheck/1.0 (+https://github.com/jasoncheung94/url-shortener)"
+
+// LinkCheckerConfig controls how often and how politely destinations are checked.
+type LinkCheckerConfig struct {
+	Interval         time.Duration // Time between rounds over all links.
+	Concurrency      int           // Checks running at the same time.
+	HostDelay        time.Duration // Minimum time between two requests to the same host.
+	FailureThreshold int           // Consecutive failed checks before a link is flagged as broken.
+	BatchSize        int           // Links read from the repository at a time.
+}
+
+// LinkChecker periodically checks link destinations, records the results and flags broken links.
+type LinkChecker struct {
+	urls   repository.URL
+	checks repository.LinkChecks
+	client *http.Client
+	events events.Publisher
+	cfg    LinkCheckerConfig
+}
+
+// NewLinkChecker returns a LinkChecker. The client should refuse internal addresses, see metadata.NewSafeClient.
+func NewLinkChecker(urls repository.URL, checks repository.LinkChecks, client *http.Client,
+	publisher events.Publisher, cfg LinkCheckerConfig) *LinkChecker {
+	cfg.Concurrency = max(1, cfg.Concurrency)
+	cfg.FailureThreshold = max(1, cfg.FailureThreshold)
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &LinkChecker{urls: urls, checks: checks, client: client, events: publisher, cfg: cfg}
+}
+
+// Run checks all links every interval until the context is cancelled.
+func (c *LinkChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckAll(ctx); err != nil {
+			l.Logger.Error("link check round failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every link that hasn't expired once.
+func (c *LinkChecker) CheckAll(ctx context.Context) error {
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, c.cfg.Concurrency)
+		hosts = newHostLimiter(c.cfg.HostDelay)
+	)
+	defer wg.Wait()
+
+	for offset := 0; ; offset += c.cfg.BatchSize {
+		links, err := c.urls.ListURLs(ctx, model.URLFilter{Limit: c.cfg.BatchSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			if link.ExpirationDate != nil && link.ExpirationDate.Before(time.Now()) {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				c.checkLink(ctx, hosts, link)
+			}()
+		}
+
+		if len(links) < c.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// checkLink probes one link, stores the result and updates its broken flag.
+func (c *LinkChecker) checkLink(ctx context.Context, hosts *hostLimiter, link model.URL) {
+	destination, err := url.Parse(link.OriginalURL)
+	if err != nil {
+		return
+	}
+
+	release, err := hosts.acquire(ctx, destination.Host)
+	if err != nil {
+		return
+	}
+	check, conclusive := c.probe(ctx, link.OriginalURL)
+	release()
+	if !conclusive {
+		return
+	}
+
+	check.ShortURL, check.Domain = link.ShortURL, link.Domain
+	if err := c.checks.SaveLinkCheck(ctx, &check); err != nil {
+		l.Logger.Error("failed to save link check", "shorturl", link.ShortURL, "error", err)
+		return
+	}
+
+	broken := link.Broken
+	if check.OK {
+		broken = false
+	} else if !link.Broken {
+		history, err := c.checks.ListLinkChecks(ctx, link.Domain, link.ShortURL, c.cfg.FailureThreshold)
+		if err != nil {
+			l.Logger.Error("failed to list link checks", "shorturl", link.ShortURL, "error", err)
+			return
+		}
+		broken = len(history) >= c.cfg.FailureThreshold
+		for _, previous := range history {
+			broken = broken && !previous.OK
+		}
+	}
+	if broken != link.Broken {
+		c.setBroken(ctx, link.Domain, link.ShortURL, broken, check)
+	}
+}
+
+// setBroken stores the new flag on a fresh copy of the link and publishes the change.
+func (c *LinkChecker) setBroken(ctx context.Context, domain, shortURL string, broken bool,
+	check model.LinkCheck) {
+	data, err := c.urls.GetURL(ctx, domain, shortURL)
+	if err != nil {
+		l.Logger.Error("failed to get link", "shorturl", shortURL, "error", err)
+		return
+	}
+	data.Broken = broken
+	touch(data)
+	if err := c.urls.UpdateURL(ctx, data); err != nil {
+		l.Logger.Error("failed to update broken flag", "shorturl", shortURL, "error", err)
+		return
+	}
+
+	event := events.Event{
+		Type:       events.LinkRecovered,
+		ShortURL:   shortURL,
+		Domain:     domain,
+		Workspace:  data.Workspace,
+		OccurredAt: check.CheckedAt,
+		Data:       map[string]any{"originalURL": data.OriginalURL, "statusCode": check.StatusCode},
+	}
+	if broken {
+		event.Type = events.LinkBroken
+		event.Data["error"] = check.Error
+	}
+	c.events.Publish(ctx, event)
+}
+
+// probe requests the destination with HEAD, falling back to GET for servers that don't handle HEAD.
+// Rate limited or cancelled checks aren't conclusive and aren't recorded.
+func (c *LinkChecker) probe(ctx context.Context, destination string) (model.LinkCheck, bool) {
+	check := model.LinkCheck{CheckedAt: time.Now().UTC()}
+
+	status, err := c.request(ctx, http.MethodHead, destination)
+	if err == nil && status >= http.StatusBadRequest && status != http.StatusTooManyRequests {
+		status, err = c.request(ctx, http.MethodGet, destination)
+	}
+	switch {
+	case ctx.Err() != nil, status == http.StatusTooManyRequests:
+		return check, false
+	case err != nil:
+		check.Error = err.Error()
+	default:
+		check.StatusCode = status
+		check.OK = status < http.StatusBadRequest
+		if !check.OK {
+			check.Error = http.StatusText(status)
+		}
+	}
+	return check, true
+}
+
+func (c *LinkChecker) request(ctx context.Context, method, destination string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, destination, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", linkCheckUserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Lets the connection be reused.
+	return resp.StatusCode, nil
+}
+
+// hostLimiter allows one request per host at a time with a minimum delay between them.
+type hostLimiter struct {
+	delay time.Duration
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+type hostSlot struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{delay: delay, hosts: make(map[string]*hostSlot)}
+}
+
+// acquire waits for the host to be free and returns the function to call once the request is done.
+func (h *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h.mu.Lock()
+	slot, ok := h.hosts[host]
+	if !ok {
+		slot = &hostSlot{}
+		h.hosts[host] = slot
+	}
+	h.mu.Unlock()
+
+	slot.mu.Lock()
+	if wait := time.Until(slot.last.Add(h.delay)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			slot.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		slot.last = time.Now()
+		slot.mu.Unlock()
+	}, nil
+}
@@ -0,0 +1,161 @@
+package shortener
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkChecker(t *testing.T) {
+	t.Parallel()
+	var restored atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, _ *http.Request) {
+		if !restored.Load() {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	repo := repository.NewInMemory()
+	ctx := context.Background()
+	links := map[string]string{"ok": "/ok", "gone": "/gone", "nohead": "/no-head", "limited": "/limited"}
+	for code, path := range links {
+		require.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: code, OriginalURL: server.URL + path}))
+	}
+	require.NoError(t, repo.SaveURL(ctx, &model.URL{
+		ShortURL: "expired", OriginalURL: server.URL + "/gone", ExpirationDate: ptr.Of(time.Now().Add(-time.Hour)),
+	}))
+
+	var (
+		mu       sync.Mutex
+		received []events.Event
+	)
+	bus := events.NewBus()
+	bus.Subscribe(func(_ context.Context, e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, e)
+	})
+	checker := NewLinkChecker(repo, repo, server.Client(), bus, LinkCheckerConfig{
+		Concurrency: 2, FailureThreshold: 2, BatchSize: 2,
+	})
+
+	// First failure isn't enough to flag the link.
+	require.NoError(t, checker.CheckAll(ctx))
+	assertBroken(t, repo, "gone", false)
+	assert.Empty(t, received)
+
+	require.NoError(t, checker.CheckAll(ctx))
+	assertBroken(t, repo, "gone", true)
+	assertBroken(t, repo, "ok", false)
+	assertBroken(t, repo, "nohead", false)
+	require.Len(t, received, 1)
+	assert.Equal(t, events.LinkBroken, received[0].Type)
+	assert.Equal(t, "gone", received[0].ShortURL)
+	assert.Equal(t, http.StatusNotFound, received[0].Data["statusCode"])
+
+	// Still broken: no new event.
+	require.NoError(t, checker.CheckAll(ctx))
+	assert.Len(t, received, 1)
+
+	restored.Store(true)
+	require.NoError(t, checker.CheckAll(ctx))
+	assertBroken(t, repo, "gone", false)
+	require.Len(t, received, 2)
+	assert.Equal(t, events.LinkRecovered, received[1].Type)
+
+	history, err := repo.ListLinkChecks(ctx, "", "gone", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.True(t, history[0].OK)
+	assert.Equal(t, http.StatusNotFound, history[1].StatusCode)
+
+	history, err = repo.ListLinkChecks(ctx, "", "nohead", 10)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, history[0].StatusCode, "GET is used when HEAD isn't supported")
+
+	for _, code := range []string{"limited", "expired"} {
+		history, err = repo.ListLinkChecks(ctx, "", code, 10)
+		require.NoError(t, err)
+		assert.Empty(t, history, code)
+	}
+}
+
+func TestLinkChecker_Unreachable(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	destination := server.URL
+	server.Close()
+
+	repo := repository.NewInMemory()
+	ctx := context.Background()
+	require.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: "down", OriginalURL: destination}))
+	checker := NewLinkChecker(repo, repo, http.DefaultClient, events.NewBus(), LinkCheckerConfig{FailureThreshold: 1})
+
+	require.NoError(t, checker.CheckAll(ctx))
+	assertBroken(t, repo, "down", true)
+	history, err := repo.ListLinkChecks(ctx, "", "down", 1)
+	require.NoError(t, err)
+	assert.Zero(t, history[0].StatusCode)
+	assert.NotEmpty(t, history[0].Error)
+}
+
+func TestLinkChecker_HostPoliteness(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	repo := repository.NewInMemory()
+	ctx := context.Background()
+	for _, code := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: code, OriginalURL: server.URL + "/" + code}))
+	}
+	checker := NewLinkChecker(repo, repo, server.Client(), events.NewBus(), LinkCheckerConfig{
+		Concurrency: 4, HostDelay: 20 * time.Millisecond, FailureThreshold: 1,
+	})
+
+	start := time.Now()
+	require.NoError(t, checker.CheckAll(ctx))
+	assert.Equal(t, int32(1), maxInFlight.Load(), "one request per host at a time")
+	assert.GreaterOrEqual(t, time.Since(start), 3*20*time.Millisecond, "requests to one host are spaced out")
+}
+
+func assertBroken(t *testing.T, repo repository.URL, shortURL string, expected bool) {
+	t.Helper()
+	data, err := repo.GetURL(context.Background(), "", shortURL)
+	require.NoError(t, err)
+	assert.Equal(t, expected, data.Broken, shortURL)
+}
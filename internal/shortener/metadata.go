@@ -0,0 +1,159 @@
+package shortener
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/metadata"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// Limits of the preview fields, see the validate tags on model.URL.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	maxImageLength       = 2048
+)
+
+// MetadataFetcher fetches the preview metadata of a destination page.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (metadata.Metadata, error)
+}
+
+// MetadataEnricher fills in missing link previews from the destination page in the background.
+// Preview fields set by the user are never overwritten.
+type MetadataEnricher struct {
+	fetcher MetadataFetcher
+	repo    repository.URL
+	timeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	jobs   chan linkRef
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMetadataEnricher starts the workers. Each fetch is limited to timeout.
+func NewMetadataEnricher(fetcher MetadataFetcher, repo repository.URL, workers int,
+	timeout time.Duration) *MetadataEnricher {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &MetadataEnricher{
+		fetcher: fetcher,
+		repo:    repo,
+		timeout: timeout,
+		jobs:    make(chan linkRef, 100),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for range max(1, workers) {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// linkRef identifies a link by its domain and short URL.
+type linkRef struct {
+	domain   string
+	shortURL string
+}
+
+// Enqueue schedules the link for enrichment. Links are dropped when the queue is full.
+func (m *MetadataEnricher) Enqueue(domain, shortURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+
+	select {
+	case m.jobs <- linkRef{domain: domain, shortURL: shortURL}:
+	default:
+		l.Logger.Warn("metadata queue full, skipping link", "shorturl", shortURL, "domain", domain)
+	}
+}
+
+// Close stops the workers, cancelling in-flight fetches and dropping queued links.
+func (m *MetadataEnricher) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.cancel()
+	close(m.jobs)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+func (m *MetadataEnricher) work() {
+	defer m.wg.Done()
+	for link := range m.jobs {
+		if m.ctx.Err() != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(m.ctx, m.timeout)
+		if err := m.enrich(ctx, link); err != nil {
+			l.Logger.Info("failed to fetch link metadata", "shorturl", link.shortURL, "domain", link.domain,
+				"error", err)
+		}
+		cancel()
+	}
+}
+
+// enrich fetches the destination of the link and stores any preview fields it's missing.
+func (m *MetadataEnricher) enrich(ctx context.Context, link linkRef) error {
+	data, err := m.repo.GetURL(ctx, link.domain, link.shortURL)
+	if err != nil {
+		return err
+	}
+	if !needsMetadata(data) {
+		return nil
+	}
+
+	meta, err := m.fetcher.Fetch(ctx, data.OriginalURL)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	fill := func(field *string, value string, limit int) {
+		if *field == "" && value != "" {
+			*field = truncate(value, limit)
+			changed = true
+		}
+	}
+	fill(&data.Title, meta.Title, maxTitleLength)
+	fill(&data.Description, meta.Description, maxDescriptionLength)
+	if len(meta.Image) <= maxImageLength { // A cut off URL is useless.
+		fill(&data.Image, meta.Image, maxImageLength)
+	}
+	if !changed {
+		return nil
+	}
+	touch(data)
+	return m.repo.UpdateURL(ctx, data)
+}
+
+// needsMetadata reports whether any preview field is empty.
+func needsMetadata(data *model.URL) bool {
+	return data.Title == "" || data.Description == "" || data.Image == ""
+}
+
+// truncate cuts s to at most limit characters.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,118 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/metadata"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataEnricher(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<head><title>` + strings.Repeat("t", 300) + `</title>
+			<meta name="description" content="Fetched description"><meta property="og:image" content="/og.png"></head>`))
+	}))
+	defer server.Close()
+
+	repo := repository.NewInMemory()
+	enricher := NewMetadataEnricher(metadata.NewFetcher(server.Client(), 1<<20), repo, 2, time.Second)
+	defer enricher.Close()
+	service := NewService(repo, WithMetadataEnricher(enricher))
+
+	shortURL, err := service.SaveURL(context.Background(), &model.URL{
+		OriginalURL: server.URL + "/page",
+		Description: "Set by the user",
+	})
+	require.NoError(t, err)
+
+	var data *model.URL
+	require.Eventually(t, func() bool {
+		data, err = repo.GetURL(context.Background(), "", shortURL)
+		return err == nil && data.Image != ""
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, strings.Repeat("t", maxTitleLength), data.Title, "title is cut to the column size")
+	assert.Equal(t, "Set by the user", data.Description, "user fields are kept")
+	assert.Equal(t, server.URL+"/og.png", data.Image)
+}
+
+type fakeFetcher struct {
+	meta metadata.Metadata
+	err  error
+}
+
+func (f fakeFetcher) Fetch(context.Context, string) (metadata.Metadata, error) {
+	return f.meta, f.err
+}
+
+func TestMetadataEnricher_Enrich(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		data        model.URL
+		fetcher     fakeFetcher
+		expected    model.URL
+		expectError bool
+	}{
+		{
+			name:     "Complete link is not fetched",
+			data:     model.URL{ShortURL: "abc", Title: "a", Description: "b", Image: "https://example.com/c.png"},
+			fetcher:  fakeFetcher{err: errors.New("should not be called")},
+			expected: model.URL{ShortURL: "abc", Title: "a", Description: "b", Image: "https://example.com/c.png"},
+		},
+		{
+			name:        "Fetch error leaves link untouched",
+			data:        model.URL{ShortURL: "abc"},
+			fetcher:     fakeFetcher{err: errors.New("timeout")},
+			expected:    model.URL{ShortURL: "abc"},
+			expectError: true,
+		},
+		{
+			name:     "Overlong image is dropped",
+			data:     model.URL{ShortURL: "abc"},
+			fetcher:  fakeFetcher{meta: metadata.Metadata{Title: "T", Image: "https://x/" + strings.Repeat("a", 2048)}},
+			expected: model.URL{ShortURL: "abc", Title: "T"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			repo := repository.NewInMemory()
+			require.NoError(t, repo.SaveURL(context.Background(), &tt.data))
+			enricher := &MetadataEnricher{fetcher: tt.fetcher, repo: repo}
+
+			err := enricher.enrich(context.Background(), linkRef{shortURL: "abc"})
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			data, err := repo.GetURL(context.Background(), "", "abc")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.Title, data.Title)
+			assert.Equal(t, tt.expected.Description, data.Description)
+			assert.Equal(t, tt.expected.Image, data.Image)
+		})
+	}
+}
+
+func TestMetadataEnricher_Close(t *testing.T) {
+	t.Parallel()
+	enricher := NewMetadataEnricher(fakeFetcher{}, repository.NewInMemory(), 1, time.Second)
+	enricher.Close()
+	enricher.Close()
+	enricher.Enqueue("", "abc") // No panic after close.
+}
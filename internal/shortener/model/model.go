@@ -1,16 +1,524 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // URL represents the data for the URL.
 //
 //nolint:lll
 type URL struct {
-	ID             int64      `json:"id,omitempty"`
-	ObjectID       string     `json:"objectID,omitempty" bson:"_id"`
-	OriginalURL    string     `json:"originalURL" db:"original_url" bson:"original_url" validate:"required,url"`
-	ShortURL       string     `json:"shortURL" db:"short_url" bson:"short_url"`
-	CustomURL      *string    `json:"customURL" db:"custom_url" bson:"custom_url" validate:"omitempty,alphanum,min=3,max=20"`
-	ExpirationDate *time.Time `json:"expirationDate" db:"expiration_date" bson:"expiration_date" validate:"omitempty"`
-	CreatedAt      time.Time  `json:"createdAt" db:"created_at" bson:"created_at"`
+	ID             int64        `json:"id,omitempty"`
+	ObjectID       string       `json:"objectID,omitempty" bson:"_id"`
+	OriginalURL    string       `json:"originalURL" db:"original_url" bson:"original_url" validate:"required,url"`
+	ShortURL       string       `json:"shortURL" db:"short_url" bson:"short_url"`
+	Domain         string       `json:"domain,omitempty" db:"domain" bson:"domain,omitempty" validate:"omitempty,max=253"` // Verified custom domain, empty for the default host.
+	Workspace      string       `json:"workspace,omitempty" db:"tenant_id" bson:"tenant_id,omitempty"`                     // Set from the API key, empty for the default workspace.
+	CustomURL      *string      `json:"customURL" db:"custom_url" bson:"custom_url" validate:"omitempty,alias"`            // See shortener.CodeGrammar.
+	ExpirationDate *time.Time   `json:"expirationDate" db:"expiration_date" bson:"expiration_date" validate:"omitempty"`
+	UTM            *UTM         `json:"utm,omitempty" db:"utm" bson:"utm,omitempty"`
+	ForwardQuery   ForwardQuery `json:"forwardQuery,omitempty" db:"forward_query" bson:"forward_query,omitempty" validate:"omitempty,oneof=none merge override"`
+	Prefix         bool         `json:"prefix,omitempty" db:"prefix" bson:"prefix,omitempty"`                                                                // Forward /{shorturl}/any/path to OriginalURL + /any/path.
+	RedirectType   int          `json:"redirectType,omitempty" db:"redirect_type" bson:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"` // 0 uses the server default.
+	Title          string       `json:"title,omitempty" db:"title" bson:"title,omitempty" validate:"omitempty,max=200"`                                      // Open Graph title shown when the link is shared.
+	Description    string       `json:"description,omitempty" db:"description" bson:"description,omitempty" validate:"omitempty,max=500"`                    // Open Graph description.
+	Image          string       `json:"image,omitempty" db:"image" bson:"image,omitempty" validate:"omitempty,http_url,max=2048"`                            // Open Graph image URL.
+	Broken         bool         `json:"broken,omitempty" db:"broken" bson:"broken,omitempty"`                                                                // Set by the link checker after repeated failures.
+	Generator      string       `json:"generator,omitempty" db:"generator" bson:"generator,omitempty" validate:"omitempty,oneof=counter random hash words"`  // Code generator of the link, empty for custom aliases.
+	Tags           Tags         `json:"tags,omitempty" db:"tags" bson:"tags,omitempty" validate:"omitempty,max=20"`                                          // See shortener.NormalizeTags.
+	Folder         string       `json:"folder,omitempty" db:"folder" bson:"folder,omitempty" validate:"omitempty,max=100"`                                   // Folder or campaign the link is filed under.
+	Notes          string       `json:"notes,omitempty" db:"notes" bson:"notes,omitempty" validate:"omitempty,max=2000"`                                     // Free text, not shown to visitors.
+	CreatedAt      time.Time    `json:"createdAt" db:"created_at" bson:"created_at"`
+	UpdatedAt      *time.Time   `json:"updatedAt,omitempty" db:"updated_at" bson:"updated_at,omitempty"` // Last change, nil if never changed.
+}
+
+// LastModified returns when the link last changed.
+func (u URL) LastModified() time.Time {
+	if u.UpdatedAt != nil {
+		return *u.UpdatedAt
+	}
+	return u.CreatedAt
+}
+
+// URLFilter narrows down a list of URLs.
+type URLFilter struct {
+	Broken *bool    // Only broken or only working links, nil for both.
+	Tags   []string // Only links with all of the tags.
+	Folder *string  // Only links in the folder, "" for links without one, nil for any.
+	Limit  int
+	Offset int
+}
+
+// URLUpdate are the changes to a link. Nil fields are left as they are.
+//
+//nolint:lll
+type URLUpdate struct {
+	OriginalURL    *string       `json:"originalURL,omitempty"`
+	ExpirationDate *time.Time    `json:"expirationDate,omitempty"`
+	NeverExpires   bool          `json:"neverExpires,omitempty"` // Removes the expiration date.
+	UTM            *UTM          `json:"utm,omitempty"`          // {} removes the template.
+	ForwardQuery   *ForwardQuery `json:"forwardQuery,omitempty" enums:"none,merge,override"`
+	Prefix         *bool         `json:"prefix,omitempty"`
+	RedirectType   *int          `json:"redirectType,omitempty" enums:"0,301,302,307,308"` // 0 uses the server default.
+	Tags           *Tags         `json:"tags,omitempty"`                                   // Replaces all tags, [] removes them.
+	Folder         *string       `json:"folder,omitempty"`
+	Notes          *string       `json:"notes,omitempty"`
+}
+
+// Version actions.
+const (
+	VersionCreate   = "create"
+	VersionUpdate   = "update"
+	VersionRollback = "rollback"
+)
+
+// URLVersion is the destination, expiry and redirect rules of a link after a change, with who made it.
+// Versions are only ever added, a rollback adds a version with the content of an older one.
+//
+//nolint:lll
+type URLVersion struct {
+	Domain         string       `json:"-" db:"domain" bson:"domain,omitempty"`
+	ShortURL       string       `json:"-" db:"short_url" bson:"short_url"`
+	Version        int          `json:"version" db:"version" bson:"version"` // 1 is the link as it was created.
+	OriginalURL    string       `json:"originalURL" db:"original_url" bson:"original_url"`
+	ExpirationDate *time.Time   `json:"expirationDate,omitempty" db:"expiration_date" bson:"expiration_date,omitempty"`
+	UTM            *UTM         `json:"utm,omitempty" db:"utm" bson:"utm,omitempty"`
+	ForwardQuery   ForwardQuery `json:"forwardQuery,omitempty" db:"forward_query" bson:"forward_query,omitempty"`
+	Prefix         bool         `json:"prefix,omitempty" db:"prefix" bson:"prefix,omitempty"`
+	RedirectType   int          `json:"redirectType,omitempty" db:"redirect_type" bson:"redirect_type,omitempty"`
+	Actor          string       `json:"actor,omitempty" db:"actor" bson:"actor,omitempty"` // Member who made the change, empty without an API key or for links older than the history.
+	Action         string       `json:"action" db:"action" bson:"action" enums:"create,update,rollback"`
+	RollbackOf     int          `json:"rollbackOf,omitempty" db:"rollback_of" bson:"rollback_of,omitempty"` // Version a rollback restored.
+	CreatedAt      time.Time    `json:"createdAt" db:"created_at" bson:"created_at"`
+}
+
+// TagCount is a tag and how many links have it.
+type TagCount struct {
+	Tag   string `json:"tag" db:"tag" bson:"_id"`
+	Links int    `json:"links" db:"links" bson:"links"`
+}
+
+// TagMerge replaces tags of every link with another one.
+type TagMerge struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+// URLList is a page of URLs.
+type URLList struct {
+	URLs   []URL `json:"urls"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// LinkCheck is the result of one health check of a link's destination.
+//
+//nolint:lll
+type LinkCheck struct {
+	ShortURL   string    `json:"shortURL" db:"short_url" bson:"short_url"`
+	Domain     string    `json:"domain,omitempty" db:"domain" bson:"domain,omitempty"`
+	StatusCode int       `json:"statusCode,omitempty" db:"status_code" bson:"status_code,omitempty"` // 0 when no response was received.
+	Error      string    `json:"error,omitempty" db:"error" bson:"error,omitempty"`
+	OK         bool      `json:"ok" db:"ok" bson:"ok"`
+	CheckedAt  time.Time `json:"checkedAt" db:"checked_at" bson:"checked_at"`
+}
+
+// CounterRange is the reserved counter values First to Last, inclusive.
+type CounterRange struct {
+	First uint64
+	Last  uint64
+}
+
+// Len returns the number of values in the range.
+func (r CounterRange) Len() uint64 {
+	return r.Last - r.First + 1
+}
+
+// WorkerLease is a worker ID held by one instance until it expires.
+type WorkerLease struct {
+	WorkerID  int       `db:"worker_id" bson:"_id"`
+	Owner     string    `db:"owner" bson:"owner"`
+	ExpiresAt time.Time `db:"expires_at" bson:"expires_at"`
+	LastTime  time.Time `db:"last_time" bson:"last_time,omitempty"` // Latest ID generated with the worker ID, zero if unknown.
+}
+
+// Domain is a custom domain short links can be served from once its owner proved control of it.
+//
+//nolint:lll
+type Domain struct {
+	Name       string     `json:"name" db:"name" bson:"_id"` // Lower case, IDNA ASCII form.
+	Owner      string     `json:"owner" db:"owner" bson:"owner"`
+	Token      string     `json:"-" db:"token" bson:"token"` // Expected in the verification TXT record.
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty" db:"verified_at" bson:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at" bson:"created_at"`
+	Record     *DNSRecord `json:"verificationRecord,omitempty" db:"-" bson:"-"` // Set while the domain isn't verified.
+}
+
+// Verified reports whether the owner proved control of the domain.
+func (d Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// DNSRecord is a DNS record the owner of a domain has to create.
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Workspace is a tenant owning links, API keys and custom domains.
+//
+//nolint:lll
+type Workspace struct {
+	ID           string    `json:"id" db:"id" bson:"_id"` // Lower case letters, digits and -, e.g. marketing.
+	Name         string    `json:"name" db:"name" bson:"name" validate:"max=100"`
+	LinkQuota    int       `json:"linkQuota" db:"link_quota" bson:"link_quota" validate:"min=0"`          // Links the workspace may have, 0 for no limit.
+	MonthlyQuota int       `json:"monthlyQuota" db:"monthly_quota" bson:"monthly_quota" validate:"min=0"` // Links it may create per calendar month (UTC), 0 for no limit.
+	CreatedAt    time.Time `json:"createdAt" db:"created_at" bson:"created_at"`
+}
+
+// APIKey authenticates requests as a member of its workspace. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	ID        string    `json:"id" db:"id" bson:"_id"` // Public part of the key.
+	Workspace string    `json:"workspace" db:"workspace_id" bson:"workspace_id"`
+	Member    string    `json:"member,omitempty" db:"member_id" bson:"member_id,omitempty"` // Whose role the key has.
+	Name      string    `json:"name" db:"name" bson:"name"`
+	Hash      string    `json:"-" db:"hash" bson:"hash"`
+	Key       string    `json:"key,omitempty" db:"-" bson:"-"` // Only returned when the key is created.
+	CreatedAt time.Time `json:"createdAt" db:"created_at" bson:"created_at"`
+}
+
+// Role is what a member can do in a workspace. Each role can do everything the roles below it can.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"  // Manages owners on top of what admins can.
+	RoleAdmin  Role = "admin"  // Manages members, API keys and domains.
+	RoleEditor Role = "editor" // Creates and updates links.
+	RoleViewer Role = "viewer" // Sees links and stats.
+)
+
+// Rank orders the roles, 0 for unknown ones.
+func (r Role) Rank() int {
+	switch r {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Member is a user's membership of a workspace.
+type Member struct {
+	Workspace string    `json:"workspace" db:"workspace_id" bson:"workspace_id"`
+	ID        string    `json:"id" db:"member_id" bson:"member_id"` // User name or email.
+	Role      Role      `json:"role" db:"role" bson:"role" enums:"owner,admin,editor,viewer"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at" bson:"created_at"`
+}
+
+// WorkspaceUsage is how many links a workspace has and created this month, next to its quotas.
+type WorkspaceUsage struct {
+	Workspace    string    `json:"workspace"`
+	Links        int       `json:"links"`
+	LinkQuota    int       `json:"linkQuota"`
+	MonthlyLinks int       `json:"monthlyLinks"`
+	MonthlyQuota int       `json:"monthlyQuota"`
+	PeriodStart  time.Time `json:"periodStart"` // Start of the month MonthlyLinks counts.
+	PeriodEnd    time.Time `json:"periodEnd"`   // When MonthlyLinks starts over.
+}
+
+// AliasStatus tells whether a custom alias can be claimed.
+type AliasStatus string
+
+const (
+	// AliasAvailable is free to claim.
+	AliasAvailable AliasStatus = "available"
+	// AliasReserved can't be claimed: it's a route name or contains a blocked word.
+	AliasReserved AliasStatus = "reserved"
+	// AliasTaken is already used by another link.
+	AliasTaken AliasStatus = "taken"
+)
+
+// AliasAvailability is the result of an alias availability check.
+type AliasAvailability struct {
+	Alias       string      `json:"alias"` // As it would be stored, e.g. lower case.
+	Status      AliasStatus `json:"status"`
+	Reason      string      `json:"reason,omitempty"`
+	Suggestions []string    `json:"suggestions,omitempty"` // Free alternatives when the alias isn't available.
+}
+
+// ForwardQuery controls what happens to the query string of the short URL on redirect.
+type ForwardQuery string
+
+const (
+	// ForwardQueryNone drops the incoming query string. This is the default.
+	ForwardQueryNone ForwardQuery = "none"
+	// ForwardQueryMerge adds incoming parameters the destination doesn't already have.
+	ForwardQueryMerge ForwardQuery = "merge"
+	// ForwardQueryOverride adds incoming parameters, replacing any the destination already has.
+	ForwardQueryOverride ForwardQuery = "override"
+)
+
+// Tags are the labels of a link, stored as a text array in SQL databases.
+type Tags []string
+
+// Value stores the tags as a text array, an empty one for no tags.
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	return pq.StringArray(t).Value()
+}
+
+// Scan reads the tags from a text array column.
+func (t *Tags) Scan(src any) error {
+	var tags pq.StringArray
+	if err := tags.Scan(src); err != nil {
+		return err
+	}
+	*t = Tags(tags)
+	return nil
+}
+
+// UTM is the campaign template appended to the destination on redirect.
+// Values may contain the {shorturl} placeholder which is replaced with the short code.
+//
+//nolint:lll
+type UTM struct {
+	Source   string `json:"source,omitempty" bson:"source,omitempty" validate:"omitempty,max=100"`
+	Medium   string `json:"medium,omitempty" bson:"medium,omitempty" validate:"omitempty,max=100"`
+	Campaign string `json:"campaign,omitempty" bson:"campaign,omitempty" validate:"omitempty,max=100"`
+	Term     string `json:"term,omitempty" bson:"term,omitempty" validate:"omitempty,max=100"`
+	Content  string `json:"content,omitempty" bson:"content,omitempty" validate:"omitempty,max=100"`
+}
+
+// Params returns the UTM query parameter names and their values in a stable order.
+func (u UTM) Params() [][2]string {
+	return [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	}
+}
+
+// Value stores the UTM template as JSON for SQL databases.
+func (u UTM) Value() (driver.Value, error) {
+	return json.Marshal(u)
+}
+
+// Scan reads the UTM template from a JSON column.
+func (u *UTM) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, u)
+	case string:
+		return json.Unmarshal([]byte(v), u)
+	default:
+		return errors.New("model: unsupported type for utm")
+	}
+}
+
+// Audit actions, the kind of resource changed and how.
+const (
+	AuditURLCreate       = "url.create"
+	AuditURLUpdate       = "url.update"
+	AuditURLRollback     = "url.rollback"
+	AuditURLDelete       = "url.delete"
+	AuditDomainCreate    = "domain.create"
+	AuditDomainVerify    = "domain.verify"
+	AuditWorkspaceCreate = "workspace.create"
+	AuditWorkspaceUpdate = "workspace.update"
+	AuditKeyCreate       = "key.create"
+	AuditKeyDelete       = "key.delete"
+	AuditMemberCreate    = "member.create"
+	AuditMemberUpdate    = "member.update"
+	AuditMemberDelete    = "member.delete"
+	AuditWebhookCreate   = "webhook.create"
+	AuditWebhookDelete   = "webhook.delete"
+)
+
+// AuditEntry is one change in the audit log: who changed what, from where and how. Entries are hash chained,
+// Hash covers the entry and the Hash of the entry before it, so changing or removing one breaks the chain.
+//
+//nolint:lll
+type AuditEntry struct {
+	Seq       int64        `json:"seq" db:"seq" bson:"_id"` // Position in the chain, from 1.
+	Time      time.Time    `json:"time" db:"time" bson:"time"`
+	Actor     string       `json:"actor,omitempty" db:"actor" bson:"actor,omitempty"` // Member ID, admin for the admin key, empty without an API key.
+	Workspace string       `json:"workspace,omitempty" db:"workspace_id" bson:"workspace_id,omitempty"`
+	Action    string       `json:"action" db:"action" bson:"action"`       // e.g. url.update, see the Audit constants.
+	Resource  string       `json:"resource" db:"resource" bson:"resource"` // ID of what changed, e.g. the short URL or key ID.
+	IP        string       `json:"ip,omitempty" db:"ip" bson:"ip,omitempty"`
+	RequestID string       `json:"requestID,omitempty" db:"request_id" bson:"request_id,omitempty"`
+	Changes   AuditChanges `json:"changes,omitempty" db:"changes" bson:"changes,omitempty"`
+	PrevHash  string       `json:"prevHash" db:"prev_hash" bson:"prev_hash"` // Empty for the first entry.
+	Hash      string       `json:"hash" db:"hash" bson:"hash"`               // Hex SHA-256, see audit.Hash.
+}
+
+// AuditChange is the JSON value of a field before and after a change, null if it wasn't set.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty" bson:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" bson:"after,omitempty" swaggertype:"object"`
+}
+
+// AuditChanges are the changed fields of a resource by their JSON name.
+type AuditChanges map[string]AuditChange
+
+// Value stores the changes as JSON for SQL databases.
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan reads the changes from a JSON column.
+func (c *AuditChanges) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return errors.New("model: unsupported type for audit changes")
+	}
+}
+
+// AuditFilter narrows down the audit log. Empty fields match every entry.
+type AuditFilter struct {
+	Actor     string
+	Workspace string
+	Action    string
+	Resource  string
+	From      *time.Time // Entries at or after.
+	To        *time.Time // Entries before.
+	AfterSeq  int64      // Entries after this position, to page through the log.
+	Limit     int
+}
+
+// Matches reports whether the entry passes the filter, apart from the limit.
+func (f AuditFilter) Matches(entry AuditEntry) bool {
+	return entry.Seq > f.AfterSeq &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.Workspace == "" || entry.Workspace == f.Workspace) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Resource == "" || entry.Resource == f.Resource) &&
+		(f.From == nil || !entry.Time.Before(*f.From)) &&
+		(f.To == nil || entry.Time.Before(*f.To))
+}
+
+// AuditVerification is the result of checking the hash chain of the audit log.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`            // Entries checked, up to the first broken one.
+	Head     string `json:"head,omitempty"`     // Hash of the last entry, keep a copy elsewhere to detect truncation.
+	BrokenAt int64  `json:"brokenAt,omitempty"` // Seq of the first entry that doesn't match the chain.
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Webhook is a workspace's subscription to link events, sent to URL as JSON signed with Secret.
+//
+//nolint:lll
+type Webhook struct {
+	ID        string         `json:"id" db:"id" bson:"_id"`
+	Workspace string         `json:"workspace,omitempty" db:"workspace_id" bson:"workspace_id"`
+	URL       string         `json:"url" db:"url" bson:"url" validate:"required,http_url,max=2048"`
+	Events    pq.StringArray `json:"events" db:"events" bson:"events" swaggertype:"array,string"` // Event types sent, every type if empty. See events.Type.
+	Secret    string         `json:"secret,omitempty" db:"secret" bson:"secret"`                  // Only returned when the webhook is created.
+	CreatedAt time.Time      `json:"createdAt" db:"created_at" bson:"created_at"`
+}
+
+// DeliveryStatus is where a webhook delivery is in the queue.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending waits for its next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered was accepted by the receiver with a 2xx status.
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead failed every attempt and is only sent again when redelivered.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is an event queued for a webhook, with the log of its attempts.
+//
+//nolint:lll
+type WebhookDelivery struct {
+	ID            string          `json:"id" db:"id" bson:"_id"` // Derived from the webhook and event IDs, so an event is queued once per webhook.
+	Webhook       string          `json:"webhook" db:"webhook_id" bson:"webhook_id"`
+	Workspace     string          `json:"workspace,omitempty" db:"workspace_id" bson:"workspace_id"`
+	Event         string          `json:"event" db:"event_id" bson:"event_id"`
+	Type          string          `json:"type" db:"event_type" bson:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload" bson:"payload" swaggertype:"object"` // The body sent, the event as JSON.
+	Status        DeliveryStatus  `json:"status" db:"status" bson:"status" enums:"pending,delivered,dead"`
+	Attempts      int             `json:"attempts" db:"attempts" bson:"attempts"` // Since it was queued or redelivered.
+	NextAttemptAt time.Time       `json:"nextAttemptAt" db:"next_attempt_at" bson:"next_attempt_at"`
+	Log           DeliveryLog     `json:"log,omitempty" db:"log" bson:"log,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at" bson:"created_at"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty" db:"delivered_at" bson:"delivered_at,omitempty"`
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once.
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"status_code,omitempty"` // 0 when no response was received.
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	Response   string    `json:"response,omitempty" bson:"response,omitempty"` // Start of the response body.
+	DurationMS int64     `json:"durationMs" bson:"duration_ms"`
+}
+
+// DeliveryLog are the latest attempts of a delivery, oldest first.
+type DeliveryLog []DeliveryAttempt
+
+// Value stores the log as JSON for SQL databases.
+func (d DeliveryLog) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan reads the log from a JSON column.
+func (d *DeliveryLog) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, d)
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	default:
+		return errors.New("model: unsupported type for delivery log")
+	}
+}
+
+// OutboxMessage is an event stored in the transaction of the change it records, until the outbox relay
+// publishes it. Messages are published at least once, consumers skip the IDs they already processed.
+//
+//nolint:lll
+type OutboxMessage struct {
+	ID            string          `json:"id" db:"id" bson:"_id"` // The event ID.
+	Type          string          `json:"type" db:"event_type" bson:"event_type"`
+	Key           string          `json:"key" db:"aggregate_key" bson:"aggregate_key"` // The link the event is about, transports that partition keep its messages in order.
+	Payload       json.RawMessage `json:"payload" db:"payload" bson:"payload"`         // The event as JSON.
+	Attempts      int             `json:"attempts" db:"attempts" bson:"attempts"`      // Times the message was claimed by a relay.
+	NextAttemptAt time.Time       `json:"nextAttemptAt" db:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at" bson:"created_at"`
+	PublishedAt   *time.Time      `json:"publishedAt,omitempty" db:"published_at" bson:"published_at,omitempty"`
 }
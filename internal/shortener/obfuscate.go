@@ -0,0 +1,99 @@
+package shortener
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// feistelRounds is the number of rounds of the permutation. Four is enough for a pseudorandom permutation,
+// the extra rounds make the output of neighbouring counters look unrelated.
+const feistelRounds = 8
+
+// Obfuscator widths. 58 bits is the widest whose codes still fit in MaxGeneratedLength characters.
+const (
+	MinObfuscatorBits = 8
+	MaxObfuscatorBits = 58
+)
+
+// ErrIDOutOfRange is returned for IDs that don't fit in the obfuscator width.
+var ErrIDOutOfRange = errors.New("shortener: id out of obfuscator range")
+
+// IDObfuscator is a keyed bijective permutation of the integers below 2^bits, implemented as a
+// balanced Feistel network with HMAC-SHA256 as round function. Encoding counters with it before
+// base62 makes short codes non-sequential while staying unique, and the key decodes them back.
+//
+// The width bounds the code length: 36 bits is at most 7 base62 characters, 34 bits at most 6.
+// Changing the key or width after codes were issued can make new codes collide with old ones.
+type IDObfuscator struct {
+	key      []byte
+	bits     int
+	halfBits int
+	halfMask uint64
+}
+
+// NewIDObfuscator returns an obfuscator for IDs of the given even bit width.
+func NewIDObfuscator(key []byte, bits int) (*IDObfuscator, error) {
+	if len(key) == 0 {
+		return nil, errors.New("shortener: obfuscator key is empty")
+	}
+	if bits < MinObfuscatorBits || bits > MaxObfuscatorBits || bits%2 != 0 {
+		return nil, fmt.Errorf("shortener: obfuscator width must be an even number of bits between %d and %d, got %d",
+			MinObfuscatorBits, MaxObfuscatorBits, bits)
+	}
+
+	half := bits / 2
+	return &IDObfuscator{
+		key:      key,
+		bits:     bits,
+		halfBits: half,
+		halfMask: 1<<half - 1,
+	}, nil
+}
+
+// Max returns the largest ID the obfuscator accepts.
+func (o *IDObfuscator) Max() uint64 {
+	return o.halfMask<<o.halfBits | o.halfMask
+}
+
+// Encode maps the ID to its obfuscated value.
+func (o *IDObfuscator) Encode(id uint64) (uint64, error) {
+	if id > o.Max() {
+		return 0, fmt.Errorf("%w: %d > %d", ErrIDOutOfRange, id, o.Max())
+	}
+
+	mac := hmac.New(sha256.New, o.key)
+	left, right := id>>o.halfBits, id&o.halfMask
+	for round := range feistelRounds {
+		left, right = right, left^o.round(mac, round, right)
+	}
+	return left<<o.halfBits | right, nil
+}
+
+// Decode reverses Encode.
+func (o *IDObfuscator) Decode(value uint64) (uint64, error) {
+	if value > o.Max() {
+		return 0, fmt.Errorf("%w: %d > %d", ErrIDOutOfRange, value, o.Max())
+	}
+
+	mac := hmac.New(sha256.New, o.key)
+	left, right := value>>o.halfBits, value&o.halfMask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^o.round(mac, round, left), left
+	}
+	return left<<o.halfBits | right, nil
+}
+
+// round is the Feistel round function, keyed by the round number.
+func (o *IDObfuscator) round(mac hash.Hash, round int, half uint64) uint64 {
+	var input [9]byte
+	input[0] = byte(round)
+	binary.BigEndian.PutUint64(input[1:], half)
+
+	mac.Reset()
+	mac.Write(input[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & o.halfMask
+}
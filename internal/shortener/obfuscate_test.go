@@ -0,0 +1,125 @@
+package shortener
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIDObfuscator(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		key     string
+		bits    int
+		wantErr bool
+	}{
+		{name: "Valid", key: "secret", bits: 36},
+		{name: "Smallest width", key: "secret", bits: MinObfuscatorBits},
+		{name: "Largest width", key: "secret", bits: MaxObfuscatorBits},
+		{name: "Empty key", key: "", bits: 36, wantErr: true},
+		{name: "Odd width", key: "secret", bits: 35, wantErr: true},
+		{name: "Too narrow", key: "secret", bits: 6, wantErr: true},
+		{name: "Too wide", key: "secret", bits: 64, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewIDObfuscator([]byte(tt.key), tt.bits)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestIDObfuscatorBijective checks every value of a small width maps to a distinct value in range.
+func TestIDObfuscatorBijective(t *testing.T) {
+	t.Parallel()
+
+	for _, bits := range []int{8, 16} {
+		obfuscator, err := NewIDObfuscator([]byte("secret"), bits)
+		require.NoError(t, err)
+
+		seen := make(map[uint64]bool, 1<<bits)
+		for id := range uint64(1) << bits {
+			value, err := obfuscator.Encode(id)
+			require.NoError(t, err)
+			require.LessOrEqual(t, value, obfuscator.Max())
+			require.False(t, seen[value], "%d bits: %d collides", bits, id)
+			seen[value] = true
+
+			decoded, err := obfuscator.Decode(value)
+			require.NoError(t, err)
+			require.Equal(t, id, decoded)
+		}
+	}
+}
+
+// TestIDObfuscatorRoundTrip checks Decode reverses Encode across the full width.
+func TestIDObfuscatorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, bits := range []int{36, MaxObfuscatorBits} {
+		obfuscator, err := NewIDObfuscator([]byte("secret"), bits)
+		require.NoError(t, err)
+
+		roundTrip := func(id uint64) bool {
+			id &= obfuscator.Max()
+			value, err := obfuscator.Encode(id)
+			if err != nil || value > obfuscator.Max() {
+				return false
+			}
+			decoded, err := obfuscator.Decode(value)
+			return err == nil && decoded == id
+		}
+		require.NoError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 10000}), "%d bits", bits)
+
+		code := EncodeBase62(obfuscator.Max())
+		assert.True(t, isGeneratedCode(code), "%d bits: %s", bits, code)
+	}
+}
+
+func TestIDObfuscatorNonSequential(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewIDObfuscator([]byte("secret"), 36)
+	require.NoError(t, err)
+	b, err := NewIDObfuscator([]byte("other"), 36)
+	require.NoError(t, err)
+
+	var sequential, sameAcrossKeys int
+	previous, _ := a.Encode(0)
+	for id := uint64(1); id <= 1000; id++ {
+		value, err := a.Encode(id)
+		require.NoError(t, err)
+		if value == previous+1 {
+			sequential++
+		}
+		if other, _ := b.Encode(id); other == value {
+			sameAcrossKeys++
+		}
+		previous = value
+	}
+	assert.Zero(t, sequential)
+	assert.Zero(t, sameAcrossKeys)
+}
+
+func TestIDObfuscatorOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	obfuscator, err := NewIDObfuscator([]byte("secret"), 16)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1<<16-1), obfuscator.Max())
+
+	_, err = obfuscator.Encode(1 << 16)
+	assert.ErrorIs(t, err, ErrIDOutOfRange)
+	_, err = obfuscator.Decode(1 << 16)
+	assert.ErrorIs(t, err, ErrIDOutOfRange)
+}
@@ -0,0 +1,105 @@
+package shortener
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// crawlerAgents are lowercase User-Agent fragments of the bots that unfurl links in chat and social apps.
+var crawlerAgents = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"slackbot",
+	"slack-imgproxy",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"skypeuripreview",
+	"microsoftpreview",
+	"teamsbot",
+	"pinterestbot",
+	"redditbot",
+	"applebot",
+	"googlebot",
+	"bingbot",
+	"embedly",
+	"iframely",
+	"mastodon",
+	"bluesky",
+	"vkshare",
+	"w3c_validator",
+}
+
+// isCrawler reports whether the User-Agent belongs to a known link preview crawler.
+func isCrawler(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range crawlerAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPreview reports whether the link has any Open Graph metadata to show.
+func hasPreview(data *model.URL) bool {
+	return data.Title != "" || data.Description != "" || data.Image != ""
+}
+
+var openGraphTemplate = template.Must(template.New("opengraph").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.URL}}">
+{{- with .Title}}
+<meta property="og:title" content="{{.}}">
+<meta name="twitter:title" content="{{.}}">
+{{- end}}
+{{- with .Description}}
+<meta name="description" content="{{.}}">
+<meta property="og:description" content="{{.}}">
+<meta name="twitter:description" content="{{.}}">
+{{- end}}
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+<meta http-equiv="refresh" content="0; url={{.Target}}">
+</head>
+<body>
+<a href="{{.Target}}">{{if .Title}}{{.Title}}{{else}}{{.Target}}{{end}}</a>
+</body>
+</html>
+`))
+
+// writeOpenGraph writes an HTML page with the link's Open Graph and Twitter card tags.
+// Crawlers read the tags, anything that renders the page follows the refresh to the target.
+func writeOpenGraph(w http.ResponseWriter, data *model.URL, link, target string) {
+	var buf bytes.Buffer
+	err := openGraphTemplate.Execute(&buf, map[string]string{
+		"URL":         link,
+		"Title":       data.Title,
+		"Description": data.Description,
+		"Image":       data.Image,
+		"Target":      target,
+	})
+	if err != nil {
+		http.Error(w, "Error loading page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
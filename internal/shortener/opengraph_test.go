@@ -0,0 +1,110 @@
+package shortener
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIsCrawler(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		userAgent string
+		expected  bool
+	}{
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", true},
+		{"Twitterbot/1.0", true},
+		{"WhatsApp/2.23.20.0", true},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 Safari/605.1.15", false},
+		{"curl/8.4.0", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, isCrawler(tt.userAgent))
+		})
+	}
+}
+
+func TestRedirectURL_OpenGraph(t *testing.T) {
+	t.Parallel()
+	const bot = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+	preview := model.URL{
+		ShortURL:    "abc",
+		OriginalURL: "https://example.com/page",
+		Title:       `Spring <sale> & "more"`,
+		Description: "Everything must go",
+		Image:       "https://example.com/og.png",
+	}
+	tests := []struct {
+		name           string
+		data           model.URL
+		userAgent      string
+		expectedStatus int
+		expectedVary   string
+		contains       []string
+	}{
+		{
+			name: "Crawler gets Open Graph page", data: preview, userAgent: bot,
+			expectedStatus: http.StatusOK, expectedVary: "User-Agent",
+			contains: []string{
+				`<meta property="og:title" content="Spring &lt;sale&gt; &amp; &#34;more&#34;">`,
+				`<meta property="og:description" content="Everything must go">`,
+				`<meta property="og:image" content="https://example.com/og.png">`,
+				`<meta property="og:url" content="http://localhost:8080/abc">`,
+				`<meta name="twitter:card" content="summary_large_image">`,
+				`<meta http-equiv="refresh" content="0; url=https://example.com/page">`,
+			},
+		},
+		{
+			name: "Crawler gets summary card without an image", userAgent: bot, expectedStatus: http.StatusOK,
+			data:         model.URL{ShortURL: "abc", OriginalURL: "https://example.com/page", Title: "Title"},
+			expectedVary: "User-Agent",
+			contains:     []string{`<meta name="twitter:card" content="summary">`},
+		},
+		{
+			name: "Browser is redirected", data: preview, userAgent: "Mozilla/5.0 Firefox/120.0",
+			expectedStatus: http.StatusFound, expectedVary: "User-Agent",
+		},
+		{
+			name: "Crawler is redirected when the link has no preview", userAgent: bot,
+			data:           model.URL{ShortURL: "abc", OriginalURL: "https://example.com/page"},
+			expectedStatus: http.StatusFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := newMockService(t)
+			handler := NewHandler(mockService)
+			mux := http.NewServeMux()
+			handler.Routes(mux)
+			mockService.EXPECT().GetURL(gomock.Any(), "", "abc").Return(&tt.data, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedVary, rr.Header().Get("Vary"))
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, tt.data.OriginalURL, rr.Header().Get("Location"))
+				return
+			}
+			assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+			for _, s := range tt.contains {
+				assert.Contains(t, rr.Body.String(), s)
+			}
+		})
+	}
+}
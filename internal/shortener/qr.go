@@ -0,0 +1,204 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/qrcode"
+)
+
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 2048
+	qrMaxAge      = 24 * time.Hour
+)
+
+// WithQRLogo sets the PNG logo that can be drawn over the centre of QR codes with logo=true.
+// An invalid image is ignored and logos stay unavailable.
+func WithQRLogo(data []byte) HandlerOption {
+	return func(h *Handler) {
+		logo, err := qrcode.LoadLogo(data)
+		if err != nil {
+			l.Logger.Warn("ignoring invalid qr logo", "error", err)
+			return
+		}
+		sum := sha256.Sum256(data)
+		h.qrLogo = logo
+		h.qrLogoTag = hex.EncodeToString(sum[:8])
+	}
+}
+
+// qrRequest holds the parsed query parameters of a QR code request.
+type qrRequest struct {
+	size   int
+	format string
+	level  qrcode.Level
+	fg, bg color.RGBA
+	logo   bool
+}
+
+// QRCode returns a QR code that encodes the absolute short URL.
+// @Summary QR code for a short URL
+// @Description Encodes the absolute short URL as a PNG or SVG QR code. The output is deterministic so it's
+// @Description cached with an ETag. A logo forces the High error correction level.
+// @Tags URL Shortener
+// @Produce png
+// @Produce image/svg+xml
+// @Param shorturl path string true "Short URL code"
+// @Param size query int false "Width and height in pixels (64-2048)" default(256)
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param ecc query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param fg query string false "Dark module colour as hex" default(000000)
+// @Param bg query string false "Background colour as hex" default(ffffff)
+// @Param logo query bool false "Draw the configured logo over the centre"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /qr/{shorturl} [get]
+func (h *Handler) QRCode(w http.ResponseWriter, r *http.Request) {
+	shortURL := r.PathValue("shorturl")
+
+	req, err := h.parseQRRequest(r)
+	if err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid qr code options",
+			err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := h.service.DomainForHost(ctx, h.baseURL.Host(r))
+	_, err = h.service.GetURL(ctx, domain, shortURL)
+	switch {
+	case errors.Is(err, e.BadRequestError{}):
+		writeInvalidShortURL(w)
+		return
+	case errors.Is(err, e.NotFoundError{}):
+		e.WriteJSONError(w, http.StatusNotFound, e.NewErrorResponse(http.StatusNotFound, "url not found", err.Error()))
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	content := h.baseURL.Link(r, domain, shortURL)
+	etag := h.qrETag(content, req)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(qrMaxAge.Seconds())))
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	code, err := qrcode.Encode(content, req.level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	opts := qrcode.RenderOptions{Size: req.size, Foreground: req.fg, Background: req.bg}
+	if req.logo {
+		opts.Logo = h.qrLogo
+	}
+
+	var body []byte
+	if req.format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		body, err = code.SVG(opts)
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+		body, err = code.PNG(opts)
+	}
+	if err != nil {
+		w.Header().Del("ETag")
+		http.Error(w, "Failed to render qr code", http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+func (h *Handler) parseQRRequest(r *http.Request) (qrRequest, error) {
+	q := r.URL.Query()
+	req := qrRequest{
+		size:   defaultQRSize,
+		format: "png",
+		level:  qrcode.Medium,
+		fg:     color.RGBA{A: 0xff},
+		bg:     color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	var err error
+	if v := q.Get("size"); v != "" {
+		req.size, err = strconv.Atoi(v)
+		if err != nil || req.size < minQRSize || req.size > maxQRSize {
+			return req, fmt.Errorf("size must be a number between %d and %d", minQRSize, maxQRSize)
+		}
+	}
+	if v := q.Get("format"); v != "" {
+		if v != "png" && v != "svg" {
+			return req, errors.New("format must be png or svg")
+		}
+		req.format = v
+	}
+	if v := q.Get("ecc"); v != "" {
+		if req.level, err = qrcode.ParseLevel(v); err != nil {
+			return req, errors.New("ecc must be one of L, M, Q or H")
+		}
+	}
+	if v := q.Get("fg"); v != "" {
+		if req.fg, err = parseHexColor(v); err != nil {
+			return req, fmt.Errorf("fg: %w", err)
+		}
+	}
+	if v := q.Get("bg"); v != "" {
+		if req.bg, err = parseHexColor(v); err != nil {
+			return req, fmt.Errorf("bg: %w", err)
+		}
+	}
+	if v := q.Get("logo"); v != "" {
+		if req.logo, err = strconv.ParseBool(v); err != nil {
+			return req, errors.New("logo must be true or false")
+		}
+		if req.logo && h.qrLogo == nil {
+			return req, errors.New("no logo is configured")
+		}
+		if req.logo {
+			req.level = qrcode.High // The logo hides modules, the highest level keeps the code readable.
+		}
+	}
+	return req, nil
+}
+
+// qrETag hashes everything the output depends on.
+func (h *Handler) qrETag(content string, req qrRequest) string {
+	logoTag := ""
+	if req.logo {
+		logoTag = h.qrLogoTag
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%s|%s|%v|%v|%s",
+		content, req.size, req.format, req.level, req.fg, req.bg, logoTag))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// parseHexColor parses RRGGBB with an optional leading #.
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 3 {
+		return color.RGBA{}, errors.New("colour must be 6 hex digits")
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+}
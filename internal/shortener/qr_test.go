@@ -0,0 +1,125 @@
+package shortener
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func testLogo(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 0xff, A: 0xff})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestQRCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                string
+		path                string
+		opts                []HandlerOption
+		expectedStatus      int
+		expectedContentType string
+		expectLookup        bool
+	}{
+		{name: "Default PNG", path: "/qr/abc", expectedStatus: http.StatusOK, expectedContentType: "image/png",
+			expectLookup: true},
+		{name: "SVG with colours", path: "/qr/abc?format=svg&fg=%23112233&bg=ffffff&ecc=Q",
+			expectedStatus: http.StatusOK, expectedContentType: "image/svg+xml", expectLookup: true},
+		{name: "Logo", path: "/qr/abc?logo=true&size=512", opts: []HandlerOption{WithQRLogo(testLogo(t))},
+			expectedStatus: http.StatusOK, expectedContentType: "image/png", expectLookup: true},
+		{name: "Logo not configured", path: "/qr/abc?logo=true", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid logo is ignored", path: "/qr/abc?logo=true", opts: []HandlerOption{WithQRLogo([]byte("x"))},
+			expectedStatus: http.StatusBadRequest},
+		{name: "Size too small", path: "/qr/abc?size=10", expectedStatus: http.StatusBadRequest},
+		{name: "Size not a number", path: "/qr/abc?size=big", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown format", path: "/qr/abc?format=gif", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown ecc", path: "/qr/abc?ecc=Z", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid colour", path: "/qr/abc?fg=red", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockService := newMockService(t)
+			handler := NewHandler(mockService, tt.opts...)
+			mux := http.NewServeMux()
+			handler.Routes(mux)
+			if tt.expectLookup {
+				mockService.EXPECT().GetURL(gomock.Any(), "", "abc").
+					Return(&model.URL{ShortURL: "abc", OriginalURL: "https://example.com"}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.expectedContentType, rr.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rr.Header().Get("ETag"))
+			if tt.expectedContentType == "image/png" {
+				_, err := png.Decode(rr.Body)
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, strings.HasPrefix(rr.Body.String(), "<svg "))
+			}
+		})
+	}
+}
+
+func TestQRCode_ETag(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	handler := NewHandler(mockService)
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+	mockService.EXPECT().GetURL(gomock.Any(), "", "abc").
+		Return(&model.URL{ShortURL: "abc", OriginalURL: "https://example.com"}, nil).Times(3)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/abc", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/qr/abc", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/abc?size=512", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+}
+
+func TestQRCode_NotFound(t *testing.T) {
+	t.Parallel()
+	mockService := newMockService(t)
+	handler := NewHandler(mockService)
+	mux := http.NewServeMux()
+	handler.Routes(mux)
+	mockService.EXPECT().GetURL(gomock.Any(), "", "abc").Return(nil, e.NewNotFoundError("not found"))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/abc", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
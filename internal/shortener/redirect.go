@@ -0,0 +1,114 @@
+package shortener
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// BuildRedirectURL returns the destination to redirect to for the given link.
+// The link's UTM template is appended and, depending on the link's forward query mode,
+// the query string of the incoming request is merged in.
+// Parameters already on the destination are kept as they are and never duplicated.
+func BuildRedirectURL(data *model.URL, incoming url.Values) (string, error) {
+	forward := data.ForwardQuery == model.ForwardQueryMerge || data.ForwardQuery == model.ForwardQueryOverride
+	if data.UTM == nil && (!forward || len(incoming) == 0) {
+		return data.OriginalURL, nil // Nothing to add, keep the destination untouched.
+	}
+
+	dest, err := url.Parse(data.OriginalURL)
+	if err != nil {
+		return "", err
+	}
+	existing, err := url.ParseQuery(dest.RawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	added := url.Values{}
+	if data.UTM != nil {
+		for _, param := range data.UTM.Params() {
+			key, value := param[0], param[1]
+			if value == "" || existing.Has(key) {
+				continue
+			}
+			added.Set(key, strings.ReplaceAll(value, "{shorturl}", data.ShortURL))
+		}
+	}
+
+	replaced := map[string]bool{}
+	if forward {
+		for key, values := range incoming {
+			if existing.Has(key) {
+				if data.ForwardQuery != model.ForwardQueryOverride {
+					continue
+				}
+				replaced[key] = true
+			}
+			added[key] = values // Incoming values win over the UTM template.
+		}
+	}
+
+	dest.RawQuery = mergeRawQuery(dest.RawQuery, replaced, added)
+	return dest.String(), nil
+}
+
+// mergeRawQuery keeps the original query string as is, minus the replaced keys,
+// and appends the added parameters in key order.
+func mergeRawQuery(rawQuery string, replaced map[string]bool, added url.Values) string {
+	var parts []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(key); err == nil && replaced[key] {
+			continue
+		}
+		parts = append(parts, pair)
+	}
+
+	keys := make([]string, 0, len(added))
+	for key := range added {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range added[key] {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// JoinPrefixPath appends the path suffix of a prefix link request to the destination.
+// The suffix is cleaned as if it was rooted, so dot segments can never climb above the destination path.
+// The destination's query string and fragment are kept.
+func JoinPrefixPath(destination, suffix string) (string, error) {
+	// Backslashes are treated as separators by some servers, so they could be used to escape the prefix.
+	if strings.ContainsFunc(suffix, func(r rune) bool { return r == '\\' || unicode.IsControl(r) }) {
+		return "", errors.New("path contains invalid characters")
+	}
+
+	dest, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+
+	cleaned := path.Clean("/" + suffix)
+	if cleaned == "/" {
+		cleaned = ""
+	}
+	if strings.HasSuffix(suffix, "/") || suffix == "" {
+		cleaned += "/" // Keep the trailing slash, sites often treat it differently.
+	}
+
+	dest.Path = strings.TrimSuffix(dest.Path, "/") + cleaned
+	dest.RawPath = ""
+	return dest.String(), nil
+}
@@ -0,0 +1,174 @@
+package shortener
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildRedirectURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		data     model.URL
+		incoming string
+		expected string
+	}{
+		{
+			name:     "No template and no forwarding keeps destination untouched",
+			data:     model.URL{OriginalURL: "https://example.com/a?b=1#top"},
+			incoming: "ref=email",
+			expected: "https://example.com/a?b=1#top",
+		},
+		{
+			name:     "Default mode drops incoming query",
+			data:     model.URL{OriginalURL: "https://example.com/", ForwardQuery: model.ForwardQueryNone},
+			incoming: "ref=email",
+			expected: "https://example.com/",
+		},
+		{
+			name: "UTM template appended",
+			data: model.URL{
+				OriginalURL: "https://example.com/page",
+				UTM:         &model.UTM{Source: "newsletter", Medium: "email", Campaign: "spring sale"},
+			},
+			expected: "https://example.com/page?utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name: "UTM template placeholder replaced with short code",
+			data: model.URL{
+				OriginalURL: "https://example.com/",
+				ShortURL:    "abc",
+				UTM:         &model.UTM{Content: "{shorturl}"},
+			},
+			expected: "https://example.com/?utm_content=abc",
+		},
+		{
+			name: "UTM does not override destination parameter",
+			data: model.URL{
+				OriginalURL: "https://example.com/?utm_source=partner&x=1",
+				UTM:         &model.UTM{Source: "newsletter", Medium: "email"},
+			},
+			expected: "https://example.com/?utm_source=partner&x=1&utm_medium=email",
+		},
+		{
+			name: "Fragment is kept after the query string",
+			data: model.URL{
+				OriginalURL: "https://example.com/docs#install",
+				UTM:         &model.UTM{Source: "qr"},
+			},
+			expected: "https://example.com/docs?utm_source=qr#install",
+		},
+		{
+			name: "Merge forwards new keys only",
+			data: model.URL{
+				OriginalURL:  "https://example.com/?ref=site",
+				ForwardQuery: model.ForwardQueryMerge,
+			},
+			incoming: "ref=email&lang=en",
+			expected: "https://example.com/?ref=site&lang=en",
+		},
+		{
+			name: "Override replaces destination keys without duplicates",
+			data: model.URL{
+				OriginalURL:  "https://example.com/?ref=site&keep=1",
+				ForwardQuery: model.ForwardQueryOverride,
+			},
+			incoming: "ref=email",
+			expected: "https://example.com/?keep=1&ref=email",
+		},
+		{
+			name: "Incoming query wins over UTM template",
+			data: model.URL{
+				OriginalURL:  "https://example.com/",
+				ForwardQuery: model.ForwardQueryMerge,
+				UTM:          &model.UTM{Source: "newsletter"},
+			},
+			incoming: "utm_source=twitter",
+			expected: "https://example.com/?utm_source=twitter",
+		},
+		{
+			name: "Multiple values for one key are forwarded together",
+			data: model.URL{
+				OriginalURL:  "https://example.com/#frag",
+				ForwardQuery: model.ForwardQueryMerge,
+			},
+			incoming: "tag=a&tag=b",
+			expected: "https://example.com/?tag=a&tag=b#frag",
+		},
+		{
+			name: "Original encoding of destination query is preserved",
+			data: model.URL{
+				OriginalURL:  "https://example.com/?q=a%20b&z=1",
+				ForwardQuery: model.ForwardQueryMerge,
+			},
+			incoming: "a=%2F",
+			expected: "https://example.com/?q=a%20b&z=1&a=%2F",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			incoming, err := url.ParseQuery(tt.incoming)
+			assert.NoError(t, err)
+
+			result, err := BuildRedirectURL(&tt.data, incoming)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestBuildRedirectURL_InvalidDestination(t *testing.T) {
+	t.Parallel()
+	data := &model.URL{OriginalURL: "http://[::1", UTM: &model.UTM{Source: "x"}}
+	_, err := BuildRedirectURL(data, nil)
+	assert.Error(t, err)
+}
+
+func TestJoinPrefixPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		destination string
+		suffix      string
+		expected    string
+		expectError bool
+	}{
+		{name: "Simple suffix", destination: "https://example.com", suffix: "docs/page",
+			expected: "https://example.com/docs/page"},
+		{name: "Destination path with trailing slash", destination: "https://example.com/base/", suffix: "docs",
+			expected: "https://example.com/base/docs"},
+		{name: "Trailing slash on suffix is kept", destination: "https://example.com/base", suffix: "docs/",
+			expected: "https://example.com/base/docs/"},
+		{name: "Empty suffix keeps a trailing slash", destination: "https://example.com/base", suffix: "",
+			expected: "https://example.com/base/"},
+		{name: "Query and fragment are kept", destination: "https://example.com/base?x=1#top", suffix: "a",
+			expected: "https://example.com/base/a?x=1#top"},
+		{name: "Dot segments can't climb above the destination", destination: "https://example.com/base",
+			suffix: "../../admin", expected: "https://example.com/base/admin"},
+		{name: "Dot segments inside the suffix are resolved", destination: "https://example.com/base",
+			suffix: "docs/./a/../b", expected: "https://example.com/base/docs/b"},
+		{name: "Special characters are escaped", destination: "https://example.com", suffix: "a b/c?d",
+			expected: "https://example.com/a%20b/c%3Fd"},
+		{name: "Backslash is rejected", destination: "https://example.com", suffix: "..\\admin", expectError: true},
+		{name: "Control characters are rejected", destination: "https://example.com", suffix: "a\r\nb",
+			expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := JoinPrefixPath(tt.destination, tt.suffix)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
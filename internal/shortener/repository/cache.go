@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	l "github.com/jasoncheung94/url-shortener/internal/logger"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/cache"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
@@ -20,8 +21,19 @@ func NewCache(repo URL, cache cache.RedisInterface) *CacheWrapper {
 	return &CacheWrapper{repo, cache}
 }
 
+var _ CounterBlocks = &CacheWrapper{}
+
+// counterKey is the redis key of the short URL counter.
+const counterKey = "url_shortener_counter"
+
 var ttl = time.Hour // short ttl, every time a cache hit, redis will set a new ttl of 2 hours. See Redis code.
 
+// urlCacheKey is the redis key of a cached link, shorturl:{code} on the default host and
+// shorturl:{domain}/{code} on a custom domain.
+func urlCacheKey(domain, shortURL string) string {
+	return "shorturl:" + linkKey(domain, shortURL)
+}
+
 // SaveURL saves the URL to redis using a cache key.
 func (c *CacheWrapper) SaveURL(ctx context.Context, data *model.URL) error {
 	err := c.repo.SaveURL(ctx, data)
@@ -29,7 +41,7 @@ func (c *CacheWrapper) SaveURL(ctx context.Context, data *model.URL) error {
 		return err
 	}
 
-	cacheKey := "shorturl:" + data.ShortURL
+	cacheKey := urlCacheKey(data.Domain, data.ShortURL)
 	err = c.cache.Set(ctx, cacheKey, data, ttl)
 	if err != nil {
 		// Cache doesn't cause hard failure. DB still worked.
@@ -39,15 +51,18 @@ func (c *CacheWrapper) SaveURL(ctx context.Context, data *model.URL) error {
 }
 
 // GetURL gets the URL from redis using a cache key.
-func (c *CacheWrapper) GetURL(ctx context.Context, shortURL string) (*model.URL, error) {
+func (c *CacheWrapper) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
 	var data *model.URL
-	cacheKey := "shorturl:" + shortURL
+	cacheKey := urlCacheKey(domain, shortURL)
 
-	if err := c.cache.Get(ctx, "shorturl:"+shortURL, &data); err == nil {
+	if err := c.cache.Get(ctx, cacheKey, &data); err == nil {
+		if !visible(ctx, data) {
+			return nil, e.NewNotFoundError("url with short_url '%s' not found", shortURL)
+		}
 		return data, nil
 	}
 
-	data, err := c.repo.GetURL(ctx, shortURL)
+	data, err := c.repo.GetURL(ctx, domain, shortURL)
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +75,52 @@ func (c *CacheWrapper) GetURL(ctx context.Context, shortURL string) (*model.URL,
 	return data, nil
 }
 
+// UpdateURL updates the URL in the repository and drops the cached copy so the next read sees the change.
+func (c *CacheWrapper) UpdateURL(ctx context.Context, data *model.URL) error {
+	if err := c.repo.UpdateURL(ctx, data); err != nil {
+		return err
+	}
+
+	cacheKey := urlCacheKey(data.Domain, data.ShortURL)
+	if err := c.cache.Delete(ctx, cacheKey); err != nil {
+		// The cached copy expires with its ttl, the DB is the source of truth.
+		l.Logger.Error("failed to delete key", "cache", cacheKey, "error", err.Error())
+	}
+	return nil
+}
+
+// DeleteURL deletes the URL from the repository and drops the cached copy so redirects stop right away.
+func (c *CacheWrapper) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	if err := c.repo.DeleteURL(ctx, domain, shortURL); err != nil {
+		return err
+	}
+
+	cacheKey := urlCacheKey(domain, shortURL)
+	if err := c.cache.Delete(ctx, cacheKey); err != nil {
+		// The cached copy expires with its ttl, the DB is the source of truth.
+		l.Logger.Error("failed to delete key", "cache", cacheKey, "error", err.Error())
+	}
+	return nil
+}
+
+// ListURLs lists URLs from the repository, lists aren't cached.
+func (c *CacheWrapper) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	return c.repo.ListURLs(ctx, filter)
+}
+
+// ListTags lists the tags from the repository.
+func (c *CacheWrapper) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	return c.repo.ListTags(ctx)
+}
+
+// ExistingURLs checks the repository, a cache miss doesn't mean the short URL is free.
+func (c *CacheWrapper) ExistingURLs(ctx context.Context, domain string, shortURLs []string) ([]string, error) {
+	return c.repo.ExistingURLs(ctx, domain, shortURLs)
+}
+
 // IncrementCounter increments counter and fetches latest value from redis
 func (c *CacheWrapper) IncrementCounter() (uint64, error) {
-	if counterValue, err := c.cache.Increment(context.Background(), "url_shortener_counter"); err == nil {
+	if counterValue, err := c.cache.Increment(context.Background(), counterKey); err == nil {
 		return uint64(counterValue), nil
 	} else {
 		l.Logger.Error(err.Error())
@@ -71,3 +129,63 @@ func (c *CacheWrapper) IncrementCounter() (uint64, error) {
 	// Check redis otherwise fallback to repository solution.
 	return c.repo.IncrementCounter()
 }
+
+// ReserveCounters reserves n values of the redis counter with a single INCRBY. When redis is down
+// it falls back to the repository, one value at a time if the repository can't reserve blocks.
+func (c *CacheWrapper) ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error) {
+	last, err := c.cache.IncrementBy(ctx, counterKey, int64(n))
+	if err == nil {
+		return []model.CounterRange{{First: uint64(last) - uint64(n) + 1, Last: uint64(last)}}, nil
+	}
+	l.Logger.Error("failed to reserve counters", "cache", err.Error())
+
+	if blocks, ok := c.repo.(CounterBlocks); ok {
+		return blocks.ReserveCounters(ctx, n)
+	}
+	counter, err := c.repo.IncrementCounter()
+	if err != nil {
+		return nil, err
+	}
+	return []model.CounterRange{{First: counter, Last: counter}}, nil
+}
+
+// ReconcileCounterEvery reconciles the counters every interval until the context is cancelled,
+// so a flushed or restarted redis is caught up with the database.
+func (c *CacheWrapper) ReconcileCounterEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.ReconcileCounter(ctx); err != nil {
+			l.Logger.Error("failed to reconcile counter", "cache", err.Error())
+		}
+	}
+}
+
+// ReconcileCounter brings the redis counter and the durable counter of the repository up to the
+// higher of the two, so neither hands out values the other already did. It does nothing when the
+// repository has no durable counter.
+func (c *CacheWrapper) ReconcileCounter(ctx context.Context) error {
+	durable, ok := c.repo.(DurableCounter)
+	if !ok {
+		return nil
+	}
+
+	current, err := durable.CurrentCounter(ctx)
+	if err != nil {
+		return err
+	}
+	value, err := c.cache.SetMax(ctx, counterKey, int64(current)) // Also creates a missing key.
+	if err != nil {
+		return err
+	}
+	if uint64(value) > current {
+		return durable.AdvanceCounter(ctx, uint64(value))
+	}
+	return nil
+}
@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/mocks"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -76,36 +78,36 @@ func TestCacheWrapper_GetURL(t *testing.T) {
 				return nil
 			})
 
-		url, err := c.GetURL(context.Background(), "abc123")
+		url, err := c.GetURL(context.Background(), "", "abc123")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedURL, url)
 	})
 
 	t.Run("cache miss, db hit, cache set succeeds", func(t *testing.T) {
 		mockCache.EXPECT().Get(gomock.Any(), "shorturl:abc123", gomock.Any()).Return(errors.New("cache miss"))
-		mockRepo.EXPECT().GetURL(gomock.Any(), "abc123").Return(expectedURL, nil)
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "abc123").Return(expectedURL, nil)
 		mockCache.EXPECT().Set(gomock.Any(), "shorturl:abc123", expectedURL, time.Hour).Return(nil)
 
-		url, err := c.GetURL(context.Background(), "abc123")
+		url, err := c.GetURL(context.Background(), "", "abc123")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedURL, url)
 	})
 
 	t.Run("cache miss, db hit, cache set fails", func(t *testing.T) {
 		mockCache.EXPECT().Get(gomock.Any(), "shorturl:abc123", gomock.Any()).Return(errors.New("cache miss"))
-		mockRepo.EXPECT().GetURL(gomock.Any(), "abc123").Return(expectedURL, nil)
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "abc123").Return(expectedURL, nil)
 		mockCache.EXPECT().Set(gomock.Any(), "shorturl:abc123", expectedURL, time.Hour).Return(errors.New("redis error"))
 
-		url, err := c.GetURL(context.Background(), "abc123")
+		url, err := c.GetURL(context.Background(), "", "abc123")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedURL, url)
 	})
 
 	t.Run("cache miss, db error", func(t *testing.T) {
 		mockCache.EXPECT().Get(gomock.Any(), "shorturl:abc123", gomock.Any()).Return(errors.New("cache miss"))
-		mockRepo.EXPECT().GetURL(gomock.Any(), "abc123").Return(nil, errors.New("db error"))
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "abc123").Return(nil, errors.New("db error"))
 
-		url, err := c.GetURL(context.Background(), "abc123")
+		url, err := c.GetURL(context.Background(), "", "abc123")
 		assert.Error(t, err)
 		assert.Nil(t, url)
 	})
@@ -139,3 +141,239 @@ func TestCacheWrapper_IncrementCounter(t *testing.T) {
 		assert.Equal(t, uint64(42), val)
 	})
 }
+
+//nolint:paralleltest
+func TestCacheWrapper_ReserveCounters(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+	c := NewCache(mockRepo, mockCache)
+
+	t.Run("cache hit", func(t *testing.T) {
+		mockCache.EXPECT().IncrementBy(gomock.Any(), "url_shortener_counter", int64(1000)).Return(int64(3000), nil)
+
+		ranges, err := c.ReserveCounters(context.Background(), 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.CounterRange{{First: 2001, Last: 3000}}, ranges)
+	})
+
+	t.Run("cache failure, fallback to repo", func(t *testing.T) {
+		mockCache.EXPECT().IncrementBy(gomock.Any(), "url_shortener_counter", int64(1000)).
+			Return(int64(0), errors.New("redis error"))
+		mockRepo.EXPECT().IncrementCounter().Return(uint64(42), nil)
+
+		ranges, err := c.ReserveCounters(context.Background(), 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.CounterRange{{First: 42, Last: 42}}, ranges)
+	})
+
+	t.Run("cache failure, fallback to repo blocks", func(t *testing.T) {
+		mockCache.EXPECT().IncrementBy(gomock.Any(), "url_shortener_counter", int64(5)).
+			Return(int64(0), errors.New("redis error"))
+		repo := NewInMemory()
+		c := NewCache(repo, mockCache)
+
+		ranges, err := c.ReserveCounters(context.Background(), 5)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.CounterRange{{First: 1, Last: 5}}, ranges)
+	})
+}
+
+// durableRepo is a repository with a durable counter.
+type durableRepo struct {
+	*mocks.MockURL
+	*mocks.MockDurableCounter
+}
+
+func TestCacheWrapper_ReconcileCounter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		mockBehavior func(cache *mocks.MockRedisInterface, counter *mocks.MockDurableCounter)
+		wantErr      bool
+	}{
+		{
+			name: "redis behind",
+			mockBehavior: func(cache *mocks.MockRedisInterface, counter *mocks.MockDurableCounter) {
+				counter.EXPECT().CurrentCounter(gomock.Any()).Return(uint64(500), nil)
+				cache.EXPECT().SetMax(gomock.Any(), "url_shortener_counter", int64(500)).Return(int64(500), nil)
+			},
+		},
+		{
+			name: "database behind",
+			mockBehavior: func(cache *mocks.MockRedisInterface, counter *mocks.MockDurableCounter) {
+				counter.EXPECT().CurrentCounter(gomock.Any()).Return(uint64(500), nil)
+				cache.EXPECT().SetMax(gomock.Any(), "url_shortener_counter", int64(500)).Return(int64(900), nil)
+				counter.EXPECT().AdvanceCounter(gomock.Any(), uint64(900)).Return(nil)
+			},
+		},
+		{
+			name: "redis error",
+			mockBehavior: func(cache *mocks.MockRedisInterface, counter *mocks.MockDurableCounter) {
+				counter.EXPECT().CurrentCounter(gomock.Any()).Return(uint64(500), nil)
+				cache.EXPECT().SetMax(gomock.Any(), "url_shortener_counter", int64(500)).
+					Return(int64(0), errors.New("redis error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			mockCache := mocks.NewMockRedisInterface(ctrl)
+			mockCounter := mocks.NewMockDurableCounter(ctrl)
+			tt.mockBehavior(mockCache, mockCounter)
+
+			c := NewCache(durableRepo{mocks.NewMockURL(ctrl), mockCounter}, mockCache)
+			err := c.ReconcileCounter(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("no durable counter", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		c := NewCache(mocks.NewMockURL(ctrl), mocks.NewMockRedisInterface(ctrl))
+		assert.NoError(t, c.ReconcileCounter(context.Background()))
+	})
+}
+
+func TestCacheWrapper_ReconcileCounterEvery(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+	repo := NewInMemory()
+	assert.NoError(t, repo.AdvanceCounter(context.Background(), 10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Redis was flushed: it's raised to the database value, then the loop stops.
+	mockCache.EXPECT().SetMax(gomock.Any(), "url_shortener_counter", int64(10)).
+		DoAndReturn(func(context.Context, string, int64) (int64, error) {
+			cancel()
+			return 10, nil
+		})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewCache(repo, mockCache).ReconcileCounterEvery(ctx, time.Millisecond)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconciliation didn't stop")
+	}
+}
+
+//nolint:paralleltest
+func TestCacheWrapper_UpdateURL(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+
+	c := NewCache(mockRepo, mockCache)
+
+	url := &model.URL{
+		ShortURL:    "abc123",
+		OriginalURL: "https://example.com",
+		Title:       "Example",
+	}
+
+	t.Run("success - repo updated and cache invalidated", func(t *testing.T) {
+		mockRepo.EXPECT().UpdateURL(gomock.Any(), url).Return(nil)
+		mockCache.EXPECT().Delete(gomock.Any(), "shorturl:abc123").Return(nil)
+
+		err := c.UpdateURL(context.Background(), url)
+		assert.NoError(t, err)
+	})
+
+	t.Run("repo failure - cache untouched", func(t *testing.T) {
+		mockRepo.EXPECT().UpdateURL(gomock.Any(), url).Return(errors.New("db error"))
+
+		err := c.UpdateURL(context.Background(), url)
+		assert.Error(t, err)
+	})
+
+	t.Run("cache failure - logs only", func(t *testing.T) {
+		mockRepo.EXPECT().UpdateURL(gomock.Any(), url).Return(nil)
+		mockCache.EXPECT().Delete(gomock.Any(), "shorturl:abc123").Return(errors.New("redis error"))
+
+		err := c.UpdateURL(context.Background(), url)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCacheWrapper_DeleteURL(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+	c := NewCache(mockRepo, mockCache)
+
+	mockRepo.EXPECT().DeleteURL(gomock.Any(), "example.com", "abc").Return(nil)
+	mockCache.EXPECT().Delete(gomock.Any(), "shorturl:example.com/abc").Return(nil)
+	assert.NoError(t, c.DeleteURL(context.Background(), "example.com", "abc"))
+
+	mockRepo.EXPECT().DeleteURL(gomock.Any(), "", "missing").Return(e.NewNotFoundError("not found"))
+	assert.ErrorIs(t, c.DeleteURL(context.Background(), "", "missing"), e.NotFoundError{})
+}
+
+func TestCacheWrapper_ExistingURLs(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	c := NewCache(mockRepo, mocks.NewMockRedisInterface(ctrl))
+
+	mockRepo.EXPECT().ExistingURLs(gomock.Any(), "", []string{"abc", "abc2"}).Return([]string{"abc"}, nil)
+	existing, err := c.ExistingURLs(context.Background(), "", []string{"abc", "abc2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc"}, existing)
+}
+
+func TestCacheWrapper_GetURL_Domain(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+	c := NewCache(mockRepo, mockCache)
+
+	expectedURL := &model.URL{Domain: "go.acme.com", ShortURL: "abc", OriginalURL: "https://acme.com"}
+	mockCache.EXPECT().Get(gomock.Any(), "shorturl:go.acme.com/abc", gomock.Any()).Return(errors.New("cache miss"))
+	mockRepo.EXPECT().GetURL(gomock.Any(), "go.acme.com", "abc").Return(expectedURL, nil)
+	mockCache.EXPECT().Set(gomock.Any(), "shorturl:go.acme.com/abc", expectedURL, time.Hour).Return(nil)
+
+	url, err := c.GetURL(context.Background(), "go.acme.com", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedURL, url)
+}
+
+func TestCacheWrapper_GetURL_Workspace(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockRedisInterface(ctrl)
+	c := NewCache(mocks.NewMockURL(ctrl), mockCache)
+
+	cached := func(_ context.Context, _ string, dest any) error {
+		*(dest.(**model.URL)) = &model.URL{ShortURL: "abc", Workspace: "acme"}
+		return nil
+	}
+	mockCache.EXPECT().Get(gomock.Any(), "shorturl:abc", gomock.Any()).DoAndReturn(cached).Times(2)
+
+	_, err := c.GetURL(tenant.With(context.Background(), "other"), "", "abc")
+	assert.ErrorIs(t, err, e.NotFoundError{}, "cached links of other workspaces aren't visible")
+	url, err := c.GetURL(tenant.With(context.Background(), "acme"), "", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", url.Workspace)
+}
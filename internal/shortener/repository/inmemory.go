@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"slices"
 	"sync"
+	"time"
 
 	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/logger"
@@ -13,50 +17,343 @@ import (
 type InMemoryRepo struct {
 	mu      sync.RWMutex
 	store   map[string]model.URL
+	checks  map[string][]model.LinkCheck
+	history map[string][]model.URLVersion
+	audit   []model.AuditEntry // By Seq.
+	leases  map[int]model.WorkerLease
+	domains map[string]model.Domain
+	spaces  map[string]model.Workspace
+	keys    map[string]model.APIKey
+	members map[string]model.Member
+	locks   map[string]*sync.Mutex // Workspace locks.
+	hooks   map[string]model.Webhook
+	queue   map[string]model.WebhookDelivery
+	outbox  []model.OutboxMessage
 	counter uint64 // not a good solution if scaled.
+	lastID  int64  // IDs of deleted URLs aren't reused.
 }
 
-var _ URL = &InMemoryRepo{}
+var (
+	_ URL            = &InMemoryRepo{}
+	_ LinkChecks     = &InMemoryRepo{}
+	_ History        = &InMemoryRepo{}
+	_ AuditLog       = &InMemoryRepo{}
+	_ DurableCounter = &InMemoryRepo{}
+	_ WorkerLeases   = &InMemoryRepo{}
+	_ Domains        = &InMemoryRepo{}
+	_ Workspaces     = &InMemoryRepo{}
+	_ Webhooks       = &InMemoryRepo{}
+	_ Outbox         = &InMemoryRepo{}
+)
 
 // NewInMemory returns an instance of the in memory repo.
 func NewInMemory() *InMemoryRepo {
 	return &InMemoryRepo{
 		mu:      sync.RWMutex{},
 		store:   make(map[string]model.URL),
+		checks:  make(map[string][]model.LinkCheck),
+		history: make(map[string][]model.URLVersion),
+		leases:  make(map[int]model.WorkerLease),
+		domains: make(map[string]model.Domain),
+		spaces:  make(map[string]model.Workspace),
+		keys:    make(map[string]model.APIKey),
+		members: make(map[string]model.Member),
+		locks:   make(map[string]*sync.Mutex),
+		hooks:   make(map[string]model.Webhook),
+		queue:   make(map[string]model.WebhookDelivery),
 		counter: 1,
 	}
 }
 
 // SaveURL saves the URL to memory.
-func (r *InMemoryRepo) SaveURL(_ context.Context, data *model.URL) error {
+func (r *InMemoryRepo) SaveURL(ctx context.Context, data *model.URL) error {
+	assignWorkspace(ctx, data)
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.saveURL(data)
+}
+
+// saveURL stores the URL, the caller holds the lock.
+func (r *InMemoryRepo) saveURL(data *model.URL) error {
 	lookupURL := data.ShortURL
 	if data.CustomURL != nil {
 		lookupURL = *data.CustomURL
 	}
 
-	if _, ok := r.store[lookupURL]; ok {
-		logger.Logger.Info("short url already exists:", "url", lookupURL)
+	if _, ok := r.store[linkKey(data.Domain, lookupURL)]; ok {
+		logger.Logger.Info("short url already exists:", "url", lookupURL, "domain", data.Domain)
 		return e.NewConflictError("short url already exists")
 	}
 
-	data.ID = int64(len(r.store) + 1)
-	r.store[data.ShortURL] = *data
+	r.lastID++
+	data.ID = r.lastID
+	r.store[linkKey(data.Domain, data.ShortURL)] = *data
 	return nil
 }
 
 // GetURL retrieves the URL from memory.
-func (r *InMemoryRepo) GetURL(_ context.Context, shortURL string) (*model.URL, error) {
+func (r *InMemoryRepo) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if data, ok := r.store[shortURL]; ok {
+	if data, ok := r.store[linkKey(domain, shortURL)]; ok && visible(ctx, &data) {
 		return &data, nil
 	}
 	return nil, e.NewNotFoundError("failed to get original url")
 }
 
+// UpdateURL replaces the stored URL with the same domain and short URL.
+func (r *InMemoryRepo) UpdateURL(ctx context.Context, data *model.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := linkKey(data.Domain, data.ShortURL)
+	existing, ok := r.store[key]
+	if !ok || !visible(ctx, &existing) {
+		return e.NewNotFoundError("failed to get original url")
+	}
+	updated := *data
+	updated.ID, updated.CreatedAt, updated.Workspace = existing.ID, existing.CreatedAt, existing.Workspace
+	r.store[key] = updated
+	return nil
+}
+
+// DeleteURL removes the URL with the domain and short URL, its versions and checks.
+func (r *InMemoryRepo) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := linkKey(domain, shortURL)
+	if existing, ok := r.store[key]; !ok || !visible(ctx, &existing) {
+		return e.NewNotFoundError("failed to get original url")
+	}
+	delete(r.store, key)
+	delete(r.history, key)
+	delete(r.checks, key)
+	return nil
+}
+
+// ListURLs returns the URLs matching the filter, oldest first.
+func (r *InMemoryRepo) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := []model.URL{}
+	for _, data := range r.store {
+		if !visible(ctx, &data) || filter.Broken != nil && data.Broken != *filter.Broken ||
+			filter.Folder != nil && data.Folder != *filter.Folder || !hasTags(data.Tags, filter.Tags) {
+			continue
+		}
+		urls = append(urls, data)
+	}
+	slices.SortFunc(urls, func(a, b model.URL) int { return cmp.Compare(a.ID, b.ID) })
+
+	start := min(filter.Offset, len(urls))
+	end := min(start+filter.Limit, len(urls))
+	return urls[start:end], nil
+}
+
+// hasTags reports whether the link has all of the tags.
+func hasTags(linkTags model.Tags, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(linkTags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListTags returns the tags of the links by name, with how many links have each.
+func (r *InMemoryRepo) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, data := range r.store {
+		if !visible(ctx, &data) {
+			continue
+		}
+		for _, tag := range data.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]model.TagCount, 0, len(counts))
+	for tag, links := range counts {
+		tags = append(tags, model.TagCount{Tag: tag, Links: links})
+	}
+	slices.SortFunc(tags, func(a, b model.TagCount) int { return cmp.Compare(a.Tag, b.Tag) })
+	return tags, nil
+}
+
+// ExistingURLs returns which of the short URLs are stored on the domain.
+func (r *InMemoryRepo) ExistingURLs(_ context.Context, domain string, shortURLs []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	existing := []string{}
+	for _, shortURL := range shortURLs {
+		if _, ok := r.store[linkKey(domain, shortURL)]; ok {
+			existing = append(existing, shortURL)
+		}
+	}
+	return existing, nil
+}
+
+// SaveLinkCheck stores the result of a link health check.
+func (r *InMemoryRepo) SaveLinkCheck(_ context.Context, check *model.LinkCheck) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := linkKey(check.Domain, check.ShortURL)
+	r.checks[key] = append(r.checks[key], *check)
+	return nil
+}
+
+// ListLinkChecks returns the latest health checks of a link, newest first.
+func (r *InMemoryRepo) ListLinkChecks(_ context.Context, domain, shortURL string,
+	limit int) ([]model.LinkCheck, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := r.checks[linkKey(domain, shortURL)]
+	checks := make([]model.LinkCheck, 0, min(limit, len(history)))
+	for i := len(history) - 1; i >= 0 && len(checks) < limit; i-- {
+		checks = append(checks, history[i])
+	}
+	return checks, nil
+}
+
+// SaveVersion appends a version of a link.
+func (r *InMemoryRepo) SaveVersion(_ context.Context, version *model.URLVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := linkKey(version.Domain, version.ShortURL)
+	for _, saved := range r.history[key] {
+		if saved.Version == version.Version {
+			return e.NewConflictError("version %d of %q already exists", version.Version, version.ShortURL)
+		}
+	}
+	r.history[key] = append(r.history[key], *version)
+	slices.SortFunc(r.history[key], func(a, b model.URLVersion) int { return cmp.Compare(a.Version, b.Version) })
+	return nil
+}
+
+// ListVersions returns the versions of a link, oldest first.
+func (r *InMemoryRepo) ListVersions(_ context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]model.URLVersion{}, r.history[linkKey(domain, shortURL)]...), nil
+}
+
+// AppendAudit appends an entry to the audit log.
+func (r *InMemoryRepo) AppendAudit(_ context.Context, entry *model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, found := slices.BinarySearchFunc(r.audit, entry.Seq, func(saved model.AuditEntry, seq int64) int {
+		return cmp.Compare(saved.Seq, seq)
+	})
+	if found {
+		return e.NewConflictError("audit entry %d already exists", entry.Seq)
+	}
+	r.audit = slices.Insert(r.audit, i, *entry)
+	return nil
+}
+
+// LastAudit returns the last entry of the audit log, nil if it's empty.
+func (r *InMemoryRepo) LastAudit(_ context.Context) (*model.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.audit) == 0 {
+		return nil, nil
+	}
+	last := r.audit[len(r.audit)-1]
+	return &last, nil
+}
+
+// ListAudit returns the entries of the audit log passing the filter by Seq.
+func (r *InMemoryRepo) ListAudit(_ context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []model.AuditEntry{}
+	for _, entry := range r.audit {
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// SaveDomain stores a new domain.
+func (r *InMemoryRepo) SaveDomain(_ context.Context, domain *model.Domain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.domains[domain.Name]; ok {
+		return e.NewConflictError("domain %q is already registered", domain.Name)
+	}
+	r.domains[domain.Name] = *domain
+	return nil
+}
+
+// GetDomain returns the registered domain.
+func (r *InMemoryRepo) GetDomain(_ context.Context, name string) (*model.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	domain, ok := r.domains[name]
+	if !ok {
+		return nil, e.NewNotFoundError("domain %q not found", name)
+	}
+	return &domain, nil
+}
+
+// UpdateDomain stores the verification time of the domain.
+func (r *InMemoryRepo) UpdateDomain(_ context.Context, domain *model.Domain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.domains[domain.Name]
+	if !ok {
+		return e.NewNotFoundError("domain %q not found", domain.Name)
+	}
+	stored.VerifiedAt = domain.VerifiedAt
+	r.domains[domain.Name] = stored
+	return nil
+}
+
+// ReplacePendingDomain stores the domain in place of an unverified one registered before registeredBefore.
+func (r *InMemoryRepo) ReplacePendingDomain(_ context.Context, domain *model.Domain, registeredBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.domains[domain.Name]
+	if !ok || stored.Verified() || !stored.CreatedAt.Before(registeredBefore) {
+		return e.NewConflictError("domain %q is already registered", domain.Name)
+	}
+	r.domains[domain.Name] = *domain
+	return nil
+}
+
+// ListDomains returns the owner's domains by name.
+func (r *InMemoryRepo) ListDomains(_ context.Context, owner string) ([]model.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	domains := []model.Domain{}
+	for _, domain := range r.domains {
+		if domain.Owner == owner {
+			domains = append(domains, domain)
+		}
+	}
+	slices.SortFunc(domains, func(a, b model.Domain) int { return cmp.Compare(a.Name, b.Name) })
+	return domains, nil
+}
+
 // IncrementCounter returns the next counter value and increments it.
 func (r *InMemoryRepo) IncrementCounter() (uint64, error) {
 	r.mu.Lock() // Lock to ensure only one goroutine can increment the counter at a time.
@@ -71,3 +368,490 @@ func (r *InMemoryRepo) IncrementCounter() (uint64, error) {
 
 	return counter, nil
 }
+
+// ReserveCounters reserves the next n counter values.
+func (r *InMemoryRepo) ReserveCounters(_ context.Context, n int) ([]model.CounterRange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counter < uint64(len(r.store)) {
+		r.counter = uint64(len(r.store) + 1)
+	}
+
+	reserved := model.CounterRange{First: r.counter, Last: r.counter + uint64(n) - 1}
+	r.counter += uint64(n)
+	return []model.CounterRange{reserved}, nil
+}
+
+// CurrentCounter returns the last counter value handed out.
+func (r *InMemoryRepo) CurrentCounter(_ context.Context) (uint64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.counter - 1, nil
+}
+
+// AdvanceCounter moves the counter forward so the next value is after value.
+func (r *InMemoryRepo) AdvanceCounter(_ context.Context, value uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counter = max(r.counter, value+1)
+	return nil
+}
+
+// AcquireWorker leases the lowest free worker ID.
+func (r *InMemoryRepo) AcquireWorker(_ context.Context, owner string, workers int,
+	ttl time.Duration) (*model.WorkerLease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id := range workers {
+		previous, ok := r.leases[id]
+		if ok && previous.ExpiresAt.After(now) {
+			continue
+		}
+		lease := model.WorkerLease{WorkerID: id, Owner: owner, ExpiresAt: now.Add(ttl), LastTime: previous.LastTime}
+		r.leases[id] = lease
+		return &lease, nil
+	}
+	return nil, ErrNoFreeWorker
+}
+
+// RenewWorker extends the lease while the owner still holds it.
+func (r *InMemoryRepo) RenewWorker(_ context.Context, lease *model.WorkerLease, ttl time.Duration) error {
+	return r.updateLease(lease, time.Now().Add(ttl))
+}
+
+// ReleaseWorker expires the lease now.
+func (r *InMemoryRepo) ReleaseWorker(_ context.Context, lease *model.WorkerLease) error {
+	if err := r.updateLease(lease, time.Now()); !errors.Is(err, ErrLeaseLost) {
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryRepo) updateLease(lease *model.WorkerLease, expires time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.leases[lease.WorkerID]
+	if !ok || stored.Owner != lease.Owner {
+		return ErrLeaseLost
+	}
+	stored.ExpiresAt = expires
+	if lease.LastTime.After(stored.LastTime) {
+		stored.LastTime = lease.LastTime
+	}
+	r.leases[lease.WorkerID] = stored
+	lease.ExpiresAt = expires
+	return nil
+}
+
+// SaveWorkspace stores a new workspace.
+func (r *InMemoryRepo) SaveWorkspace(_ context.Context, workspace *model.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.spaces[workspace.ID]; ok {
+		return e.NewConflictError("workspace %q already exists", workspace.ID)
+	}
+	r.spaces[workspace.ID] = *workspace
+	return nil
+}
+
+// GetWorkspace returns the workspace.
+func (r *InMemoryRepo) GetWorkspace(_ context.Context, id string) (*model.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.spaces[id]
+	if !ok {
+		return nil, e.NewNotFoundError("workspace %q not found", id)
+	}
+	return &workspace, nil
+}
+
+// UpdateWorkspace stores the name and quotas of the workspace.
+func (r *InMemoryRepo) UpdateWorkspace(_ context.Context, workspace *model.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.spaces[workspace.ID]
+	if !ok {
+		return e.NewNotFoundError("workspace %q not found", workspace.ID)
+	}
+	stored.Name, stored.LinkQuota, stored.MonthlyQuota = workspace.Name, workspace.LinkQuota, workspace.MonthlyQuota
+	r.spaces[workspace.ID] = stored
+	return nil
+}
+
+// SaveAPIKey stores a new API key.
+func (r *InMemoryRepo) SaveAPIKey(_ context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key.ID]; ok {
+		return e.NewConflictError("api key %q already exists", key.ID)
+	}
+	stored := *key
+	stored.Key = ""
+	r.keys[key.ID] = stored
+	return nil
+}
+
+// GetAPIKey returns the API key.
+func (r *InMemoryRepo) GetAPIKey(_ context.Context, id string) (*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, e.NewNotFoundError("api key %q not found", id)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the keys of the workspace, oldest first.
+func (r *InMemoryRepo) ListAPIKeys(_ context.Context, workspace string) ([]model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []model.APIKey{}
+	for _, key := range r.keys {
+		if key.Workspace == workspace {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b model.APIKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return keys, nil
+}
+
+// DeleteAPIKey deletes the workspace's key.
+func (r *InMemoryRepo) DeleteAPIKey(_ context.Context, workspace, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; !ok || key.Workspace != workspace {
+		return e.NewNotFoundError("api key %q not found", id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// CountURLs returns how many links of the workspace were created at or after since.
+func (r *InMemoryRepo) CountURLs(_ context.Context, workspace string, since time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, data := range r.store {
+		if data.Workspace == workspace && !data.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SaveMember adds a member to the workspace.
+func (r *InMemoryRepo) SaveMember(_ context.Context, member *model.Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memberKey(member.Workspace, member.ID)
+	if _, ok := r.members[key]; ok {
+		return e.NewConflictError("%q is already a member of workspace %q", member.ID, member.Workspace)
+	}
+	r.members[key] = *member
+	return nil
+}
+
+// GetMember returns the member of the workspace.
+func (r *InMemoryRepo) GetMember(_ context.Context, workspace, id string) (*model.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member, ok := r.members[memberKey(workspace, id)]
+	if !ok {
+		return nil, e.NewNotFoundError("member %q not found", id)
+	}
+	return &member, nil
+}
+
+// UpdateMember stores the role of the member.
+func (r *InMemoryRepo) UpdateMember(_ context.Context, member *model.Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memberKey(member.Workspace, member.ID)
+	stored, ok := r.members[key]
+	if !ok {
+		return e.NewNotFoundError("member %q not found", member.ID)
+	}
+	stored.Role = member.Role
+	r.members[key] = stored
+	return nil
+}
+
+// ListMembers returns the members of the workspace by ID.
+func (r *InMemoryRepo) ListMembers(_ context.Context, workspace string) ([]model.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := []model.Member{}
+	for _, member := range r.members {
+		if member.Workspace == workspace {
+			members = append(members, member)
+		}
+	}
+	slices.SortFunc(members, func(a, b model.Member) int { return cmp.Compare(a.ID, b.ID) })
+	return members, nil
+}
+
+// DeleteMember removes the member from the workspace.
+func (r *InMemoryRepo) DeleteMember(_ context.Context, workspace, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memberKey(workspace, id)
+	if _, ok := r.members[key]; !ok {
+		return e.NewNotFoundError("member %q not found", id)
+	}
+	delete(r.members, key)
+	return nil
+}
+
+// LockWorkspace locks the workspace until unlock is called. The store lives in one instance, so its locks
+// never outlive their holder.
+func (r *InMemoryRepo) LockWorkspace(_ context.Context, workspace string, _ time.Duration) (func(), error) {
+	r.mu.Lock()
+	lock, ok := r.locks[workspace]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.locks[workspace] = lock
+	}
+	r.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock, nil
+}
+
+// memberKey identifies a member in the members map. Workspace IDs can't contain a slash.
+func memberKey(workspace, id string) string {
+	return workspace + "/" + id
+}
+
+// SaveWebhook stores a new webhook.
+func (r *InMemoryRepo) SaveWebhook(_ context.Context, hook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hooks[hook.ID]; ok {
+		return e.NewConflictError("webhook %q already exists", hook.ID)
+	}
+	r.hooks[hook.ID] = *hook
+	return nil
+}
+
+// GetWebhook returns the workspace's webhook.
+func (r *InMemoryRepo) GetWebhook(_ context.Context, workspace, id string) (*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hook, ok := r.hooks[id]
+	if !ok || hook.Workspace != workspace {
+		return nil, e.NewNotFoundError("webhook %q not found", id)
+	}
+	return &hook, nil
+}
+
+// ListWebhooks returns the webhooks of the workspace, oldest first.
+func (r *InMemoryRepo) ListWebhooks(_ context.Context, workspace string) ([]model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hooks := []model.Webhook{}
+	for _, hook := range r.hooks {
+		if hook.Workspace == workspace {
+			hooks = append(hooks, hook)
+		}
+	}
+	slices.SortFunc(hooks, func(a, b model.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return hooks, nil
+}
+
+// DeleteWebhook removes the workspace's webhook and its deliveries.
+func (r *InMemoryRepo) DeleteWebhook(_ context.Context, workspace, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if hook, ok := r.hooks[id]; !ok || hook.Workspace != workspace {
+		return e.NewNotFoundError("webhook %q not found", id)
+	}
+	delete(r.hooks, id)
+	for key, delivery := range r.queue {
+		if delivery.Webhook == id {
+			delete(r.queue, key)
+		}
+	}
+	return nil
+}
+
+// SaveDelivery queues a delivery.
+func (r *InMemoryRepo) SaveDelivery(_ context.Context, delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queue[delivery.ID]; ok {
+		return e.NewConflictError("delivery %q already exists", delivery.ID)
+	}
+	r.queue[delivery.ID] = cloneDelivery(*delivery)
+	return nil
+}
+
+// GetDelivery returns the webhook's delivery.
+func (r *InMemoryRepo) GetDelivery(_ context.Context, webhook, id string) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, ok := r.queue[id]
+	if !ok || delivery.Webhook != webhook {
+		return nil, e.NewNotFoundError("delivery %q not found", id)
+	}
+	delivery = cloneDelivery(delivery)
+	return &delivery, nil
+}
+
+// UpdateDelivery stores the status, attempts, next attempt and log of the delivery.
+func (r *InMemoryRepo) UpdateDelivery(_ context.Context, delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.queue[delivery.ID]
+	if !ok {
+		return e.NewNotFoundError("delivery %q not found", delivery.ID)
+	}
+	stored.Status, stored.Attempts, stored.NextAttemptAt = delivery.Status, delivery.Attempts, delivery.NextAttemptAt
+	stored.Log, stored.DeliveredAt = delivery.Log, delivery.DeliveredAt
+	r.queue[delivery.ID] = cloneDelivery(stored)
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries of the webhook, newest first.
+func (r *InMemoryRepo) ListDeliveries(_ context.Context, webhook string, limit int) ([]model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []model.WebhookDelivery{}
+	for _, delivery := range r.queue {
+		if delivery.Webhook == webhook {
+			deliveries = append(deliveries, cloneDelivery(delivery))
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b model.WebhookDelivery) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	return deliveries[:min(limit, len(deliveries))], nil
+}
+
+// ClaimDeliveries returns the pending deliveries due at now, oldest first, and leases them.
+func (r *InMemoryRepo) ClaimDeliveries(_ context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := []model.WebhookDelivery{}
+	for _, delivery := range r.queue {
+		if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+	due = due[:min(limit, len(due))]
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		r.queue[due[i].ID] = due[i]
+		due[i] = cloneDelivery(due[i])
+	}
+	return due, nil
+}
+
+// SaveURLWithEvent saves the URL and its outbox message under one lock. The URL is removed again if the
+// message can't be built.
+func (r *InMemoryRepo) SaveURLWithEvent(ctx context.Context, data *model.URL,
+	event func(*model.URL) (*model.OutboxMessage, error)) error {
+	assignWorkspace(ctx, data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.saveURL(data); err != nil {
+		return err
+	}
+	msg, err := event(data)
+	if err != nil {
+		delete(r.store, linkKey(data.Domain, data.ShortURL))
+		return err
+	}
+	if slices.ContainsFunc(r.outbox, func(m model.OutboxMessage) bool { return m.ID == msg.ID }) {
+		delete(r.store, linkKey(data.Domain, data.ShortURL))
+		return e.NewConflictError("outbox message %q already exists", msg.ID)
+	}
+	r.outbox = append(r.outbox, *msg)
+	return nil
+}
+
+// ClaimOutbox leases the unpublished messages due at now, oldest first.
+func (r *InMemoryRepo) ClaimOutbox(_ context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []model.OutboxMessage{}
+	for i := range r.outbox {
+		if len(claimed) == limit {
+			break
+		}
+		msg := &r.outbox[i]
+		if msg.PublishedAt == nil && !msg.NextAttemptAt.After(now) {
+			msg.Attempts++
+			msg.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *msg)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkPublished sets the publication time of the messages.
+func (r *InMemoryRepo) MarkPublished(_ context.Context, ids []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.outbox {
+		if slices.Contains(ids, r.outbox[i].ID) {
+			r.outbox[i].PublishedAt = &at
+		}
+	}
+	return nil
+}
+
+// PurgeOutbox deletes the messages published before the time.
+func (r *InMemoryRepo) PurgeOutbox(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.outbox)
+	r.outbox = slices.DeleteFunc(r.outbox, func(msg model.OutboxMessage) bool {
+		return msg.PublishedAt != nil && msg.PublishedAt.Before(before)
+	})
+	return n - len(r.outbox), nil
+}
+
+// cloneDelivery copies the delivery so the caller can't change the stored log.
+func cloneDelivery(delivery model.WebhookDelivery) model.WebhookDelivery {
+	delivery.Log = slices.Clone(delivery.Log)
+	return delivery
+}
@@ -2,13 +2,19 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSaveURL(t *testing.T) {
@@ -26,7 +32,513 @@ func TestSaveURL(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	url, err := repo.GetURL(ctx, data.ShortURL)
+	url, err := repo.GetURL(ctx, "", data.ShortURL)
 	assert.NoError(t, err)
 	assert.Equal(t, data.OriginalURL, url.OriginalURL)
 }
+
+func TestUpdateURL(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	data := model.URL{OriginalURL: "https://example.com/", ShortURL: "abc"}
+	assert.NoError(t, repo.SaveURL(ctx, &data))
+
+	update := data
+	update.ID = 99
+	update.Title = "Example"
+	assert.NoError(t, repo.UpdateURL(ctx, &update))
+
+	url, err := repo.GetURL(ctx, "", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "Example", url.Title)
+	assert.Equal(t, data.ID, url.ID)
+
+	err = repo.UpdateURL(ctx, &model.URL{ShortURL: "missing"})
+	assert.ErrorIs(t, err, e.NotFoundError{})
+}
+
+func TestDeleteURL(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	first := model.URL{OriginalURL: "https://example.com/", ShortURL: "abc"}
+	assert.NoError(t, repo.SaveURL(ctx, &first))
+	assert.NoError(t, repo.SaveVersion(ctx, &model.URLVersion{ShortURL: "abc", Version: 1}))
+
+	err := repo.DeleteURL(tenant.With(ctx, "acme"), "", "abc")
+	assert.ErrorIs(t, err, e.NotFoundError{}, "other workspaces can't delete the link")
+	assert.NoError(t, repo.DeleteURL(ctx, "", "abc"))
+	_, err = repo.GetURL(ctx, "", "abc")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	assert.ErrorIs(t, repo.DeleteURL(ctx, "", "abc"), e.NotFoundError{})
+	versions, err := repo.ListVersions(ctx, "", "abc")
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	second := model.URL{OriginalURL: "https://example.com/", ShortURL: "abc"}
+	assert.NoError(t, repo.SaveURL(ctx, &second), "the short url is free again")
+	assert.Greater(t, second.ID, first.ID)
+}
+
+func TestListURLs(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	for _, code := range []string{"a", "b", "c"} {
+		assert.NoError(t, repo.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com/", ShortURL: code}))
+	}
+	assert.NoError(t, repo.UpdateURL(ctx, &model.URL{OriginalURL: "https://example.com/", ShortURL: "b", Broken: true}))
+
+	urls, err := repo.ListURLs(ctx, model.URLFilter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, shortURLs(urls))
+
+	urls, err = repo.ListURLs(ctx, model.URLFilter{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, shortURLs(urls))
+
+	urls, err = repo.ListURLs(ctx, model.URLFilter{Broken: ptr.Of(true), Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, shortURLs(urls))
+
+	urls, err = repo.ListURLs(ctx, model.URLFilter{Broken: ptr.Of(false), Limit: 10, Offset: 5})
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestInMemory_Tags(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := tenant.With(context.Background(), "acme")
+	for _, data := range []model.URL{
+		{ShortURL: "a", Tags: model.Tags{"launch", "promo"}}, {ShortURL: "b", Tags: model.Tags{"launch"}}, {ShortURL: "c"},
+	} {
+		data.Folder = "summer"
+		assert.NoError(t, repo.SaveURL(ctx, &data))
+	}
+	assert.NoError(t, repo.SaveURL(tenant.With(ctx, "other"), &model.URL{ShortURL: "d", Tags: model.Tags{"promo"}}))
+	assert.NoError(t, repo.UpdateURL(ctx, &model.URL{ShortURL: "c"}))
+
+	urls, err := repo.ListURLs(ctx, model.URLFilter{Tags: []string{"launch", "promo"}, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, shortURLs(urls))
+	urls, err = repo.ListURLs(ctx, model.URLFilter{Folder: ptr.Of("summer"), Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, shortURLs(urls))
+	urls, err = repo.ListURLs(ctx, model.URLFilter{Folder: ptr.Of(""), Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, shortURLs(urls), "links without a folder")
+
+	tags, err := repo.ListTags(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.TagCount{{Tag: "launch", Links: 2}, {Tag: "promo", Links: 1}}, tags)
+	tags, err = repo.ListTags(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []model.TagCount{{Tag: "launch", Links: 2}, {Tag: "promo", Links: 2}}, tags)
+}
+
+func TestExistingURLs(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	assert.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: "abc"}))
+
+	existing, err := repo.ExistingURLs(ctx, "", []string{"abc", "abc2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc"}, existing)
+}
+
+func TestInMemory_CodesPerDomain(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	assert.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: "abc", OriginalURL: "https://default.com"}))
+	acme := &model.URL{Domain: "go.acme.com", ShortURL: "abc", OriginalURL: "https://acme.com"}
+	assert.NoError(t, repo.SaveURL(ctx, acme))
+	assert.ErrorIs(t, repo.SaveURL(ctx, &model.URL{Domain: "go.acme.com", ShortURL: "abc"}), e.ConflictError{})
+
+	url, err := repo.GetURL(ctx, "go.acme.com", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://acme.com", url.OriginalURL)
+	url, err = repo.GetURL(ctx, "", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://default.com", url.OriginalURL)
+
+	_, err = repo.GetURL(ctx, "go.other.com", "abc")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	existing, err := repo.ExistingURLs(ctx, "go.other.com", []string{"abc"})
+	assert.NoError(t, err)
+	assert.Empty(t, existing)
+}
+
+func TestInMemory_Domains(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	for _, name := range []string{"go.b.com", "go.a.com"} {
+		assert.NoError(t, repo.SaveDomain(ctx, &model.Domain{Name: name, Owner: "acme", Token: "t"}))
+	}
+	assert.NoError(t, repo.SaveDomain(ctx, &model.Domain{Name: "go.c.com", Owner: "other"}))
+	assert.ErrorIs(t, repo.SaveDomain(ctx, &model.Domain{Name: "go.a.com"}), e.ConflictError{})
+
+	_, err := repo.GetDomain(ctx, "go.missing.com")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+
+	now := time.Now()
+	assert.NoError(t, repo.UpdateDomain(ctx, &model.Domain{Name: "go.a.com", VerifiedAt: &now}))
+	domain, err := repo.GetDomain(ctx, "go.a.com")
+	assert.NoError(t, err)
+	assert.True(t, domain.Verified())
+	assert.Equal(t, "acme", domain.Owner)
+	assert.ErrorIs(t, repo.UpdateDomain(ctx, &model.Domain{Name: "go.missing.com"}), e.NotFoundError{})
+
+	later := now.Add(time.Hour)
+	assert.ErrorIs(t, repo.ReplacePendingDomain(ctx, &model.Domain{Name: "go.a.com", Owner: "other"}, later),
+		e.ConflictError{}, "verified")
+	assert.ErrorIs(t, repo.ReplacePendingDomain(ctx, &model.Domain{Name: "go.b.com", Owner: "other"}, time.Time{}),
+		e.ConflictError{}, "registered since")
+	assert.NoError(t, repo.ReplacePendingDomain(ctx, &model.Domain{Name: "go.c.com", Owner: "acme"}, later))
+
+	domains, err := repo.ListDomains(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Len(t, domains, 3)
+	assert.Equal(t, "go.a.com", domains[0].Name)
+	assert.Equal(t, "go.b.com", domains[1].Name)
+	assert.Equal(t, "go.c.com", domains[2].Name)
+}
+
+func TestInMemory_TenantIsolation(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	acme := tenant.With(context.Background(), "acme")
+	other := tenant.With(context.Background(), "other")
+
+	assert.NoError(t, repo.SaveURL(acme, &model.URL{ShortURL: "a", OriginalURL: "https://a.com", CreatedAt: time.Now()}))
+	assert.NoError(t, repo.SaveURL(tenant.With(context.Background(), tenant.Default),
+		&model.URL{ShortURL: "b", OriginalURL: "https://b.com"}))
+	assert.ErrorIs(t, repo.SaveURL(other, &model.URL{ShortURL: "a"}), e.ConflictError{}, "codes stay global")
+
+	url, err := repo.GetURL(acme, "", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", url.Workspace)
+	_, err = repo.GetURL(other, "", "a")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	assert.ErrorIs(t, repo.UpdateURL(other, &model.URL{ShortURL: "a"}), e.NotFoundError{})
+	_, err = repo.GetURL(context.Background(), "", "a")
+	assert.NoError(t, err, "redirects aren't scoped")
+
+	assert.NoError(t, repo.UpdateURL(acme, &model.URL{ShortURL: "a", OriginalURL: "https://c.com"}))
+	url, _ = repo.GetURL(acme, "", "a")
+	assert.Equal(t, "acme", url.Workspace, "updates keep the workspace")
+
+	urls, err := repo.ListURLs(other, model.URLFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	urls, _ = repo.ListURLs(context.Background(), model.URLFilter{Limit: 10})
+	assert.Len(t, urls, 2)
+
+	count, err := repo.CountURLs(acme, "acme", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, _ = repo.CountURLs(acme, "acme", time.Now().Add(time.Hour))
+	assert.Equal(t, 0, count)
+}
+
+func TestInMemory_Workspaces(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	assert.NoError(t, repo.SaveWorkspace(ctx, &model.Workspace{ID: "acme", LinkQuota: 10}))
+	assert.ErrorIs(t, repo.SaveWorkspace(ctx, &model.Workspace{ID: "acme"}), e.ConflictError{})
+	assert.NoError(t, repo.UpdateWorkspace(ctx, &model.Workspace{ID: "acme", Name: "Acme", LinkQuota: 5}))
+	assert.ErrorIs(t, repo.UpdateWorkspace(ctx, &model.Workspace{ID: "missing"}), e.NotFoundError{})
+	workspace, err := repo.GetWorkspace(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", workspace.Name)
+	assert.Equal(t, 5, workspace.LinkQuota)
+
+	now := time.Now()
+	assert.NoError(t, repo.SaveAPIKey(ctx, &model.APIKey{ID: "k2", Workspace: "acme", CreatedAt: now}))
+	assert.NoError(t, repo.SaveAPIKey(ctx, &model.APIKey{ID: "k1", Workspace: "acme", CreatedAt: now.Add(-time.Hour)}))
+	assert.NoError(t, repo.SaveAPIKey(ctx, &model.APIKey{ID: "k3", Workspace: "other", CreatedAt: now}))
+	keys, err := repo.ListAPIKeys(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "k1", keys[0].ID)
+
+	assert.ErrorIs(t, repo.DeleteAPIKey(ctx, "acme", "k3"), e.NotFoundError{}, "keys of other workspaces stay")
+	assert.NoError(t, repo.DeleteAPIKey(ctx, "acme", "k1"))
+	_, err = repo.GetAPIKey(ctx, "k1")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+}
+
+func TestInMemory_Members(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	for _, id := range []string{"zoe", "ann"} {
+		assert.NoError(t, repo.SaveMember(ctx, &model.Member{Workspace: "acme", ID: id, Role: model.RoleViewer}))
+	}
+	assert.NoError(t, repo.SaveMember(ctx, &model.Member{Workspace: "other", ID: "ann", Role: model.RoleOwner}))
+	assert.ErrorIs(t, repo.SaveMember(ctx, &model.Member{Workspace: "acme", ID: "ann"}), e.ConflictError{})
+
+	assert.NoError(t, repo.UpdateMember(ctx, &model.Member{Workspace: "acme", ID: "ann", Role: model.RoleAdmin}))
+	assert.ErrorIs(t, repo.UpdateMember(ctx, &model.Member{Workspace: "acme", ID: "bob"}), e.NotFoundError{})
+	member, err := repo.GetMember(ctx, "acme", "ann")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, member.Role)
+	member, _ = repo.GetMember(ctx, "other", "ann")
+	assert.Equal(t, model.RoleOwner, member.Role, "memberships are per workspace")
+
+	members, err := repo.ListMembers(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, "ann", members[0].ID)
+
+	assert.NoError(t, repo.DeleteMember(ctx, "acme", "ann"))
+	assert.ErrorIs(t, repo.DeleteMember(ctx, "acme", "ann"), e.NotFoundError{})
+	_, err = repo.GetMember(ctx, "acme", "ann")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+}
+
+func TestInMemory_History(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	for _, version := range []int{2, 1} {
+		assert.NoError(t, repo.SaveVersion(ctx, &model.URLVersion{ShortURL: "abc", Version: version}))
+	}
+	assert.NoError(t, repo.SaveVersion(ctx, &model.URLVersion{Domain: "go.acme.com", ShortURL: "abc", Version: 1}))
+	assert.ErrorIs(t, repo.SaveVersion(ctx, &model.URLVersion{ShortURL: "abc", Version: 2}), e.ConflictError{})
+
+	versions, err := repo.ListVersions(ctx, "", "abc")
+	assert.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, []int{1, 2}, []int{versions[0].Version, versions[1].Version})
+	versions, err = repo.ListVersions(ctx, "", "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestInMemory_AuditLog(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	last, err := repo.LastAudit(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+
+	for _, entry := range []model.AuditEntry{
+		{Seq: 2, Actor: "eve", Action: model.AuditURLUpdate},
+		{Seq: 1, Actor: "ann", Action: model.AuditURLCreate},
+		{Seq: 3, Actor: "ann", Action: model.AuditURLUpdate},
+	} {
+		assert.NoError(t, repo.AppendAudit(ctx, &entry))
+	}
+	assert.ErrorIs(t, repo.AppendAudit(ctx, &model.AuditEntry{Seq: 2}), e.ConflictError{})
+
+	last, err = repo.LastAudit(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), last.Seq)
+	entries, err := repo.ListAudit(ctx, model.AuditFilter{Actor: "ann"})
+	assert.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, []int64{1, 3}, []int64{entries[0].Seq, entries[1].Seq})
+	entries, err = repo.ListAudit(ctx, model.AuditFilter{Action: model.AuditURLUpdate, AfterSeq: 1, Limit: 1})
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].Seq)
+}
+
+func TestLinkChecks(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	for _, status := range []int{200, 500, 404} {
+		assert.NoError(t, repo.SaveLinkCheck(ctx, &model.LinkCheck{ShortURL: "abc", StatusCode: status}))
+	}
+
+	checks, err := repo.ListLinkChecks(ctx, "", "abc", 2)
+	assert.NoError(t, err)
+	assert.Len(t, checks, 2)
+	assert.Equal(t, 404, checks[0].StatusCode)
+	assert.Equal(t, 500, checks[1].StatusCode)
+
+	checks, err = repo.ListLinkChecks(ctx, "", "missing", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, checks)
+}
+
+func TestReserveCounters(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+
+	ranges, err := repo.ReserveCounters(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CounterRange{{First: 1, Last: 10}}, ranges)
+	assert.Equal(t, uint64(10), ranges[0].Len())
+
+	counter, err := repo.IncrementCounter()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), counter)
+}
+
+func TestDurableCounter(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+
+	current, err := repo.CurrentCounter(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), current)
+
+	assert.NoError(t, repo.AdvanceCounter(ctx, 100))
+	assert.NoError(t, repo.AdvanceCounter(ctx, 50), "never moves backwards")
+	current, err = repo.CurrentCounter(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), current)
+
+	counter, err := repo.IncrementCounter()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), counter)
+}
+
+func TestWorkerLeases(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+
+	a, err := repo.AcquireWorker(ctx, "a", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, a.WorkerID)
+	b, err := repo.AcquireWorker(ctx, "b", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, b.WorkerID)
+	_, err = repo.AcquireWorker(ctx, "c", 2, time.Minute)
+	assert.ErrorIs(t, err, ErrNoFreeWorker)
+
+	last := time.Now().Add(-time.Second)
+	a.LastTime = last
+	assert.NoError(t, repo.RenewWorker(ctx, a, time.Minute))
+	assert.NoError(t, repo.ReleaseWorker(ctx, a))
+
+	// The next owner gets the time of the last ID generated with the worker ID.
+	c, err := repo.AcquireWorker(ctx, "c", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c.WorkerID)
+	assert.Equal(t, last, c.LastTime)
+
+	assert.ErrorIs(t, repo.RenewWorker(ctx, a, time.Minute), ErrLeaseLost)
+	assert.NoError(t, repo.ReleaseWorker(ctx, a), "releasing a lost lease is a no-op")
+}
+
+func TestCounterRanges(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, counterRanges(nil))
+	assert.Equal(t, []model.CounterRange{{First: 1, Last: 3}, {First: 7, Last: 8}, {First: 10, Last: 10}},
+		counterRanges([]uint64{8, 1, 2, 10, 3, 7}))
+}
+
+func shortURLs(urls []model.URL) []string {
+	codes := make([]string, 0, len(urls))
+	for _, u := range urls {
+		codes = append(codes, u.ShortURL)
+	}
+	return codes
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.SaveWebhook(ctx, &model.Webhook{ID: "h1", Workspace: "acme", URL: "https://a.com"}))
+	assert.ErrorIs(t, repo.SaveWebhook(ctx, &model.Webhook{ID: "h1"}), e.ConflictError{})
+	_, err := repo.GetWebhook(ctx, "other", "h1")
+	assert.ErrorIs(t, err, e.NotFoundError{}, "webhooks of other workspaces")
+
+	for i, due := range []time.Time{now.Add(time.Minute), now, now.Add(-time.Minute)} {
+		require.NoError(t, repo.SaveDelivery(ctx, &model.WebhookDelivery{ID: string(rune('a' + i)), Webhook: "h1",
+			Status: model.DeliveryPending, NextAttemptAt: due, CreatedAt: now.Add(time.Duration(i) * time.Second)}))
+	}
+	assert.ErrorIs(t, repo.SaveDelivery(ctx, &model.WebhookDelivery{ID: "a", Webhook: "h1"}), e.ConflictError{})
+
+	claimed, err := repo.ClaimDeliveries(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2, "only the due deliveries")
+	assert.Equal(t, "c", claimed[0].ID, "oldest first")
+	assert.Equal(t, now.Add(time.Minute), claimed[0].NextAttemptAt)
+	claimed, err = repo.ClaimDeliveries(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "leased deliveries aren't claimed again")
+
+	delivery, err := repo.GetDelivery(ctx, "h1", "c")
+	require.NoError(t, err)
+	delivery.Status, delivery.Log = model.DeliveryDelivered, model.DeliveryLog{{StatusCode: 200}}
+	require.NoError(t, repo.UpdateDelivery(ctx, delivery))
+	delivery.Log[0].StatusCode = 500
+	stored, err := repo.GetDelivery(ctx, "h1", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 200, stored.Log[0].StatusCode, "the stored log is a copy")
+
+	listed, err := repo.ListDeliveries(ctx, "h1", 2)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "c", listed[0].ID, "newest first")
+
+	require.NoError(t, repo.DeleteWebhook(ctx, "acme", "h1"))
+	_, err = repo.GetDelivery(ctx, "h1", "a")
+	assert.ErrorIs(t, err, e.NotFoundError{}, "deleted with the webhook")
+	assert.ErrorIs(t, repo.DeleteWebhook(ctx, "acme", "h1"), e.NotFoundError{})
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+	repo := NewInMemory()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := func(id string) func(*model.URL) (*model.OutboxMessage, error) {
+		return func(data *model.URL) (*model.OutboxMessage, error) {
+			return &model.OutboxMessage{ID: id, Type: "link.created", Key: data.ShortURL, NextAttemptAt: now}, nil
+		}
+	}
+
+	require.NoError(t, repo.SaveURLWithEvent(ctx, &model.URL{ShortURL: "a"}, message("m1")))
+	require.NoError(t, repo.SaveURLWithEvent(ctx, &model.URL{ShortURL: "b"}, message("m2")))
+	err := repo.SaveURLWithEvent(ctx, &model.URL{ShortURL: "a"}, message("m3"))
+	assert.ErrorIs(t, err, e.ConflictError{}, "taken short url")
+	err = repo.SaveURLWithEvent(ctx, &model.URL{ShortURL: "c"}, func(*model.URL) (*model.OutboxMessage, error) {
+		return nil, errors.New("encode failed")
+	})
+	require.Error(t, err)
+	_, err = repo.GetURL(ctx, "", "c")
+	assert.ErrorIs(t, err, e.NotFoundError{}, "the link isn't saved without its message")
+	err = repo.SaveURLWithEvent(ctx, &model.URL{ShortURL: "d"}, message("m1"))
+	assert.ErrorIs(t, err, e.ConflictError{}, "taken message ID")
+	_, err = repo.GetURL(ctx, "", "d")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+
+	claimed, err := repo.ClaimOutbox(ctx, now, time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "m1", claimed[0].ID, "oldest first")
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Equal(t, now.Add(time.Minute), claimed[0].NextAttemptAt)
+	claimed, err = repo.ClaimOutbox(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "leased messages aren't claimed again")
+	assert.Equal(t, "m2", claimed[0].ID)
+
+	require.NoError(t, repo.MarkPublished(ctx, []string{"m1"}, now))
+	claimed, err = repo.ClaimOutbox(ctx, now.Add(time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "published messages aren't claimed")
+	assert.Equal(t, "m2", claimed[0].ID)
+	assert.Equal(t, 2, claimed[0].Attempts)
+
+	n, err := repo.PurgeOutbox(ctx, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only published messages")
+}
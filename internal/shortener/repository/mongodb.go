@@ -4,51 +4,113 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"time"
 
 	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoRepo represents the methods for handling a URL.
 type MongoRepo struct {
-	client  *mongo.Collection
-	counter uint64
-	sync.RWMutex
+	client *mongo.Collection
 }
 
-var _ URL = &MongoRepo{}
+var (
+	_ URL            = &MongoRepo{}
+	_ LinkChecks     = &MongoRepo{}
+	_ History        = &MongoRepo{}
+	_ AuditLog       = &MongoRepo{}
+	_ DurableCounter = &MongoRepo{}
+	_ WorkerLeases   = &MongoRepo{}
+	_ Domains        = &MongoRepo{}
+	_ Workspaces     = &MongoRepo{}
+	_ Webhooks       = &MongoRepo{}
+	_ Outbox         = &MongoRepo{}
+)
+
+// counterID is the _id of the short URL counter in the counters collection, named like the redis key.
+const counterID = "url_shortener_counter"
 
 // NewMongoDB returns an instance of MongoRepo.
 func NewMongoDB(client *mongo.Collection) *MongoRepo {
-	return &MongoRepo{
-		client:  client,
-		counter: 1,
-		RWMutex: sync.RWMutex{},
-	}
+	return &MongoRepo{client: client}
 }
 
 // SaveURL saves URL data to MongoDB.
 func (m *MongoRepo) SaveURL(ctx context.Context, data *model.URL) error {
-	result, err := m.client.InsertOne(ctx, map[string]interface{}{
+	assignWorkspace(ctx, data)
+	document := map[string]interface{}{
 		"short_url":       data.ShortURL,
 		"original_url":    data.OriginalURL,
 		"created_at":      data.CreatedAt,
 		"expiration_date": data.ExpirationDate,
 		"custom_url":      data.CustomURL,
-	})
-	if err != nil {
+		"utm":             data.UTM,
+		"forward_query":   data.ForwardQuery,
+		"prefix":          data.Prefix,
+		"redirect_type":   data.RedirectType,
+		"title":           data.Title,
+		"description":     data.Description,
+		"image":           data.Image,
+	}
+	if data.Domain != "" {
+		document["domain"] = data.Domain
+	}
+	if data.Workspace != "" {
+		document["tenant_id"] = data.Workspace
+	}
+	if len(data.Tags) > 0 {
+		document["tags"] = data.Tags
+	}
+	if data.Folder != "" {
+		document["folder"] = data.Folder
+	}
+	if data.Notes != "" {
+		document["notes"] = data.Notes
+	}
+	if data.Generator != "" {
+		document["generator"] = data.Generator
+	}
+	if _, err := m.client.InsertOne(ctx, document); err != nil {
 		if isDuplicateError(err) {
 			return e.NewConflictError("short url already exists")
 		}
 		return errors.New("failed to insert url:" + err.Error())
 	}
-	fmt.Println("Created!", result.InsertedID)
 	return nil
 }
 
+// linkFilter matches the link with the short URL on the domain. Links on the default host don't store a
+// domain, and null matches a missing field, so the unique (domain, short_url) index covers them too.
+func linkFilter(domain, shortURL string) bson.D {
+	var value any
+	if domain != "" {
+		value = domain
+	}
+	return bson.D{{Key: "domain", Value: value}, {Key: "short_url", Value: shortURL}}
+}
+
+// workspaceValue matches the links of the workspace. The default workspace isn't stored, null matches it.
+func workspaceValue(workspace string) any {
+	if workspace == "" {
+		return nil
+	}
+	return workspace
+}
+
+// scoped limits the filter to the workspace of a scoped context.
+func scoped(ctx context.Context, filter bson.D) bson.D {
+	if workspace, ok := tenant.From(ctx); ok {
+		filter = append(filter, bson.E{Key: "tenant_id", Value: workspaceValue(workspace)})
+	}
+	return filter
+}
+
 func isDuplicateError(err error) bool {
 	var e mongo.WriteException
 	if errors.As(err, &e) {
@@ -71,11 +133,9 @@ func isDuplicateError(err error) bool {
 //		"expiration_date": 1, // MongoDB field "expiration_date"
 //		"created_at":      1, // MongoDB field "created_at"
 //	}
-func (m *MongoRepo) GetURL(ctx context.Context, shortURL string) (*model.URL, error) {
+func (m *MongoRepo) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
 	var result model.URL
-	err := m.client.FindOne(ctx, bson.D{
-		{Key: "short_url", Value: shortURL},
-	}).Decode(&result)
+	err := m.client.FindOne(ctx, scoped(ctx, linkFilter(domain, shortURL))).Decode(&result)
 	// }, options.FindOne().SetProjection(projection)).Decode(&result)
 
 	if err != nil {
@@ -90,19 +150,918 @@ func (m *MongoRepo) GetURL(ctx context.Context, shortURL string) (*model.URL, er
 	return &result, nil
 }
 
+// UpdateURL updates the editable fields of the URL with the same domain and short URL.
+func (m *MongoRepo) UpdateURL(ctx context.Context, data *model.URL) error {
+	result, err := m.client.UpdateOne(ctx, scoped(ctx, linkFilter(data.Domain, data.ShortURL)), bson.M{
+		"$set": bson.M{
+			"original_url":    data.OriginalURL,
+			"expiration_date": data.ExpirationDate,
+			"utm":             data.UTM,
+			"forward_query":   data.ForwardQuery,
+			"prefix":          data.Prefix,
+			"redirect_type":   data.RedirectType,
+			"title":           data.Title,
+			"description":     data.Description,
+			"image":           data.Image,
+			"broken":          data.Broken,
+			"tags":            data.Tags,
+			"folder":          data.Folder,
+			"notes":           data.Notes,
+			"updated_at":      data.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error while updating URL: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewNotFoundError("url with short_url '%s' not found", data.ShortURL)
+	}
+	return nil
+}
+
+// DeleteURL deletes the URL with the domain and short URL, then its versions and checks so a new link with
+// the short URL starts without them.
+func (m *MongoRepo) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	result, err := m.client.DeleteOne(ctx, scoped(ctx, linkFilter(domain, shortURL)))
+	if err != nil {
+		return fmt.Errorf("error while deleting URL: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return e.NewNotFoundError("url with short_url '%s' not found", shortURL)
+	}
+	for _, collection := range []*mongo.Collection{m.versions(), m.linkChecks()} {
+		if _, err := collection.DeleteMany(ctx, linkFilter(domain, shortURL)); err != nil {
+			return fmt.Errorf("error while deleting URL history: %v", err)
+		}
+	}
+	return nil
+}
+
+// ListURLs returns the URLs matching the filter, oldest first.
+func (m *MongoRepo) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	query := bson.M{}
+	if filter.Broken != nil {
+		if *filter.Broken {
+			query["broken"] = true
+		} else {
+			query["broken"] = bson.M{"$ne": true} // Working links don't store the field.
+		}
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if filter.Folder != nil {
+		if *filter.Folder == "" {
+			query["folder"] = bson.M{"$in": bson.A{nil, ""}} // Links never filed don't store the field.
+		} else {
+			query["folder"] = *filter.Folder
+		}
+	}
+	if workspace, ok := tenant.From(ctx); ok {
+		query["tenant_id"] = workspaceValue(workspace)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit))
+	cursor, err := m.client.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing URLs: %v", err)
+	}
+
+	urls := []model.URL{}
+	if err := cursor.All(ctx, &urls); err != nil {
+		return nil, fmt.Errorf("error while decoding URLs: %v", err)
+	}
+	return urls, nil
+}
+
+// ListTags returns the tags of the links by name, with how many links have each.
+func (m *MongoRepo) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: scoped(ctx, bson.D{{Key: "tags.0", Value: bson.M{"$exists": true}}})}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "links": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+	cursor, err := m.client.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing tags: %v", err)
+	}
+
+	tags := []model.TagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, fmt.Errorf("error while decoding tags: %v", err)
+	}
+	return tags, nil
+}
+
+// ExistingURLs returns which of the short URLs are taken on the domain. Distinct on the (domain, short_url)
+// index doesn't load the documents.
+func (m *MongoRepo) ExistingURLs(ctx context.Context, domain string, shortURLs []string) ([]string, error) {
+	filter := linkFilter(domain, "")
+	filter[1].Value = bson.M{"$in": shortURLs}
+	values, err := m.client.Distinct(ctx, "short_url", filter)
+	if err != nil {
+		return nil, fmt.Errorf("error while checking URLs: %v", err)
+	}
+
+	existing := make([]string, 0, len(values))
+	for _, v := range values {
+		if shortURL, ok := v.(string); ok {
+			existing = append(existing, shortURL)
+		}
+	}
+	return existing, nil
+}
+
+// linkChecks returns the collection holding the link health check history.
+func (m *MongoRepo) linkChecks() *mongo.Collection {
+	return m.client.Database().Collection("link_checks")
+}
+
+// SaveLinkCheck stores the result of a link health check.
+func (m *MongoRepo) SaveLinkCheck(ctx context.Context, check *model.LinkCheck) error {
+	if _, err := m.linkChecks().InsertOne(ctx, check); err != nil {
+		return fmt.Errorf("error while saving link check: %v", err)
+	}
+	return nil
+}
+
+// ListLinkChecks returns the latest health checks of a link, newest first.
+func (m *MongoRepo) ListLinkChecks(ctx context.Context, domain, shortURL string,
+	limit int) ([]model.LinkCheck, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "checked_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := m.linkChecks().Find(ctx, linkFilter(domain, shortURL), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing link checks: %v", err)
+	}
+
+	checks := []model.LinkCheck{}
+	if err := cursor.All(ctx, &checks); err != nil {
+		return nil, fmt.Errorf("error while decoding link checks: %v", err)
+	}
+	return checks, nil
+}
+
+// versions returns the collection holding the version history of links.
+func (m *MongoRepo) versions() *mongo.Collection {
+	return m.client.Database().Collection("url_versions")
+}
+
+// SaveVersion appends a version of a link. The unique (domain, short_url, version) index rejects a second
+// version with the same number.
+func (m *MongoRepo) SaveVersion(ctx context.Context, version *model.URLVersion) error {
+	if _, err := m.versions().InsertOne(ctx, version); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("version %d of %q already exists", version.Version, version.ShortURL)
+		}
+		return fmt.Errorf("error while saving url version: %v", err)
+	}
+	return nil
+}
+
+// ListVersions returns the versions of a link, oldest first.
+func (m *MongoRepo) ListVersions(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := m.versions().Find(ctx, linkFilter(domain, shortURL), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing url versions: %v", err)
+	}
+
+	versions := []model.URLVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("error while decoding url versions: %v", err)
+	}
+	return versions, nil
+}
+
+// auditLog returns the collection holding the audit log, with the seq as _id.
+func (m *MongoRepo) auditLog() *mongo.Collection {
+	return m.client.Database().Collection("audit_log")
+}
+
+// AppendAudit appends an entry to the audit log. The seq is the _id, so a second entry with it is rejected.
+func (m *MongoRepo) AppendAudit(ctx context.Context, entry *model.AuditEntry) error {
+	if _, err := m.auditLog().InsertOne(ctx, entry); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("audit entry %d already exists", entry.Seq)
+		}
+		return fmt.Errorf("error while saving audit entry: %v", err)
+	}
+	return nil
+}
+
+// LastAudit returns the entry of the audit log with the highest seq, nil if it's empty.
+func (m *MongoRepo) LastAudit(ctx context.Context) (*model.AuditEntry, error) {
+	var entry model.AuditEntry
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err := m.auditLog().FindOne(ctx, bson.M{}, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while getting last audit entry: %v", err)
+	}
+	return &entry, nil
+}
+
+// ListAudit returns the entries of the audit log passing the filter by seq.
+func (m *MongoRepo) ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	query := bson.M{"_id": bson.M{"$gt": filter.AfterSeq}}
+	for field, value := range map[string]string{
+		"actor":        filter.Actor,
+		"workspace_id": filter.Workspace,
+		"action":       filter.Action,
+		"resource":     filter.Resource,
+	} {
+		if value != "" {
+			query[field] = value
+		}
+	}
+	if filter.From != nil || filter.To != nil {
+		times := bson.M{}
+		if filter.From != nil {
+			times["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			times["$lt"] = *filter.To
+		}
+		query["time"] = times
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := m.auditLog().Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing audit entries: %v", err)
+	}
+
+	entries := []model.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("error while decoding audit entries: %v", err)
+	}
+	return entries, nil
+}
+
+// domains returns the collection holding the custom domains, with the name as _id.
+func (m *MongoRepo) domains() *mongo.Collection {
+	return m.client.Database().Collection("domains")
+}
+
+// SaveDomain stores a new domain.
+func (m *MongoRepo) SaveDomain(ctx context.Context, domain *model.Domain) error {
+	if _, err := m.domains().InsertOne(ctx, domain); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("domain %q is already registered", domain.Name)
+		}
+		return fmt.Errorf("error while saving domain: %v", err)
+	}
+	return nil
+}
+
+// GetDomain retrieves a domain by name.
+func (m *MongoRepo) GetDomain(ctx context.Context, name string) (*model.Domain, error) {
+	var domain model.Domain
+	err := m.domains().FindOne(ctx, bson.D{{Key: "_id", Value: name}}).Decode(&domain)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("domain %q not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving domain: %v", err)
+	}
+	return &domain, nil
+}
+
+// UpdateDomain stores the verification time of the domain.
+func (m *MongoRepo) UpdateDomain(ctx context.Context, domain *model.Domain) error {
+	result, err := m.domains().UpdateOne(ctx, bson.D{{Key: "_id", Value: domain.Name}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "verified_at", Value: domain.VerifiedAt}}}})
+	if err != nil {
+		return fmt.Errorf("error while updating domain: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewNotFoundError("domain %q not found", domain.Name)
+	}
+	return nil
+}
+
+// ReplacePendingDomain stores the domain in place of an unverified one registered before registeredBefore.
+func (m *MongoRepo) ReplacePendingDomain(ctx context.Context, domain *model.Domain, registeredBefore time.Time) error {
+	filter := bson.D{
+		{Key: "_id", Value: domain.Name},
+		{Key: "verified_at", Value: nil},
+		{Key: "created_at", Value: bson.D{{Key: "$lt", Value: registeredBefore}}},
+	}
+	result, err := m.domains().ReplaceOne(ctx, filter, domain)
+	if err != nil {
+		return fmt.Errorf("error while replacing domain: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewConflictError("domain %q is already registered", domain.Name)
+	}
+	return nil
+}
+
+// ListDomains returns the owner's domains by name.
+func (m *MongoRepo) ListDomains(ctx context.Context, owner string) ([]model.Domain, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.domains().Find(ctx, bson.D{{Key: "owner", Value: owner}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing domains: %v", err)
+	}
+
+	domains := []model.Domain{}
+	if err := cursor.All(ctx, &domains); err != nil {
+		return nil, fmt.Errorf("error while decoding domains: %v", err)
+	}
+	return domains, nil
+}
+
+// workspaces returns the collection holding the workspaces, with the ID as _id.
+func (m *MongoRepo) workspaces() *mongo.Collection {
+	return m.client.Database().Collection("workspaces")
+}
+
+// apiKeys returns the collection holding the API keys, with the ID as _id.
+func (m *MongoRepo) apiKeys() *mongo.Collection {
+	return m.client.Database().Collection("api_keys")
+}
+
+// SaveWorkspace stores a new workspace.
+func (m *MongoRepo) SaveWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	if _, err := m.workspaces().InsertOne(ctx, workspace); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("workspace %q already exists", workspace.ID)
+		}
+		return fmt.Errorf("error while saving workspace: %v", err)
+	}
+	return nil
+}
+
+// GetWorkspace retrieves a workspace by ID.
+func (m *MongoRepo) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	var workspace model.Workspace
+	err := m.workspaces().FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&workspace)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("workspace %q not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving workspace: %v", err)
+	}
+	return &workspace, nil
+}
+
+// UpdateWorkspace stores the name and quotas of the workspace.
+func (m *MongoRepo) UpdateWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	result, err := m.workspaces().UpdateOne(ctx, bson.D{{Key: "_id", Value: workspace.ID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "name", Value: workspace.Name},
+			{Key: "link_quota", Value: workspace.LinkQuota},
+			{Key: "monthly_quota", Value: workspace.MonthlyQuota},
+		}}})
+	if err != nil {
+		return fmt.Errorf("error while updating workspace: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewNotFoundError("workspace %q not found", workspace.ID)
+	}
+	return nil
+}
+
+// SaveAPIKey stores a new API key.
+func (m *MongoRepo) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	if _, err := m.apiKeys().InsertOne(ctx, key); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("api key %q already exists", key.ID)
+		}
+		return fmt.Errorf("error while saving api key: %v", err)
+	}
+	return nil
+}
+
+// GetAPIKey retrieves an API key by ID.
+func (m *MongoRepo) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := m.apiKeys().FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("api key %q not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving api key: %v", err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the keys of the workspace, oldest first.
+func (m *MongoRepo) ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := m.apiKeys().Find(ctx, bson.D{{Key: "workspace_id", Value: workspace}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing api keys: %v", err)
+	}
+
+	keys := []model.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("error while decoding api keys: %v", err)
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey deletes the workspace's key.
+func (m *MongoRepo) DeleteAPIKey(ctx context.Context, workspace, id string) error {
+	result, err := m.apiKeys().DeleteOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "workspace_id", Value: workspace}})
+	if err != nil {
+		return fmt.Errorf("error while deleting api key: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return e.NewNotFoundError("api key %q not found", id)
+	}
+	return nil
+}
+
+// CountURLs counts the workspace's links created at or after since, using the {tenant_id, created_at} index.
+func (m *MongoRepo) CountURLs(ctx context.Context, workspace string, since time.Time) (int, error) {
+	count, err := m.client.CountDocuments(ctx, bson.D{
+		{Key: "tenant_id", Value: workspaceValue(workspace)},
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error while counting URLs: %v", err)
+	}
+	return int(count), nil
+}
+
+// workspaceLocks returns the collection holding the workspace locks, with the workspace ID as _id.
+func (m *MongoRepo) workspaceLocks() *mongo.Collection {
+	return m.client.Database().Collection("workspace_locks")
+}
+
+// workspaceLockPoll is how long LockWorkspace waits before trying a held lock again.
+const workspaceLockPoll = 10 * time.Millisecond
+
+// LockWorkspace takes the workspace's lock with an upsert that only matches an expired lock, so taking a held
+// one fails with a duplicate key error and is tried again until it's released or runs out.
+func (m *MongoRepo) LockWorkspace(ctx context.Context, workspace string, ttl time.Duration) (func(), error) {
+	token := primitive.NewObjectID() // Unlocking a lock that ran out mustn't release the next holder's.
+	for {
+		now := time.Now().UTC()
+		_, err := m.workspaceLocks().UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: workspace}, {Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: token}, {Key: "expires_at", Value: now.Add(ttl)}}}},
+			options.Update().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("error while locking workspace: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(workspaceLockPoll):
+		}
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// A lock that can't be deleted runs out after ttl.
+		_, _ = m.workspaceLocks().DeleteOne(ctx, bson.D{{Key: "_id", Value: workspace}, {Key: "token", Value: token}})
+	}, nil
+}
+
+// members returns the collection holding workspace memberships, unique by workspace_id and member_id.
+func (m *MongoRepo) members() *mongo.Collection {
+	return m.client.Database().Collection("workspace_members")
+}
+
+// SaveMember stores a new member of a workspace.
+func (m *MongoRepo) SaveMember(ctx context.Context, member *model.Member) error {
+	if _, err := m.members().InsertOne(ctx, member); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("%q is already a member of workspace %q", member.ID, member.Workspace)
+		}
+		return fmt.Errorf("error while saving member: %v", err)
+	}
+	return nil
+}
+
+// GetMember retrieves a member of a workspace.
+func (m *MongoRepo) GetMember(ctx context.Context, workspace, id string) (*model.Member, error) {
+	var member model.Member
+	err := m.members().FindOne(ctx, memberFilter(workspace, id)).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("member %q not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving member: %v", err)
+	}
+	return &member, nil
+}
+
+// UpdateMember stores the role of the member.
+func (m *MongoRepo) UpdateMember(ctx context.Context, member *model.Member) error {
+	result, err := m.members().UpdateOne(ctx, memberFilter(member.Workspace, member.ID),
+		bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: member.Role}}}})
+	if err != nil {
+		return fmt.Errorf("error while updating member: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewNotFoundError("member %q not found", member.ID)
+	}
+	return nil
+}
+
+// ListMembers returns the members of the workspace by ID.
+func (m *MongoRepo) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "member_id", Value: 1}})
+	cursor, err := m.members().Find(ctx, bson.D{{Key: "workspace_id", Value: workspace}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing members: %v", err)
+	}
+
+	members := []model.Member{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("error while decoding members: %v", err)
+	}
+	return members, nil
+}
+
+// DeleteMember removes the member from the workspace.
+func (m *MongoRepo) DeleteMember(ctx context.Context, workspace, id string) error {
+	result, err := m.members().DeleteOne(ctx, memberFilter(workspace, id))
+	if err != nil {
+		return fmt.Errorf("error while deleting member: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return e.NewNotFoundError("member %q not found", id)
+	}
+	return nil
+}
+
+func memberFilter(workspace, id string) bson.D {
+	return bson.D{{Key: "workspace_id", Value: workspace}, {Key: "member_id", Value: id}}
+}
+
+// webhooks returns the collection holding the webhooks, with the ID as _id.
+func (m *MongoRepo) webhooks() *mongo.Collection {
+	return m.client.Database().Collection("webhooks")
+}
+
+// deliveries returns the collection holding the webhook delivery queue, with the ID as _id.
+func (m *MongoRepo) deliveries() *mongo.Collection {
+	return m.client.Database().Collection("webhook_deliveries")
+}
+
+// SaveWebhook stores a new webhook.
+func (m *MongoRepo) SaveWebhook(ctx context.Context, hook *model.Webhook) error {
+	if _, err := m.webhooks().InsertOne(ctx, hook); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("webhook %q already exists", hook.ID)
+		}
+		return fmt.Errorf("error while saving webhook: %v", err)
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook of the workspace.
+func (m *MongoRepo) GetWebhook(ctx context.Context, workspace, id string) (*model.Webhook, error) {
+	var hook model.Webhook
+	err := m.webhooks().FindOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "workspace_id", Value: workspace}}).
+		Decode(&hook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("webhook %q not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving webhook: %v", err)
+	}
+	return &hook, nil
+}
+
+// ListWebhooks returns the webhooks of the workspace, oldest first.
+func (m *MongoRepo) ListWebhooks(ctx context.Context, workspace string) ([]model.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := m.webhooks().Find(ctx, bson.D{{Key: "workspace_id", Value: workspace}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing webhooks: %v", err)
+	}
+
+	hooks := []model.Webhook{}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		return nil, fmt.Errorf("error while decoding webhooks: %v", err)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook deletes the workspace's webhook, then its deliveries.
+func (m *MongoRepo) DeleteWebhook(ctx context.Context, workspace, id string) error {
+	result, err := m.webhooks().DeleteOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "workspace_id", Value: workspace}})
+	if err != nil {
+		return fmt.Errorf("error while deleting webhook: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return e.NewNotFoundError("webhook %q not found", id)
+	}
+	if _, err := m.deliveries().DeleteMany(ctx, bson.D{{Key: "webhook_id", Value: id}}); err != nil {
+		return fmt.Errorf("error while deleting webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+// SaveDelivery queues a new delivery.
+func (m *MongoRepo) SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if _, err := m.deliveries().InsertOne(ctx, delivery); err != nil {
+		if isDuplicateError(err) {
+			return e.NewConflictError("delivery %q already exists", delivery.ID)
+		}
+		return fmt.Errorf("error while saving delivery: %v", err)
+	}
+	return nil
+}
+
+// GetDelivery retrieves a delivery of the webhook.
+func (m *MongoRepo) GetDelivery(ctx context.Context, webhook, id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := m.deliveries().FindOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "webhook_id", Value: webhook}}).
+		Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, e.NewNotFoundError("delivery %q not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving delivery: %v", err)
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery stores the status, attempts, next attempt and log of the delivery.
+func (m *MongoRepo) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	result, err := m.deliveries().UpdateOne(ctx, bson.D{{Key: "_id", Value: delivery.ID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: delivery.Status},
+			{Key: "attempts", Value: delivery.Attempts},
+			{Key: "next_attempt_at", Value: delivery.NextAttemptAt},
+			{Key: "log", Value: delivery.Log},
+			{Key: "delivered_at", Value: delivery.DeliveredAt},
+		}}})
+	if err != nil {
+		return fmt.Errorf("error while updating delivery: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return e.NewNotFoundError("delivery %q not found", delivery.ID)
+	}
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries of the webhook, newest first.
+func (m *MongoRepo) ListDeliveries(ctx context.Context, webhook string,
+	limit int) ([]model.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := m.deliveries().Find(ctx, bson.D{{Key: "webhook_id", Value: webhook}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing deliveries: %v", err)
+	}
+
+	deliveries := []model.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("error while decoding deliveries: %v", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries leases the pending deliveries due at now one at a time. Each lease is a single atomic
+// update, so instances claiming at the same time never get the same delivery.
+func (m *MongoRepo) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.WebhookDelivery, error) {
+	filter := bson.D{
+		{Key: "status", Value: model.DeliveryPending},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "next_attempt_at", Value: now.Add(lease)}}}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	deliveries := []model.WebhookDelivery{}
+	for len(deliveries) < limit {
+		var delivery model.WebhookDelivery
+		err := m.deliveries().FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return deliveries, fmt.Errorf("error while claiming deliveries: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// outbox returns the collection holding the messages for the outbox relay.
+func (m *MongoRepo) outbox() *mongo.Collection {
+	return m.client.Database().Collection("outbox")
+}
+
+// SaveURLWithEvent saves the URL and its outbox message in a transaction, which needs a replica set.
+func (m *MongoRepo) SaveURLWithEvent(ctx context.Context, data *model.URL,
+	event func(*model.URL) (*model.OutboxMessage, error)) error {
+	session, err := m.client.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("error while starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (any, error) {
+		if err := m.SaveURL(ctx, data); err != nil {
+			return nil, err
+		}
+		msg, err := event(data)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := m.outbox().InsertOne(ctx, msg); err != nil {
+			return nil, fmt.Errorf("error while saving outbox message: %v", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// ClaimOutbox leases the messages one at a time, each update is atomic so relays don't claim the same message.
+func (m *MongoRepo) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.OutboxMessage, error) {
+	filter := bson.D{
+		{Key: "published_at", Value: nil},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "next_attempt_at", Value: now.Add(lease)}}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	messages := []model.OutboxMessage{}
+	for len(messages) < limit {
+		var msg model.OutboxMessage
+		err := m.outbox().FindOneAndUpdate(ctx, filter, update, opts).Decode(&msg)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return messages, fmt.Errorf("error while claiming outbox messages: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// MarkPublished sets the publication time of the messages.
+func (m *MongoRepo) MarkPublished(ctx context.Context, ids []string, at time.Time) error {
+	_, err := m.outbox().UpdateMany(ctx,
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "published_at", Value: at}}}})
+	if err != nil {
+		return fmt.Errorf("error while marking outbox messages published: %v", err)
+	}
+	return nil
+}
+
+// PurgeOutbox deletes the messages published before the time.
+func (m *MongoRepo) PurgeOutbox(ctx context.Context, before time.Time) (int, error) {
+	result, err := m.outbox().DeleteMany(ctx,
+		bson.D{{Key: "published_at", Value: bson.D{{Key: "$lt", Value: before}}}})
+	if err != nil {
+		return 0, fmt.Errorf("error while purging outbox: %v", err)
+	}
+	return int(result.DeletedCount), nil
+}
+
+// counters returns the collection holding the durable counters, one document per counter.
+func (m *MongoRepo) counters() *mongo.Collection {
+	return m.client.Database().Collection("counters")
+}
+
 // IncrementCounter increments the counter and returns it's value.
-// Hacky solution if redis + replicas fail. Not expecting to reach this code but safety net.
+// Used when redis is down, the counter is stored so it survives restarts and is shared by all instances.
 func (m *MongoRepo) IncrementCounter() (uint64, error) {
-	m.Lock()
-	defer m.Unlock()
-	count, err := m.client.CountDocuments(context.Background(), bson.D{})
+	ranges, err := m.ReserveCounters(context.Background(), 1)
 	if err != nil {
 		return 0, err
 	}
+	return ranges[0].First, nil
+}
 
-	if m.counter < uint64(count) {
-		m.counter = uint64(count + 1)
+// ReserveCounters atomically increments the counter by n, creating it if needed.
+func (m *MongoRepo) ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
 	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := m.counters().FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: counterID}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "value", Value: int64(n)}}}},
+		opts,
+	).Decode(&counter)
+	if err != nil {
+		return nil, fmt.Errorf("error while incrementing counter: %v", err)
+	}
+
+	last := uint64(counter.Value)
+	return []model.CounterRange{{First: last - uint64(n) + 1, Last: last}}, nil
+}
 
-	return m.counter, nil
+// CurrentCounter returns the last value handed out by the counter, 0 when it doesn't exist yet.
+func (m *MongoRepo) CurrentCounter(ctx context.Context) (uint64, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	err := m.counters().FindOne(ctx, bson.D{{Key: "_id", Value: counterID}}).Decode(&counter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error while reading counter: %v", err)
+	}
+	return uint64(counter.Value), nil
+}
+
+// AdvanceCounter moves the counter forward to at least value with $max, so it never moves backwards.
+func (m *MongoRepo) AdvanceCounter(ctx context.Context, value uint64) error {
+	_, err := m.counters().UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: counterID}},
+		bson.D{{Key: "$max", Value: bson.D{{Key: "value", Value: int64(value)}}}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error while advancing counter: %v", err)
+	}
+	return nil
+}
+
+// workerLeases returns the collection holding the worker ID leases, with the worker ID as _id.
+func (m *MongoRepo) workerLeases() *mongo.Collection {
+	return m.client.Database().Collection("worker_leases")
+}
+
+// AcquireWorker leases the lowest free worker ID. Each ID is claimed with an upsert that only matches
+// an expired lease, so taking a held ID fails with a duplicate key error and the next is tried.
+func (m *MongoRepo) AcquireWorker(ctx context.Context, owner string, workers int,
+	ttl time.Duration) (*model.WorkerLease, error) {
+	for id := range workers {
+		now := time.Now().UTC()
+		var previous model.WorkerLease
+		err := m.workerLeases().FindOneAndUpdate(ctx,
+			bson.D{{Key: "_id", Value: id}, {Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}, {Key: "expires_at", Value: now.Add(ttl)}}}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+		).Decode(&previous)
+		switch {
+		case mongo.IsDuplicateKeyError(err):
+			continue
+		case err != nil && !errors.Is(err, mongo.ErrNoDocuments): // No document means it was inserted.
+			return nil, fmt.Errorf("error while acquiring worker: %v", err)
+		}
+		return &model.WorkerLease{WorkerID: id, Owner: owner, ExpiresAt: now.Add(ttl), LastTime: previous.LastTime}, nil
+	}
+	return nil, ErrNoFreeWorker
+}
+
+// RenewWorker extends the lease while the owner still holds it.
+func (m *MongoRepo) RenewWorker(ctx context.Context, lease *model.WorkerLease, ttl time.Duration) error {
+	expires := time.Now().UTC().Add(ttl)
+	if err := m.updateLease(ctx, lease, expires); err != nil {
+		return err
+	}
+	lease.ExpiresAt = expires
+	return nil
+}
+
+// ReleaseWorker expires the lease now.
+func (m *MongoRepo) ReleaseWorker(ctx context.Context, lease *model.WorkerLease) error {
+	err := m.updateLease(ctx, lease, time.Now().UTC())
+	if errors.Is(err, ErrLeaseLost) {
+		return nil
+	}
+	return err
+}
+
+func (m *MongoRepo) updateLease(ctx context.Context, lease *model.WorkerLease, expires time.Time) error {
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "expires_at", Value: expires}}}}
+	if !lease.LastTime.IsZero() {
+		update = append(update, bson.E{Key: "$max", Value: bson.D{{Key: "last_time", Value: lease.LastTime}}})
+	}
+
+	result, err := m.workerLeases().UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: lease.WorkerID}, {Key: "owner", Value: lease.Owner}}, update)
+	if err != nil {
+		return fmt.Errorf("error while updating worker lease: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
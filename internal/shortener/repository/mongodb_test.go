@@ -6,8 +6,11 @@ import (
 	"time"
 
 	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
@@ -41,6 +44,30 @@ func TestSaveURL_Success(t *testing.T) {
 	// The mt object will be automatically cleaned up after the test completes.
 }
 
+func TestSaveURL_Generator(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test SaveURL stores the generator", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		repo := NewMongoDB(mt.Coll)
+
+		err := repo.SaveURL(context.Background(), &model.URL{
+			ShortURL: "short123", OriginalURL: "http://example.com", Generator: "random",
+		})
+		require.NoError(t, err)
+		document := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "random", document.Lookup("generator").StringValue())
+
+		// Custom aliases have no generator and don't store the field.
+		err = repo.SaveURL(context.Background(), &model.URL{ShortURL: "mylink", OriginalURL: "http://example.com"})
+		require.NoError(t, err)
+		document = mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		_, err = document.LookupErr("generator")
+		assert.Error(t, err)
+	})
+}
+
 func TestSaveURL_DuplicateError(t *testing.T) {
 	t.Parallel()
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
@@ -95,7 +122,7 @@ func TestGetURL_Success(t *testing.T) {
 		repo := NewMongoDB(mt.Coll)
 
 		// Call the GetURL method
-		result, err := repo.GetURL(context.Background(), "short123")
+		result, err := repo.GetURL(context.Background(), "", "short123")
 
 		// Assert that the result is correct and there is no error
 		assert.Nil(t, err)
@@ -119,7 +146,7 @@ func TestGetURL_NotFound(t *testing.T) {
 		repo := NewMongoDB(mt.Coll)
 
 		// Call the GetURL method.
-		result, err := repo.GetURL(context.Background(), "nonexistent")
+		result, err := repo.GetURL(context.Background(), "", "nonexistent")
 
 		// Assert that the error is the "not found" error
 		assert.Nil(t, result)
@@ -127,58 +154,556 @@ func TestGetURL_NotFound(t *testing.T) {
 	})
 }
 
-func TestIncrementCounter_Success(t *testing.T) {
+func TestUpdateURL_Success(t *testing.T) {
 	t.Parallel()
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
-	mt.Run("Test IncrementCounter Success", func(mt *mtest.T) {
-		// Mock the CountDocuments call to return a count of 5 documents in the collection.
+	mt.Run("Test UpdateURL Success", func(mt *mtest.T) {
+		// Mock the UpdateOne call to match one document.
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		repo := NewMongoDB(mt.Coll)
 
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.collection", mtest.FirstBatch,
-			bson.D{{Key: "n", Value: int32(5)}}, // Simulating 5 documents in the collection.
-		))
+		err := repo.UpdateURL(context.Background(), &model.URL{ShortURL: "short123", Title: "Example"})
+		assert.Nil(t, err)
+	})
+}
 
-		// Define MongoRepo using the mocked client and collection.
+func TestUpdateURL_NotFound(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test UpdateURL Not Found", func(mt *mtest.T) {
+		// Mock the UpdateOne call to match nothing.
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
 		repo := NewMongoDB(mt.Coll)
 
-		// Initially, counter is set to 1.
-		// The next count after 5 should be 6, so it will increment it.
-		repo.counter = 1
+		err := repo.UpdateURL(context.Background(), &model.URL{ShortURL: "nonexistent"})
+		assert.Equal(t, e.NewNotFoundError("url with short_url 'nonexistent' not found"), err)
+	})
+}
 
-		// Call the IncrementCounter method.
-		count, err := repo.IncrementCounter()
+func TestDeleteURL_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test DeleteURL", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}}, // The link.
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}}, // Its versions.
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}}, // Its checks.
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		assert.NoError(t, repo.DeleteURL(context.Background(), "", "short123"))
+		err := repo.DeleteURL(context.Background(), "", "nonexistent")
+		assert.Equal(t, e.NewNotFoundError("url with short_url 'nonexistent' not found"), err)
+	})
+}
 
-		// Assert that the incremented counter value is 6.
+func TestListURLs_Success(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test ListURLs Success", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test.collection", mtest.FirstBatch,
+				bson.D{{Key: "short_url", Value: "abc"}, {Key: "broken", Value: true}}),
+			mtest.CreateCursorResponse(0, "test.collection", mtest.NextBatch),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		urls, err := repo.ListURLs(context.Background(), model.URLFilter{Broken: ptr.Of(true), Limit: 10})
 		assert.Nil(t, err)
-		assert.Equal(t, uint64(6), count)
+		assert.Len(t, urls, 1)
+		assert.True(t, urls[0].Broken)
 	})
 }
 
-func TestIncrementCounter_Error(t *testing.T) {
+func TestListTags_Success(t *testing.T) {
 	t.Parallel()
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
-	mt.Run("Test IncrementCounter Error", func(mt *mtest.T) {
-		// Mock the CountDocuments call to return an error.
+	mt.Run("Test ListTags Success", func(mt *mtest.T) {
 		mt.AddMockResponses(
-			mtest.CreateCursorResponse(1, "test.collection", "batch1",
-				// Simulate an error by not returning a valid "count"
-				bson.D{
-					{Key: "error", Value: "failed to count documents"},
-				}),
+			mtest.CreateCursorResponse(1, "test.collection", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "launch"}, {Key: "links", Value: 3}},
+				bson.D{{Key: "_id", Value: "promo"}, {Key: "links", Value: 1}}),
+			mtest.CreateCursorResponse(0, "test.collection", mtest.NextBatch),
 		)
+		repo := NewMongoDB(mt.Coll)
 
-		// Define MongoRepo using the mocked client and collection.
+		tags, err := repo.ListTags(tenant.With(context.Background(), "acme"))
+		assert.Nil(t, err)
+		assert.Equal(t, []model.TagCount{{Tag: "launch", Links: 3}, {Tag: "promo", Links: 1}}, tags)
+	})
+}
+
+func TestExistingURLs_Success(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test ExistingURLs Success", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"abc"}}))
+		repo := NewMongoDB(mt.Coll)
+
+		existing, err := repo.ExistingURLs(context.Background(), "", []string{"abc", "abc2"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"abc"}, existing)
+	})
+}
+
+func TestLinkChecks_Success(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test LinkChecks Success", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(1, "test.link_checks", mtest.FirstBatch,
+				bson.D{{Key: "short_url", Value: "abc"}, {Key: "status_code", Value: 500}, {Key: "ok", Value: false}}),
+			mtest.CreateCursorResponse(0, "test.link_checks", mtest.NextBatch),
+		)
 		repo := NewMongoDB(mt.Coll)
 
-		// Initially, counter is set to 1.
-		repo.counter = 1
+		err := repo.SaveLinkCheck(context.Background(), &model.LinkCheck{ShortURL: "abc", StatusCode: 500})
+		assert.Nil(t, err)
+
+		checks, err := repo.ListLinkChecks(context.Background(), "", "abc", 5)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.LinkCheck{{ShortURL: "abc", StatusCode: 500}}, checks)
+	})
+}
+
+func TestIncrementCounter_Success(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test IncrementCounter Success", func(mt *mtest.T) {
+		// FindOneAndUpdate returns the counter document after $inc.
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{
+			Key: "value", Value: bson.D{{Key: "_id", Value: "url_shortener_counter"}, {Key: "value", Value: int64(6)}},
+		}))
+		repo := NewMongoDB(mt.Coll)
 
-		// Call the IncrementCounter method.
 		count, err := repo.IncrementCounter()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(6), count)
+	})
+}
 
-		// Assert that the error is returned and count is 0.
+func TestIncrementCounter_Error(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test IncrementCounter Error", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "failed"}))
+		repo := NewMongoDB(mt.Coll)
+
+		count, err := repo.IncrementCounter()
 		assert.NotNil(t, err)
 		assert.Equal(t, uint64(0), count)
 	})
 }
+
+func TestReserveCounters_Success(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test ReserveCounters Success", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{
+			Key: "value", Value: bson.D{{Key: "_id", Value: "url_shortener_counter"}, {Key: "value", Value: int64(3000)}},
+		}))
+		repo := NewMongoDB(mt.Coll)
+
+		ranges, err := repo.ReserveCounters(context.Background(), 1000)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.CounterRange{{First: 2001, Last: 3000}}, ranges)
+	})
+}
+
+func TestCurrentCounter(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test CurrentCounter", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.counters", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "url_shortener_counter"}, {Key: "value", Value: int64(42)}}),
+			mtest.CreateCursorResponse(0, "test.counters", mtest.FirstBatch),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		value, err := repo.CurrentCounter(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(42), value)
+
+		value, err = repo.CurrentCounter(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), value, "missing counter")
+	})
+}
+
+func TestAdvanceCounter(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test AdvanceCounter", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "failed"}),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		assert.Nil(t, repo.AdvanceCounter(context.Background(), 100))
+		assert.NotNil(t, repo.AdvanceCounter(context.Background(), 100))
+	})
+}
+
+func TestMongoWorkerLeases(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test AcquireWorker skips held IDs", func(mt *mtest.T) {
+		last := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(
+			// Worker 0 is held: the upsert hits its _id.
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+			// Worker 1 had expired, its previous document is returned.
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: 1}, {Key: "owner", Value: "old"}, {Key: "last_time", Value: last},
+			}}),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		lease, err := repo.AcquireWorker(context.Background(), "a", 2, time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, lease.WorkerID)
+		assert.Equal(t, "a", lease.Owner)
+		assert.True(t, last.Equal(lease.LastTime))
+	})
+
+	mt.Run("Test AcquireWorker none free", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		_, err := repo.AcquireWorker(context.Background(), "a", 1, time.Minute)
+		assert.ErrorIs(t, err, ErrNoFreeWorker)
+	})
+
+	mt.Run("Test RenewWorker", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+		repo := NewMongoDB(mt.Coll)
+		lease := &model.WorkerLease{WorkerID: 1, Owner: "a", LastTime: time.Now()}
+
+		assert.Nil(t, repo.RenewWorker(context.Background(), lease, time.Minute))
+		assert.ErrorIs(t, repo.RenewWorker(context.Background(), lease, time.Minute), ErrLeaseLost)
+	})
+}
+
+func TestLockWorkspace_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Test LockWorkspace waits for a held lock", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			// Held by another instance: the upsert hits its _id.
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		repo := NewMongoDB(mt.Coll)
+
+		unlock, err := repo.LockWorkspace(context.Background(), "acme", time.Minute)
+		require.NoError(t, err)
+		unlock()
+		mt.GetStartedEvent() // The held lock.
+		locked := mt.GetStartedEvent().Command
+		deleted := mt.GetStartedEvent().Command
+		assert.Equal(t, "delete", deleted.Index(0).Key())
+		// Only the lock taken is released.
+		token := locked.Lookup("updates").Array().Index(0).Value().Document().
+			Lookup("u", "$set", "token").ObjectID()
+		assert.Equal(t, token, deleted.Lookup("deletes").Array().Index(0).Value().Document().
+			Lookup("q", "token").ObjectID())
+	})
+}
+
+func TestHistory_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SaveVersion duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).SaveVersion(context.Background(), &model.URLVersion{ShortURL: "abc", Version: 2})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("ListVersions", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test.url_versions", mtest.FirstBatch,
+				bson.D{{Key: "short_url", Value: "abc"}, {Key: "version", Value: 1}, {Key: "action", Value: "create"}},
+				bson.D{{Key: "short_url", Value: "abc"}, {Key: "version", Value: 2}, {Key: "actor", Value: "eve"}}),
+			mtest.CreateCursorResponse(0, "test.url_versions", mtest.NextBatch),
+		)
+
+		versions, err := NewMongoDB(mt.Coll).ListVersions(context.Background(), "", "abc")
+		assert.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "eve", versions[1].Actor)
+	})
+}
+
+func TestAuditLog_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AppendAudit duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).AppendAudit(context.Background(), &model.AuditEntry{Seq: 2})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("LastAudit empty", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.audit_log", mtest.FirstBatch))
+
+		last, err := NewMongoDB(mt.Coll).LastAudit(context.Background())
+		assert.NoError(t, err)
+		assert.Nil(t, last)
+	})
+
+	mt.Run("ListAudit", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test.audit_log", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: int64(1)}, {Key: "action", Value: "url.create"}, {Key: "hash", Value: "a"}},
+				bson.D{{Key: "_id", Value: int64(2)}, {Key: "actor", Value: "eve"}, {Key: "prev_hash", Value: "a"}}),
+			mtest.CreateCursorResponse(0, "test.audit_log", mtest.NextBatch),
+		)
+
+		entries, err := NewMongoDB(mt.Coll).ListAudit(context.Background(), model.AuditFilter{Limit: 2})
+		assert.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, int64(2), entries[1].Seq)
+		assert.Equal(t, "eve", entries[1].Actor)
+	})
+}
+
+func TestDomains_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SaveDomain duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).SaveDomain(context.Background(), &model.Domain{Name: "go.acme.com"})
+		assert.Equal(t, e.NewConflictError("domain %q is already registered", "go.acme.com"), err)
+	})
+
+	mt.Run("GetDomain not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.domains", mtest.FirstBatch))
+
+		domain, err := NewMongoDB(mt.Coll).GetDomain(context.Background(), "go.acme.com")
+		assert.Nil(t, domain)
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+
+	mt.Run("GetDomain found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.domains", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "go.acme.com"},
+			{Key: "owner", Value: "acme"},
+			{Key: "verified_at", Value: time.Now()},
+		}))
+
+		domain, err := NewMongoDB(mt.Coll).GetDomain(context.Background(), "go.acme.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", domain.Owner)
+		assert.True(t, domain.Verified())
+	})
+
+	mt.Run("UpdateDomain not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		err := NewMongoDB(mt.Coll).UpdateDomain(context.Background(), &model.Domain{Name: "go.acme.com"})
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+
+	mt.Run("ReplacePendingDomain verified", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		err := NewMongoDB(mt.Coll).ReplacePendingDomain(context.Background(), &model.Domain{Name: "go.acme.com"},
+			time.Now())
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+}
+
+func TestWorkspaces_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SaveWorkspace duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).SaveWorkspace(context.Background(), &model.Workspace{ID: "acme"})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("GetAPIKey found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.api_keys", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "k1"},
+			{Key: "workspace_id", Value: "acme"},
+			{Key: "hash", Value: "abc"},
+		}))
+
+		key, err := NewMongoDB(mt.Coll).GetAPIKey(context.Background(), "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", key.Workspace)
+		assert.Equal(t, "abc", key.Hash)
+	})
+
+	mt.Run("SaveMember duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).SaveMember(context.Background(), &model.Member{Workspace: "acme", ID: "ann"})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("GetMember found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.workspace_members", mtest.FirstBatch, bson.D{
+			{Key: "workspace_id", Value: "acme"},
+			{Key: "member_id", Value: "ann"},
+			{Key: "role", Value: "viewer"},
+		}))
+
+		member, err := NewMongoDB(mt.Coll).GetMember(context.Background(), "acme", "ann")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleViewer, member.Role)
+	})
+
+	mt.Run("DeleteMember not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := NewMongoDB(mt.Coll).DeleteMember(context.Background(), "acme", "ann")
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+
+	mt.Run("GetURL scoped to another workspace", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.urls", mtest.FirstBatch))
+
+		_, err := NewMongoDB(mt.Coll).GetURL(tenant.With(context.Background(), "acme"), "", "abc")
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+
+	mt.Run("CountURLs", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test.urls", mtest.FirstBatch, bson.D{{Key: "n", Value: 3}}))
+
+		count, err := NewMongoDB(mt.Coll).CountURLs(context.Background(), "acme", time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+}
+
+func TestWebhooks_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SaveDelivery duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}))
+
+		err := NewMongoDB(mt.Coll).SaveDelivery(context.Background(), &model.WebhookDelivery{ID: "d1"})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("GetWebhook not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.webhooks", mtest.FirstBatch))
+
+		_, err := NewMongoDB(mt.Coll).GetWebhook(context.Background(), "acme", "h1")
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+
+	mt.Run("ClaimDeliveries", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+				{Key: "_id", Value: "d1"},
+				{Key: "webhook_id", Value: "h1"},
+				{Key: "status", Value: "pending"},
+			}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+		)
+
+		claimed, err := NewMongoDB(mt.Coll).ClaimDeliveries(context.Background(), time.Now(), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, "h1", claimed[0].Webhook)
+	})
+
+	mt.Run("DeleteWebhook not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := NewMongoDB(mt.Coll).DeleteWebhook(context.Background(), "acme", "h1")
+		assert.ErrorIs(t, err, e.NotFoundError{})
+	})
+}
+
+func TestOutbox_Mongo(t *testing.T) {
+	t.Parallel()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("SaveURLWithEvent", func(mt *mtest.T) {
+		// The link, its message and the commit.
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		var saved *model.URL
+		err := NewMongoDB(mt.Coll).SaveURLWithEvent(context.Background(), &model.URL{ShortURL: "abc"},
+			func(data *model.URL) (*model.OutboxMessage, error) {
+				saved = data
+				return &model.OutboxMessage{ID: "m1", Key: data.ShortURL}, nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, "abc", saved.ShortURL)
+	})
+
+	mt.Run("SaveURLWithEvent duplicate", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate"}),
+			mtest.CreateSuccessResponse()) // The abort.
+
+		err := NewMongoDB(mt.Coll).SaveURLWithEvent(context.Background(), &model.URL{ShortURL: "abc"},
+			func(*model.URL) (*model.OutboxMessage, error) {
+				t.Error("no message for a link that isn't saved")
+				return nil, nil
+			})
+		assert.ErrorIs(t, err, e.ConflictError{})
+	})
+
+	mt.Run("ClaimOutbox", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+				{Key: "_id", Value: "m1"},
+				{Key: "event_type", Value: "link.created"},
+				{Key: "attempts", Value: 2},
+			}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+		)
+
+		claimed, err := NewMongoDB(mt.Coll).ClaimOutbox(context.Background(), now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, "m1", claimed[0].ID)
+		assert.Equal(t, 2, claimed[0].Attempts)
+	})
+
+	mt.Run("PurgeOutbox", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}))
+
+		n, err := NewMongoDB(mt.Coll).PurgeOutbox(context.Background(), now)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+	})
+}
@@ -1,13 +1,19 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	l "github.com/jasoncheung94/url-shortener/internal/logger"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
@@ -17,7 +23,18 @@ type PostgresRepo struct {
 	db *sqlx.DB
 }
 
-var _ URL = &PostgresRepo{}
+var (
+	_ URL            = &PostgresRepo{}
+	_ LinkChecks     = &PostgresRepo{}
+	_ History        = &PostgresRepo{}
+	_ AuditLog       = &PostgresRepo{}
+	_ DurableCounter = &PostgresRepo{}
+	_ WorkerLeases   = &PostgresRepo{}
+	_ Domains        = &PostgresRepo{}
+	_ Workspaces     = &PostgresRepo{}
+	_ Webhooks       = &PostgresRepo{}
+	_ Outbox         = &PostgresRepo{}
+)
 
 // NewPostgres an instance of PostgresRepo.
 func NewPostgres(db *sqlx.DB) *PostgresRepo {
@@ -26,19 +43,39 @@ func NewPostgres(db *sqlx.DB) *PostgresRepo {
 
 // SaveURL inserts a new URL into the database and returns the ID of the newly created URL.
 func (r *PostgresRepo) SaveURL(ctx context.Context, data *model.URL) error {
+	return insertURL(ctx, r.db, data)
+}
+
+// insertURL inserts the URL with the database or a transaction.
+func insertURL(ctx context.Context, q sqlx.QueryerContext, data *model.URL) error {
+	assignWorkspace(ctx, data)
 	query := `INSERT INTO urls
-	(original_url, short_url, custom_url, expiration_date, created_at)
+	(original_url, short_url, custom_url, expiration_date, utm, forward_query, prefix, redirect_type, title, description,
+	image, created_at, domain, tenant_id, tags, folder, notes, generator)
 	VALUES
-	($1, $2, $3, $4, $5)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 	RETURNING id`
 
 	// Use QueryRow to retrieve the auto-generated ID.
-	err := r.db.QueryRowContext(ctx, query,
+	err := q.QueryRowxContext(ctx, query,
 		data.OriginalURL,
 		data.ShortURL,
 		data.CustomURL,
 		data.ExpirationDate,
+		data.UTM,
+		data.ForwardQuery,
+		data.Prefix,
+		data.RedirectType,
+		data.Title,
+		data.Description,
+		data.Image,
 		data.CreatedAt,
+		data.Domain,
+		data.Workspace,
+		data.Tags,
+		data.Folder,
+		data.Notes,
+		data.Generator,
 	).Scan(&data.ID) // Scanning the returned ID into the data struct
 	if err != nil {
 		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
@@ -50,16 +87,32 @@ func (r *PostgresRepo) SaveURL(ctx context.Context, data *model.URL) error {
 	return nil
 }
 
-// GetURL retrieves a URL record by its short URL.
-func (r *PostgresRepo) GetURL(c context.Context, shortURL string) (*model.URL, error) {
-	query := `SELECT id, original_url, short_url, custom_url, expiration_date, created_at FROM urls WHERE short_url = $1`
+// urlColumns are the columns of the urls table read into model.URL.
+const urlColumns = `id, original_url, short_url, domain, tenant_id, custom_url, expiration_date, utm, forward_query,
+	prefix, redirect_type, title, description, image, broken, tags, folder, notes, generator, created_at, updated_at`
+
+// workspaceCondition limits a query to the workspace of a scoped context, added to args. It's empty for
+// unscoped contexts.
+func workspaceCondition(ctx context.Context, args []any) (string, []any) {
+	workspace, scoped := tenant.From(ctx)
+	if !scoped {
+		return "", args
+	}
+	args = append(args, workspace)
+	return fmt.Sprintf(" AND tenant_id = $%d", len(args)), args
+}
+
+// GetURL retrieves a URL record by its domain and short URL.
+func (r *PostgresRepo) GetURL(c context.Context, domain, shortURL string) (*model.URL, error) {
+	condition, args := workspaceCondition(c, []any{domain, shortURL})
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE domain = $1 AND short_url = $2` + condition
 
 	var data model.URL
 	// Use Get since we expect at most one result (single row).
-	err := r.db.GetContext(c, &data, query, shortURL)
+	err := r.db.GetContext(c, &data, query, args...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("short URL not found")
+			return nil, e.NewNotFoundError("short url '%s' not found", shortURL)
 		}
 		return nil, errors.New("failed to find short URL")
 	}
@@ -67,6 +120,726 @@ func (r *PostgresRepo) GetURL(c context.Context, shortURL string) (*model.URL, e
 	return &data, nil
 }
 
+// UpdateURL updates the editable fields of the URL with the same domain and short URL.
+func (r *PostgresRepo) UpdateURL(ctx context.Context, data *model.URL) error {
+	query := `UPDATE urls SET
+	original_url = $1, expiration_date = $2, utm = $3, forward_query = $4, prefix = $5, redirect_type = $6, title = $7,
+	description = $8, image = $9, broken = $10, tags = $11, folder = $12, notes = $13, updated_at = $14
+	WHERE domain = $15 AND short_url = $16`
+	condition, args := workspaceCondition(ctx, []any{
+		data.OriginalURL,
+		data.ExpirationDate,
+		data.UTM,
+		data.ForwardQuery,
+		data.Prefix,
+		data.RedirectType,
+		data.Title,
+		data.Description,
+		data.Image,
+		data.Broken,
+		data.Tags,
+		data.Folder,
+		data.Notes,
+		data.UpdatedAt,
+		data.Domain,
+		data.ShortURL,
+	})
+
+	result, err := r.db.ExecContext(ctx, query+condition, args...)
+	if err != nil {
+		return errors.New("failed to update url:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("short url '%s' not found", data.ShortURL)
+	}
+	return nil
+}
+
+// DeleteURL deletes the URL with the domain and short URL, its versions and checks are deleted in cascade.
+func (r *PostgresRepo) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	condition, args := workspaceCondition(ctx, []any{domain, shortURL})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM urls WHERE domain = $1 AND short_url = $2`+condition, args...)
+	if err != nil {
+		return errors.New("failed to delete url:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("short url '%s' not found", shortURL)
+	}
+	return nil
+}
+
+// ListURLs returns the URLs matching the filter, oldest first.
+func (r *PostgresRepo) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.Broken != nil {
+		args = append(args, *filter.Broken)
+		conditions = append(conditions, fmt.Sprintf("broken = $%d", len(args)))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.Array(filter.Tags))
+		conditions = append(conditions, fmt.Sprintf("tags @> $%d", len(args)))
+	}
+	if filter.Folder != nil {
+		args = append(args, *filter.Folder)
+		conditions = append(conditions, fmt.Sprintf("folder = $%d", len(args)))
+	}
+	if workspace, scoped := tenant.From(ctx); scoped {
+		args = append(args, workspace)
+		conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM urls`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	urls := []model.URL{}
+	if err := r.db.SelectContext(ctx, &urls, query, args...); err != nil {
+		return nil, errors.New("failed to list urls:" + err.Error())
+	}
+	return urls, nil
+}
+
+// ListTags returns the tags of the links by name, with how many links have each.
+func (r *PostgresRepo) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	query := `SELECT tag, COUNT(*) AS links FROM urls, unnest(tags) AS tag`
+	var args []any
+	if workspace, scoped := tenant.From(ctx); scoped {
+		query += ` WHERE tenant_id = $1`
+		args = append(args, workspace)
+	}
+	query += ` GROUP BY tag ORDER BY tag`
+
+	tags := []model.TagCount{}
+	if err := r.db.SelectContext(ctx, &tags, query, args...); err != nil {
+		return nil, errors.New("failed to list tags:" + err.Error())
+	}
+	return tags, nil
+}
+
+// SaveLinkCheck stores the result of a link health check.
+func (r *PostgresRepo) SaveLinkCheck(ctx context.Context, check *model.LinkCheck) error {
+	query := `INSERT INTO link_checks (domain, short_url, status_code, error, ok, checked_at)
+	VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		check.Domain, check.ShortURL, check.StatusCode, check.Error, check.OK, check.CheckedAt)
+	if err != nil {
+		return errors.New("failed to insert link check:" + err.Error())
+	}
+	return nil
+}
+
+// ListLinkChecks returns the latest health checks of a link, newest first.
+func (r *PostgresRepo) ListLinkChecks(ctx context.Context, domain, shortURL string,
+	limit int) ([]model.LinkCheck, error) {
+	query := `SELECT domain, short_url, status_code, error, ok, checked_at FROM link_checks
+	WHERE domain = $1 AND short_url = $2 ORDER BY checked_at DESC, id DESC LIMIT $3`
+
+	checks := []model.LinkCheck{}
+	if err := r.db.SelectContext(ctx, &checks, query, domain, shortURL, limit); err != nil {
+		return nil, errors.New("failed to list link checks:" + err.Error())
+	}
+	return checks, nil
+}
+
+// ExistingURLs returns which of the short URLs are in the urls table, using its (domain, short_url) index.
+func (r *PostgresRepo) ExistingURLs(ctx context.Context, domain string, shortURLs []string) ([]string, error) {
+	existing := []string{}
+	err := r.db.SelectContext(ctx, &existing, `SELECT short_url FROM urls WHERE domain = $1 AND short_url = ANY($2)`,
+		domain, pq.Array(shortURLs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check urls: %w", err)
+	}
+	return existing, nil
+}
+
+// versionColumns are the columns of the url_versions table read into model.URLVersion.
+const versionColumns = `domain, short_url, version, original_url, expiration_date, utm, forward_query, prefix,
+	redirect_type, actor, action, rollback_of, created_at`
+
+// SaveVersion inserts a version of a link.
+func (r *PostgresRepo) SaveVersion(ctx context.Context, version *model.URLVersion) error {
+	query := `INSERT INTO url_versions (` + versionColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.ExecContext(ctx, query, version.Domain, version.ShortURL, version.Version, version.OriginalURL,
+		version.ExpirationDate, version.UTM, version.ForwardQuery, version.Prefix, version.RedirectType, version.Actor,
+		version.Action, version.RollbackOf, version.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("version %d of %q already exists", version.Version, version.ShortURL)
+		}
+		return errors.New("failed to insert url version:" + err.Error())
+	}
+	return nil
+}
+
+// ListVersions returns the versions of a link, oldest first.
+func (r *PostgresRepo) ListVersions(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	versions := []model.URLVersion{}
+	err := r.db.SelectContext(ctx, &versions, `SELECT `+versionColumns+` FROM url_versions
+	WHERE domain = $1 AND short_url = $2 ORDER BY version`, domain, shortURL)
+	if err != nil {
+		return nil, errors.New("failed to list url versions:" + err.Error())
+	}
+	return versions, nil
+}
+
+// auditColumns are the columns of the audit_log table read into model.AuditEntry.
+const auditColumns = `seq, time, actor, workspace_id, action, resource, ip, request_id, changes, prev_hash, hash`
+
+// AppendAudit inserts an entry of the audit log.
+func (r *PostgresRepo) AppendAudit(ctx context.Context, entry *model.AuditEntry) error {
+	query := `INSERT INTO audit_log (` + auditColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := r.db.ExecContext(ctx, query, entry.Seq, entry.Time, entry.Actor, entry.Workspace, entry.Action,
+		entry.Resource, entry.IP, entry.RequestID, entry.Changes, entry.PrevHash, entry.Hash)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("audit entry %d already exists", entry.Seq)
+		}
+		return errors.New("failed to insert audit entry:" + err.Error())
+	}
+	return nil
+}
+
+// LastAudit returns the entry of the audit log with the highest seq, nil if it's empty.
+func (r *PostgresRepo) LastAudit(ctx context.Context) (*model.AuditEntry, error) {
+	var entry model.AuditEntry
+	err := r.db.GetContext(ctx, &entry, `SELECT `+auditColumns+` FROM audit_log ORDER BY seq DESC LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get last audit entry:" + err.Error())
+	}
+	return &entry, nil
+}
+
+// ListAudit returns the entries of the audit log passing the filter by seq.
+func (r *PostgresRepo) ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	args := []any{filter.AfterSeq}
+	conditions := []string{"seq > $1"}
+	for _, match := range []struct {
+		column, value string
+	}{
+		{"actor", filter.Actor},
+		{"workspace_id", filter.Workspace},
+		{"action", filter.Action},
+		{"resource", filter.Resource},
+	} {
+		if match.value != "" {
+			args = append(args, match.value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", match.column, len(args)))
+		}
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("time >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("time < $%d", len(args)))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY seq`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	entries := []model.AuditEntry{}
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, errors.New("failed to list audit entries:" + err.Error())
+	}
+	return entries, nil
+}
+
+// SaveDomain inserts a new domain.
+func (r *PostgresRepo) SaveDomain(ctx context.Context, domain *model.Domain) error {
+	query := `INSERT INTO domains (name, owner, token, verified_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, domain.Name, domain.Owner, domain.Token, domain.VerifiedAt, domain.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("domain %q is already registered", domain.Name)
+		}
+		return errors.New("failed to insert domain:" + err.Error())
+	}
+	return nil
+}
+
+// domainColumns are the columns of the domains table read into model.Domain.
+const domainColumns = `name, owner, token, verified_at, created_at`
+
+// GetDomain retrieves a domain by name.
+func (r *PostgresRepo) GetDomain(ctx context.Context, name string) (*model.Domain, error) {
+	var domain model.Domain
+	err := r.db.GetContext(ctx, &domain, `SELECT `+domainColumns+` FROM domains WHERE name = $1`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("domain %q not found", name)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find domain:" + err.Error())
+	}
+	return &domain, nil
+}
+
+// UpdateDomain stores the verification time of the domain.
+func (r *PostgresRepo) UpdateDomain(ctx context.Context, domain *model.Domain) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE domains SET verified_at = $1 WHERE name = $2`,
+		domain.VerifiedAt, domain.Name)
+	if err != nil {
+		return errors.New("failed to update domain:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("domain %q not found", domain.Name)
+	}
+	return nil
+}
+
+// ReplacePendingDomain stores the domain in place of an unverified one registered before registeredBefore.
+func (r *PostgresRepo) ReplacePendingDomain(ctx context.Context, domain *model.Domain,
+	registeredBefore time.Time) error {
+	query := `UPDATE domains SET owner = $1, token = $2, verified_at = $3, created_at = $4
+		WHERE name = $5 AND verified_at IS NULL AND created_at < $6`
+
+	result, err := r.db.ExecContext(ctx, query, domain.Owner, domain.Token, domain.VerifiedAt, domain.CreatedAt,
+		domain.Name, registeredBefore)
+	if err != nil {
+		return errors.New("failed to replace domain:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewConflictError("domain %q is already registered", domain.Name)
+	}
+	return nil
+}
+
+// ListDomains returns the owner's domains by name.
+func (r *PostgresRepo) ListDomains(ctx context.Context, owner string) ([]model.Domain, error) {
+	domains := []model.Domain{}
+	err := r.db.SelectContext(ctx, &domains,
+		`SELECT `+domainColumns+` FROM domains WHERE owner = $1 ORDER BY name`, owner)
+	if err != nil {
+		return nil, errors.New("failed to list domains:" + err.Error())
+	}
+	return domains, nil
+}
+
+// SaveWorkspace inserts a new workspace.
+func (r *PostgresRepo) SaveWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	query := `INSERT INTO workspaces (id, name, link_quota, monthly_quota, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, workspace.ID, workspace.Name, workspace.LinkQuota, workspace.MonthlyQuota,
+		workspace.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("workspace %q already exists", workspace.ID)
+		}
+		return errors.New("failed to insert workspace:" + err.Error())
+	}
+	return nil
+}
+
+// GetWorkspace retrieves a workspace by ID.
+func (r *PostgresRepo) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	var workspace model.Workspace
+	err := r.db.GetContext(ctx, &workspace,
+		`SELECT id, name, link_quota, monthly_quota, created_at FROM workspaces WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("workspace %q not found", id)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find workspace:" + err.Error())
+	}
+	return &workspace, nil
+}
+
+// UpdateWorkspace stores the name and quotas of the workspace.
+func (r *PostgresRepo) UpdateWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE workspaces SET name = $1, link_quota = $2, monthly_quota = $3
+	WHERE id = $4`, workspace.Name, workspace.LinkQuota, workspace.MonthlyQuota, workspace.ID)
+	if err != nil {
+		return errors.New("failed to update workspace:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("workspace %q not found", workspace.ID)
+	}
+	return nil
+}
+
+// apiKeyColumns are the columns of the api_keys table read into model.APIKey.
+const apiKeyColumns = `id, workspace_id, member_id, name, hash, created_at`
+
+// SaveAPIKey inserts a new API key.
+func (r *PostgresRepo) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	query := `INSERT INTO api_keys (` + apiKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.Workspace, key.Member, key.Name, key.Hash, key.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("api key %q already exists", key.ID)
+		}
+		return errors.New("failed to insert api key:" + err.Error())
+	}
+	return nil
+}
+
+// GetAPIKey retrieves an API key by ID.
+func (r *PostgresRepo) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.GetContext(ctx, &key, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("api key %q not found", id)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find api key:" + err.Error())
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the keys of the workspace, oldest first.
+func (r *PostgresRepo) ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	err := r.db.SelectContext(ctx, &keys,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE workspace_id = $1 ORDER BY created_at, id`, workspace)
+	if err != nil {
+		return nil, errors.New("failed to list api keys:" + err.Error())
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey deletes the workspace's key.
+func (r *PostgresRepo) DeleteAPIKey(ctx context.Context, workspace, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1 AND workspace_id = $2`, id, workspace)
+	if err != nil {
+		return errors.New("failed to delete api key:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("api key %q not found", id)
+	}
+	return nil
+}
+
+// CountURLs counts the workspace's links created at or after since, using the (tenant_id, created_at) index.
+func (r *PostgresRepo) CountURLs(ctx context.Context, workspace string, since time.Time) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM urls WHERE tenant_id = $1 AND created_at >= $2`,
+		workspace, since)
+	if err != nil {
+		return 0, errors.New("failed to count urls:" + err.Error())
+	}
+	return count, nil
+}
+
+// LockWorkspace takes a transaction level advisory lock on the workspace, held until unlock rolls the
+// transaction back. The database releases it when the connection goes, so ttl isn't needed.
+func (r *PostgresRepo) LockWorkspace(ctx context.Context, workspace string, _ time.Duration) (func(), error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.New("failed to begin transaction:" + err.Error())
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "workspace:"+workspace); err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("failed to lock workspace:" + err.Error())
+	}
+	return func() { _ = tx.Rollback() }, nil
+}
+
+// memberColumns are the columns of the workspace_members table read into model.Member.
+const memberColumns = `workspace_id, member_id, role, created_at`
+
+// SaveMember inserts a new member of a workspace.
+func (r *PostgresRepo) SaveMember(ctx context.Context, member *model.Member) error {
+	query := `INSERT INTO workspace_members (` + memberColumns + `) VALUES ($1, $2, $3, $4)`
+
+	_, err := r.db.ExecContext(ctx, query, member.Workspace, member.ID, member.Role, member.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("%q is already a member of workspace %q", member.ID, member.Workspace)
+		}
+		return errors.New("failed to insert member:" + err.Error())
+	}
+	return nil
+}
+
+// GetMember retrieves a member of a workspace.
+func (r *PostgresRepo) GetMember(ctx context.Context, workspace, id string) (*model.Member, error) {
+	var member model.Member
+	err := r.db.GetContext(ctx, &member,
+		`SELECT `+memberColumns+` FROM workspace_members WHERE workspace_id = $1 AND member_id = $2`, workspace, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("member %q not found", id)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find member:" + err.Error())
+	}
+	return &member, nil
+}
+
+// UpdateMember stores the role of the member.
+func (r *PostgresRepo) UpdateMember(ctx context.Context, member *model.Member) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND member_id = $3`,
+		member.Role, member.Workspace, member.ID)
+	if err != nil {
+		return errors.New("failed to update member:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("member %q not found", member.ID)
+	}
+	return nil
+}
+
+// ListMembers returns the members of the workspace by ID.
+func (r *PostgresRepo) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	members := []model.Member{}
+	err := r.db.SelectContext(ctx, &members,
+		`SELECT `+memberColumns+` FROM workspace_members WHERE workspace_id = $1 ORDER BY member_id`, workspace)
+	if err != nil {
+		return nil, errors.New("failed to list members:" + err.Error())
+	}
+	return members, nil
+}
+
+// DeleteMember removes the member from the workspace.
+func (r *PostgresRepo) DeleteMember(ctx context.Context, workspace, id string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM workspace_members WHERE workspace_id = $1 AND member_id = $2`, workspace, id)
+	if err != nil {
+		return errors.New("failed to delete member:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("member %q not found", id)
+	}
+	return nil
+}
+
+// webhookColumns are the columns of the webhooks table read into model.Webhook.
+const webhookColumns = `id, workspace_id, url, events, secret, created_at`
+
+// SaveWebhook inserts a new webhook.
+func (r *PostgresRepo) SaveWebhook(ctx context.Context, hook *model.Webhook) error {
+	query := `INSERT INTO webhooks (` + webhookColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query, hook.ID, hook.Workspace, hook.URL, hook.Events, hook.Secret, hook.CreatedAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("webhook %q already exists", hook.ID)
+		}
+		return errors.New("failed to insert webhook:" + err.Error())
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook of the workspace.
+func (r *PostgresRepo) GetWebhook(ctx context.Context, workspace, id string) (*model.Webhook, error) {
+	var hook model.Webhook
+	err := r.db.GetContext(ctx, &hook,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND workspace_id = $2`, id, workspace)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("webhook %q not found", id)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find webhook:" + err.Error())
+	}
+	return &hook, nil
+}
+
+// ListWebhooks returns the webhooks of the workspace, oldest first.
+func (r *PostgresRepo) ListWebhooks(ctx context.Context, workspace string) ([]model.Webhook, error) {
+	hooks := []model.Webhook{}
+	err := r.db.SelectContext(ctx, &hooks,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE workspace_id = $1 ORDER BY created_at, id`, workspace)
+	if err != nil {
+		return nil, errors.New("failed to list webhooks:" + err.Error())
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook deletes the workspace's webhook, its deliveries are deleted in cascade.
+func (r *PostgresRepo) DeleteWebhook(ctx context.Context, workspace, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2`, id, workspace)
+	if err != nil {
+		return errors.New("failed to delete webhook:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("webhook %q not found", id)
+	}
+	return nil
+}
+
+// deliveryColumns are the columns of the webhook_deliveries table read into model.WebhookDelivery.
+const deliveryColumns = `id, webhook_id, workspace_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	log, created_at, delivered_at`
+
+// SaveDelivery inserts a new delivery.
+func (r *PostgresRepo) SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (` + deliveryColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	// The payload is passed as text, pq would send a []byte as bytea.
+	_, err := r.db.ExecContext(ctx, query, delivery.ID, delivery.Webhook, delivery.Workspace, delivery.Event,
+		delivery.Type, string(delivery.Payload), delivery.Status, delivery.Attempts, delivery.NextAttemptAt,
+		delivery.Log, delivery.CreatedAt, delivery.DeliveredAt)
+	if err != nil {
+		if pq, ok := err.(*pq.Error); ok && pq.Code == "23505" {
+			return e.NewConflictError("delivery %q already exists", delivery.ID)
+		}
+		return errors.New("failed to insert delivery:" + err.Error())
+	}
+	return nil
+}
+
+// GetDelivery retrieves a delivery of the webhook.
+func (r *PostgresRepo) GetDelivery(ctx context.Context, webhook, id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.GetContext(ctx, &delivery,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2`, id, webhook)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, e.NewNotFoundError("delivery %q not found", id)
+	}
+	if err != nil {
+		return nil, errors.New("failed to find delivery:" + err.Error())
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery stores the status, attempts, next attempt and log of the delivery.
+func (r *PostgresRepo) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET
+	status = $1, attempts = $2, next_attempt_at = $3, log = $4, delivered_at = $5 WHERE id = $6`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.Log, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		return errors.New("failed to update delivery:" + err.Error())
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return e.NewNotFoundError("delivery %q not found", delivery.ID)
+	}
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries of the webhook, newest first.
+func (r *PostgresRepo) ListDeliveries(ctx context.Context, webhook string,
+	limit int) ([]model.WebhookDelivery, error) {
+	deliveries := []model.WebhookDelivery{}
+	err := r.db.SelectContext(ctx, &deliveries, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+	WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, webhook, limit)
+	if err != nil {
+		return nil, errors.New("failed to list deliveries:" + err.Error())
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries leases the pending deliveries due at now in one statement. SKIP LOCKED lets instances
+// claiming at the same time take different deliveries instead of waiting for each other.
+func (r *PostgresRepo) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $1
+	WHERE id IN (
+		SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= $3
+		ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + deliveryColumns
+
+	deliveries := []model.WebhookDelivery{}
+	err := r.db.SelectContext(ctx, &deliveries, query, now.Add(lease), model.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, errors.New("failed to claim deliveries:" + err.Error())
+	}
+	return deliveries, nil
+}
+
+const outboxColumns = `id, event_type, aggregate_key, payload, attempts, next_attempt_at, created_at, published_at`
+
+// outboxRow is a claimed message with its position in the outbox.
+type outboxRow struct {
+	Seq int64 `db:"seq"`
+	model.OutboxMessage
+}
+
+// SaveURLWithEvent inserts the URL and its outbox message in one transaction.
+func (r *PostgresRepo) SaveURLWithEvent(ctx context.Context, data *model.URL,
+	event func(*model.URL) (*model.OutboxMessage, error)) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction:" + err.Error())
+	}
+	defer tx.Rollback() //nolint:errcheck // A no-op once committed.
+
+	if err := insertURL(ctx, tx, data); err != nil {
+		return err
+	}
+	msg, err := event(data)
+	if err != nil {
+		return err
+	}
+	// The payload is passed as text, pq would send a []byte as bytea.
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox (`+outboxColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		msg.ID, msg.Type, msg.Key, string(msg.Payload), msg.Attempts, msg.NextAttemptAt, msg.CreatedAt,
+		msg.PublishedAt)
+	if err != nil {
+		return errors.New("failed to insert outbox message:" + err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit url:" + err.Error())
+	}
+	return nil
+}
+
+// ClaimOutbox leases the unpublished messages due at now in one statement, SKIP LOCKED lets relays claiming at
+// the same time take different messages.
+func (r *PostgresRepo) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration,
+	limit int) ([]model.OutboxMessage, error) {
+	query := `UPDATE outbox SET next_attempt_at = $1, attempts = attempts + 1
+	WHERE seq IN (
+		SELECT seq FROM outbox WHERE published_at IS NULL AND next_attempt_at <= $2
+		ORDER BY seq LIMIT $3 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING seq, ` + outboxColumns
+
+	// RETURNING doesn't keep the order of the subquery.
+	rows := []outboxRow{}
+	if err := r.db.SelectContext(ctx, &rows, query, now.Add(lease), now, limit); err != nil {
+		return nil, errors.New("failed to claim outbox messages:" + err.Error())
+	}
+	slices.SortFunc(rows, func(a, b outboxRow) int { return cmp.Compare(a.Seq, b.Seq) })
+	messages := make([]model.OutboxMessage, len(rows))
+	for i, row := range rows {
+		messages[i] = row.OutboxMessage
+	}
+	return messages, nil
+}
+
+// MarkPublished sets the publication time of the messages.
+func (r *PostgresRepo) MarkPublished(ctx context.Context, ids []string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET published_at = $1 WHERE id = ANY($2)`, at, pq.Array(ids))
+	if err != nil {
+		return errors.New("failed to mark outbox messages published:" + err.Error())
+	}
+	return nil
+}
+
+// PurgeOutbox deletes the messages published before the time.
+func (r *PostgresRepo) PurgeOutbox(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, errors.New("failed to purge outbox:" + err.Error())
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.New("failed to purge outbox:" + err.Error())
+	}
+	return int(n), nil
+}
+
 // IncrementCounter increments the counter and returns it's value.
 func (r *PostgresRepo) IncrementCounter() (uint64, error) {
 	var counter uint64
@@ -77,3 +850,115 @@ func (r *PostgresRepo) IncrementCounter() (uint64, error) {
 	}
 	return counter, nil
 }
+
+// ReserveCounters takes n values of the sequence in one query. Concurrent callers can interleave,
+// so the values may come back as several ranges.
+func (r *PostgresRepo) ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error) {
+	var values []uint64
+	err := r.db.SelectContext(ctx, &values, "SELECT nextval('url_shortener_seq') FROM generate_series(1, $1);", n)
+	if err != nil {
+		l.Logger.Error("failed to reserve counters", "repo", err)
+		return nil, errors.New("failed to retrieve counter")
+	}
+	return counterRanges(values), nil
+}
+
+// CurrentCounter returns the last value of the sequence handed out, 0 if none.
+func (r *PostgresRepo) CurrentCounter(ctx context.Context) (uint64, error) {
+	var counter uint64
+	err := r.db.GetContext(ctx, &counter,
+		"SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END FROM url_shortener_seq;")
+	if err != nil {
+		l.Logger.Error("failed to read counter", "repo", err)
+		return 0, errors.New("failed to retrieve counter")
+	}
+	return counter, nil
+}
+
+// AdvanceCounter moves the sequence forward to value if it's behind. Sequences aren't transactional,
+// so a nextval running at the same moment can still be moved back over; the service retries the
+// conflicts that would cause.
+func (r *PostgresRepo) AdvanceCounter(ctx context.Context, value uint64) error {
+	_, err := r.db.ExecContext(ctx,
+		"SELECT setval('url_shortener_seq', $1) FROM url_shortener_seq WHERE NOT is_called OR last_value < $1;", value)
+	if err != nil {
+		l.Logger.Error("failed to advance counter", "repo", err)
+		return errors.New("failed to advance counter")
+	}
+	return nil
+}
+
+// workerLeaseRow is a worker_leases row, last_time is NULL until the first renewal.
+type workerLeaseRow struct {
+	WorkerID  int          `db:"worker_id"`
+	Owner     string       `db:"owner"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	LastTime  sql.NullTime `db:"last_time"`
+}
+
+// acquireWorkerAttempts is how often AcquireWorker retries when another instance takes the same ID.
+const acquireWorkerAttempts = 3
+
+// AcquireWorker leases the lowest free worker ID. Expiry is checked against the database clock.
+func (r *PostgresRepo) AcquireWorker(ctx context.Context, owner string, workers int,
+	ttl time.Duration) (*model.WorkerLease, error) {
+	query := `INSERT INTO worker_leases (worker_id, owner, expires_at)
+	SELECT id, $1, NOW() + make_interval(secs => $3) FROM generate_series(0, $2 - 1) AS id
+	WHERE NOT EXISTS (SELECT 1 FROM worker_leases w WHERE w.worker_id = id AND w.expires_at > NOW())
+	ORDER BY id LIMIT 1
+	ON CONFLICT (worker_id) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+	WHERE worker_leases.expires_at <= NOW()
+	RETURNING worker_id, owner, expires_at, last_time`
+
+	for range acquireWorkerAttempts {
+		var row workerLeaseRow
+		err := r.db.GetContext(ctx, &row, query, owner, workers, ttl.Seconds())
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // No free ID, or another instance took it first.
+		}
+		if err != nil {
+			l.Logger.Error("failed to acquire worker", "repo", err)
+			return nil, errors.New("failed to acquire worker")
+		}
+		return &model.WorkerLease{
+			WorkerID:  row.WorkerID,
+			Owner:     row.Owner,
+			ExpiresAt: row.ExpiresAt,
+			LastTime:  row.LastTime.Time,
+		}, nil
+	}
+	return nil, ErrNoFreeWorker
+}
+
+// RenewWorker extends the lease while the owner still holds it.
+func (r *PostgresRepo) RenewWorker(ctx context.Context, lease *model.WorkerLease, ttl time.Duration) error {
+	query := `UPDATE worker_leases SET expires_at = NOW() + make_interval(secs => $3), last_time = GREATEST(last_time, $4)
+	WHERE worker_id = $1 AND owner = $2 RETURNING expires_at`
+
+	err := r.db.GetContext(ctx, &lease.ExpiresAt, query,
+		lease.WorkerID, lease.Owner, ttl.Seconds(), nullTime(lease.LastTime))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		l.Logger.Error("failed to renew worker", "repo", err)
+		return errors.New("failed to renew worker")
+	}
+	return nil
+}
+
+// ReleaseWorker expires the lease now.
+func (r *PostgresRepo) ReleaseWorker(ctx context.Context, lease *model.WorkerLease) error {
+	query := `UPDATE worker_leases SET expires_at = NOW(), last_time = GREATEST(last_time, $3)
+	WHERE worker_id = $1 AND owner = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, lease.WorkerID, lease.Owner, nullTime(lease.LastTime)); err != nil {
+		l.Logger.Error("failed to release worker", "repo", err)
+		return errors.New("failed to release worker")
+	}
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"testing"
@@ -11,9 +14,12 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	postm "github.com/golang-migrate/migrate/v4/database/postgres" // golang-migrate postgres driver
 	_ "github.com/golang-migrate/migrate/v4/source/file"           // Import the file driver here
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/ptr"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -105,14 +111,25 @@ func TestIntegrationPostgresRepo_SaveAndGetURL(t *testing.T) {
 	require.NoError(t, err)
 	require.NotZero(t, url.ID)
 
-	result, err := repo.GetURL(context.Background(), "abc123")
+	result, err := repo.GetURL(context.Background(), "", "abc123")
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	require.Equal(t, url.OriginalURL, result.OriginalURL)
 	require.Equal(t, url.ShortURL, result.ShortURL)
-	result, err = repo.GetURL(context.Background(), "fake-doesnt-exist")
+	result, err = repo.GetURL(context.Background(), "", "fake-doesnt-exist")
 	require.Error(t, err)
 	require.Nil(t, result)
+
+	// The same code is free on a custom domain, but only once.
+	branded := &model.URL{OriginalURL: "https://example.org", ShortURL: "abc123", Domain: "go.acme.com",
+		CreatedAt: time.Now()}
+	require.NoError(t, repo.SaveURL(context.Background(), branded))
+	result, err = repo.GetURL(context.Background(), "go.acme.com", "abc123")
+	require.NoError(t, err)
+	require.Equal(t, branded.OriginalURL, result.OriginalURL)
+	err = repo.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.net", ShortURL: "abc123",
+		Domain: "go.acme.com", CreatedAt: time.Now()})
+	require.ErrorIs(t, err, e.ConflictError{})
 }
 
 func TestPostgresSaveURL(t *testing.T) {
@@ -135,12 +152,20 @@ func TestPostgresSaveURL(t *testing.T) {
 		ShortURL:       "short123",
 		CustomURL:      ptr.Of("custom123"),
 		ExpirationDate: ptr.Of(time.Now().Add(24 * time.Hour)),
+		UTM:            &model.UTM{Source: "newsletter"},
+		ForwardQuery:   model.ForwardQueryMerge,
+		Title:          "Example",
+		Tags:           model.Tags{"launch", "summer-sale"},
+		Folder:         "Summer",
+		Generator:      "words",
 		CreatedAt:      time.Now(),
 	}
 
 	// Set up the expected query and mock behavior
 	mock.ExpectQuery(`INSERT INTO urls`).
-		WithArgs(data.OriginalURL, data.ShortURL, data.CustomURL, data.ExpirationDate, data.CreatedAt).
+		WithArgs(data.OriginalURL, data.ShortURL, data.CustomURL, data.ExpirationDate, data.UTM, data.ForwardQuery,
+			data.Prefix, data.RedirectType, data.Title, data.Description, data.Image, data.CreatedAt, data.Domain,
+			data.Workspace, "{\"launch\",\"summer-sale\"}", data.Folder, data.Notes, data.Generator).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	// Call the method
@@ -171,28 +196,63 @@ func TestGetURL(t *testing.T) {
 		ID:             1,
 		OriginalURL:    "https://example.com",
 		ShortURL:       shortURL,
+		Domain:         "go.acme.com",
 		CustomURL:      ptr.Of("custom123"),
 		ExpirationDate: ptr.Of(time.Now().Add(24 * time.Hour)),
+		UTM:            &model.UTM{Source: "newsletter", Campaign: "spring"},
+		ForwardQuery:   model.ForwardQueryOverride,
+		Prefix:         true,
+		RedirectType:   301,
+		Title:          "Example",
+		Description:    "An example page",
+		Image:          "https://example.com/og.png",
+		Tags:           model.Tags{"launch", "summer sale"},
+		Folder:         "Summer",
+		Notes:          "Printed on the flyers",
+		Generator:      "counter",
 		CreatedAt:      time.Now(),
+		UpdatedAt:      ptr.Of(time.Now()),
 	}
 
 	// Set up the expected query and mock behavior
 	mock.ExpectQuery(
-		`SELECT id, original_url, short_url, custom_url, expiration_date, created_at FROM urls WHERE short_url =`,
-	).WithArgs(shortURL).
+		`SELECT id, original_url, short_url, domain, tenant_id, custom_url, expiration_date, utm, forward_query,\s+`+
+			`prefix, redirect_type, title, description, image, broken, tags, folder, notes, generator, created_at, `+
+			`updated_at FROM urls `+
+			`WHERE domain = \$1 AND short_url = \$2`,
+	).WithArgs(expectedURL.Domain, shortURL).
 		WillReturnRows(sqlmock.NewRows(
-			[]string{"id", "original_url", "short_url", "custom_url", "expiration_date", "created_at"},
+			[]string{
+				"id", "original_url", "short_url", "domain", "tenant_id", "custom_url", "expiration_date", "utm",
+				"forward_query", "prefix", "redirect_type", "title", "description", "image", "broken", "tags",
+				"folder", "notes", "generator", "created_at", "updated_at",
+			},
 		).AddRow(
 			expectedURL.ID,
 			expectedURL.OriginalURL,
 			expectedURL.ShortURL,
+			expectedURL.Domain,
+			expectedURL.Workspace,
 			expectedURL.CustomURL,
 			expectedURL.ExpirationDate,
+			[]byte(`{"source":"newsletter","campaign":"spring"}`),
+			string(expectedURL.ForwardQuery),
+			expectedURL.Prefix,
+			expectedURL.RedirectType,
+			expectedURL.Title,
+			expectedURL.Description,
+			expectedURL.Image,
+			expectedURL.Broken,
+			[]byte(`{launch,"summer sale"}`),
+			expectedURL.Folder,
+			expectedURL.Notes,
+			expectedURL.Generator,
 			expectedURL.CreatedAt,
+			expectedURL.UpdatedAt,
 		))
 
 	// Call the method
-	url, err := repo.GetURL(context.Background(), shortURL)
+	url, err := repo.GetURL(context.Background(), expectedURL.Domain, shortURL)
 
 	// Assert the expectations
 	assert.NoError(t, err)
@@ -200,6 +260,329 @@ func TestGetURL(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestPostgresUpdateURL(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	data := model.URL{
+		OriginalURL: "https://example.com",
+		ShortURL:    "short123",
+		Title:       "Example",
+		Image:       "https://example.com/og.png",
+		UpdatedAt:   ptr.Of(time.Now()),
+	}
+
+	mock.ExpectExec(`UPDATE urls SET`).
+		WithArgs(data.OriginalURL, data.ExpirationDate, data.UTM, data.ForwardQuery, data.Prefix, data.RedirectType,
+			data.Title, data.Description, data.Image, data.Broken, "{}", data.Folder, data.Notes, data.UpdatedAt,
+			data.Domain, data.ShortURL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE urls SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UpdateURL(context.Background(), &data))
+	err = repo.UpdateURL(context.Background(), &model.URL{ShortURL: "missing"})
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDeleteURL(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	mock.ExpectExec(`DELETE FROM urls WHERE domain = \$1 AND short_url = \$2 AND tenant_id = \$3`).
+		WithArgs("", "short123", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM urls`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.DeleteURL(tenant.With(context.Background(), "acme"), "", "short123"))
+	err = repo.DeleteURL(context.Background(), "", "missing")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresListURLs(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	columns := []string{
+		"id", "original_url", "short_url", "custom_url", "expiration_date", "utm", "forward_query", "prefix",
+		"redirect_type", "title", "description", "image", "broken", "created_at",
+	}
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`SELECT .+ FROM urls WHERE broken = \$1 ORDER BY id LIMIT \$2 OFFSET \$3`).
+		WithArgs(true, 10, 20).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			1, "https://example.com", "abc", nil, nil, nil, "", false, 0, "", "", "", true, createdAt,
+		))
+	mock.ExpectQuery(`SELECT .+ FROM urls ORDER BY id LIMIT \$1 OFFSET \$2`).
+		WithArgs(5, 0).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(`SELECT .+ FROM urls WHERE tags @> \$1 AND folder = \$2 ORDER BY id LIMIT \$3 OFFSET \$4`).
+		WithArgs(pq.Array([]string{"launch", "promo"}), "", 5, 0).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	urls, err := repo.ListURLs(context.Background(), model.URLFilter{Broken: ptr.Of(true), Limit: 10, Offset: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.URL{
+		{ID: 1, OriginalURL: "https://example.com", ShortURL: "abc", Broken: true, CreatedAt: createdAt},
+	}, urls)
+
+	urls, err = repo.ListURLs(context.Background(), model.URLFilter{Limit: 5})
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	urls, err = repo.ListURLs(context.Background(),
+		model.URLFilter{Tags: []string{"launch", "promo"}, Folder: ptr.Of(""), Limit: 5})
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresListTags(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	mock.ExpectQuery(`SELECT tag, COUNT\(\*\) AS links FROM urls, unnest\(tags\) AS tag WHERE tenant_id = \$1 ` +
+		`GROUP BY tag ORDER BY tag`).
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "links"}).AddRow("launch", 3).AddRow("promo", 1))
+
+	tags, err := repo.ListTags(tenant.With(context.Background(), "acme"))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.TagCount{{Tag: "launch", Links: 3}, {Tag: "promo", Links: 1}}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresTenantScope(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	ctx := tenant.With(context.Background(), "acme")
+	since := time.Now()
+
+	mock.ExpectQuery(`SELECT .+ FROM urls WHERE domain = \$1 AND short_url = \$2 AND tenant_id = \$3`).
+		WithArgs("", "abc", "acme").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT .+ FROM urls WHERE tenant_id = \$1 ORDER BY id LIMIT \$2 OFFSET \$3`).
+		WithArgs("acme", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM urls WHERE tenant_id = \$1 AND created_at >= \$2`).
+		WithArgs("acme", since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	_, err = repo.GetURL(ctx, "", "abc")
+	assert.Error(t, err)
+	urls, err := repo.ListURLs(ctx, model.URLFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+	count, err := repo.CountURLs(ctx, "acme", since)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresLockWorkspace(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs("workspace:acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	unlock, err := repo.LockWorkspace(context.Background(), "acme", time.Minute)
+	require.NoError(t, err)
+	unlock()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresMembers(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	ctx := context.Background()
+	member := &model.Member{Workspace: "acme", ID: "ann", Role: model.RoleEditor, CreatedAt: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO workspace_members`).
+		WithArgs(member.Workspace, member.ID, member.Role, member.CreatedAt).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectQuery(`SELECT workspace_id, member_id, role, created_at FROM workspace_members `+
+		`WHERE workspace_id = \$1 AND member_id = \$2`).
+		WithArgs("acme", "ann").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "member_id", "role", "created_at"}).
+			AddRow("acme", "ann", "editor", member.CreatedAt))
+	mock.ExpectExec(`UPDATE workspace_members SET role = \$1 WHERE workspace_id = \$2 AND member_id = \$3`).
+		WithArgs(model.RoleAdmin, "acme", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM workspace_members WHERE workspace_id = \$1 AND member_id = \$2`).
+		WithArgs("acme", "ann").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.ErrorIs(t, repo.SaveMember(ctx, member), e.ConflictError{})
+	stored, err := repo.GetMember(ctx, "acme", "ann")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleEditor, stored.Role)
+	err = repo.UpdateMember(ctx, &model.Member{Workspace: "acme", ID: "bob", Role: model.RoleAdmin})
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	assert.NoError(t, repo.DeleteMember(ctx, "acme", "ann"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresExistingURLs(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	mock.ExpectQuery(`SELECT short_url FROM urls WHERE domain = \$1 AND short_url = ANY\(\$2\)`).
+		WithArgs("go.acme.com", pq.Array([]string{"abc", "abc2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"short_url"}).AddRow("abc"))
+
+	existing, err := repo.ExistingURLs(context.Background(), "go.acme.com", []string{"abc", "abc2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc"}, existing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresLinkChecks(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	check := model.LinkCheck{ShortURL: "abc", Domain: "go.acme.com", StatusCode: 404, OK: false, CheckedAt: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO link_checks`).
+		WithArgs(check.Domain, check.ShortURL, check.StatusCode, check.Error, check.OK, check.CheckedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT domain, short_url, status_code, error, ok, checked_at FROM link_checks`).
+		WithArgs("go.acme.com", "abc", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"domain", "short_url", "status_code", "error", "ok", "checked_at"}).
+			AddRow(check.Domain, check.ShortURL, check.StatusCode, check.Error, check.OK, check.CheckedAt))
+
+	assert.NoError(t, repo.SaveLinkCheck(context.Background(), &check))
+	checks, err := repo.ListLinkChecks(context.Background(), "go.acme.com", "abc", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.LinkCheck{check}, checks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresHistory(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	version := model.URLVersion{ShortURL: "abc", Version: 2, OriginalURL: "https://example.com", RedirectType: 301,
+		Actor: "eve", Action: model.VersionUpdate, CreatedAt: time.Now()}
+	columns := []string{"domain", "short_url", "version", "original_url", "expiration_date", "utm", "forward_query",
+		"prefix", "redirect_type", "actor", "action", "rollback_of", "created_at"}
+
+	mock.ExpectExec(`INSERT INTO url_versions`).
+		WithArgs(version.Domain, version.ShortURL, version.Version, version.OriginalURL, version.ExpirationDate,
+			version.UTM, version.ForwardQuery, version.Prefix, version.RedirectType, version.Actor, version.Action,
+			version.RollbackOf, version.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO url_versions`).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectQuery(`SELECT .+ FROM url_versions\s+WHERE domain = \$1 AND short_url = \$2 ORDER BY version`).
+		WithArgs("", "abc").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(version.Domain, version.ShortURL, version.Version,
+			version.OriginalURL, nil, nil, "", false, version.RedirectType, version.Actor, version.Action, 0,
+			version.CreatedAt))
+
+	assert.NoError(t, repo.SaveVersion(context.Background(), &version))
+	assert.ErrorIs(t, repo.SaveVersion(context.Background(), &version), e.ConflictError{})
+	versions, err := repo.ListVersions(context.Background(), "", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.URLVersion{version}, versions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresAuditLog(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	entry := model.AuditEntry{Seq: 2, Time: time.Now(), Actor: "eve", Workspace: "acme", Action: model.AuditURLUpdate,
+		Resource: "abc", IP: "203.0.113.9", RequestID: "req-1", PrevHash: "prev", Hash: "hash",
+		Changes: model.AuditChanges{"originalURL": {Before: json.RawMessage(`"https://a.com"`),
+			After: json.RawMessage(`"https://b.com"`)}}}
+	changes, _ := json.Marshal(entry.Changes)
+	columns := []string{"seq", "time", "actor", "workspace_id", "action", "resource", "ip", "request_id", "changes",
+		"prev_hash", "hash"}
+	from := time.Now().Add(-time.Hour)
+
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(entry.Seq, entry.Time, entry.Actor, entry.Workspace, entry.Action, entry.Resource, entry.IP,
+			entry.RequestID, changes, entry.PrevHash, entry.Hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectQuery(`SELECT .+ FROM audit_log ORDER BY seq DESC LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(`SELECT .+ FROM audit_log WHERE seq > \$1 AND actor = \$2 AND action = \$3 AND time >= \$4 `+
+		`ORDER BY seq LIMIT \$5`).
+		WithArgs(int64(1), "eve", model.AuditURLUpdate, from, 10).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(entry.Seq, entry.Time, entry.Actor, entry.Workspace,
+			entry.Action, entry.Resource, entry.IP, entry.RequestID, changes, entry.PrevHash, entry.Hash))
+
+	assert.NoError(t, repo.AppendAudit(context.Background(), &entry))
+	assert.ErrorIs(t, repo.AppendAudit(context.Background(), &entry), e.ConflictError{})
+	last, err := repo.LastAudit(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+	entries, err := repo.ListAudit(context.Background(), model.AuditFilter{Actor: "eve", Action: model.AuditURLUpdate,
+		From: &from, AfterSeq: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.AuditEntry{entry}, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestIncrementCounter(t *testing.T) {
 	t.Parallel()
 	// Create a mock database and a mock sqlx.DB
@@ -225,3 +608,191 @@ func TestIncrementCounter(t *testing.T) {
 	assert.Equal(t, uint64(123), counter)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestPostgresReserveCounters(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+
+	mock.ExpectQuery(`SELECT nextval\('url_shortener_seq'\) FROM generate_series\(1, \$1\);`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(5).AddRow(6).AddRow(9).AddRow(10))
+	mock.ExpectQuery(`SELECT nextval`).WillReturnError(errors.New("db down"))
+
+	ranges, err := repo.ReserveCounters(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CounterRange{{First: 5, Last: 6}, {First: 9, Last: 10}}, ranges)
+
+	_, err = repo.ReserveCounters(context.Background(), 4)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresCurrentCounter(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+
+	mock.ExpectQuery(`SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END FROM url_shortener_seq;`).
+		WillReturnRows(sqlmock.NewRows([]string{"last_value"}).AddRow(41))
+
+	counter, err := repo.CurrentCounter(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(41), counter)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresAdvanceCounter(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+
+	mock.ExpectExec(`SELECT setval\('url_shortener_seq', \$1\) FROM url_shortener_seq WHERE NOT is_called`).
+		WithArgs(500).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT setval`).WillReturnError(errors.New("db down"))
+
+	assert.NoError(t, repo.AdvanceCounter(context.Background(), 500))
+	assert.Error(t, repo.AdvanceCounter(context.Background(), 500))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresWorkerLeases(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	ctx := context.Background()
+	expires := time.Now().Add(time.Minute).UTC()
+	last := time.Now().UTC()
+
+	mock.ExpectQuery(`INSERT INTO worker_leases`).
+		WithArgs("a", 256, 60.0).
+		WillReturnRows(sqlmock.NewRows([]string{"worker_id", "owner", "expires_at", "last_time"}).
+			AddRow(3, "a", expires, last))
+	mock.ExpectQuery(`INSERT INTO worker_leases`).WillReturnRows(sqlmock.NewRows([]string{"worker_id"}))
+	mock.ExpectQuery(`INSERT INTO worker_leases`).WillReturnRows(sqlmock.NewRows([]string{"worker_id"}))
+	mock.ExpectQuery(`INSERT INTO worker_leases`).WillReturnRows(sqlmock.NewRows([]string{"worker_id"}))
+	mock.ExpectQuery(`UPDATE worker_leases SET expires_at = NOW\(\) \+ make_interval`).
+		WithArgs(3, "a", 60.0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at"}).AddRow(expires))
+	mock.ExpectQuery(`UPDATE worker_leases SET expires_at = NOW\(\) \+ make_interval`).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at"}))
+	mock.ExpectExec(`UPDATE worker_leases SET expires_at = NOW\(\), last_time`).
+		WithArgs(3, "a", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	lease, err := repo.AcquireWorker(ctx, "a", 256, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, &model.WorkerLease{WorkerID: 3, Owner: "a", ExpiresAt: expires, LastTime: last}, lease)
+
+	_, err = repo.AcquireWorker(ctx, "b", 256, time.Minute)
+	assert.ErrorIs(t, err, ErrNoFreeWorker)
+
+	assert.NoError(t, repo.RenewWorker(ctx, lease, time.Minute))
+	assert.ErrorIs(t, repo.RenewWorker(ctx, lease, time.Minute), ErrLeaseLost)
+	assert.NoError(t, repo.ReleaseWorker(ctx, lease))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresWebhooks(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO webhooks`).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectQuery(`SELECT .+ FROM webhooks WHERE id = \$1 AND workspace_id = \$2`).WithArgs("h1", "acme").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`UPDATE webhook_deliveries SET next_attempt_at = \$1 .+ FOR UPDATE SKIP LOCKED .+ RETURNING`).
+		WithArgs(now.Add(time.Minute), model.DeliveryPending, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "payload", "status", "log"}).
+			AddRow("d1", "h1", []byte(`{"type":"link.created"}`), "pending", []byte(`[{"statusCode":500}]`)))
+
+	err = repo.SaveWebhook(context.Background(), &model.Webhook{ID: "h1"})
+	assert.ErrorIs(t, err, e.ConflictError{})
+	_, err = repo.GetWebhook(context.Background(), "acme", "h1")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	claimed, err := repo.ClaimDeliveries(context.Background(), now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.JSONEq(t, `{"type":"link.created"}`, string(claimed[0].Payload))
+	assert.Equal(t, 500, claimed[0].Log[0].StatusCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresOutbox(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgres(sqlx.NewDb(db, "postgres"))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(data *model.URL) (*model.OutboxMessage, error) {
+		return &model.OutboxMessage{ID: "m1", Type: "link.created", Key: data.ShortURL, Payload: []byte(`{}`),
+			NextAttemptAt: now, CreatedAt: now}, nil
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO urls`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs("m1", "link.created", "abc", `{}`, 0, now, now, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// A taken short URL rolls back without an outbox message.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO urls`).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	// So does a message that can't be stored.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO urls`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectExec(`INSERT INTO outbox`).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	mock.ExpectQuery(`UPDATE outbox SET next_attempt_at = \$1, attempts = attempts \+ 1 .+ FOR UPDATE SKIP LOCKED`).
+		WithArgs(now.Add(time.Minute), now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "id", "event_type", "payload", "attempts"}).
+			AddRow(2, "m2", "link.created", []byte(`{"id":"m2"}`), 1).
+			AddRow(1, "m1", "link.created", []byte(`{"id":"m1"}`), 3))
+	mock.ExpectExec(`UPDATE outbox SET published_at = \$1 WHERE id = ANY\(\$2\)`).
+		WithArgs(now, pq.Array([]string{"m1", "m2"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM outbox WHERE published_at < \$1`).WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	data := &model.URL{ShortURL: "abc"}
+	require.NoError(t, repo.SaveURLWithEvent(context.Background(), data, event))
+	assert.Equal(t, int64(7), data.ID)
+	err = repo.SaveURLWithEvent(context.Background(), &model.URL{ShortURL: "abc"}, event)
+	assert.ErrorIs(t, err, e.ConflictError{})
+	err = repo.SaveURLWithEvent(context.Background(), &model.URL{ShortURL: "def"}, event)
+	assert.Error(t, err)
+
+	claimed, err := repo.ClaimOutbox(context.Background(), now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "m1", claimed[0].ID, "in outbox order")
+	assert.Equal(t, 3, claimed[0].Attempts)
+	assert.JSONEq(t, `{"id":"m1"}`, string(claimed[0].Payload))
+	require.NoError(t, repo.MarkPublished(context.Background(), []string{"m1", "m2"}, now))
+	n, err := repo.PurgeOutbox(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,15 +2,213 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"time"
 
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
 )
 
 //go:generate mockgen -source=repository.go -destination=../../mocks/mock_repo.go -package=mocks
 
 // URL represents the methods for interacting with URL storage.
+// Short URLs are unique per domain, the empty domain being the default host.
+//
+// With a context scoped by tenant.With, links are saved in that workspace and only its links are found,
+// listed and updated. ExistingURLs sees every workspace, codes are shared by all of them.
 type URL interface {
 	SaveURL(ctx context.Context, data *model.URL) error
-	GetURL(ctx context.Context, shortURL string) (*model.URL, error)
+	GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error)
+	// UpdateURL updates the link with the same domain and short URL.
+	UpdateURL(ctx context.Context, data *model.URL) error
+	// DeleteURL removes the link with the domain and short URL along with its versions and health checks, a
+	// NotFoundError if there's none.
+	DeleteURL(ctx context.Context, domain, shortURL string) error
+	ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error)
+	// ListTags returns the tags of the links by name, with how many links have each.
+	ListTags(ctx context.Context) ([]model.TagCount, error)
+	// ExistingURLs returns which of the short URLs are taken on the domain, in one query and without loading
+	// the links.
+	ExistingURLs(ctx context.Context, domain string, shortURLs []string) ([]string, error)
 	IncrementCounter() (uint64, error)
 }
+
+// LinkChecks represents the methods for storing the health check history of links.
+type LinkChecks interface {
+	SaveLinkCheck(ctx context.Context, check *model.LinkCheck) error
+	// ListLinkChecks returns the latest checks of a link, newest first.
+	ListLinkChecks(ctx context.Context, domain, shortURL string, limit int) ([]model.LinkCheck, error)
+}
+
+// History represents the methods for storing the versions of links. Versions are never changed or removed.
+type History interface {
+	// SaveVersion appends a version of a link, a ConflictError if the link already has a version with its number.
+	SaveVersion(ctx context.Context, version *model.URLVersion) error
+	// ListVersions returns the versions of a link, oldest first.
+	ListVersions(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error)
+}
+
+// AuditLog represents the methods for storing the audit log. Entries are never changed or removed.
+type AuditLog interface {
+	// AppendAudit appends an entry, a ConflictError if there's already one with its Seq.
+	AppendAudit(ctx context.Context, entry *model.AuditEntry) error
+	// LastAudit returns the entry with the highest Seq, nil if the log is empty.
+	LastAudit(ctx context.Context) (*model.AuditEntry, error)
+	// ListAudit returns the entries passing the filter by Seq.
+	ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
+}
+
+// Webhooks represents the methods for storing webhook subscriptions and their delivery queue.
+type Webhooks interface {
+	// SaveWebhook stores a new webhook, a ConflictError if the ID is taken.
+	SaveWebhook(ctx context.Context, hook *model.Webhook) error
+	// GetWebhook returns a NotFoundError if the workspace has no such webhook.
+	GetWebhook(ctx context.Context, workspace, id string) (*model.Webhook, error)
+	// ListWebhooks returns the webhooks of the workspace, oldest first.
+	ListWebhooks(ctx context.Context, workspace string) ([]model.Webhook, error)
+	// DeleteWebhook removes the webhook and its deliveries, a NotFoundError if the workspace has no such webhook.
+	DeleteWebhook(ctx context.Context, workspace, id string) error
+	// SaveDelivery queues a delivery, a ConflictError if there's already one with its ID.
+	SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// GetDelivery returns a NotFoundError if the webhook has no such delivery.
+	GetDelivery(ctx context.Context, webhook, id string) (*model.WebhookDelivery, error)
+	// UpdateDelivery stores the status, attempts, next attempt and log of the delivery.
+	UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ListDeliveries returns the latest deliveries of the webhook, newest first.
+	ListDeliveries(ctx context.Context, webhook string, limit int) ([]model.WebhookDelivery, error)
+	// ClaimDeliveries returns up to limit pending deliveries due at now and moves their next attempt to now +
+	// lease, so other instances don't send them at the same time. A delivery whose sender stops is sent again
+	// once the lease is over.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+}
+
+// Outbox represents the methods for storing events with the change they record, see outbox.Relay.
+type Outbox interface {
+	// SaveURLWithEvent saves the link like URL.SaveURL and, in the same transaction, the message event returns for
+	// the saved link. Neither is stored if either fails.
+	SaveURLWithEvent(ctx context.Context, data *model.URL, event func(*model.URL) (*model.OutboxMessage, error)) error
+	// ClaimOutbox returns up to limit unpublished messages due at now, oldest first, counts the attempt and moves
+	// their next attempt to now + lease, so other relays don't publish them at the same time.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.OutboxMessage, error)
+	// MarkPublished records that the messages were published, they aren't claimed again.
+	MarkPublished(ctx context.Context, ids []string, at time.Time) error
+	// PurgeOutbox deletes the messages published before the time and returns how many were deleted.
+	PurgeOutbox(ctx context.Context, before time.Time) (int, error)
+}
+
+// Domains represents the methods for storing custom domains.
+type Domains interface {
+	// SaveDomain stores a new domain, a ConflictError if it's already registered.
+	SaveDomain(ctx context.Context, domain *model.Domain) error
+	// GetDomain returns a NotFoundError for domains that aren't registered.
+	GetDomain(ctx context.Context, name string) (*model.Domain, error)
+	// UpdateDomain stores the verification time of the domain.
+	UpdateDomain(ctx context.Context, domain *model.Domain) error
+	// ReplacePendingDomain stores the domain in place of an unverified one registered before registeredBefore,
+	// a ConflictError if the stored domain is verified or newer.
+	ReplacePendingDomain(ctx context.Context, domain *model.Domain, registeredBefore time.Time) error
+	// ListDomains returns the owner's domains by name.
+	ListDomains(ctx context.Context, owner string) ([]model.Domain, error)
+}
+
+// Workspaces represents the methods for storing workspaces and their API keys.
+type Workspaces interface {
+	// SaveWorkspace stores a new workspace, a ConflictError if the ID is taken.
+	SaveWorkspace(ctx context.Context, workspace *model.Workspace) error
+	// GetWorkspace returns a NotFoundError for workspaces that don't exist.
+	GetWorkspace(ctx context.Context, id string) (*model.Workspace, error)
+	// UpdateWorkspace stores the name and quotas of the workspace.
+	UpdateWorkspace(ctx context.Context, workspace *model.Workspace) error
+	SaveAPIKey(ctx context.Context, key *model.APIKey) error
+	// GetAPIKey returns a NotFoundError for keys that don't exist.
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	// ListAPIKeys returns the keys of the workspace, oldest first.
+	ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error)
+	// DeleteAPIKey returns a NotFoundError if the workspace has no such key.
+	DeleteAPIKey(ctx context.Context, workspace, id string) error
+	// CountURLs returns how many links of the workspace were created at or after since.
+	CountURLs(ctx context.Context, workspace string, since time.Time) (int, error)
+	// LockWorkspace waits until ctx is done for the workspace's lock, which is shared by every instance using
+	// the store. unlock releases it, the lock of an instance that stopped without unlocking runs out after ttl.
+	LockWorkspace(ctx context.Context, workspace string, ttl time.Duration) (unlock func(), err error)
+	// SaveMember adds a member to the workspace, a ConflictError if it's already one.
+	SaveMember(ctx context.Context, member *model.Member) error
+	// GetMember returns a NotFoundError if the user isn't a member of the workspace.
+	GetMember(ctx context.Context, workspace, id string) (*model.Member, error)
+	// UpdateMember stores the role of the member.
+	UpdateMember(ctx context.Context, member *model.Member) error
+	// ListMembers returns the members of the workspace by ID.
+	ListMembers(ctx context.Context, workspace string) ([]model.Member, error)
+	// DeleteMember returns a NotFoundError if the user isn't a member of the workspace.
+	DeleteMember(ctx context.Context, workspace, id string) error
+}
+
+// CounterBlocks represents storage that can reserve many counter values in one round trip.
+type CounterBlocks interface {
+	// ReserveCounters reserves n counter values that no other caller will get.
+	ReserveCounters(ctx context.Context, n int) ([]model.CounterRange, error)
+}
+
+// DurableCounter represents storage that keeps the counter across restarts, so it can take over
+// from redis and bring redis back up to date.
+type DurableCounter interface {
+	CounterBlocks
+	// CurrentCounter returns the last value handed out, 0 if none.
+	CurrentCounter(ctx context.Context) (uint64, error)
+	// AdvanceCounter moves the counter forward to at least value. It never moves backwards.
+	AdvanceCounter(ctx context.Context, value uint64) error
+}
+
+// Errors of WorkerLeases.
+var (
+	ErrNoFreeWorker = errors.New("repository: all worker IDs are leased")
+	ErrLeaseLost    = errors.New("repository: worker lease lost")
+)
+
+// WorkerLeases represents storage handing out unique worker IDs to running instances.
+type WorkerLeases interface {
+	// AcquireWorker leases the lowest worker ID below workers that's free or whose lease expired.
+	// LastTime of the returned lease is what the previous owner recorded.
+	AcquireWorker(ctx context.Context, owner string, workers int, ttl time.Duration) (*model.WorkerLease, error)
+	// RenewWorker extends the lease by ttl and records its LastTime. It returns ErrLeaseLost when the
+	// lease expired and was taken by another owner.
+	RenewWorker(ctx context.Context, lease *model.WorkerLease, ttl time.Duration) error
+	// ReleaseWorker frees the worker ID, keeping its LastTime for the next owner.
+	ReleaseWorker(ctx context.Context, lease *model.WorkerLease) error
+}
+
+// linkKey identifies a link across domains: the short URL on the default host, domain/short URL otherwise.
+func linkKey(domain, shortURL string) string {
+	if domain == "" {
+		return shortURL
+	}
+	return domain + "/" + shortURL
+}
+
+// assignWorkspace saves the link in the workspace the context is scoped to.
+func assignWorkspace(ctx context.Context, data *model.URL) {
+	if workspace, scoped := tenant.From(ctx); scoped {
+		data.Workspace = workspace
+	}
+}
+
+// visible reports whether the link can be seen with the context, i.e. it's unscoped or the link's workspace.
+func visible(ctx context.Context, data *model.URL) bool {
+	workspace, scoped := tenant.From(ctx)
+	return !scoped || data.Workspace == workspace
+}
+
+// counterRanges sorts values and merges consecutive ones into ranges.
+func counterRanges(values []uint64) []model.CounterRange {
+	slices.Sort(values)
+	var ranges []model.CounterRange
+	for _, v := range values {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].Last+1 == v {
+			ranges[last].Last = v
+			continue
+		}
+		ranges = append(ranges, model.CounterRange{First: v, Last: v})
+	}
+	return ranges
+}
@@ -1,42 +1,259 @@
 package shortener
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
-	"regexp"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/jasoncheung94/url-shortener/internal/audit"
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/events"
 	l "github.com/jasoncheung94/url-shortener/internal/logger"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	v "github.com/jasoncheung94/url-shortener/internal/validator"
 )
 
 //go:generate mockgen -source=service.go -destination=../mocks/mock_service.go -package=mocks
 
 // Service defines the methods for interacting with the URL service.
 // Run go generate ./... to generate mocks or run manually the command.
+//
+// Short URLs are unique per domain, the empty domain being the default host.
 type Service interface {
 	SaveURL(ctx context.Context, data *model.URL) (string, error)
-	GetURL(ctx context.Context, shortURL string) (*model.URL, error)
+	GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error)
+	ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error)
+	// UpdateURL applies the changes of the update that aren't nil and records a version when the destination,
+	// expiry or redirect rules changed.
+	UpdateURL(ctx context.Context, domain, shortURL string, update model.URLUpdate) (*model.URL, error)
+	// DeleteURL removes a link with its history, its short URL can be used again.
+	DeleteURL(ctx context.Context, domain, shortURL string) error
+	// URLHistory returns the versions of a link, oldest first.
+	URLHistory(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error)
+	// RollbackURL restores the destination, expiry and redirect rules of a version as a new version.
+	RollbackURL(ctx context.Context, domain, shortURL string, version int) (*model.URL, error)
+	ListTags(ctx context.Context) ([]model.TagCount, error)
+	// RenameTag renames the tag on every link and returns how many changed. The new name can't be in use.
+	RenameTag(ctx context.Context, tag, to string) (int, error)
+	// MergeTags replaces the tags with into on every link and returns how many changed.
+	MergeTags(ctx context.Context, tags []string, into string) (int, error)
+	AliasAvailability(ctx context.Context, domain, alias string) (*model.AliasAvailability, error)
+	RegisterDomain(ctx context.Context, name, owner string) (*model.Domain, error)
+	VerifyDomain(ctx context.Context, name string) (*model.Domain, error)
+	ListDomains(ctx context.Context, owner string) ([]model.Domain, error)
+	// DomainForHost returns the verified custom domain of a Host header, "" for the default host.
+	DomainForHost(ctx context.Context, host string) string
+	CreateWorkspace(ctx context.Context, workspace *model.Workspace, owner string) (*model.Workspace, error)
+	GetWorkspace(ctx context.Context, id string) (*model.Workspace, error)
+	UpdateWorkspace(ctx context.Context, workspace *model.Workspace) (*model.Workspace, error)
+	WorkspaceUsage(ctx context.Context, id string) (*model.WorkspaceUsage, error)
+	// CreateAPIKey creates a key acting as the member, the caller if empty.
+	CreateAPIKey(ctx context.Context, workspace, member, name string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, workspace, id string) error
+	AddMember(ctx context.Context, member *model.Member) (*model.Member, error)
+	ListMembers(ctx context.Context, workspace string) ([]model.Member, error)
+	UpdateMember(ctx context.Context, member *model.Member) (*model.Member, error)
+	RemoveMember(ctx context.Context, workspace, id string) error
+	// Authenticate returns the workspace member an API key acts as.
+	Authenticate(ctx context.Context, key string) (*model.Member, error)
+	// AuditLog returns the entries of the audit log passing the filter, oldest first.
+	AuditLog(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
+	// VerifyAuditLog checks the hash chain of the whole audit log.
+	VerifyAuditLog(ctx context.Context) (*model.AuditVerification, error)
+	// CreateWebhook sends the link events of the context's workspace to the webhook. The returned secret signs
+	// the requests.
+	CreateWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	// WebhookDeliveries returns the latest deliveries of a webhook, newest first.
+	WebhookDeliveries(ctx context.Context, id string, limit int) ([]model.WebhookDelivery, error)
+	// RedeliverWebhook sends a delivered or dead delivery of a webhook again.
+	RedeliverWebhook(ctx context.Context, id, delivery string) (*model.WebhookDelivery, error)
 }
 
-// NewService returns an instance of Service.
-func NewService(repo repository.URL) Service {
-	return &shortenerService{repo: repo}
+// Page size limits for listing URLs.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ServiceOption configures the service.
+type ServiceOption func(*shortenerService)
+
+// WithMetadataEnricher fetches missing link previews from the destination page after a link is saved.
+func WithMetadataEnricher(enricher *MetadataEnricher) ServiceOption {
+	return func(s *shortenerService) {
+		s.enricher = enricher
+	}
 }
 
-type shortenerService struct {
-	repo repository.URL
+// WithIDObfuscator permutes counters before they're base62 encoded so generated short codes aren't sequential.
+// It applies to the default counter generator, not to one registered with WithCodeGenerator.
+func WithIDObfuscator(obfuscator *IDObfuscator) ServiceOption {
+	return func(s *shortenerService) {
+		s.obfuscator = obfuscator
+	}
+}
+
+// WithCounter sets where the default counter generator gets its values from, e.g. a CounterAllocator.
+// Defaults to the repository.
+func WithCounter(counter Counter) ServiceOption {
+	return func(s *shortenerService) {
+		s.counter = counter
+	}
+}
+
+// WithCodeGenerator registers a generator that links can select by name.
+// The counter generator is always available under GeneratorCounter unless replaced.
+func WithCodeGenerator(name string, generator CodeGenerator) ServiceOption {
+	return func(s *shortenerService) {
+		s.generators[name] = generator
+	}
+}
+
+// WithDefaultCodeGenerator sets the generator used for links that don't select one.
+func WithDefaultCodeGenerator(name string) ServiceOption {
+	return func(s *shortenerService) {
+		s.generator = name
+	}
+}
+
+// WithGeneratorMetrics records code generation attempts and collisions in metrics.
+func WithGeneratorMetrics(metrics *GeneratorMetrics) ServiceOption {
+	return func(s *shortenerService) {
+		s.metrics = metrics
+	}
+}
+
+// WithAliasPolicy checks custom aliases against the policy. Generated codes that are reserved are
+// retried, and with a disjoint policy the default counter generator skips codes in the alias namespace.
+func WithAliasPolicy(policy *AliasPolicy) ServiceOption {
+	return func(s *shortenerService) {
+		s.aliases = policy
+	}
+}
+
+// WithCodeGrammar sets the syntax custom aliases and looked up codes must follow. Defaults to ASCII
+// letters and digits.
+func WithCodeGrammar(grammar CodeGrammar) ServiceOption {
+	return func(s *shortenerService) {
+		s.grammar = grammar
+	}
+}
+
+// WithDomains lets links be created on verified custom domains and served by the Host they're requested on.
+func WithDomains(domains *Domains) ServiceOption {
+	return func(s *shortenerService) {
+		s.domains = domains
+	}
+}
+
+// WithSelfURL sets the public base URL of the app. Links to it are rejected, they would redirect to
+// another short link. Defaults to DefaultBaseURL.
+func WithSelfURL(base *BaseURL) ServiceOption {
+	return func(s *shortenerService) {
+		s.baseURL = base
+	}
+}
+
+// WithWorkspaces lets links, API keys and domains belong to workspaces and keeps workspaces within their quotas.
+func WithWorkspaces(workspaces *Workspaces) ServiceOption {
+	return func(s *shortenerService) {
+		s.workspaces = workspaces
+	}
+}
+
+// WithHistory keeps a version of a link every time its destination, expiry or redirect rules change, so
+// changes can be looked up and rolled back.
+func WithHistory(history repository.History) ServiceOption {
+	return func(s *shortenerService) {
+		s.history = history
+	}
+}
+
+// WithAuditLog records every change made through the service, with who made it and from where.
+func WithAuditLog(log *audit.Log) ServiceOption {
+	return func(s *shortenerService) {
+		s.auditLog = log
+	}
+}
+
+// WithEventPublisher publishes an event every time a link is created, updated or deleted, see events.Type.
+func WithEventPublisher(publisher events.Publisher) ServiceOption {
+	return func(s *shortenerService) {
+		s.events = publisher
+	}
 }
 
-// isValidShortURL ensures the short URL is only alphanumeric
-func isValidShortURL(shortURL string) bool {
-	match, _ := regexp.MatchString(`^[a-zA-Z0-9]{1,10}$`, shortURL)
-	return match
+// WithWebhooks lets workspaces subscribe webhooks to the link events, see WithEventPublisher.
+func WithWebhooks(webhooks *Webhooks) ServiceOption {
+	return func(s *shortenerService) {
+		s.webhooks = webhooks
+	}
+}
+
+// WithOutbox stores the link.created event of a link in the transaction that saves it, for an outbox.Relay to
+// publish, instead of publishing it after the link is saved. The outbox saves links itself, they're cached when
+// first read.
+func WithOutbox(outbox repository.Outbox) ServiceOption {
+	return func(s *shortenerService) {
+		s.outbox = outbox
+	}
+}
+
+// NewService returns an instance of Service.
+func NewService(repo repository.URL, opts ...ServiceOption) Service {
+	s := &shortenerService{
+		repo:       repo,
+		counter:    repo,
+		generators: make(map[string]CodeGenerator),
+		generator:  GeneratorCounter,
+		metrics:    NewGeneratorMetrics(),
+		baseURL:    defaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, ok := s.generators[GeneratorCounter]; !ok {
+		generator := NewCounterGenerator(s.counter, s.obfuscator)
+		if s.aliases != nil {
+			generator.skip = s.aliases.skipCode
+		}
+		s.generators[GeneratorCounter] = generator
+	}
+	return s
+}
+
+// maxGenerateAttempts is how many generated codes are tried before giving up on a link.
+const maxGenerateAttempts = 5
+
+type shortenerService struct {
+	repo       repository.URL
+	enricher   *MetadataEnricher
+	counter    Counter
+	obfuscator *IDObfuscator
+	generators map[string]CodeGenerator
+	generator  string // Name of the default generator.
+	metrics    *GeneratorMetrics
+	aliases    *AliasPolicy
+	grammar    CodeGrammar
+	domains    *Domains
+	baseURL    *BaseURL
+	workspaces *Workspaces
+	history    repository.History
+	auditLog   *audit.Log
+	events     events.Publisher
+	webhooks   *Webhooks
+	outbox     repository.Outbox
 }
 
 // ValidateURL checks if the provided URL is valid and has a proper scheme.
@@ -57,52 +274,894 @@ func ValidateURL(rawURL string) error {
 		return errors.New("URL must contain a valid domain")
 	}
 
-	// Prevent self-referencing redirects
-	if strings.HasPrefix(rawURL, "http://localhost:8080/") { // Change this to your actual domain
-		return errors.New("URL must contain a valid domain")
-	}
-
 	return nil
 }
 
+// selfReferencing reports whether the URL points at the app itself, on the base URL or a verified domain.
+func (s *shortenerService) selfReferencing(ctx context.Context, rawURL string) bool {
+	if s.baseURL.Contains(rawURL) {
+		return true
+	}
+	if s.domains == nil {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	return err == nil && s.baseURL.mounted(u.Path) && s.domains.Resolve(ctx, u.Host) != ""
+}
+
 func (s *shortenerService) SaveURL(ctx context.Context, data *model.URL) (string, error) {
 	err := ValidateURL(data.OriginalURL)
-	if err != nil {
+	if err != nil || s.selfReferencing(ctx, data.OriginalURL) {
 		return "", errors.New("invalid url")
 	}
-
-	counter, err := s.repo.IncrementCounter()
-	if err != nil {
+	if err := normalizeLabels(data); err != nil {
 		return "", err
 	}
 
-	var shortURL string
-	// base62HashedCounter := hashCounter(counter)
-	if data.CustomURL == nil || *data.CustomURL == "" {
-		shortURL = EncodeBase62(counter)
-	} else {
-		shortURL = *data.CustomURL
+	if data.Domain != "" {
+		if s.domains == nil {
+			return "", errNoDomains
+		}
+		if data.Domain, err = s.domains.Verified(ctx, data.Domain); err != nil {
+			return "", fmt.Errorf("shortener/service: failed to create url: %w", err)
+		}
 	}
 
-	log.Println("Hashed url with counter:", data.OriginalURL, counter, shortURL)
-	data.ShortURL = shortURL
-	data.CreatedAt = time.Now().UTC()
+	if workspace, scoped := tenant.From(ctx); scoped && workspace != tenant.Default {
+		if s.workspaces == nil {
+			return "", errNoWorkspaces
+		}
+		if err := authorize(ctx, PermissionEditLinks); err != nil {
+			return "", err
+		}
+		release, err := s.workspaces.admit(ctx, workspace)
+		if err != nil {
+			return "", fmt.Errorf("shortener/service: failed to create url: %w", err)
+		}
+		defer release()
+	}
 
-	err = s.repo.SaveURL(ctx, data)
+	data.CreatedAt = time.Now().UTC()
+	if data.CustomURL == nil || *data.CustomURL == "" {
+		err = s.saveGenerated(ctx, data)
+	} else {
+		err = s.saveAlias(ctx, data)
+	}
 	if err != nil {
 		return "", fmt.Errorf("shortener/service: failed to create url: %w", err)
 	}
-	return shortURL, err
+	shortURL := data.ShortURL
+	s.record(ctx, audit.Change{Action: model.AuditURLCreate, Resource: linkResource(data.Domain, shortURL),
+		Workspace: data.Workspace, After: data})
+	if s.outbox == nil {
+		s.publish(ctx, linkEvent(events.LinkCreated, data, nil))
+	}
+
+	if s.enricher != nil && needsMetadata(data) {
+		s.enricher.Enqueue(data.Domain, shortURL)
+	}
+	return shortURL, nil
+}
+
+// saveAlias saves the link under its custom alias if the alias policy allows it.
+func (s *shortenerService) saveAlias(ctx context.Context, data *model.URL) error {
+	alias := s.grammar.Normalize(*data.CustomURL)
+	if err := s.grammar.ValidateAlias(alias); err != nil {
+		return e.NewBadRequestError("custom url %q %s", alias, err)
+	}
+	if s.aliases != nil {
+		checked, err := s.aliases.Check(alias)
+		if err != nil {
+			return e.NewBadRequestError("custom url %q: %s", alias, err)
+		}
+		alias = checked
+	}
+	data.CustomURL = &alias
+	data.ShortURL = alias
+	data.Generator = ""
+	return s.insertURL(ctx, data)
 }
 
-func (s *shortenerService) GetURL(ctx context.Context, shortURL string) (*model.URL, error) {
-	if !isValidShortURL(shortURL) {
-		return nil, errors.New("invalid url")
+// saveGenerated saves the link under a code from its generator, trying new codes while they're taken.
+func (s *shortenerService) saveGenerated(ctx context.Context, data *model.URL) error {
+	name := cmp.Or(data.Generator, s.generator)
+	generator, ok := s.generators[name]
+	if !ok {
+		return e.NewBadRequestError("unknown code generator %q", name)
 	}
+	counters := s.metrics.counters(name)
+	data.Generator = name // Stored so SeedCounter only decodes counter codes.
+
+	for attempt := range maxGenerateAttempts {
+		code, err := generator.Generate(ctx, data, attempt)
+		if errors.Is(err, ErrGeneratorExhausted) {
+			break
+		}
+		if err != nil {
+			counters.failures.Add(1)
+			return fmt.Errorf("failed to generate code: %w", err)
+		}
+		counters.attempts.Add(1)
 
-	data, err := s.repo.GetURL(ctx, shortURL)
+		if s.aliases != nil && s.aliases.IsReserved(code) {
+			err = e.NewConflictError("%q is reserved", code)
+		} else {
+			data.ShortURL = code
+			err = s.insertURL(ctx, data)
+		}
+		if !errors.Is(err, e.ConflictError{}) {
+			return err
+		}
+		counters.collisions.Add(1)
+		l.Logger.Info("generated short url already exists", "generator", name, "shorturl", code, "attempt", attempt)
+	}
+
+	counters.failures.Add(1)
+	data.ShortURL = ""
+	return e.NewConflictError("no free short url found, try again")
+}
+
+// insertURL saves the link, with its link.created event when there's an outbox.
+func (s *shortenerService) insertURL(ctx context.Context, data *model.URL) error {
+	if s.outbox == nil {
+		return s.repo.SaveURL(ctx, data)
+	}
+	return s.outbox.SaveURLWithEvent(ctx, data, func(saved *model.URL) (*model.OutboxMessage, error) {
+		return outboxMessage(linkEvent(events.LinkCreated, saved, nil))
+	})
+}
+
+func (s *shortenerService) GetURL(ctx context.Context, domain, shortURL string) (*model.URL, error) {
+	shortURL = s.grammar.Normalize(shortURL)
+	if !s.grammar.ValidCode(shortURL) {
+		return nil, e.NewBadRequestError("invalid url")
+	}
+
+	data, err := s.repo.GetURL(ctx, domain, shortURL)
+	// Case insensitive aliases are stored in lower case, so MyLink still finds mylink.
+	if errors.Is(err, e.NotFoundError{}) && s.aliases != nil && s.aliases.CaseInsensitive() {
+		if lower := strings.ToLower(shortURL); lower != shortURL {
+			data, err = s.repo.GetURL(ctx, domain, lower)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("shortener/service: failed to get url: %w", err)
 	}
 	return data, nil
 }
+
+func (s *shortenerService) AliasAvailability(ctx context.Context, domain,
+	alias string) (*model.AliasAvailability, error) {
+	if domain != "" {
+		normalized, err := NormalizeDomain(domain)
+		if err != nil {
+			return nil, e.NewBadRequestError("domain %q: %s", domain, err)
+		}
+		domain = normalized
+	}
+	alias = s.grammar.Normalize(alias)
+	if err := s.grammar.ValidateAlias(alias); err != nil {
+		return nil, e.NewBadRequestError("custom url %q %s", alias, err)
+	}
+
+	result := &model.AliasAvailability{Alias: alias, Status: model.AliasAvailable}
+	if s.aliases != nil {
+		if s.aliases.CaseInsensitive() {
+			result.Alias = strings.ToLower(alias)
+		}
+		_, err := s.aliases.Check(alias)
+		switch {
+		case errors.Is(err, ErrAliasReserved), errors.Is(err, ErrAliasBlocked):
+			result.Status, result.Reason = model.AliasReserved, err.Error()
+		case err != nil:
+			return nil, e.NewBadRequestError("custom url %q: %s", alias, err)
+		}
+	}
+
+	// The alias and every suggestion are checked in one query.
+	var candidates []string
+	for _, candidate := range aliasCandidates(result.Alias) {
+		if s.grammar.ValidateAlias(candidate) != nil {
+			continue
+		}
+		if s.aliases == nil {
+			candidates = append(candidates, candidate)
+		} else if checked, err := s.aliases.Check(candidate); err == nil {
+			candidates = append(candidates, checked)
+		}
+	}
+	existing, err := s.repo.ExistingURLs(ctx, domain, append([]string{result.Alias}, candidates...))
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to check alias: %w", err)
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, shortURL := range existing {
+		taken[shortURL] = true
+	}
+
+	if result.Status == model.AliasAvailable && taken[result.Alias] {
+		result.Status = model.AliasTaken
+	}
+	if result.Status == model.AliasAvailable {
+		return result, nil
+	}
+	for _, candidate := range candidates {
+		if !taken[candidate] && !slices.Contains(result.Suggestions, candidate) {
+			result.Suggestions = append(result.Suggestions, candidate)
+			if len(result.Suggestions) == maxAliasSuggestions {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *shortenerService) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	filter.Limit = min(filter.Limit, MaxListLimit)
+	filter.Offset = max(filter.Offset, 0)
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+
+	urls, err := s.repo.ListURLs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list urls: %w", err)
+	}
+	return urls, nil
+}
+
+func (s *shortenerService) UpdateURL(ctx context.Context, domain, shortURL string,
+	update model.URLUpdate) (*model.URL, error) {
+	if err := authorize(ctx, PermissionEditLinks); err != nil {
+		return nil, err
+	}
+	data, err := s.GetURL(ctx, domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	before, previous := versionOf(data), snapshot(data)
+	if err := s.applyUpdate(ctx, data, update); err != nil {
+		return nil, err
+	}
+	if err := s.saveVersion(ctx, before, data, model.VersionUpdate, 0); err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(previous, data) {
+		touch(data)
+	}
+	if err := s.repo.UpdateURL(ctx, data); err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to update url: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditURLUpdate, Resource: linkResource(data.Domain, data.ShortURL),
+		Workspace: data.Workspace, Before: previous, After: data})
+	s.publish(ctx, linkEvent(events.LinkUpdated, data, previous))
+	return data, nil
+}
+
+// touch sets the time the link changed to now, before it's stored.
+func touch(data *model.URL) {
+	now := time.Now().UTC()
+	data.UpdatedAt = &now
+}
+
+func (s *shortenerService) DeleteURL(ctx context.Context, domain, shortURL string) error {
+	if err := authorize(ctx, PermissionEditLinks); err != nil {
+		return err
+	}
+	data, err := s.GetURL(ctx, domain, shortURL)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteURL(ctx, data.Domain, data.ShortURL); err != nil {
+		return fmt.Errorf("shortener/service: failed to delete url: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditURLDelete, Resource: linkResource(data.Domain, data.ShortURL),
+		Workspace: data.Workspace, Before: data})
+	s.publish(ctx, linkEvent(events.LinkDeleted, data, nil))
+	return nil
+}
+
+// applyUpdate validates the changes of the update and applies them to the link.
+func (s *shortenerService) applyUpdate(ctx context.Context, data *model.URL, update model.URLUpdate) error {
+	if update.OriginalURL != nil {
+		if ValidateURL(*update.OriginalURL) != nil || s.selfReferencing(ctx, *update.OriginalURL) {
+			return e.NewBadRequestError("invalid url")
+		}
+		data.OriginalURL = *update.OriginalURL
+	}
+	switch {
+	case update.NeverExpires:
+		data.ExpirationDate = nil
+	case update.ExpirationDate != nil:
+		data.ExpirationDate = update.ExpirationDate
+	}
+	if update.UTM != nil {
+		if err := v.Validate.Struct(update.UTM); err != nil {
+			return e.NewBadRequestError("utm values can't be longer than 100 characters")
+		}
+		data.UTM = update.UTM
+		if *update.UTM == (model.UTM{}) {
+			data.UTM = nil
+		}
+	}
+	if update.ForwardQuery != nil {
+		switch *update.ForwardQuery {
+		case "", model.ForwardQueryNone, model.ForwardQueryMerge, model.ForwardQueryOverride:
+			data.ForwardQuery = *update.ForwardQuery
+		default:
+			return e.NewBadRequestError("forward query must be none, merge or override")
+		}
+	}
+	if update.Prefix != nil {
+		data.Prefix = *update.Prefix
+	}
+	if update.RedirectType != nil {
+		if *update.RedirectType != 0 && !isRedirectStatus(*update.RedirectType) {
+			return e.NewBadRequestError("redirect type must be 301, 302, 307 or 308, 0 for the default")
+		}
+		data.RedirectType = *update.RedirectType
+	}
+	if update.Tags != nil {
+		data.Tags = *update.Tags
+	}
+	if update.Folder != nil {
+		data.Folder = *update.Folder
+	}
+	if update.Notes != nil {
+		data.Notes = *update.Notes
+	}
+	return normalizeLabels(data)
+}
+
+// errNoHistory is returned by the history methods when the service has no History.
+var errNoHistory = e.NewBadRequestError("link history isn't supported")
+
+func (s *shortenerService) URLHistory(ctx context.Context, domain, shortURL string) ([]model.URLVersion, error) {
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	if s.history == nil {
+		return nil, errNoHistory
+	}
+	data, err := s.GetURL(ctx, domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.versions(ctx, data)
+}
+
+func (s *shortenerService) RollbackURL(ctx context.Context, domain, shortURL string, version int) (*model.URL, error) {
+	if err := authorize(ctx, PermissionEditLinks); err != nil {
+		return nil, err
+	}
+	if s.history == nil {
+		return nil, errNoHistory
+	}
+	data, err := s.GetURL(ctx, domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.versions(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(versions, func(v model.URLVersion) bool { return v.Version == version })
+	if i < 0 {
+		return nil, e.NewNotFoundError("version %d of %q not found", version, data.ShortURL)
+	}
+
+	before, previous := versionOf(data), snapshot(data)
+	restoreVersion(data, versions[i])
+	if err := s.saveVersion(ctx, before, data, model.VersionRollback, version); err != nil {
+		return nil, err
+	}
+	touch(data)
+	if err := s.repo.UpdateURL(ctx, data); err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to roll back url: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditURLRollback, Resource: linkResource(data.Domain, data.ShortURL),
+		Workspace: data.Workspace, Before: previous, After: data})
+	s.publish(ctx, linkEvent(events.LinkUpdated, data, previous))
+	return data, nil
+}
+
+// versions returns the stored versions of the link. Links that never changed have none stored, their only
+// version is the link itself.
+func (s *shortenerService) versions(ctx context.Context, data *model.URL) ([]model.URLVersion, error) {
+	versions, err := s.history.ListVersions(ctx, data.Domain, data.ShortURL)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list url versions: %w", err)
+	}
+	if len(versions) == 0 {
+		versions = append(versions, firstVersion(data))
+	}
+	return versions, nil
+}
+
+// saveVersion records the link as a new version if its destination, expiry or redirect rules changed from
+// before. The first change also records the link as it was before as version 1. Versions are saved before the
+// link, so a concurrent change of the same link is a ConflictError instead of a lost version.
+func (s *shortenerService) saveVersion(ctx context.Context, before model.URLVersion, data *model.URL,
+	action string, rollbackOf int) error {
+	after := versionOf(data)
+	if s.history == nil || sameVersion(before, after) {
+		return nil
+	}
+	versions, err := s.history.ListVersions(ctx, data.Domain, data.ShortURL)
+	if err != nil {
+		return fmt.Errorf("shortener/service: failed to list url versions: %w", err)
+	}
+	if len(versions) == 0 {
+		before.Version, before.Action, before.CreatedAt = 1, model.VersionCreate, data.CreatedAt
+		if err := s.history.SaveVersion(ctx, &before); err != nil {
+			return versionError(data, err)
+		}
+		versions = append(versions, before)
+	}
+
+	after.Version = versions[len(versions)-1].Version + 1
+	after.Action, after.RollbackOf = action, rollbackOf
+	after.CreatedAt = time.Now().UTC()
+	if member, ok := memberFrom(ctx); ok {
+		after.Actor = member.ID
+	}
+	if err := s.history.SaveVersion(ctx, &after); err != nil {
+		return versionError(data, err)
+	}
+	return nil
+}
+
+func versionError(data *model.URL, err error) error {
+	if errors.Is(err, e.ConflictError{}) {
+		return e.NewConflictError("%q was changed at the same time, try again", data.ShortURL)
+	}
+	return fmt.Errorf("shortener/service: failed to save url version: %w", err)
+}
+
+func (s *shortenerService) ListTags(ctx context.Context) ([]model.TagCount, error) {
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	tags, err := s.repo.ListTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *shortenerService) RenameTag(ctx context.Context, tag, to string) (int, error) {
+	if err := authorize(ctx, PermissionEditLinks); err != nil {
+		return 0, err
+	}
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return 0, err
+	}
+	if to, err = normalizeTag(to); err != nil {
+		return 0, err
+	}
+	if tag == to {
+		return 0, e.NewBadRequestError("tag %q already has that name", tag)
+	}
+
+	for _, check := range []struct {
+		tag  string
+		used bool
+	}{{tag, true}, {to, false}} {
+		links, err := s.repo.ListURLs(ctx, model.URLFilter{Tags: []string{check.tag}, Limit: 1})
+		switch {
+		case err != nil:
+			return 0, fmt.Errorf("shortener/service: failed to rename tag: %w", err)
+		case check.used && len(links) == 0:
+			return 0, e.NewNotFoundError("no link is tagged %q", tag)
+		case !check.used && len(links) > 0:
+			return 0, e.NewConflictError("tag %q is already used, merge the tags instead", to)
+		}
+	}
+	return s.replaceTags(ctx, []string{tag}, to)
+}
+
+func (s *shortenerService) MergeTags(ctx context.Context, tags []string, into string) (int, error) {
+	if err := authorize(ctx, PermissionEditLinks); err != nil {
+		return 0, err
+	}
+	if len(tags) == 0 {
+		return 0, e.NewBadRequestError("tags to merge are required")
+	}
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	if into, err = normalizeTag(into); err != nil {
+		return 0, err
+	}
+	return s.replaceTags(ctx, normalized, into)
+}
+
+// replaceTags replaces the tags with into on every link and returns how many links changed. Links are
+// updated one by one through the repository, so cached copies are dropped too.
+func (s *shortenerService) replaceTags(ctx context.Context, tags []string, into string) (int, error) {
+	changed := map[string]bool{}
+	for _, tag := range tags {
+		if tag == into {
+			continue
+		}
+		for {
+			// Updated links lose the tag, so the first page always has the ones left.
+			links, err := s.repo.ListURLs(ctx, model.URLFilter{Tags: []string{tag}, Limit: MaxListLimit})
+			if err != nil {
+				return len(changed), fmt.Errorf("shortener/service: failed to replace tag: %w", err)
+			}
+			if len(links) == 0 {
+				break
+			}
+			for _, data := range links {
+				previous := snapshot(&data)
+				data.Tags = replaceTag(data.Tags, tag, into)
+				touch(&data)
+				if err := s.repo.UpdateURL(ctx, &data); err != nil {
+					return len(changed), fmt.Errorf("shortener/service: failed to replace tag: %w", err)
+				}
+				s.record(ctx, audit.Change{Action: model.AuditURLUpdate, Resource: linkResource(data.Domain,
+					data.ShortURL), Workspace: data.Workspace, Before: previous, After: &data})
+				s.publish(ctx, linkEvent(events.LinkUpdated, &data, previous))
+				changed[data.Domain+"/"+data.ShortURL] = true
+			}
+		}
+	}
+	return len(changed), nil
+}
+
+// errNoDomains is returned by the domain methods when the service has no Domains.
+var errNoDomains = e.NewBadRequestError("custom domains aren't supported")
+
+func (s *shortenerService) RegisterDomain(ctx context.Context, name, owner string) (*model.Domain, error) {
+	if s.domains == nil {
+		return nil, errNoDomains
+	}
+	if err := authorize(ctx, PermissionManageDomains); err != nil {
+		return nil, err
+	}
+	domain, err := s.domains.Register(ctx, name, owner)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to register domain: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditDomainCreate, Resource: domain.Name, After: domain})
+	return domain, nil
+}
+
+func (s *shortenerService) VerifyDomain(ctx context.Context, name string) (*model.Domain, error) {
+	if s.domains == nil {
+		return nil, errNoDomains
+	}
+	if err := authorize(ctx, PermissionManageDomains); err != nil {
+		return nil, err
+	}
+	var before *model.Domain
+	if normalized, err := NormalizeDomain(name); err == nil {
+		before, _ = s.domains.repo.GetDomain(ctx, normalized)
+	}
+	domain, err := s.domains.Verify(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to verify domain: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditDomainVerify, Resource: domain.Name, Before: before, After: domain})
+	return domain, nil
+}
+
+func (s *shortenerService) ListDomains(ctx context.Context, owner string) ([]model.Domain, error) {
+	if s.domains == nil {
+		return nil, errNoDomains
+	}
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	domains, err := s.domains.List(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list domains: %w", err)
+	}
+	return domains, nil
+}
+
+func (s *shortenerService) DomainForHost(ctx context.Context, host string) string {
+	if s.domains == nil {
+		return ""
+	}
+	return s.domains.Resolve(ctx, host)
+}
+
+// errNoWorkspaces is returned by the workspace methods when the service has no Workspaces.
+var errNoWorkspaces = e.NewBadRequestError("workspaces aren't supported")
+
+func (s *shortenerService) CreateWorkspace(ctx context.Context, workspace *model.Workspace,
+	owner string) (*model.Workspace, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	created, err := s.workspaces.Create(ctx, workspace, owner)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to create workspace: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditWorkspaceCreate, Resource: created.ID, Workspace: created.ID,
+		After: created})
+	s.record(ctx, audit.Change{Action: model.AuditMemberCreate, Resource: owner, Workspace: created.ID,
+		After: model.Member{Workspace: created.ID, ID: owner, Role: model.RoleOwner, CreatedAt: created.CreatedAt}})
+	return created, nil
+}
+
+func (s *shortenerService) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	workspace, err := s.workspaces.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to get workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+func (s *shortenerService) UpdateWorkspace(ctx context.Context, workspace *model.Workspace) (*model.Workspace, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	before, _ := s.workspaces.Get(ctx, workspace.ID)
+	updated, err := s.workspaces.Update(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to update workspace: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditWorkspaceUpdate, Resource: updated.ID, Workspace: updated.ID,
+		Before: before, After: updated})
+	return updated, nil
+}
+
+func (s *shortenerService) WorkspaceUsage(ctx context.Context, id string) (*model.WorkspaceUsage, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	usage, err := s.workspaces.Usage(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to get workspace usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (s *shortenerService) CreateAPIKey(ctx context.Context, workspace, member, name string) (*model.APIKey, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageKeys); err != nil {
+		return nil, err
+	}
+	key, err := s.workspaces.CreateKey(ctx, workspace, member, name)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to create api key: %w", err)
+	}
+	issued := *key
+	issued.Key = "" // Never log the secret.
+	s.record(ctx, audit.Change{Action: model.AuditKeyCreate, Resource: key.ID, Workspace: workspace, After: issued})
+	return key, nil
+}
+
+func (s *shortenerService) ListAPIKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageKeys); err != nil {
+		return nil, err
+	}
+	keys, err := s.workspaces.ListKeys(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *shortenerService) DeleteAPIKey(ctx context.Context, workspace, id string) error {
+	if s.workspaces == nil {
+		return errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageKeys); err != nil {
+		return err
+	}
+	before, _ := s.workspaces.repo.GetAPIKey(ctx, id)
+	if err := s.workspaces.DeleteKey(ctx, workspace, id); err != nil {
+		return fmt.Errorf("shortener/service: failed to delete api key: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditKeyDelete, Resource: id, Workspace: workspace, Before: before})
+	return nil
+}
+
+func (s *shortenerService) AddMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageMembers); err != nil {
+		return nil, err
+	}
+	added, err := s.workspaces.AddMember(ctx, member)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to add member: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditMemberCreate, Resource: added.ID, Workspace: added.Workspace,
+		After: added})
+	return added, nil
+}
+
+func (s *shortenerService) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionViewLinks); err != nil {
+		return nil, err
+	}
+	members, err := s.workspaces.ListMembers(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list members: %w", err)
+	}
+	return members, nil
+}
+
+func (s *shortenerService) UpdateMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	if s.workspaces == nil {
+		return nil, errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageMembers); err != nil {
+		return nil, err
+	}
+	before, _ := s.workspaces.repo.GetMember(ctx, member.Workspace, member.ID)
+	updated, err := s.workspaces.UpdateMember(ctx, member)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to update member: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditMemberUpdate, Resource: updated.ID, Workspace: updated.Workspace,
+		Before: before, After: updated})
+	return updated, nil
+}
+
+func (s *shortenerService) RemoveMember(ctx context.Context, workspace, id string) error {
+	if s.workspaces == nil {
+		return errNoWorkspaces
+	}
+	if err := authorize(ctx, PermissionManageMembers); err != nil {
+		return err
+	}
+	before, _ := s.workspaces.repo.GetMember(ctx, workspace, id)
+	if err := s.workspaces.RemoveMember(ctx, workspace, id); err != nil {
+		return fmt.Errorf("shortener/service: failed to remove member: %w", err)
+	}
+	s.record(ctx, audit.Change{Action: model.AuditMemberDelete, Resource: id, Workspace: workspace, Before: before})
+	return nil
+}
+
+func (s *shortenerService) Authenticate(ctx context.Context, key string) (*model.Member, error) {
+	if s.workspaces == nil {
+		return nil, e.NewUnauthorizedError("api keys aren't supported")
+	}
+	return s.workspaces.Authenticate(ctx, key)
+}
+
+// errNoAuditLog is returned by the audit log methods when the service has no audit log.
+var errNoAuditLog = e.NewBadRequestError("the audit log isn't enabled")
+
+func (s *shortenerService) AuditLog(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	if s.auditLog == nil {
+		return nil, errNoAuditLog
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	filter.Limit = min(filter.Limit, MaxListLimit)
+	entries, err := s.auditLog.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *shortenerService) VerifyAuditLog(ctx context.Context) (*model.AuditVerification, error) {
+	if s.auditLog == nil {
+		return nil, errNoAuditLog
+	}
+	verification, err := s.auditLog.Verify(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to verify audit log: %w", err)
+	}
+	return verification, nil
+}
+
+func (s *shortenerService) CreateWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error) {
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+	if err := authorize(ctx, PermissionManageHooks); err != nil {
+		return nil, err
+	}
+	workspace, _ := tenant.From(ctx)
+	created, err := s.webhooks.Create(ctx, workspace, hook)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to create webhook: %w", err)
+	}
+	logged := *created
+	logged.Secret = "" // Never log the secret.
+	s.record(ctx, audit.Change{Action: model.AuditWebhookCreate, Resource: created.ID, Workspace: workspace,
+		After: logged})
+	return created, nil
+}
+
+func (s *shortenerService) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+	if err := authorize(ctx, PermissionManageHooks); err != nil {
+		return nil, err
+	}
+	workspace, _ := tenant.From(ctx)
+	hooks, err := s.webhooks.List(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+func (s *shortenerService) DeleteWebhook(ctx context.Context, id string) error {
+	if s.webhooks == nil {
+		return errNoWebhooks
+	}
+	if err := authorize(ctx, PermissionManageHooks); err != nil {
+		return err
+	}
+	workspace, _ := tenant.From(ctx)
+	before, _ := s.webhooks.repo.GetWebhook(ctx, workspace, id)
+	if err := s.webhooks.Delete(ctx, workspace, id); err != nil {
+		return fmt.Errorf("shortener/service: failed to delete webhook: %w", err)
+	}
+	if before != nil {
+		before.Secret = ""
+	}
+	s.record(ctx, audit.Change{Action: model.AuditWebhookDelete, Resource: id, Workspace: workspace, Before: before})
+	return nil
+}
+
+func (s *shortenerService) WebhookDeliveries(ctx context.Context, id string, limit int) ([]model.WebhookDelivery,
+	error) {
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+	if err := authorize(ctx, PermissionManageHooks); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	workspace, _ := tenant.From(ctx)
+	deliveries, err := s.webhooks.Deliveries(ctx, workspace, id, min(limit, MaxListLimit))
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *shortenerService) RedeliverWebhook(ctx context.Context, id, delivery string) (*model.WebhookDelivery,
+	error) {
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+	if err := authorize(ctx, PermissionManageHooks); err != nil {
+		return nil, err
+	}
+	workspace, _ := tenant.From(ctx)
+	queued, err := s.webhooks.Redeliver(ctx, workspace, id, delivery)
+	if err != nil {
+		return nil, fmt.Errorf("shortener/service: failed to redeliver webhook: %w", err)
+	}
+	return queued, nil
+}
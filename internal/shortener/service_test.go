@@ -5,13 +5,17 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
 	"github.com/jasoncheung94/url-shortener/internal/logger"
 	"github.com/jasoncheung94/url-shortener/internal/mocks"
 	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/validator"
 	"go.uber.org/mock/gomock"
 )
 
@@ -20,6 +24,10 @@ import (
 func TestMain(m *testing.M) {
 	// Set up test logger
 	logger.Logger = slog.New(slog.NewTextHandler(os.Stdout, nil)) // io.Discard
+	// The alias tag of model.URL needs the code grammar.
+	if err := (CodeGrammar{}).RegisterValidation(validator.Validate); err != nil {
+		panic(err)
+	}
 
 	// Run all tests
 	code := m.Run()
@@ -52,6 +60,19 @@ func TestShortenerService_SaveURL(t *testing.T) {
 			mockBehavior:  func(m *mocks.MockURL) {}, // No calls expected
 			expectedError: errors.New("invalid url"),
 		},
+		{
+			name: "Counter collision retries with fresh counter",
+			data: model.URL{OriginalURL: "https://example.com"},
+			mockBehavior: func(m *mocks.MockURL) {
+				gomock.InOrder(
+					m.EXPECT().IncrementCounter().Return(uint64(1), nil),
+					m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(e.NewConflictError("short url already exists")),
+					m.EXPECT().IncrementCounter().Return(uint64(100), nil),
+					m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil),
+				)
+			},
+			expected: model.URL{ShortURL: "1C"},
+		},
 		{
 			name: "Repository Error",
 			data: model.URL{OriginalURL: "https://example.com"},
@@ -86,6 +107,309 @@ func TestShortenerService_SaveURL(t *testing.T) {
 	}
 }
 
+func TestShortenerService_SaveURLObfuscated(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	obfuscator, err := NewIDObfuscator([]byte("secret"), 36)
+	require.NoError(t, err)
+
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockRepo.EXPECT().IncrementCounter().Return(uint64(100), nil)
+	mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewService(mockRepo, WithIDObfuscator(obfuscator))
+	shortURL, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com"})
+	require.NoError(t, err)
+	assert.NotEqual(t, "1C", shortURL)
+	assert.True(t, isGeneratedCode(shortURL))
+
+	id, err := DecodeBase62(shortURL)
+	require.NoError(t, err)
+	counter, err := obfuscator.Decode(id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), counter)
+}
+
+// fixedGenerator returns its codes in order, one per attempt.
+type fixedGenerator []string
+
+func (g fixedGenerator) Generate(_ context.Context, _ *model.URL, attempt int) (string, error) {
+	if attempt >= len(g) {
+		return "", ErrGeneratorExhausted
+	}
+	return g[attempt], nil
+}
+
+func TestShortenerService_SaveURLGenerators(t *testing.T) {
+	t.Parallel()
+	conflict := e.NewConflictError("short url already exists")
+
+	tests := []struct {
+		name          string
+		generator     string
+		codes         fixedGenerator
+		mockBehavior  func(m *mocks.MockURL)
+		expected      string
+		expectedError error
+		expectedStats GeneratorStats
+	}{
+		{
+			name:      "Selected per request",
+			generator: "fixed",
+			codes:     fixedGenerator{"abc"},
+			mockBehavior: func(m *mocks.MockURL) {
+				m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expected:      "abc",
+			expectedStats: GeneratorStats{Attempts: 1},
+		},
+		{
+			name:      "Retries collisions",
+			generator: "fixed",
+			codes:     fixedGenerator{"abc", "abcd"},
+			mockBehavior: func(m *mocks.MockURL) {
+				gomock.InOrder(
+					m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(conflict),
+					m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil),
+				)
+			},
+			expected:      "abcd",
+			expectedStats: GeneratorStats{Attempts: 2, Collisions: 1, CollisionRate: 0.5},
+		},
+		{
+			name:      "Exhausted",
+			generator: "fixed",
+			codes:     fixedGenerator{"abc"},
+			mockBehavior: func(m *mocks.MockURL) {
+				m.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(conflict)
+			},
+			expectedError: e.ConflictError{},
+			expectedStats: GeneratorStats{Attempts: 1, Collisions: 1, Failures: 1, CollisionRate: 1},
+		},
+		{
+			name:          "Unknown generator",
+			generator:     "missing",
+			mockBehavior:  func(_ *mocks.MockURL) {},
+			expectedError: e.BadRequestError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			mockRepo := mocks.NewMockURL(ctrl)
+			tt.mockBehavior(mockRepo)
+
+			metrics := NewGeneratorMetrics()
+			service := NewService(mockRepo, WithCodeGenerator("fixed", tt.codes), WithGeneratorMetrics(metrics))
+			shortURL, err := service.SaveURL(context.Background(),
+				&model.URL{OriginalURL: "https://example.com", Generator: tt.generator})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, shortURL)
+			}
+			assert.Equal(t, tt.expectedStats, metrics.Snapshot()[tt.generator])
+		})
+	}
+}
+
+func TestShortenerService_SaveURLDefaultGenerator(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewService(mockRepo,
+		WithCodeGenerator("fixed", fixedGenerator{"xyz"}), WithDefaultCodeGenerator("fixed"))
+	data := &model.URL{OriginalURL: "https://example.com"}
+	shortURL, err := service.SaveURL(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", shortURL)
+	assert.Equal(t, "fixed", data.Generator, "stored with the link")
+}
+
+func TestShortenerService_SaveURLAliasPolicy(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{
+		Reserved:        []string{"health"},
+		Blocklist:       []string{"badword"},
+		CaseInsensitive: true,
+		Disjoint:        true,
+	})
+
+	t.Run("Stored lower case", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		mockRepo := mocks.NewMockURL(ctrl)
+		mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.URL) error {
+			assert.Equal(t, "mylink", data.ShortURL)
+			assert.Equal(t, "mylink", *data.CustomURL)
+			return nil
+		})
+
+		alias := "MyLink"
+		service := NewService(mockRepo, WithAliasPolicy(policy))
+		shortURL, err := service.SaveURL(context.Background(),
+			&model.URL{OriginalURL: "https://example.com", CustomURL: &alias})
+		require.NoError(t, err)
+		assert.Equal(t, "mylink", shortURL)
+	})
+
+	for _, alias := range []string{"health", "Health", "badword1"} {
+		t.Run("Rejected "+alias, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			service := NewService(mocks.NewMockURL(ctrl), WithAliasPolicy(policy))
+			_, err := service.SaveURL(context.Background(),
+				&model.URL{OriginalURL: "https://example.com", CustomURL: &alias})
+			require.ErrorIs(t, err, e.BadRequestError{})
+		})
+	}
+
+	t.Run("Reserved generated code is retried", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		mockRepo := mocks.NewMockURL(ctrl)
+		mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+
+		service := NewService(mockRepo, WithAliasPolicy(policy),
+			WithCodeGenerator("fixed", fixedGenerator{"health", "Xyz"}), WithDefaultCodeGenerator("fixed"))
+		shortURL, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "Xyz", shortURL)
+	})
+}
+
+func TestShortenerService_CodeGrammar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Long aliases can be looked up", func(t *testing.T) {
+		t.Parallel()
+		alias := "springsale2025x" // Longer than generated codes.
+		mockRepo := mocks.NewMockURL(gomock.NewController(t))
+		mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", alias).Return(&model.URL{ShortURL: alias}, nil)
+
+		service := NewService(mockRepo)
+		_, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com", CustomURL: &alias})
+		require.NoError(t, err)
+		_, err = service.GetURL(context.Background(), "", alias)
+		require.NoError(t, err)
+	})
+
+	t.Run("Separators and unicode need the flags", func(t *testing.T) {
+		t.Parallel()
+		for _, alias := range []string{"spring-sale", "caf\u00e9"} {
+			service := NewService(mocks.NewMockURL(gomock.NewController(t)))
+			_, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com", CustomURL: &alias})
+			require.ErrorIs(t, err, e.BadRequestError{}, alias)
+
+			_, err = service.GetURL(context.Background(), "", alias)
+			require.ErrorIs(t, err, e.BadRequestError{}, alias)
+		}
+	})
+
+	t.Run("Unicode aliases are stored NFC", func(t *testing.T) {
+		t.Parallel()
+		alias := "cafe\u0301-menu"
+		mockRepo := mocks.NewMockURL(gomock.NewController(t))
+		mockRepo.EXPECT().SaveURL(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.URL) error {
+			assert.Equal(t, "caf\u00e9-menu", data.ShortURL)
+			return nil
+		})
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "caf\u00e9-menu").Return(&model.URL{}, nil)
+
+		service := NewService(mockRepo, WithCodeGrammar(CodeGrammar{Separators: true, Unicode: true}))
+		_, err := service.SaveURL(context.Background(), &model.URL{OriginalURL: "https://example.com", CustomURL: &alias})
+		require.NoError(t, err)
+		_, err = service.GetURL(context.Background(), "", alias)
+		require.NoError(t, err)
+	})
+}
+
+func TestShortenerService_GetURLCaseInsensitive(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockURL(ctrl)
+	gomock.InOrder(
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "MyLink").Return(nil, e.NewNotFoundError("not found")),
+		mockRepo.EXPECT().GetURL(gomock.Any(), "", "mylink").Return(&model.URL{OriginalURL: "https://example.com"}, nil),
+	)
+
+	service := NewService(mockRepo, WithAliasPolicy(NewAliasPolicy(AliasPolicyConfig{CaseInsensitive: true})))
+	result, err := service.GetURL(context.Background(), "", "MyLink")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+}
+
+func TestShortenerService_AliasAvailability(t *testing.T) {
+	t.Parallel()
+	policy := NewAliasPolicy(AliasPolicyConfig{Reserved: []string{"health"}, Blocklist: []string{"badword"},
+		CaseInsensitive: true, Disjoint: true})
+
+	tests := []struct {
+		name     string
+		alias    string
+		existing []string
+		expected model.AliasAvailability
+	}{
+		{
+			name:     "Available",
+			alias:    "MyLink",
+			expected: model.AliasAvailability{Alias: "mylink", Status: model.AliasAvailable},
+		},
+		{
+			name:     "Taken",
+			alias:    "mylink",
+			existing: []string{"mylink", "mylink2"},
+			expected: model.AliasAvailability{Alias: "mylink", Status: model.AliasTaken,
+				Suggestions: []string{"mylink3", "mylink4", "mylink5"}},
+		},
+		{
+			name:  "Reserved",
+			alias: "health",
+			expected: model.AliasAvailability{Alias: "health", Status: model.AliasReserved,
+				Reason: ErrAliasReserved.Error(), Suggestions: []string{"health2", "health3", "health4"}},
+		},
+		{
+			name:     "Blocked",
+			alias:    "badword",
+			expected: model.AliasAvailability{Alias: "badword", Status: model.AliasReserved, Reason: ErrAliasBlocked.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			mockRepo := mocks.NewMockURL(ctrl)
+			// One query for the alias and all of its suggestions.
+			mockRepo.EXPECT().ExistingURLs(gomock.Any(), "", gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, shortURLs []string) ([]string, error) {
+					assert.Equal(t, tt.expected.Alias, shortURLs[0])
+					return tt.existing, nil
+				})
+
+			service := NewService(mockRepo, WithAliasPolicy(policy))
+			result, err := service.AliasAvailability(context.Background(), "", tt.alias)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *result)
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		service := NewService(mocks.NewMockURL(gomock.NewController(t)), WithAliasPolicy(policy))
+		for _, alias := range []string{"ab", "my-link", strings.Repeat("a", 21)} {
+			_, err := service.AliasAvailability(context.Background(), "", alias)
+			require.ErrorIs(t, err, e.BadRequestError{}, alias)
+		}
+	})
+}
+
 func TestShortenerService_GetURL(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -99,7 +423,7 @@ func TestShortenerService_GetURL(t *testing.T) {
 			name:  "Success",
 			input: "abc123",
 			mockBehavior: func(m *mocks.MockURL) {
-				m.EXPECT().GetURL(gomock.Any(), "abc123").Return(&model.URL{
+				m.EXPECT().GetURL(gomock.Any(), "", "abc123").Return(&model.URL{
 					OriginalURL: "https://example.com",
 				}, nil)
 			},
@@ -131,7 +455,7 @@ func TestShortenerService_GetURL(t *testing.T) {
 			tt.mockBehavior(mockRepo)
 
 			service := NewService(mockRepo)
-			result, err := service.GetURL(context.Background(), tt.input)
+			result, err := service.GetURL(context.Background(), "", tt.input)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -143,3 +467,30 @@ func TestShortenerService_GetURL(t *testing.T) {
 		})
 	}
 }
+
+func TestShortenerService_ListURLs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		filter   model.URLFilter
+		expected model.URLFilter
+	}{
+		{name: "Default limit", filter: model.URLFilter{}, expected: model.URLFilter{Limit: DefaultListLimit}},
+		{name: "Limit capped", filter: model.URLFilter{Limit: 1000, Offset: 40},
+			expected: model.URLFilter{Limit: MaxListLimit, Offset: 40}},
+		{name: "Negative offset", filter: model.URLFilter{Limit: 5, Offset: -1}, expected: model.URLFilter{Limit: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			mockRepo := mocks.NewMockURL(ctrl)
+			mockRepo.EXPECT().ListURLs(gomock.Any(), tt.expected).Return([]model.URL{{ShortURL: "abc"}}, nil)
+
+			urls, err := NewService(mockRepo).ListURLs(context.Background(), tt.filter)
+			assert.NoError(t, err)
+			assert.Len(t, urls, 1)
+		})
+	}
+}
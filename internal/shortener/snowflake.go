@@ -0,0 +1,192 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/snowflake"
+)
+
+// ErrNoWorkerLease is returned by SnowflakeCounter while it doesn't hold a valid worker ID lease.
+var ErrNoWorkerLease = errors.New("shortener: no worker lease")
+
+// SnowflakeConfig controls the worker ID lease of a SnowflakeCounter.
+type SnowflakeConfig struct {
+	Owner        string        // Unique name of this instance, e.g. the hostname.
+	Workers      int           // Worker IDs to choose from, at most snowflake.MaxWorkers.
+	LeaseTTL     time.Duration // How long a lease lasts without a heartbeat.
+	Heartbeat    time.Duration // Time between renewals, well below LeaseTTL.
+	MaxBackwards time.Duration // Clock rollback waited out instead of failing.
+}
+
+// SnowflakeCounter hands out time ordered snowflake IDs instead of counter values, so instances in
+// different regions don't share a counter. The worker ID comes from a lease in the database that's
+// renewed by a heartbeat; IDs are only generated while the lease is known to be valid.
+type SnowflakeCounter struct {
+	leases repository.WorkerLeases
+	cfg    SnowflakeConfig
+
+	mu         sync.Mutex
+	lease      *model.WorkerLease
+	validUntil time.Time // By the local clock, see leaseEnd.
+	generator  *snowflake.Generator
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ Counter = &SnowflakeCounter{}
+
+// NewSnowflakeCounter acquires a worker ID and starts the heartbeat.
+func NewSnowflakeCounter(ctx context.Context, leases repository.WorkerLeases,
+	cfg SnowflakeConfig) (*SnowflakeCounter, error) {
+	cfg.Workers = min(max(1, cfg.Workers), snowflake.MaxWorkers)
+	if cfg.MaxBackwards < 0 || cfg.MaxBackwards >= cfg.LeaseTTL {
+		return nil, fmt.Errorf("shortener: snowflake lease ttl %s must be longer than the max clock rollback %s",
+			cfg.LeaseTTL, cfg.MaxBackwards)
+	}
+	// Renewals have to land before the lease runs out by the local clock, see leaseEnd.
+	if valid := cfg.LeaseTTL - cfg.MaxBackwards; cfg.Heartbeat <= 0 || cfg.Heartbeat >= valid {
+		cfg.Heartbeat = valid / 3
+	}
+
+	c := &SnowflakeCounter{
+		leases: leases,
+		cfg:    cfg,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	go c.heartbeat()
+	return c, nil
+}
+
+// IncrementCounter returns the next snowflake ID.
+func (c *SnowflakeCounter) IncrementCounter() (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Stop before the lease could have been given to another instance.
+	if c.generator == nil || time.Until(c.validUntil) <= 0 {
+		return 0, ErrNoWorkerLease
+	}
+	return c.generator.Next()
+}
+
+// WorkerID returns the leased worker ID, -1 while there's none.
+func (c *SnowflakeCounter) WorkerID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lease == nil {
+		return -1
+	}
+	return c.lease.WorkerID
+}
+
+// Close stops the heartbeat and releases the lease, recording the time of the last ID for the next owner.
+func (c *SnowflakeCounter) Close() {
+	select {
+	case <-c.stop:
+		return
+	default:
+		close(c.stop)
+	}
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lease == nil {
+		return
+	}
+	c.lease.LastTime = c.generator.Last()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.leases.ReleaseWorker(ctx, c.lease); err != nil {
+		l.Logger.Error("failed to release worker lease", "worker", c.lease.WorkerID, "error", err)
+	}
+	c.lease, c.generator = nil, nil
+}
+
+// leaseEnd returns when a lease requested at sent runs out by the local clock. The lease's ExpiresAt is by
+// the database's clock, which this instance's may be behind, and the request reached the database after it
+// was sent, so this is never later than ExpiresAt. A rollback being waited out ends MaxBackwards later.
+func (c *SnowflakeCounter) leaseEnd(sent time.Time) time.Time {
+	return sent.Add(c.cfg.LeaseTTL - c.cfg.MaxBackwards)
+}
+
+// acquire leases a worker ID and starts a generator after the last ID of its previous owner.
+func (c *SnowflakeCounter) acquire(ctx context.Context) error {
+	sent := time.Now()
+	lease, err := c.leases.AcquireWorker(ctx, c.cfg.Owner, c.cfg.Workers, c.cfg.LeaseTTL)
+	if err != nil {
+		return err
+	}
+	generator, err := snowflake.New(lease.WorkerID, snowflake.Options{
+		MaxBackwards: c.cfg.MaxBackwards,
+		NotBefore:    lease.LastTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.lease, c.validUntil, c.generator = lease, c.leaseEnd(sent), generator
+	c.mu.Unlock()
+	l.Logger.Info("acquired snowflake worker", "worker", lease.WorkerID, "owner", lease.Owner)
+	return nil
+}
+
+func (c *SnowflakeCounter) heartbeat() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.cfg.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Heartbeat)
+		if err := c.renew(ctx); err != nil {
+			l.Logger.Error("failed to renew worker lease", "error", err)
+		}
+		cancel()
+	}
+}
+
+// renew extends the lease, or acquires a new one if it was lost.
+func (c *SnowflakeCounter) renew(ctx context.Context) error {
+	c.mu.Lock()
+	if c.lease == nil {
+		c.mu.Unlock()
+		return c.acquire(ctx)
+	}
+	lease := *c.lease
+	lease.LastTime = c.generator.Last()
+	c.mu.Unlock()
+
+	sent := time.Now()
+	err := c.leases.RenewWorker(ctx, &lease, c.cfg.LeaseTTL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case errors.Is(err, repository.ErrLeaseLost):
+		l.Logger.Warn("snowflake worker lease lost", "worker", lease.WorkerID)
+		c.lease, c.generator = nil, nil
+		return err
+	case err != nil:
+		return err // Keep generating until the current lease expires.
+	}
+	c.lease.ExpiresAt, c.validUntil = lease.ExpiresAt, c.leaseEnd(sent)
+	return nil
+}
@@ -0,0 +1,162 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/snowflake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSnowflakeCounter(t *testing.T, leases repository.WorkerLeases, owner string) *SnowflakeCounter {
+	t.Helper()
+	c, err := NewSnowflakeCounter(context.Background(), leases, SnowflakeConfig{
+		Owner:     owner,
+		Workers:   2,
+		LeaseTTL:  time.Minute,
+		Heartbeat: time.Hour, // Renewed by hand in the tests.
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestSnowflakeCounter(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+
+	a := newSnowflakeCounter(t, repo, "a")
+	b := newSnowflakeCounter(t, repo, "b")
+	defer b.Close()
+	assert.Equal(t, 0, a.WorkerID())
+	assert.Equal(t, 1, b.WorkerID())
+
+	_, err := NewSnowflakeCounter(context.Background(), repo,
+		SnowflakeConfig{Owner: "c", Workers: 2, LeaseTTL: time.Minute})
+	assert.ErrorIs(t, err, repository.ErrNoFreeWorker)
+
+	seen := map[uint64]bool{}
+	var last uint64
+	for range 1000 {
+		for _, c := range []*SnowflakeCounter{a, b} {
+			id, err := c.IncrementCounter()
+			require.NoError(t, err)
+			require.False(t, seen[id], "duplicate id %d", id)
+			seen[id] = true
+			assert.True(t, isGeneratedCode(EncodeBase62(id)))
+		}
+	}
+	last, err = a.IncrementCounter()
+	require.NoError(t, err)
+
+	// The next owner of the worker ID continues after the last ID of the previous one.
+	a.Close()
+	_, err = a.IncrementCounter()
+	assert.ErrorIs(t, err, ErrNoWorkerLease)
+
+	next := newSnowflakeCounter(t, repo, "next")
+	defer next.Close()
+	assert.Equal(t, 0, next.WorkerID())
+	id, err := next.IncrementCounter()
+	require.NoError(t, err)
+	assert.Greater(t, id, last)
+	assert.Equal(t, 0, snowflake.Worker(id))
+}
+
+func TestSnowflakeCounterLeaseLost(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	c := newSnowflakeCounter(t, repo, "a")
+	defer c.Close()
+
+	// The lease expired and another instance took the worker ID.
+	require.NoError(t, repo.ReleaseWorker(context.Background(), &model.WorkerLease{WorkerID: 0, Owner: "a"}))
+	taken, err := repo.AcquireWorker(context.Background(), "other", 1, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 0, taken.WorkerID)
+
+	assert.ErrorIs(t, c.renew(context.Background()), repository.ErrLeaseLost)
+	_, err = c.IncrementCounter()
+	assert.ErrorIs(t, err, ErrNoWorkerLease)
+	assert.Equal(t, -1, c.WorkerID())
+
+	// The next heartbeat leases a free worker ID.
+	require.NoError(t, c.renew(context.Background()))
+	assert.Equal(t, 1, c.WorkerID())
+	_, err = c.IncrementCounter()
+	assert.NoError(t, err)
+}
+
+func TestSnowflakeCounterExpired(t *testing.T) {
+	t.Parallel()
+	c := newSnowflakeCounter(t, repository.NewInMemory(), "a")
+	defer c.Close()
+
+	// Heartbeats failed until the lease ran out, another instance may have the worker ID now.
+	c.mu.Lock()
+	c.validUntil = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+
+	_, err := c.IncrementCounter()
+	assert.ErrorIs(t, err, ErrNoWorkerLease)
+}
+
+// skewedLeases is a database whose clock is ahead of the instance's.
+type skewedLeases struct {
+	repository.WorkerLeases
+	skew time.Duration
+}
+
+func (s skewedLeases) AcquireWorker(ctx context.Context, owner string, workers int,
+	ttl time.Duration) (*model.WorkerLease, error) {
+	lease, err := s.WorkerLeases.AcquireWorker(ctx, owner, workers, ttl)
+	if err == nil {
+		lease.ExpiresAt = lease.ExpiresAt.Add(s.skew)
+	}
+	return lease, err
+}
+
+func (s skewedLeases) RenewWorker(ctx context.Context, lease *model.WorkerLease, ttl time.Duration) error {
+	err := s.WorkerLeases.RenewWorker(ctx, lease, ttl)
+	if err == nil {
+		lease.ExpiresAt = lease.ExpiresAt.Add(s.skew)
+	}
+	return err
+}
+
+func TestSnowflakeCounterClockSkew(t *testing.T) {
+	t.Parallel()
+	leases := skewedLeases{WorkerLeases: repository.NewInMemory(), skew: time.Hour}
+
+	// The database says the lease lasts another hour, but by the local clock it runs out a minute after it
+	// was requested, less the rollback that may be waited out.
+	checkLease := func(c *SnowflakeCounter, sent time.Time) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		assert.Greater(t, c.lease.ExpiresAt.Sub(sent), 59*time.Minute)
+		assert.False(t, c.validUntil.Before(sent.Add(59*time.Second)))
+		assert.False(t, c.validUntil.After(time.Now().Add(59*time.Second)))
+	}
+
+	sent := time.Now()
+	c, err := NewSnowflakeCounter(context.Background(), leases, SnowflakeConfig{
+		Owner:        "a",
+		LeaseTTL:     time.Minute,
+		Heartbeat:    time.Hour,
+		MaxBackwards: time.Second,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+	checkLease(c, sent)
+
+	sent = time.Now()
+	require.NoError(t, c.renew(context.Background()))
+	checkLease(c, sent)
+
+	_, err = NewSnowflakeCounter(context.Background(), leases,
+		SnowflakeConfig{Owner: "b", LeaseTTL: time.Second, MaxBackwards: time.Second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+)
+
+// Limits of the labels organizing a link.
+const (
+	MaxTags         = 20
+	MaxTagLength    = 50
+	MaxFolderLength = 100
+	MaxNotesLength  = 2000
+)
+
+// tagPattern is a lower case tag: letters and digits, and -, _ or . after the first one, e.g. summer-sale.
+var tagPattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}._-]*$`)
+
+// NormalizeTags trims and lower cases the tags, drops duplicates and sorts them. It returns a
+// BadRequestError for tags that don't match the tag grammar or when there are more than MaxTags.
+func NormalizeTags(tags []string) (model.Tags, error) {
+	normalized := make(model.Tags, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > MaxTags {
+		return nil, e.NewBadRequestError("a link can't have more than %d tags", MaxTags)
+	}
+	return normalized, nil
+}
+
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if utf8.RuneCountInString(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+		return "", e.NewBadRequestError("tag %q must be up to %d letters, digits, -, _ or ., starting with a "+
+			"letter or digit", tag, MaxTagLength)
+	}
+	return tag, nil
+}
+
+// normalizeLabels normalizes the tags and folder of the link and checks the length of its notes.
+func normalizeLabels(data *model.URL) error {
+	tags, err := NormalizeTags(data.Tags)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	data.Tags = tags
+	data.Folder = strings.TrimSpace(data.Folder)
+	switch {
+	case utf8.RuneCountInString(data.Folder) > MaxFolderLength:
+		return e.NewBadRequestError("folder can't be longer than %d characters", MaxFolderLength)
+	case utf8.RuneCountInString(data.Notes) > MaxNotesLength:
+		return e.NewBadRequestError("notes can't be longer than %d characters", MaxNotesLength)
+	}
+	return nil
+}
+
+// replaceTag replaces the tag of a link with into, keeping the tags sorted and unique.
+func replaceTag(tags model.Tags, tag, into string) model.Tags {
+	replaced := make(model.Tags, 0, len(tags))
+	for _, t := range tags {
+		if t == tag {
+			t = into
+		}
+		replaced = append(replaced, t)
+	}
+	slices.Sort(replaced)
+	return slices.Compact(replaced)
+}
+
+// ListTags lists the tags in use.
+// @Summary List tags
+// @Description Lists the tags of the links by name, with how many links have each.
+// @Tags URL Shortener
+// @Produce json
+// @Param Authorization header string false "Bearer {api key}, lists the tags of the key's workspace"
+// @Success 200 {array} model.TagCount
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /tags [get]
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	tags, err := h.service.ListTags(ctx)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// tagChange is the response of renaming or merging tags.
+type tagChange struct {
+	Links int `json:"links"` // Links whose tags changed.
+}
+
+// RenameTag renames a tag on every link.
+// @Summary Rename a tag
+// @Description Renames the tag on every link. Renaming to a tag that's already used is a conflict, merge the
+// @Description tags instead.
+// @Description Needs an editor's API key, or the admin key for the default workspace.
+// @Tags URL Shortener
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the workspace, or the admin key"
+// @Param tag path string true "Tag"
+// @Param requestBody body object{to=string} true "New name of the tag"
+// @Success 200 {object} object{links=int}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /tags/{tag}/rename [post]
+func (h *Handler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		To string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	links, err := h.service.RenameTag(ctx, r.PathValue("tag"), request.To)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, tagChange{Links: links})
+}
+
+// MergeTags replaces tags with another one on every link.
+// @Summary Merge tags
+// @Description Replaces the tags with into on every link, which may already be used.
+// @Description Needs an editor's API key, or the admin key for the default workspace.
+// @Tags URL Shortener
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the workspace, or the admin key"
+// @Param requestBody body model.TagMerge true "Tags to merge and the tag they become"
+// @Success 200 {object} object{links=int}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /tags/merge [post]
+func (h *Handler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var request model.TagMerge
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+	defer cancel()
+
+	links, err := h.service.MergeTags(ctx, request.Tags, request.Into)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, tagChange{Links: links})
+}
@@ -0,0 +1,183 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	t.Parallel()
+	tags, err := NormalizeTags([]string{" Summer-Sale", "launch", "summer-sale", "v2.1", "été_2025"})
+	require.NoError(t, err)
+	assert.Equal(t, model.Tags{"launch", "summer-sale", "v2.1", "été_2025"}, tags)
+
+	for _, invalid := range []string{"", "-sale", "summer sale", "a,b", "#promo", strings.Repeat("a", MaxTagLength+1)} {
+		_, err := NormalizeTags([]string{invalid})
+		assert.ErrorIs(t, err, e.BadRequestError{}, invalid)
+	}
+	tooMany := make([]string, MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+	_, err = NormalizeTags(tooMany)
+	assert.ErrorIs(t, err, e.BadRequestError{})
+}
+
+func TestShortenerService_Labels(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	data := &model.URL{OriginalURL: "https://example.com", Tags: model.Tags{"Promo", "launch", "promo"},
+		Folder: " Summer "}
+	code, err := service.SaveURL(ctx, data)
+	require.NoError(t, err)
+	assert.Equal(t, model.Tags{"launch", "promo"}, data.Tags)
+	assert.Equal(t, "Summer", data.Folder)
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", Tags: model.Tags{"no spaces"}})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	updated, err := service.UpdateURL(ctx, "", code, model.URLUpdate{Notes: ptr.Of("Printed on flyers")})
+	require.NoError(t, err)
+	assert.Equal(t, model.Tags{"launch", "promo"}, updated.Tags, "nil fields are kept")
+	assert.Equal(t, "Printed on flyers", updated.Notes)
+	updated, err = service.UpdateURL(ctx, "", code, model.URLUpdate{Tags: &model.Tags{}, Folder: ptr.Of("")})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Tags)
+	assert.Empty(t, updated.Folder)
+	_, err = service.UpdateURL(ctx, "", code, model.URLUpdate{Notes: ptr.Of(strings.Repeat("n", 2001))})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = service.UpdateURL(ctx, "", "missing", model.URLUpdate{})
+	assert.ErrorIs(t, err, e.NotFoundError{})
+
+	viewer := withMember(tenant.With(ctx, "acme"), &model.Member{Workspace: "acme", ID: "vic", Role: model.RoleViewer})
+	_, err = service.UpdateURL(viewer, "", code, model.URLUpdate{})
+	assert.ErrorIs(t, err, e.ForbiddenError{})
+	_, err = service.MergeTags(viewer, []string{"a"}, "b")
+	assert.ErrorIs(t, err, e.ForbiddenError{})
+}
+
+func TestShortenerService_RenameAndMergeTags(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)))
+	acme := tenant.With(context.Background(), "acme")
+	other := tenant.With(context.Background(), "other")
+	for _, ctx := range []context.Context{acme, other} {
+		workspace, _ := tenant.From(ctx)
+		_, err := service.CreateWorkspace(ctx, &model.Workspace{ID: workspace}, "ann")
+		require.NoError(t, err)
+	}
+
+	save := func(ctx context.Context, tags ...string) string {
+		code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", Tags: tags})
+		require.NoError(t, err)
+		return code
+	}
+	both := save(acme, "summer", "sale")
+	save(acme, "summer")
+	save(acme, "sale", "promo")
+	for range MaxListLimit + 1 {
+		save(acme, "bulk")
+	}
+	save(other, "summer")
+
+	_, err := service.RenameTag(acme, "summer", "sale")
+	assert.ErrorIs(t, err, e.ConflictError{}, "renaming onto a used tag is a merge")
+	_, err = service.RenameTag(acme, "winter", "cold")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	_, err = service.RenameTag(acme, "summer", "Summer")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	links, err := service.RenameTag(acme, "Summer", "summer-2025")
+	require.NoError(t, err)
+	assert.Equal(t, 2, links)
+	links, err = service.RenameTag(acme, "bulk", "many")
+	require.NoError(t, err)
+	assert.Equal(t, MaxListLimit+1, links, "more links than a page")
+
+	links, err = service.MergeTags(acme, []string{"sale", "promo"}, "deals")
+	require.NoError(t, err)
+	assert.Equal(t, 2, links, "links with both tags count once")
+	data, err := service.GetURL(acme, "", both)
+	require.NoError(t, err)
+	assert.Equal(t, model.Tags{"deals", "summer-2025"}, data.Tags)
+
+	tags, err := service.ListTags(acme)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TagCount{{Tag: "deals", Links: 2}, {Tag: "many", Links: MaxListLimit + 1},
+		{Tag: "summer-2025", Links: 2}}, tags)
+	tags, err = service.ListTags(other)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TagCount{{Tag: "summer", Links: 1}}, tags, "other workspaces keep their tags")
+
+	_, err = service.MergeTags(acme, nil, "deals")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+}
+
+func TestTagHandlers(t *testing.T) {
+	t.Parallel()
+	service := NewService(repository.NewInMemory())
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey(testAdminKey)).Routes(mux)
+
+	ctx := tenant.With(context.Background(), tenant.Default)
+	for _, tags := range []model.Tags{{"launch", "promo"}, {"launch"}} {
+		_, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of(tags[len(tags)-1]),
+			Tags: tags})
+		require.NoError(t, err)
+	}
+
+	for _, tt := range []struct {
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com", Tags: model.Tags{"a b"}}),
+			http.StatusBadRequest, "tag"},
+		{httptest.NewRequest(http.MethodGet, "/urls?tag=launch&tag=PROMO", nil), http.StatusOK, `"shortURL":"promo"`},
+		{httptest.NewRequest(http.MethodGet, "/urls?tag=-x", nil), http.StatusBadRequest, ""},
+		{httptest.NewRequest(http.MethodGet, "/urls?folder=", nil), http.StatusOK, `"shortURL":"launch"`},
+		{makeJSONRequest(http.MethodPatch, "/urls/launch", model.URLUpdate{Folder: ptr.Of("Q3")}),
+			http.StatusUnauthorized, ""},
+		{asAdmin(makeJSONRequest(http.MethodPatch, "/urls/launch", model.URLUpdate{Folder: ptr.Of("Q3")})),
+			http.StatusOK, `"folder":"Q3"`},
+		{asAdmin(makeJSONRequest(http.MethodPatch, "/urls/missing", model.URLUpdate{})), http.StatusNotFound, ""},
+		{makeJSONRequest(http.MethodPost, "/tags/launch/rename", map[string]string{"to": "go-live"}),
+			http.StatusUnauthorized, ""},
+		{makeJSONRequest(http.MethodPost, "/tags/merge", model.TagMerge{Tags: []string{"launch"}, Into: "promo"}),
+			http.StatusUnauthorized, ""},
+		{asAdmin(makeJSONRequest(http.MethodPost, "/tags/launch/rename", map[string]string{"to": "promo"})),
+			http.StatusConflict, ""},
+		{asAdmin(makeJSONRequest(http.MethodPost, "/tags/launch/rename", map[string]string{"to": "go-live"})),
+			http.StatusOK, `"links":2`},
+		{asAdmin(makeJSONRequest(http.MethodPost, "/tags/merge",
+			model.TagMerge{Tags: []string{"go-live"}, Into: "promo"})), http.StatusOK, `"links":2`},
+		{httptest.NewRequest(http.MethodGet, "/tags", nil), http.StatusOK, `[{"tag":"promo","links":2}]`},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tt.req)
+		assert.Equal(t, tt.status, rr.Code, "%s %s: %s", tt.req.Method, tt.req.URL, rr.Body)
+		assert.Contains(t, rr.Body.String(), tt.body, tt.req.URL)
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/urls?folder=Q3", nil))
+	var list model.URLList
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&list))
+	require.Len(t, list.URLs, 1)
+	assert.Equal(t, "launch", list.URLs[0].ShortURL)
+}
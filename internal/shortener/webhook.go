@@ -0,0 +1,668 @@
+package shortener
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoncheung94/url-shortener/internal/audit"
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	l "github.com/jasoncheung94/url-shortener/internal/logger"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+)
+
+// Headers of webhook requests. Receivers check the signature before trusting the body, see SignWebhook.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery" // Same for every attempt, receivers dedupe on it.
+)
+
+// webhookUserAgent identifies webhook requests to the receiver.
+const webhookUserAgent = "url-shortener-webhook/1.0 (+https://github.com/jasoncheung94/url-shortener)"
+
+// Webhook secrets look like whsec_{secret}.
+const webhookSecretPrefix = "whsec_"
+
+const (
+	maxWebhooks         = 20  // Webhooks per workspace.
+	maxDeliveryLog      = 20  // Attempts kept in the log of a delivery.
+	maxDeliveryResponse = 512 // Bytes of the response body kept in the log.
+)
+
+// WebhookConfig controls how webhook deliveries are sent and retried.
+type WebhookConfig struct {
+	MaxAttempts int           // Attempts before a delivery is dead-lettered.
+	Backoff     time.Duration // Wait after the first failed attempt, doubled after every other one.
+	MaxBackoff  time.Duration // Longest wait between attempts.
+	Interval    time.Duration // Time between looks for due deliveries.
+	Concurrency int           // Deliveries sent at the same time.
+	BatchSize   int           // Deliveries claimed from the repository at a time.
+	QueueSize   int           // Events waiting to be stored as deliveries before new ones are dropped.
+	Lease       time.Duration // How long a claimed delivery is kept from other instances, longer than a request.
+}
+
+// Webhooks manages webhook subscriptions and sends link events to them. Deliveries are stored before they're
+// sent, so they survive restarts and every instance can send them.
+type Webhooks struct {
+	repo   repository.Webhooks
+	client *http.Client
+	cfg    WebhookConfig
+	now    func() time.Time
+	queue  chan events.Event
+	wake   chan struct{}
+}
+
+// NewWebhooks returns Webhooks stored in repo. The client should refuse internal addresses and redirects, see
+// metadata.NewSafeClient.
+func NewWebhooks(repo repository.Webhooks, client *http.Client, cfg WebhookConfig) *Webhooks {
+	cfg.MaxAttempts = max(1, cfg.MaxAttempts)
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 10 * time.Second
+	}
+	cfg.MaxBackoff = max(cfg.MaxBackoff, cfg.Backoff)
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	cfg.Concurrency = max(1, cfg.Concurrency)
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &Webhooks{
+		repo:   repo,
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+		queue:  make(chan events.Event, cfg.QueueSize),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// SignWebhook returns the signature header of a webhook body sent at the Unix timestamp:
+// sha256={hex HMAC-SHA256 of "{timestamp}.{body}" keyed with the secret}.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Create subscribes the workspace to the events of the webhook, every type if it has none. The returned
+// webhook is the only time the secret is available.
+func (wh *Webhooks) Create(ctx context.Context, workspace string, hook *model.Webhook) (*model.Webhook, error) {
+	if err := ValidateURL(hook.URL); err != nil || len(hook.URL) > 2048 {
+		return nil, e.NewBadRequestError("webhook url must be an http or https URL of at most 2048 characters")
+	}
+	types := make([]string, 0, len(hook.Events))
+	for _, name := range hook.Events {
+		if !slices.Contains(events.Types, events.Type(name)) {
+			return nil, e.NewBadRequestError("unknown event type %q", name)
+		}
+		if !slices.Contains(types, name) {
+			types = append(types, name)
+		}
+	}
+	hooks, err := wh.repo.ListWebhooks(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) >= maxWebhooks {
+		return nil, e.NewForbiddenError("workspace %q can't have more than %d webhooks", workspace, maxWebhooks)
+	}
+
+	id, secret := make([]byte, 8), make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	created := &model.Webhook{
+		ID:        hex.EncodeToString(id),
+		Workspace: workspace,
+		URL:       hook.URL,
+		Events:    types,
+		Secret:    webhookSecretPrefix + hex.EncodeToString(secret),
+		CreatedAt: wh.now().UTC(),
+	}
+	if err := wh.repo.SaveWebhook(ctx, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// List returns the webhooks of the workspace without their secrets.
+func (wh *Webhooks) List(ctx context.Context, workspace string) ([]model.Webhook, error) {
+	hooks, err := wh.repo.ListWebhooks(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// Delete removes a webhook of the workspace with its deliveries.
+func (wh *Webhooks) Delete(ctx context.Context, workspace, id string) error {
+	return wh.repo.DeleteWebhook(ctx, workspace, id)
+}
+
+// Deliveries returns the latest deliveries of a webhook of the workspace, newest first.
+func (wh *Webhooks) Deliveries(ctx context.Context, workspace, id string, limit int) ([]model.WebhookDelivery,
+	error) {
+	if _, err := wh.repo.GetWebhook(ctx, workspace, id); err != nil {
+		return nil, err
+	}
+	return wh.repo.ListDeliveries(ctx, id, limit)
+}
+
+// Redeliver queues a delivered or dead delivery to be sent again now, with a fresh set of attempts. Its log
+// is kept.
+func (wh *Webhooks) Redeliver(ctx context.Context, workspace, webhook, id string) (*model.WebhookDelivery, error) {
+	if _, err := wh.repo.GetWebhook(ctx, workspace, webhook); err != nil {
+		return nil, err
+	}
+	delivery, err := wh.repo.GetDelivery(ctx, webhook, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == model.DeliveryPending {
+		return nil, e.NewConflictError("delivery %q is already queued", id)
+	}
+	delivery.Status, delivery.Attempts, delivery.NextAttemptAt = model.DeliveryPending, 0, wh.now().UTC()
+	delivery.DeliveredAt = nil
+	if err := wh.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	wh.notify()
+	return delivery, nil
+}
+
+// Handle queues the event for Run to store as deliveries, subscribe it to the event bus. It doesn't block the
+// publisher: events that don't fit in the queue are dropped and logged.
+func (wh *Webhooks) Handle(_ context.Context, event events.Event) {
+	select {
+	case wh.queue <- event:
+	default:
+		l.Logger.Error("webhook queue is full, event dropped", "type", event.Type, "shorturl", event.ShortURL)
+	}
+}
+
+// Run stores queued events as deliveries and sends the deliveries that are due until the context is
+// cancelled. Events still queued then are stored before it returns.
+func (wh *Webhooks) Run(ctx context.Context) {
+	stored := make(chan struct{})
+	go func() {
+		defer close(stored)
+		for {
+			select {
+			case event := <-wh.queue:
+				wh.store(ctx, event)
+			case <-ctx.Done():
+				for {
+					select {
+					case event := <-wh.queue:
+						wh.store(ctx, event)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wh.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := wh.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			l.Logger.Error("webhook delivery round failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			<-stored
+			return
+		case <-ticker.C:
+		case <-wh.wake:
+		}
+	}
+}
+
+// store enqueues the event, even while Run is stopping.
+func (wh *Webhooks) store(ctx context.Context, event events.Event) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := wh.Enqueue(ctx, event); err != nil {
+		l.Logger.Error("failed to queue webhook deliveries", "type", event.Type, "shorturl", event.ShortURL,
+			"error", err)
+	}
+}
+
+// Enqueue stores a delivery of the event for every webhook of its workspace subscribed to its type, and wakes
+// Run to send them. An event is queued once per webhook, so enqueueing it again does nothing.
+func (wh *Webhooks) Enqueue(ctx context.Context, event events.Event) error {
+	if event.ID == "" {
+		event.ID = events.NewID()
+	}
+	hooks, err := wh.repo.ListWebhooks(ctx, event.Workspace)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	now := wh.now().UTC()
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, string(event.Type)) {
+			continue
+		}
+		err := wh.repo.SaveDelivery(ctx, &model.WebhookDelivery{
+			ID:            deliveryID(hook.ID, event.ID),
+			Webhook:       hook.ID,
+			Workspace:     hook.Workspace,
+			Event:         event.ID,
+			Type:          string(event.Type),
+			Payload:       payload,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if errors.Is(err, e.ConflictError{}) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		queued = true
+	}
+	if queued {
+		wh.notify()
+	}
+	return nil
+}
+
+// deliveryID is the ID of the delivery of an event to a webhook.
+func deliveryID(webhook, event string) string {
+	sum := sha256.Sum256([]byte(webhook + "/" + event))
+	return hex.EncodeToString(sum[:16])
+}
+
+// notify wakes Run to send the deliveries that are due.
+func (wh *Webhooks) notify() {
+	select {
+	case wh.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDue sends every delivery that's due once.
+func (wh *Webhooks) DeliverDue(ctx context.Context) error {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, wh.cfg.Concurrency)
+	)
+	defer wg.Wait()
+
+	for {
+		due, err := wh.repo.ClaimDeliveries(ctx, wh.now().UTC(), wh.cfg.Lease, wh.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range due {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				wh.deliver(ctx, delivery)
+			}()
+		}
+
+		if len(due) < wh.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends the delivery once and stores the outcome: delivered, pending until the next attempt after a
+// backoff, or dead after the last attempt.
+func (wh *Webhooks) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	hook, err := wh.repo.GetWebhook(ctx, delivery.Workspace, delivery.Webhook)
+	if errors.Is(err, e.NotFoundError{}) {
+		return // Deleted with its deliveries.
+	}
+	if err != nil {
+		l.Logger.Error("failed to get webhook", "webhook", delivery.Webhook, "error", err)
+		return
+	}
+
+	attempt := wh.send(ctx, hook, &delivery)
+	if ctx.Err() != nil {
+		return // Cut short by the shutdown, sent again once the lease ends.
+	}
+	delivery.Attempts++
+	delivery.Log = append(delivery.Log, attempt)
+	if len(delivery.Log) > maxDeliveryLog {
+		delivery.Log = delivery.Log[len(delivery.Log)-maxDeliveryLog:]
+	}
+	now := wh.now().UTC()
+	switch {
+	case attempt.Error == "":
+		delivery.Status, delivery.DeliveredAt = model.DeliveryDelivered, &now
+	case delivery.Attempts >= wh.cfg.MaxAttempts:
+		delivery.Status = model.DeliveryDead
+		l.Logger.Warn("webhook delivery failed every attempt", "webhook", hook.ID, "delivery", delivery.ID,
+			"attempts", delivery.Attempts, "error", attempt.Error)
+	default:
+		delivery.NextAttemptAt = now.Add(wh.backoff(delivery.Attempts))
+	}
+
+	// The attempt was made, so its outcome is stored even when Run is stopping.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := wh.repo.UpdateDelivery(ctx, &delivery); err != nil {
+		l.Logger.Error("failed to update webhook delivery", "delivery", delivery.ID, "error", err)
+	}
+}
+
+// send posts the payload of the delivery to the webhook, signed with its secret.
+func (wh *Webhooks) send(ctx context.Context, hook *model.Webhook,
+	delivery *model.WebhookDelivery) model.DeliveryAttempt {
+	started := time.Now()
+	attempt := model.DeliveryAttempt{At: wh.now().UTC()}
+	defer func() { attempt.DurationMS = time.Since(started).Milliseconds() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(WebhookEventHeader, delivery.Type)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDeliveryResponse))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Lets the connection be reused.
+	attempt.StatusCode = resp.StatusCode
+	attempt.Response = strings.ToValidUTF8(string(body), "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = http.StatusText(resp.StatusCode)
+		if attempt.Error == "" {
+			attempt.Error = "status " + strconv.Itoa(resp.StatusCode)
+		}
+	}
+	return attempt
+}
+
+// backoff is the wait after the attempts failed: Backoff, doubled for every attempt after the first, up to
+// MaxBackoff.
+func (wh *Webhooks) backoff(attempts int) time.Duration {
+	wait := wh.cfg.Backoff
+	for range attempts - 1 {
+		wait *= 2
+		if wait >= wh.cfg.MaxBackoff {
+			return wh.cfg.MaxBackoff
+		}
+	}
+	return min(wait, wh.cfg.MaxBackoff)
+}
+
+// publish publishes the event when the service has a publisher.
+func (s *shortenerService) publish(ctx context.Context, event events.Event) {
+	if s.events != nil {
+		s.events.Publish(ctx, event)
+	}
+}
+
+// linkEvent is the event of a change to the link, with the link after it. Updates also have the fields that
+// changed with their value before and after.
+func linkEvent(eventType events.Type, data, before *model.URL) events.Event {
+	event := events.Event{
+		Type:       eventType,
+		ShortURL:   data.ShortURL,
+		Domain:     data.Domain,
+		Workspace:  data.Workspace,
+		OccurredAt: time.Now().UTC(),
+		Data:       map[string]any{"link": snapshot(data)},
+	}
+	if before != nil {
+		if changes, err := audit.Diff(before, data); err == nil {
+			event.Data["changes"] = changes
+		}
+	}
+	return event
+}
+
+// outboxMessage is the outbox message publishing the event, which gets its ID now so every copy the relay
+// sends has the same one.
+func outboxMessage(event events.Event) (*model.OutboxMessage, error) {
+	event.ID = events.NewID()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+	return &model.OutboxMessage{
+		ID:            event.ID,
+		Type:          string(event.Type),
+		Key:           linkResource(event.Domain, event.ShortURL),
+		Payload:       payload,
+		NextAttemptAt: event.OccurredAt,
+		CreatedAt:     event.OccurredAt,
+	}, nil
+}
+
+// clickEvent is the event of a visitor redirected by the link to the destination.
+func clickEvent(r *http.Request, data *model.URL, destination string) events.Event {
+	event := events.Event{
+		Type:       events.LinkClicked,
+		ShortURL:   data.ShortURL,
+		Domain:     data.Domain,
+		Workspace:  data.Workspace,
+		OccurredAt: time.Now().UTC(),
+		Data:       map[string]any{"destination": destination},
+	}
+	if referrer := r.Referer(); referrer != "" {
+		event.Data["referrer"] = referrer
+	}
+	if userAgent := r.UserAgent(); userAgent != "" {
+		event.Data["userAgent"] = userAgent
+	}
+	return event
+}
+
+// errNoWebhooks is returned by the webhook methods when the service has no Webhooks.
+var errNoWebhooks = e.NewBadRequestError("webhooks aren't supported")
+
+// CreateWebhook adds a webhook to a workspace.
+// @Summary Create a webhook
+// @Description Sends the events of the workspace's links to the URL as a JSON POST, every type if events is
+// @Description empty: link.created, link.updated, link.deleted, link.expired, link.clicked, link.broken and
+// @Description link.recovered. Requests are signed: X-Webhook-Signature is sha256={hex HMAC-SHA256 of
+// @Description "{X-Webhook-Timestamp}.{body}"} keyed with the secret, only returned here. Receivers answer with a
+// @Description 2xx status, other answers are retried with exponential backoff. X-Webhook-Delivery is the same for
+// @Description every attempt.
+// @Description Needs an admin of the workspace, or the admin key for the default workspace.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the webhook's workspace, or the admin key"
+// @Param requestBody body model.Webhook true "URL and event types"
+// @Success 201 {object} model.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /webhooks [post]
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request model.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	hook, err := h.service.CreateWebhook(ctx, &request)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// ListWebhooks lists the webhooks of a workspace.
+// @Summary List webhooks
+// @Description Lists the webhooks of the workspace, oldest first, without their secrets.
+// @Description Needs an admin of the workspace, or the admin key for the default workspace.
+// @Tags Webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the workspace, or the admin key"
+// @Success 200 {array} model.Webhook
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /webhooks [get]
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	hooks, err := h.service.ListWebhooks(ctx)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// DeleteWebhook removes a webhook.
+// @Summary Delete a webhook
+// @Description Stops sending events to the webhook and removes its deliveries.
+// @Description Needs an admin of the workspace, or the admin key for the default workspace.
+// @Tags Webhooks
+// @Param Authorization header string true "Bearer {api key} of the webhook's workspace, or the admin key"
+// @Param webhook path string true "Webhook ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /webhooks/{webhook} [delete]
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	if writeServiceError(w, h.service.DeleteWebhook(ctx, r.PathValue("webhook"))) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries lists the deliveries of a webhook.
+// @Summary List webhook deliveries
+// @Description Lists the latest deliveries of the webhook, newest first, with their status and the log of their
+// @Description attempts: when, the response status and the start of the response body. Dead deliveries failed
+// @Description every attempt and are only sent again when redelivered.
+// @Description Needs an admin of the workspace, or the admin key for the default workspace.
+// @Tags Webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the webhook's workspace, or the admin key"
+// @Param webhook path string true "Webhook ID"
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {array} model.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /webhooks/{webhook}/deliveries [get]
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "invalid query",
+				"limit must be a positive number"))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	deliveries, err := h.service.WebhookDeliveries(ctx, r.PathValue("webhook"), limit)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook sends a webhook delivery again.
+// @Summary Redeliver a webhook delivery
+// @Description Queues a delivered or dead delivery to be sent again now with the same payload and
+// @Description X-Webhook-Delivery, with a fresh set of attempts.
+// @Description Needs an admin of the workspace, or the admin key for the default workspace.
+// @Tags Webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer {api key} of the webhook's workspace, or the admin key"
+// @Param webhook path string true "Webhook ID"
+// @Param delivery path string true "Delivery ID"
+// @Success 202 {object} model.WebhookDelivery
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /webhooks/{webhook}/deliveries/{delivery}/redeliver [post]
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	delivery, err := h.service.RedeliverWebhook(ctx, r.PathValue("webhook"), r.PathValue("delivery"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,462 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/events"
+	"github.com/jasoncheung94/url-shortener/internal/ptr"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver is a webhook endpoint that checks signatures and answers with status.
+type webhookReceiver struct {
+	*httptest.Server
+	secret atomic.Value
+	status atomic.Int32
+
+	mu       sync.Mutex
+	received []events.Event
+	headers  []http.Header
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{}
+	receiver.secret.Store("")
+	receiver.status.Store(http.StatusOK)
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		if r.Header.Get(WebhookSignatureHeader) != SignWebhook(receiver.secret.Load().(string), timestamp, body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var event events.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, event)
+		receiver.headers = append(receiver.headers, r.Header.Clone())
+		receiver.mu.Unlock()
+		w.WriteHeader(int(receiver.status.Load()))
+		_, _ = w.Write([]byte("thanks"))
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.received...)
+}
+
+func TestSignWebhook(t *testing.T) {
+	t.Parallel()
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac whsec_test
+	assert.Equal(t, "sha256=35495024f4ef3f94e5a93e22221544c4b75e9a42300cd965ab81cb85cd994e91",
+		SignWebhook("whsec_test", 1700000000, []byte("{}")))
+	assert.NotEqual(t, SignWebhook("whsec_test", 1700000000, []byte("{}")),
+		SignWebhook("whsec_test", 1700000001, []byte("{}")), "the timestamp is signed")
+}
+
+func TestWebhooks_Deliver(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	receiver := newWebhookReceiver(t)
+	webhooks := NewWebhooks(repository.NewInMemory(), receiver.Client(), WebhookConfig{})
+
+	_, err := webhooks.Create(ctx, "acme", &model.Webhook{URL: "ftp://example.com"})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = webhooks.Create(ctx, "acme", &model.Webhook{URL: receiver.URL, Events: []string{"link.exploded"}})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+
+	hook, err := webhooks.Create(ctx, "acme", &model.Webhook{URL: receiver.URL,
+		Events: []string{"link.created", "link.deleted", "link.created"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"link.created", "link.deleted"}, []string(hook.Events))
+	assert.Regexp(t, `^whsec_[0-9a-f]{64}$`, hook.Secret)
+	receiver.secret.Store(hook.Secret)
+	listed, err := webhooks.List(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Secret, "listed webhooks have no secret")
+
+	created := events.Event{ID: "evt-1", Type: events.LinkCreated, ShortURL: "abc", Workspace: "acme"}
+	require.NoError(t, webhooks.Enqueue(ctx, created))
+	require.NoError(t, webhooks.Enqueue(ctx, created), "enqueued again")
+	require.NoError(t, webhooks.Enqueue(ctx, events.Event{ID: "evt-2", Type: events.LinkClicked,
+		Workspace: "acme"}), "not subscribed")
+	require.NoError(t, webhooks.Enqueue(ctx, events.Event{ID: "evt-3", Type: events.LinkCreated}),
+		"other workspace")
+	require.NoError(t, webhooks.DeliverDue(ctx))
+	require.NoError(t, webhooks.DeliverDue(ctx), "nothing left to send")
+
+	received := receiver.events()
+	require.Len(t, received, 1, "one delivery per event")
+	assert.Equal(t, "evt-1", received[0].ID)
+	assert.Equal(t, "abc", received[0].ShortURL)
+	header := receiver.headers[0]
+	assert.Equal(t, "link.created", header.Get(WebhookEventHeader))
+	assert.Equal(t, deliveryID(hook.ID, "evt-1"), header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+	deliveries, err := webhooks.Deliveries(ctx, "acme", hook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+	require.Len(t, deliveries[0].Log, 1)
+	assert.Equal(t, http.StatusOK, deliveries[0].Log[0].StatusCode)
+	assert.Equal(t, "thanks", deliveries[0].Log[0].Response)
+	_, err = webhooks.Deliveries(ctx, "other", hook.ID, 10)
+	assert.ErrorIs(t, err, e.NotFoundError{}, "webhooks of other workspaces")
+
+	require.NoError(t, webhooks.Delete(ctx, "acme", hook.ID))
+	_, err = webhooks.Deliveries(ctx, "acme", hook.ID, 10)
+	assert.ErrorIs(t, err, e.NotFoundError{})
+}
+
+func TestWebhooks_RetryAndDeadLetter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	receiver := newWebhookReceiver(t)
+	receiver.status.Store(http.StatusInternalServerError)
+	webhooks := NewWebhooks(repository.NewInMemory(), receiver.Client(), WebhookConfig{
+		MaxAttempts: 4,
+		Backoff:     time.Minute,
+		MaxBackoff:  3 * time.Minute,
+	})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	webhooks.now = func() time.Time { return now }
+
+	hook, err := webhooks.Create(ctx, "", &model.Webhook{URL: receiver.URL})
+	require.NoError(t, err)
+	receiver.secret.Store(hook.Secret)
+	require.NoError(t, webhooks.Enqueue(ctx, events.Event{ID: "evt", Type: events.LinkUpdated}))
+	id := deliveryID(hook.ID, "evt")
+
+	// Retried after 1, 2 and then at most 3 minutes.
+	for i, wait := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		require.NoError(t, webhooks.DeliverDue(ctx))
+		delivery, err := webhooks.repo.GetDelivery(ctx, hook.ID, id)
+		require.NoError(t, err)
+		assert.Equal(t, model.DeliveryPending, delivery.Status)
+		assert.Equal(t, i+1, delivery.Attempts)
+		assert.Equal(t, now.Add(wait), delivery.NextAttemptAt)
+
+		require.NoError(t, webhooks.DeliverDue(ctx))
+		assert.Len(t, receiver.events(), i+1, "not sent again before the backoff")
+		now = now.Add(wait)
+	}
+
+	require.NoError(t, webhooks.DeliverDue(ctx))
+	delivery, err := webhooks.repo.GetDelivery(ctx, hook.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryDead, delivery.Status, "dead-lettered after the last attempt")
+	assert.Equal(t, 4, delivery.Attempts)
+	require.Len(t, delivery.Log, 4)
+	assert.Equal(t, http.StatusInternalServerError, delivery.Log[3].StatusCode)
+	assert.Equal(t, "Internal Server Error", delivery.Log[3].Error)
+
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, webhooks.DeliverDue(ctx))
+	assert.Len(t, receiver.events(), 4, "dead deliveries aren't retried")
+
+	receiver.status.Store(http.StatusNoContent)
+	_, err = webhooks.Redeliver(ctx, "other", hook.ID, id)
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	redelivered, err := webhooks.Redeliver(ctx, "", hook.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	_, err = webhooks.Redeliver(ctx, "", hook.ID, id)
+	assert.ErrorIs(t, err, e.ConflictError{}, "already queued")
+
+	require.NoError(t, webhooks.DeliverDue(ctx))
+	delivery, err = webhooks.repo.GetDelivery(ctx, hook.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Len(t, delivery.Log, 5, "the log is kept")
+	received := receiver.events()
+	assert.Equal(t, received[0].ID, received[4].ID, "the same event every attempt")
+}
+
+func TestWebhooks_Unreachable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	receiver := newWebhookReceiver(t)
+	receiver.Close()
+	webhooks := NewWebhooks(repository.NewInMemory(), receiver.Client(), WebhookConfig{MaxAttempts: 1})
+
+	hook, err := webhooks.Create(ctx, "", &model.Webhook{URL: receiver.URL})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Enqueue(ctx, events.Event{ID: "evt", Type: events.LinkUpdated}))
+	require.NoError(t, webhooks.DeliverDue(ctx))
+
+	delivery, err := webhooks.repo.GetDelivery(ctx, hook.ID, deliveryID(hook.ID, "evt"))
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryDead, delivery.Status)
+	require.Len(t, delivery.Log, 1)
+	assert.Zero(t, delivery.Log[0].StatusCode)
+	assert.NotEmpty(t, delivery.Log[0].Error)
+}
+
+func TestWebhooks_Run(t *testing.T) {
+	t.Parallel()
+	receiver := newWebhookReceiver(t)
+	repo := repository.NewInMemory()
+	webhooks := NewWebhooks(repo, receiver.Client(), WebhookConfig{Interval: time.Hour})
+	bus := events.NewBus()
+	bus.Subscribe(webhooks.Handle)
+	service := NewService(repo, WithEventPublisher(bus), WithWebhooks(webhooks))
+	ctx := tenant.With(context.Background(), tenant.Default)
+
+	hook, err := service.CreateWebhook(ctx, &model.Webhook{URL: receiver.URL})
+	require.NoError(t, err)
+	receiver.secret.Store(hook.Secret)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		webhooks.Run(runCtx)
+	}()
+
+	code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", Tags: []string{"a"}})
+	require.NoError(t, err)
+	_, err = service.UpdateURL(ctx, "", code, model.URLUpdate{OriginalURL: ptr.Of("https://example.org")})
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteURL(ctx, "", code))
+	assert.ErrorIs(t, service.DeleteURL(ctx, "", code), e.NotFoundError{})
+
+	// Woken up by the new deliveries, long before the interval.
+	require.Eventually(t, func() bool { return len(receiver.events()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	stop()
+	<-done
+
+	received := receiver.events()
+	types := map[events.Type]events.Event{}
+	for _, event := range received {
+		types[event.Type] = event
+	}
+	require.Contains(t, types, events.LinkCreated)
+	require.Contains(t, types, events.LinkUpdated)
+	require.Contains(t, types, events.LinkDeleted)
+	link := types[events.LinkCreated].Data["link"].(map[string]any)
+	assert.Equal(t, "https://example.com", link["originalURL"])
+	changes := types[events.LinkUpdated].Data["changes"].(map[string]any)
+	assert.Contains(t, changes, "originalURL")
+}
+
+func TestOutboxEvents(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	bus := events.NewBus()
+	var published []events.Event
+	bus.Subscribe(func(_ context.Context, event events.Event) { published = append(published, event) })
+	service := NewService(repo, WithEventPublisher(bus), WithOutbox(repo))
+	ctx := context.Background()
+
+	code, err := service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	require.NoError(t, err)
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of("promo")})
+	require.NoError(t, err)
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com", CustomURL: ptr.Of("promo")})
+	require.ErrorIs(t, err, e.ConflictError{})
+	assert.Empty(t, published, "the relay publishes link.created")
+
+	messages, err := repo.ClaimOutbox(ctx, time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2, "one message per saved link")
+	assert.Equal(t, code, messages[0].Key)
+	assert.Equal(t, "promo", messages[1].Key)
+	var event events.Event
+	require.NoError(t, json.Unmarshal(messages[0].Payload, &event))
+	assert.Equal(t, messages[0].ID, event.ID, "consumers dedupe by the event ID")
+	assert.Equal(t, events.LinkCreated, event.Type)
+	assert.Equal(t, code, event.ShortURL)
+	link := event.Data["link"].(map[string]any)
+	assert.Equal(t, "https://example.com", link["originalURL"])
+
+	require.NoError(t, service.DeleteURL(ctx, "", code))
+	require.Len(t, published, 1, "other events are still published directly")
+	assert.Equal(t, events.LinkDeleted, published[0].Type)
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	t.Parallel()
+	receiver := newWebhookReceiver(t)
+	repo := repository.NewInMemory()
+	webhooks := NewWebhooks(repo, receiver.Client(), WebhookConfig{})
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)), WithWebhooks(webhooks))
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey(testAdminKey)).Routes(mux)
+
+	ctx := context.Background()
+	_, err := service.CreateWorkspace(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	owner, err := service.CreateAPIKey(ctx, "acme", "ann", "")
+	require.NoError(t, err)
+	_, err = service.AddMember(ctx, &model.Member{Workspace: "acme", ID: "ed", Role: model.RoleEditor})
+	require.NoError(t, err)
+	editor, err := service.CreateAPIKey(ctx, "acme", "ed", "")
+	require.NoError(t, err)
+
+	serve := func(req *http.Request, token string) *httptest.ResponseRecorder {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(makeJSONRequest(http.MethodPost, "/webhooks", model.Webhook{URL: receiver.URL}), "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "anonymous clients can't subscribe to the default workspace")
+	rr = serve(makeJSONRequest(http.MethodPost, "/webhooks", model.Webhook{URL: receiver.URL}), editor.Key)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "editors can't manage webhooks")
+	rr = serve(makeJSONRequest(http.MethodPost, "/webhooks", model.Webhook{URL: "not a url"}), owner.Key)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = serve(makeJSONRequest(http.MethodPost, "/webhooks", model.Webhook{URL: receiver.URL}), owner.Key)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var hook model.Webhook
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&hook))
+	assert.Equal(t, "acme", hook.Workspace)
+	receiver.secret.Store(hook.Secret)
+	receiver.status.Store(http.StatusGone)
+
+	require.NoError(t, webhooks.Enqueue(ctx, events.Event{ID: "evt", Type: events.LinkCreated, Workspace: "acme"}))
+	webhooks.cfg.MaxAttempts = 1
+	require.NoError(t, webhooks.DeliverDue(ctx))
+
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks", nil), owner.Key)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), hook.ID)
+	assert.NotContains(t, rr.Body.String(), hook.Secret)
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks", nil), "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks", nil), testAdminKey)
+	assert.JSONEq(t, `[]`, rr.Body.String(), "the default workspace has its own webhooks")
+
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries?limit=x", nil), owner.Key)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries", nil), "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "deliveries hold the events")
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries", nil), testAdminKey)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "webhooks of other workspaces")
+	rr = serve(httptest.NewRequest(http.MethodGet, "/webhooks/"+hook.ID+"/deliveries", nil), owner.Key)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var deliveries []model.WebhookDelivery
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryDead, deliveries[0].Status)
+	assert.Equal(t, http.StatusGone, deliveries[0].Log[0].StatusCode)
+
+	redeliver := "/webhooks/" + hook.ID + "/deliveries/" + deliveries[0].ID + "/redeliver"
+	rr = serve(httptest.NewRequest(http.MethodPost, redeliver, nil), owner.Key)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":"pending"`)
+	rr = serve(httptest.NewRequest(http.MethodPost, redeliver, nil), owner.Key)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = serve(httptest.NewRequest(http.MethodDelete, "/webhooks/"+hook.ID, nil), editor.Key)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(httptest.NewRequest(http.MethodDelete, "/webhooks/"+hook.ID, nil), owner.Key)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = serve(httptest.NewRequest(http.MethodDelete, "/webhooks/"+hook.ID, nil), owner.Key)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestClickEvents(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	var (
+		mu     sync.Mutex
+		clicks []events.Event
+	)
+	bus := events.NewBus()
+	bus.Subscribe(func(_ context.Context, event events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		clicks = append(clicks, event)
+	})
+	mux := http.NewServeMux()
+	NewHandler(NewService(repo), WithClickEvents(bus)).Routes(mux)
+	require.NoError(t, repo.SaveURL(context.Background(), &model.URL{ShortURL: "abc",
+		OriginalURL: "https://example.com"}))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/abc", nil)
+		req.Header.Set("Referer", "https://news.example")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusFound, rr.Code)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, clicks, 1, "HEAD requests aren't clicks")
+	assert.Equal(t, events.LinkClicked, clicks[0].Type)
+	assert.Equal(t, "abc", clicks[0].ShortURL)
+	assert.Equal(t, "https://example.com", clicks[0].Data["destination"])
+	assert.Equal(t, "https://news.example", clicks[0].Data["referrer"])
+}
+
+func TestExpiryNotifier(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	repo := repository.NewInMemory()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for code, expiry := range map[string]time.Time{
+		"before": start.Add(-time.Minute),
+		"soon":   start.Add(time.Minute),
+		"later":  start.Add(time.Hour),
+	} {
+		require.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: code, OriginalURL: "https://example.com",
+			ExpirationDate: ptr.Of(expiry), Workspace: "acme"}))
+	}
+	require.NoError(t, repo.SaveURL(ctx, &model.URL{ShortURL: "never", OriginalURL: "https://example.com"}))
+
+	var published []events.Event
+	bus := events.NewBus()
+	bus.Subscribe(func(_ context.Context, event events.Event) { published = append(published, event) })
+	notifier := NewExpiryNotifier(repo, bus, time.Minute)
+	notifier.batchSize, notifier.since = 2, start
+	now := start.Add(2 * time.Minute)
+	notifier.now = func() time.Time { return now }
+
+	require.NoError(t, notifier.Notify(ctx))
+	require.Len(t, published, 1, "only links that expired since it started")
+	assert.Equal(t, events.LinkExpired, published[0].Type)
+	assert.Equal(t, "soon", published[0].ShortURL)
+	assert.Equal(t, "acme", published[0].Workspace)
+	assert.Equal(t, start.Add(time.Minute), published[0].OccurredAt)
+
+	require.NoError(t, notifier.Notify(ctx))
+	assert.Len(t, published, 1, "published once")
+
+	now = start.Add(2 * time.Hour)
+	require.NoError(t, notifier.Notify(ctx))
+	require.Len(t, published, 2)
+	assert.Equal(t, "later", published[1].ShortURL)
+	assert.Equal(t, expiryEventID(&model.URL{ShortURL: "later", ExpirationDate: ptr.Of(start.Add(time.Hour))}),
+		published[1].ID, "the same ID on every instance")
+}
@@ -0,0 +1,789 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jasoncheung94/url-shortener/internal/audit"
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+)
+
+// API keys look like usk_{id}_{secret}. The ID finds the stored key, the hash of the whole key proves it.
+const apiKeyPrefix = "usk_"
+
+// workspaceIDPattern is the form of workspace IDs, usable in URLs and as a Postgres key.
+var workspaceIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// memberIDPattern is the form of member IDs: user names or email addresses.
+var memberIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+-]{0,253}$`)
+
+// Workspaces manages workspaces and their API keys, and keeps workspaces within their link quotas.
+type Workspaces struct {
+	repo repository.Workspaces
+	now  func() time.Time
+}
+
+// NewWorkspaces returns Workspaces stored in repo.
+func NewWorkspaces(repo repository.Workspaces) *Workspaces {
+	return &Workspaces{repo: repo, now: time.Now}
+}
+
+// Create stores a new workspace with the owner as its first member.
+func (ws *Workspaces) Create(ctx context.Context, workspace *model.Workspace, owner string) (*model.Workspace, error) {
+	if !workspaceIDPattern.MatchString(workspace.ID) {
+		return nil, e.NewBadRequestError("workspace id %q must be 1 to 63 lower case letters, digits and -",
+			workspace.ID)
+	}
+	if err := validateWorkspace(workspace); err != nil {
+		return nil, err
+	}
+	if err := validateMemberID(owner); err != nil {
+		return nil, err
+	}
+	created := &model.Workspace{
+		ID:           workspace.ID,
+		Name:         workspace.Name,
+		LinkQuota:    workspace.LinkQuota,
+		MonthlyQuota: workspace.MonthlyQuota,
+		CreatedAt:    ws.now().UTC(),
+	}
+	if err := ws.repo.SaveWorkspace(ctx, created); err != nil {
+		return nil, err
+	}
+	err := ws.repo.SaveMember(ctx, &model.Member{
+		Workspace: created.ID,
+		ID:        owner,
+		Role:      model.RoleOwner,
+		CreatedAt: created.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Get returns the workspace.
+func (ws *Workspaces) Get(ctx context.Context, id string) (*model.Workspace, error) {
+	return ws.repo.GetWorkspace(ctx, id)
+}
+
+// Update changes the name and quotas of the workspace. Lowering a quota below the usage only stops new links.
+func (ws *Workspaces) Update(ctx context.Context, workspace *model.Workspace) (*model.Workspace, error) {
+	if err := validateWorkspace(workspace); err != nil {
+		return nil, err
+	}
+	stored, err := ws.repo.GetWorkspace(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	stored.Name, stored.LinkQuota, stored.MonthlyQuota = workspace.Name, workspace.LinkQuota, workspace.MonthlyQuota
+	if err := ws.repo.UpdateWorkspace(ctx, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+func validateWorkspace(workspace *model.Workspace) error {
+	switch {
+	case utf8.RuneCountInString(workspace.Name) > 100:
+		return e.NewBadRequestError("workspace name can't be longer than 100 characters")
+	case workspace.LinkQuota < 0 || workspace.MonthlyQuota < 0:
+		return e.NewBadRequestError("workspace quotas can't be negative, 0 is no limit")
+	}
+	return nil
+}
+
+// Usage returns how many links the workspace has and created this month.
+func (ws *Workspaces) Usage(ctx context.Context, id string) (*model.WorkspaceUsage, error) {
+	workspace, err := ws.repo.GetWorkspace(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	start := monthStart(ws.now())
+	links, err := ws.repo.CountURLs(ctx, id, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := ws.repo.CountURLs(ctx, id, start)
+	if err != nil {
+		return nil, err
+	}
+	return &model.WorkspaceUsage{
+		Workspace:    id,
+		Links:        links,
+		LinkQuota:    workspace.LinkQuota,
+		MonthlyLinks: monthly,
+		MonthlyQuota: workspace.MonthlyQuota,
+		PeriodStart:  start,
+		PeriodEnd:    start.AddDate(0, 1, 0),
+	}, nil
+}
+
+// monthStart returns the start of the calendar month in UTC.
+func monthStart(t time.Time) time.Time {
+	year, month, _ := t.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// admit checks the workspace can create another link. Until release is called other links of the workspace
+// wait, on every instance, so concurrent requests don't go over the quota.
+func (ws *Workspaces) admit(ctx context.Context, id string) (release func(), err error) {
+	unlock, err := ws.lock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := ws.Usage(ctx, id)
+	switch {
+	case err != nil:
+		unlock()
+		return nil, err
+	case usage.LinkQuota > 0 && usage.Links >= usage.LinkQuota:
+		unlock()
+		return nil, e.NewForbiddenError("workspace %q reached its quota of %d links", id, usage.LinkQuota)
+	case usage.MonthlyQuota > 0 && usage.MonthlyLinks >= usage.MonthlyQuota:
+		unlock()
+		return nil, e.NewForbiddenError("workspace %q reached its quota of %d new links until %s", id,
+			usage.MonthlyQuota, usage.PeriodEnd.Format(time.DateOnly))
+	}
+	return unlock, nil
+}
+
+// workspaceLockTTL bounds how long the lock of an instance that stopped while holding it blocks the workspace.
+const workspaceLockTTL = 30 * time.Second
+
+// lock locks the workspace in the store until the returned function is called, serializing quota checks and
+// owner changes of the workspace across instances.
+func (ws *Workspaces) lock(ctx context.Context, id string) (unlock func(), err error) {
+	unlock, err = ws.repo.LockWorkspace(ctx, id, workspaceLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock workspace %q: %w", id, err)
+	}
+	return unlock, nil
+}
+
+// CreateKey adds an API key acting as the member, the context's member if empty. Members can't get keys for
+// members with a higher role than their own. The returned key is the only time the secret is available.
+func (ws *Workspaces) CreateKey(ctx context.Context, workspace, member, name string) (*model.APIKey, error) {
+	if utf8.RuneCountInString(name) > 100 {
+		return nil, e.NewBadRequestError("api key name can't be longer than 100 characters")
+	}
+	if _, err := ws.repo.GetWorkspace(ctx, workspace); err != nil {
+		return nil, err
+	}
+	caller, scoped := memberFrom(ctx)
+	if member == "" {
+		if !scoped || caller.ID == "" {
+			return nil, e.NewBadRequestError("the member of the api key is required")
+		}
+		member = caller.ID
+	}
+	holder, err := ws.repo.GetMember(ctx, workspace, member)
+	if errors.Is(err, e.NotFoundError{}) {
+		return nil, e.NewBadRequestError("%q isn't a member of workspace %q", member, workspace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if scoped && holder.Role.Rank() > caller.Role.Rank() {
+		return nil, e.NewForbiddenError("%s %q can't create api keys for %s %q", caller.Role, caller.ID,
+			holder.Role, holder.ID)
+	}
+
+	id, secret := make([]byte, 8), make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := &model.APIKey{
+		ID:        hex.EncodeToString(id),
+		Workspace: workspace,
+		Member:    member,
+		Name:      name,
+		CreatedAt: ws.now().UTC(),
+	}
+	key.Key = apiKeyPrefix + key.ID + "_" + hex.EncodeToString(secret)
+	key.Hash = hashAPIKey(key.Key)
+	if err := ws.repo.SaveAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListKeys returns the API keys of the workspace without their secrets.
+func (ws *Workspaces) ListKeys(ctx context.Context, workspace string) ([]model.APIKey, error) {
+	return ws.repo.ListAPIKeys(ctx, workspace)
+}
+
+// DeleteKey revokes an API key of the workspace.
+func (ws *Workspaces) DeleteKey(ctx context.Context, workspace, id string) error {
+	return ws.repo.DeleteAPIKey(ctx, workspace, id)
+}
+
+// Authenticate returns the member the API key acts as, an UnauthorizedError if the key isn't valid or its
+// member left the workspace. Keys created before members existed act as admins.
+func (ws *Workspaces) Authenticate(ctx context.Context, key string) (*model.Member, error) {
+	id, _, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, e.NewUnauthorizedError("invalid api key")
+	}
+	stored, err := ws.repo.GetAPIKey(ctx, id)
+	switch {
+	case errors.Is(err, e.NotFoundError{}):
+		return nil, e.NewUnauthorizedError("invalid api key")
+	case err != nil:
+		return nil, err
+	case subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(stored.Hash)) != 1:
+		return nil, e.NewUnauthorizedError("invalid api key")
+	case stored.Member == "":
+		return &model.Member{Workspace: stored.Workspace, Role: model.RoleAdmin}, nil
+	}
+
+	member, err := ws.repo.GetMember(ctx, stored.Workspace, stored.Member)
+	if errors.Is(err, e.NotFoundError{}) {
+		return nil, e.NewUnauthorizedError("the member of the api key left the workspace")
+	}
+	return member, err
+}
+
+// AddMember adds a user to the workspace with the role.
+func (ws *Workspaces) AddMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	if err := validateMemberID(member.ID); err != nil {
+		return nil, err
+	}
+	if err := ownerChange(ctx, "", member.Role); err != nil {
+		return nil, err
+	}
+	if _, err := ws.repo.GetWorkspace(ctx, member.Workspace); err != nil {
+		return nil, err
+	}
+	added := &model.Member{
+		Workspace: member.Workspace,
+		ID:        member.ID,
+		Role:      member.Role,
+		CreatedAt: ws.now().UTC(),
+	}
+	if err := ws.repo.SaveMember(ctx, added); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListMembers returns the members of the workspace by ID.
+func (ws *Workspaces) ListMembers(ctx context.Context, workspace string) ([]model.Member, error) {
+	return ws.repo.ListMembers(ctx, workspace)
+}
+
+// UpdateMember changes the role of a member. The last owner can't be demoted.
+func (ws *Workspaces) UpdateMember(ctx context.Context, member *model.Member) (*model.Member, error) {
+	unlock, err := ws.lock(ctx, member.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored, err := ws.repo.GetMember(ctx, member.Workspace, member.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ownerChange(ctx, stored.Role, member.Role); err != nil {
+		return nil, err
+	}
+	if stored.Role == model.RoleOwner && member.Role != model.RoleOwner {
+		if err := ws.keepOwner(ctx, member.Workspace); err != nil {
+			return nil, err
+		}
+	}
+	stored.Role = member.Role
+	if err := ws.repo.UpdateMember(ctx, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// RemoveMember removes a user from the workspace, their API keys stop working. The last owner can't leave.
+func (ws *Workspaces) RemoveMember(ctx context.Context, workspace, id string) error {
+	unlock, err := ws.lock(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := ws.repo.GetMember(ctx, workspace, id)
+	if err != nil {
+		return err
+	}
+	if err := ownerChange(ctx, stored.Role, ""); err != nil {
+		return err
+	}
+	if stored.Role == model.RoleOwner {
+		if err := ws.keepOwner(ctx, workspace); err != nil {
+			return err
+		}
+	}
+	return ws.repo.DeleteMember(ctx, workspace, id)
+}
+
+// keepOwner returns an error unless the workspace has another owner besides the one about to go.
+func (ws *Workspaces) keepOwner(ctx context.Context, workspace string) error {
+	members, err := ws.repo.ListMembers(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	for _, member := range members {
+		if member.Role == model.RoleOwner {
+			owners++
+		}
+	}
+	if owners <= 1 {
+		return e.NewConflictError("workspace %q needs at least one owner", workspace)
+	}
+	return nil
+}
+
+// ownerChange checks the role of a member can go from one role to the other, "" when added or removed:
+// the role must exist and only owners can make or unmake owners.
+func ownerChange(ctx context.Context, from, to model.Role) error {
+	if to != "" && to.Rank() == 0 {
+		return e.NewBadRequestError("role %q must be owner, admin, editor or viewer", to)
+	}
+	caller, scoped := memberFrom(ctx)
+	if scoped && caller.Role != model.RoleOwner && (from == model.RoleOwner || to == model.RoleOwner) {
+		return e.NewForbiddenError("only owners can manage owners")
+	}
+	return nil
+}
+
+func validateMemberID(id string) error {
+	if !memberIDPattern.MatchString(id) {
+		return e.NewBadRequestError("member %q must be a user name or email address", id)
+	}
+	return nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the token of the request's Authorization: Bearer header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// scoped runs next as the member of the request's API key in its workspace, in the default workspace for
+// requests without one. Admin requests act for the {workspace} of the path, the default workspace on routes
+// without one.
+func (h *Handler) scoped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.isAdmin(r) {
+			ctx := audit.WithActor(tenant.With(r.Context(), r.PathValue("workspace")), audit.ActorAdmin)
+			next(w, r.WithContext(withAdmin(ctx)))
+			return
+		}
+		key, ok := bearerToken(r)
+		if !ok {
+			next(w, r.WithContext(tenant.With(r.Context(), tenant.Default)))
+			return
+		}
+		member, err := h.service.Authenticate(r.Context(), key)
+		if writeServiceError(w, err) {
+			return
+		}
+		ctx := audit.WithActor(tenant.With(r.Context(), member.Workspace), member.ID)
+		next(w, r.WithContext(withMember(ctx, member)))
+	}
+}
+
+// isAdmin reports whether the request has the admin key. Without one configured nobody is an admin.
+func (h *Handler) isAdmin(r *http.Request) bool {
+	key, ok := bearerToken(r)
+	return ok && h.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(h.adminKey)) == 1
+}
+
+// adminOnly only runs next for requests with the admin key.
+func (h *Handler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.isAdmin(r) {
+			writeServiceError(w, e.NewForbiddenError("only the admin key can do this"))
+			return
+		}
+		next(w, r.WithContext(withAdmin(audit.WithActor(r.Context(), audit.ActorAdmin))))
+	}
+}
+
+// workspaceAccess runs next for the {workspace} of the path, for requests with the admin key or an API key of
+// that workspace.
+func (h *Handler) workspaceAccess(next http.HandlerFunc) http.HandlerFunc {
+	return h.scopedOrAdmin(func(w http.ResponseWriter, r *http.Request) {
+		if workspace, _ := tenant.From(r.Context()); workspace != r.PathValue("workspace") {
+			writeServiceError(w, e.NewForbiddenError("the api key is for another workspace"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// scopedOrAdmin is scoped for requests with an API key or the admin key. Requests without a key are refused:
+// they'd act for the default workspace with no member to authorize, so anyone could change the links of every
+// other anonymous client.
+func (h *Handler) scopedOrAdmin(next http.HandlerFunc) http.HandlerFunc {
+	scoped := h.scoped(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); !ok {
+			writeServiceError(w, e.NewUnauthorizedError("an api key is required"))
+			return
+		}
+		scoped(w, r)
+	}
+}
+
+// workspaceRequest is a new workspace and the user owning it.
+type workspaceRequest struct {
+	model.Workspace
+	Owner string `json:"owner"` // User name or email of the first owner.
+}
+
+// workspaceResponse is a new workspace with the first API key of its owner.
+type workspaceResponse struct {
+	Workspace *model.Workspace `json:"workspace"`
+	APIKey    *model.APIKey    `json:"apiKey"`
+}
+
+// CreateWorkspace creates a workspace with its owner and a first API key.
+// @Summary Create a workspace
+// @Description Creates a workspace (tenant) with optional quotas, 0 is no limit, and the owner as its first
+// @Description member. The response has the owner's first API key, send it as Authorization: Bearer {key} to
+// @Description create and list the workspace's links. Needs the admin key.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {admin key}"
+// @Param requestBody body workspaceRequest true "Workspace ID, name, quotas and owner"
+// @Success 201 {object} workspaceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces [post]
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var request workspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	workspace, err := h.service.CreateWorkspace(ctx, &request.Workspace, request.Owner)
+	if writeServiceError(w, err) {
+		return
+	}
+	key, err := h.service.CreateAPIKey(ctx, workspace.ID, request.Owner, "default")
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, workspaceResponse{Workspace: workspace, APIKey: key})
+}
+
+// GetWorkspace returns a workspace.
+// @Summary Get a workspace
+// @Tags Workspaces
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Success 200 {object} model.Workspace
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace} [get]
+func (h *Handler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	workspace, err := h.service.GetWorkspace(ctx, r.PathValue("workspace"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, workspace)
+}
+
+// UpdateWorkspace changes the name and quotas of a workspace.
+// @Summary Update a workspace
+// @Description Replaces the name and quotas of the workspace, 0 is no limit. Needs the admin key.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param requestBody body model.Workspace true "Name and quotas, the ID is taken from the path"
+// @Success 200 {object} model.Workspace
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace} [patch]
+func (h *Handler) UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var request model.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+	request.ID = r.PathValue("workspace")
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	workspace, err := h.service.UpdateWorkspace(ctx, &request)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, workspace)
+}
+
+// WorkspaceUsage reports the link usage of a workspace.
+// @Summary Get the usage of a workspace
+// @Description How many links the workspace has and created this calendar month (UTC), next to its quotas.
+// @Tags Workspaces
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Success 200 {object} model.WorkspaceUsage
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/usage [get]
+func (h *Handler) WorkspaceUsage(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	usage, err := h.service.WorkspaceUsage(ctx, r.PathValue("workspace"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// CreateAPIKey adds an API key to a workspace.
+// @Summary Create an API key
+// @Description Creates a key acting as the member, the caller if empty, and returns it. It can't be read again
+// @Description later. Needs an admin, who can't create keys for owners.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param requestBody body model.APIKey false "Name and member of the key"
+// @Success 201 {object} model.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/keys [post]
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request model.APIKey
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			e.WriteJSONError(w, http.StatusBadRequest,
+				e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.CreateAPIKey(ctx, r.PathValue("workspace"), request.Member, request.Name)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+// ListAPIKeys lists the API keys of a workspace.
+// @Summary List API keys
+// @Description Lists the keys of the workspace, oldest first, without the keys themselves.
+// @Tags Workspaces
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/keys [get]
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	keys, err := h.service.ListAPIKeys(ctx, r.PathValue("workspace"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// DeleteAPIKey revokes an API key of a workspace.
+// @Summary Delete an API key
+// @Tags Workspaces
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param key path string true "API key ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/keys/{key} [delete]
+func (h *Handler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	err := h.service.DeleteAPIKey(ctx, r.PathValue("workspace"), r.PathValue("key"))
+	if writeServiceError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMember adds a user to a workspace.
+// @Summary Add a member
+// @Description Adds the user with a role: viewers see links and stats, editors also create, update and delete links,
+// @Description admins also manage members, API keys and domains and owners also manage owners. Needs an admin.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param requestBody body model.Member true "User and role"
+// @Success 201 {object} model.Member
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/members [post]
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var request model.Member
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+	request.Workspace = r.PathValue("workspace")
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	member, err := h.service.AddMember(ctx, &request)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusCreated, member)
+}
+
+// ListMembers lists the members of a workspace.
+// @Summary List members
+// @Tags Workspaces
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Success 200 {array} model.Member
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/members [get]
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	members, err := h.service.ListMembers(ctx, r.PathValue("workspace"))
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// UpdateMember changes the role of a member.
+// @Summary Change a member's role
+// @Description Needs an admin. Only owners can make or unmake owners and the last owner can't be demoted.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param member path string true "Member ID"
+// @Param requestBody body model.Member true "New role"
+// @Success 200 {object} model.Member
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/members/{member} [patch]
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	var request model.Member
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		e.WriteJSONError(w, http.StatusBadRequest, e.NewErrorResponse(http.StatusBadRequest, "JSON error", err.Error()))
+		return
+	}
+	request.Workspace, request.ID = r.PathValue("workspace"), r.PathValue("member")
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	member, err := h.service.UpdateMember(ctx, &request)
+	if writeServiceError(w, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
+// RemoveMember removes a user from a workspace.
+// @Summary Remove a member
+// @Description Their API keys stop working. Needs an admin, only owners can remove owners and the last owner
+// @Description can't be removed.
+// @Tags Workspaces
+// @Param Authorization header string true "Bearer {api key of the workspace or admin key}"
+// @Param workspace path string true "Workspace ID"
+// @Param member path string true "Member ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {string} string
+// @Router /workspaces/{workspace}/members/{member} [delete]
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	err := h.service.RemoveMember(ctx, r.PathValue("workspace"), r.PathValue("member"))
+	if writeServiceError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,239 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	e "github.com/jasoncheung94/url-shortener/internal/errors"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/model"
+	"github.com/jasoncheung94/url-shortener/internal/shortener/repository"
+	"github.com/jasoncheung94/url-shortener/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaces_APIKeys(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	workspaces := NewWorkspaces(repository.NewInMemory())
+
+	for _, id := range []string{"", "Acme", "-acme", "acme/x", strings.Repeat("a", 64)} {
+		_, err := workspaces.Create(ctx, &model.Workspace{ID: id}, "ann")
+		assert.ErrorIs(t, err, e.BadRequestError{}, id)
+	}
+	_, err := workspaces.Create(ctx, &model.Workspace{ID: "acme", LinkQuota: -1}, "ann")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	_, err = workspaces.Create(ctx, &model.Workspace{ID: "acme"}, "")
+	assert.ErrorIs(t, err, e.BadRequestError{}, "workspaces need an owner")
+	_, err = workspaces.Create(ctx, &model.Workspace{ID: "acme"}, "ann")
+	require.NoError(t, err)
+	_, err = workspaces.CreateKey(ctx, "missing", "ann", "ci")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+
+	key, err := workspaces.CreateKey(ctx, "acme", "ann", "ci")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.Key, "usk_"+key.ID+"_"))
+	assert.NotContains(t, key.Hash, key.Key)
+
+	member, err := workspaces.Authenticate(ctx, key.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", member.Workspace)
+	assert.Equal(t, model.RoleOwner, member.Role)
+	for _, invalid := range []string{"", "usk_", key.Key + "0", "usk_" + key.ID + "_" + strings.Repeat("0", 64),
+		strings.TrimPrefix(key.Key, "usk_")} {
+		_, err = workspaces.Authenticate(ctx, invalid)
+		assert.ErrorIs(t, err, e.UnauthorizedError{}, invalid)
+	}
+
+	keys, err := workspaces.ListKeys(ctx, "acme")
+	assert.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key, "listed keys have no secret")
+	require.NoError(t, workspaces.DeleteKey(ctx, "acme", key.ID))
+	_, err = workspaces.Authenticate(ctx, key.Key)
+	assert.ErrorIs(t, err, e.UnauthorizedError{}, "deleted keys are revoked")
+}
+
+func TestShortenerService_WorkspaceQuotas(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	workspaces := NewWorkspaces(repo)
+	service := NewService(repo, WithWorkspaces(workspaces))
+	ctx := tenant.With(context.Background(), "acme")
+
+	_, err := service.CreateWorkspace(ctx, &model.Workspace{ID: "acme", LinkQuota: 3, MonthlyQuota: 2}, "ann")
+	require.NoError(t, err)
+	for range 2 {
+		_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+		require.NoError(t, err)
+	}
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, e.ForbiddenError{}, "monthly quota")
+
+	usage, err := service.WorkspaceUsage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.Links)
+	assert.Equal(t, 2, usage.MonthlyLinks)
+	assert.Equal(t, usage.PeriodStart.AddDate(0, 1, 0), usage.PeriodEnd)
+
+	workspaces.now = func() time.Time { return time.Now().AddDate(0, 1, 0) }
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	require.NoError(t, err, "a new month starts a new monthly quota")
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, e.ForbiddenError{}, "link quota")
+
+	_, err = service.UpdateWorkspace(ctx, &model.Workspace{ID: "acme"})
+	require.NoError(t, err)
+	_, err = service.SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	assert.NoError(t, err, "0 is no limit")
+
+	_, err = service.SaveURL(tenant.With(context.Background(), "missing"), &model.URL{OriginalURL: "https://a.com"})
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	_, err = NewService(repo).SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, e.BadRequestError{}, "workspaces need WithWorkspaces")
+}
+
+func TestShortenerService_WorkspaceQuotaInstances(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	ctx := tenant.With(context.Background(), "acme")
+
+	// Two instances sharing the store create links at the same time.
+	instances := []Service{
+		NewService(repo, WithWorkspaces(NewWorkspaces(repo))),
+		NewService(repo, WithWorkspaces(NewWorkspaces(repo))),
+	}
+	_, err := instances[0].CreateWorkspace(ctx, &model.Workspace{ID: "acme", LinkQuota: 5}, "ann")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := instances[i%2].SaveURL(ctx, &model.URL{OriginalURL: "https://example.com"}); err == nil {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), created.Load())
+
+	count, err := repo.CountURLs(ctx, "acme", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestShortenerService_WorkspaceDomains(t *testing.T) {
+	t.Parallel()
+	domains, repo, resolver := newTestDomains()
+	service := NewService(repo, WithDomains(domains), WithWorkspaces(NewWorkspaces(repo)))
+	acme := tenant.With(context.Background(), "acme")
+	other := tenant.With(context.Background(), "other")
+
+	_, err := service.RegisterDomain(acme, "go.acme.com", "someone")
+	assert.ErrorIs(t, err, e.BadRequestError{})
+	domain, err := service.RegisterDomain(acme, "go.acme.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", domain.Owner)
+	resolver.set(domain.Record.Name, domain.Record.Value)
+
+	_, err = service.VerifyDomain(other, "go.acme.com")
+	assert.ErrorIs(t, err, e.NotFoundError{})
+	_, err = service.VerifyDomain(acme, "go.acme.com")
+	require.NoError(t, err)
+
+	listed, err := service.ListDomains(other, "")
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+	listed, err = service.ListDomains(acme, "")
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	_, err = service.SaveURL(other, &model.URL{OriginalURL: "https://example.com", Domain: "go.acme.com"})
+	assert.ErrorIs(t, err, e.BadRequestError{})
+}
+
+func TestWorkspaceHandlers(t *testing.T) {
+	t.Parallel()
+	repo := repository.NewInMemory()
+	service := NewService(repo, WithWorkspaces(NewWorkspaces(repo)))
+	mux := http.NewServeMux()
+	NewHandler(service, WithAdminKey("admin-secret")).Routes(mux)
+
+	serve := func(req *http.Request, token string) *httptest.ResponseRecorder {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	create := func(id string) string {
+		rr := serve(makeJSONRequest(http.MethodPost, "/workspaces",
+			workspaceRequest{Workspace: model.Workspace{ID: id, LinkQuota: 1}, Owner: "ann"}), "admin-secret")
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var res workspaceResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+		return res.APIKey.Key
+	}
+	acme, other := create("acme"), create("other")
+
+	for _, tt := range []struct {
+		req    *http.Request
+		token  string
+		status int
+		body   string
+	}{
+		{makeJSONRequest(http.MethodPost, "/workspaces", model.Workspace{ID: "x"}), acme, http.StatusForbidden, ""},
+		{makeJSONRequest(http.MethodPost, "/workspaces", workspaceRequest{Workspace: model.Workspace{ID: "acme"},
+			Owner: "ann"}), "admin-secret", http.StatusConflict, ""},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), acme, http.StatusOK, `"id":"acme"`},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), "admin-secret", http.StatusOK, `"id":"acme"`},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), other, http.StatusForbidden, ""},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), "", http.StatusUnauthorized, ""},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), "usk_0_0", http.StatusUnauthorized, ""},
+		{makeJSONRequest(http.MethodPatch, "/workspaces/acme", model.Workspace{LinkQuota: 1}), acme,
+			http.StatusForbidden, ""},
+		{makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"}), "usk_0_0",
+			http.StatusUnauthorized, "invalid api key"},
+		{makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"}), acme,
+			http.StatusCreated, ""},
+		{makeJSONRequest(http.MethodPost, "/shorten", model.URL{OriginalURL: "https://a.com"}), acme,
+			http.StatusForbidden, "quota of 1 links"},
+		{httptest.NewRequest(http.MethodGet, "/urls", nil), acme, http.StatusOK, `"workspace":"acme"`},
+		{httptest.NewRequest(http.MethodGet, "/urls", nil), other, http.StatusOK, `"urls":[]`},
+		{httptest.NewRequest(http.MethodGet, "/workspaces/acme/usage", nil), acme, http.StatusOK, `"links":1`},
+		{httptest.NewRequest(http.MethodPost, "/workspaces/acme/keys", nil), acme, http.StatusCreated, `"key":"usk_`},
+		{httptest.NewRequest(http.MethodDelete, "/workspaces/acme/keys/missing", nil), acme, http.StatusNotFound, ""},
+	} {
+		rr := serve(tt.req, tt.token)
+		assert.Equal(t, tt.status, rr.Code, tt.req.Method+" "+tt.req.URL.String())
+		assert.Contains(t, rr.Body.String(), tt.body, tt.req.URL)
+	}
+
+	rr := serve(httptest.NewRequest(http.MethodGet, "/workspaces/acme/keys", nil), acme)
+	var keys []model.APIKey
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+	require.Len(t, keys, 2)
+	rr = serve(httptest.NewRequest(http.MethodDelete, "/workspaces/acme/keys/"+keys[0].ID, nil), acme)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = serve(httptest.NewRequest(http.MethodGet, "/workspaces/acme", nil), acme)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "the deleted key no longer works")
+
+	disabled := http.NewServeMux()
+	NewHandler(service).Routes(disabled)
+	req := makeJSONRequest(http.MethodPost, "/workspaces", model.Workspace{ID: "x"})
+	req.Header.Set("Authorization", "Bearer ")
+	rr = httptest.NewRecorder()
+	disabled.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "no admin without ADMIN_API_KEY")
+}
@@ -0,0 +1,162 @@
+// Package snowflake generates unique, time ordered 64-bit IDs without a central counter.
+//
+// An ID is made of a timestamp, the worker ID of the instance and a sequence number within the
+// timestamp. The layout uses 59 bits so every ID fits in 10 base62 characters:
+//
+//	| 39 bits: 10ms ticks since Epoch (174 years) | 8 bits: worker (256) | 12 bits: sequence (4096) |
+//
+// Each worker can generate 4096 IDs every 10ms. Worker IDs must be unique among the running instances.
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Layout of an ID.
+const (
+	TimeBits     = 39
+	WorkerBits   = 8
+	SequenceBits = 12
+
+	MaxWorkers = 1 << WorkerBits
+	// Tick is the resolution of the timestamp.
+	Tick = 10 * time.Millisecond
+
+	maxSequence = 1<<SequenceBits - 1
+	maxTicks    = 1<<TimeBits - 1
+)
+
+// Epoch is the start of the timestamp.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	// ErrClockBackwards is returned when the clock moved back further than the generator waits for.
+	ErrClockBackwards = errors.New("snowflake: clock moved backwards")
+	// ErrTimeOverflow is returned when the timestamp doesn't fit in the ID any more.
+	ErrTimeOverflow = errors.New("snowflake: timestamp out of range")
+)
+
+// Options configures a Generator.
+type Options struct {
+	// MaxBackwards is how far the clock may move back before Next fails instead of waiting for it to catch up.
+	MaxBackwards time.Duration
+	// NotBefore is the latest time this worker ID was used for, e.g. by a previous holder of its lease.
+	// No IDs are generated for that time or earlier.
+	NotBefore time.Time
+	// Now replaces time.Now in tests.
+	Now func() time.Time
+	// Sleep replaces time.Sleep in tests.
+	Sleep func(time.Duration)
+}
+
+// Generator generates IDs for one worker. It's safe for concurrent use.
+type Generator struct {
+	worker       uint64
+	maxBackwards time.Duration
+	now          func() time.Time
+	sleep        func(time.Duration)
+
+	mu       sync.Mutex
+	lastTick int64
+	sequence uint64
+}
+
+// New returns a Generator for the worker ID, between 0 and MaxWorkers-1.
+func New(worker int, opts Options) (*Generator, error) {
+	if worker < 0 || worker >= MaxWorkers {
+		return nil, fmt.Errorf("snowflake: worker %d out of range 0-%d", worker, MaxWorkers-1)
+	}
+
+	g := &Generator{
+		worker:       uint64(worker),
+		maxBackwards: opts.MaxBackwards,
+		now:          opts.Now,
+		sleep:        opts.Sleep,
+		lastTick:     -1,
+	}
+	if g.now == nil {
+		g.now = time.Now
+	}
+	if g.sleep == nil {
+		g.sleep = time.Sleep
+	}
+	if !opts.NotBefore.IsZero() {
+		g.lastTick = ticks(opts.NotBefore)
+		g.sequence = maxSequence // The whole tick counts as used.
+	}
+	return g, nil
+}
+
+// Next returns the next ID. It waits when the sequence of the current tick is used up or the
+// clock moved back by less than MaxBackwards.
+func (g *Generator) Next() (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tick := ticks(g.now())
+	if tick < 0 || tick > maxTicks {
+		return 0, ErrTimeOverflow
+	}
+	if tick < g.lastTick {
+		behind := time.Duration(g.lastTick-tick) * Tick
+		if behind > g.maxBackwards {
+			return 0, fmt.Errorf("%w by %s", ErrClockBackwards, behind)
+		}
+		tick = g.waitAfter(g.lastTick - 1)
+	}
+
+	if tick == g.lastTick {
+		if g.sequence == maxSequence {
+			tick = g.waitAfter(g.lastTick)
+			g.sequence = 0
+		} else {
+			g.sequence++
+		}
+	} else {
+		g.sequence = 0
+	}
+	if tick > maxTicks {
+		return 0, ErrTimeOverflow
+	}
+
+	g.lastTick = tick
+	return uint64(tick)<<(WorkerBits+SequenceBits) | g.worker<<SequenceBits | g.sequence, nil
+}
+
+// Last returns the time of the latest ID generated, zero if none.
+func (g *Generator) Last() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastTick < 0 {
+		return time.Time{}
+	}
+	return Epoch.Add(time.Duration(g.lastTick+1)*Tick - 1)
+}
+
+// waitAfter sleeps until the clock is past tick and returns the new tick.
+func (g *Generator) waitAfter(tick int64) int64 {
+	for {
+		now := ticks(g.now())
+		if now > tick {
+			return now
+		}
+		g.sleep(time.Duration(tick-now+1) * Tick)
+	}
+}
+
+// Time returns the time an ID was generated, to the Tick.
+func Time(id uint64) time.Time {
+	return Epoch.Add(time.Duration(id>>(WorkerBits+SequenceBits)) * Tick)
+}
+
+// Worker returns the worker that generated an ID.
+func Worker(id uint64) int {
+	return int(id >> SequenceBits & (MaxWorkers - 1))
+}
+
+func ticks(t time.Time) int64 {
+	return int64(t.Sub(Epoch) / Tick)
+}
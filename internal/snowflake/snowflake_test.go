@@ -0,0 +1,156 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manual clock, sleeping moves it forward.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func newGenerator(t *testing.T, worker int, clock *fakeClock, opts Options) *Generator {
+	t.Helper()
+	opts.Now, opts.Sleep = clock.Now, clock.Sleep
+	g, err := New(worker, opts)
+	require.NoError(t, err)
+	return g
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	_, err := New(-1, Options{})
+	assert.Error(t, err)
+	_, err = New(MaxWorkers, Options{})
+	assert.Error(t, err)
+	_, err = New(MaxWorkers-1, Options{})
+	assert.NoError(t, err)
+}
+
+func TestNextLayout(t *testing.T) {
+	t.Parallel()
+	start := Epoch.Add(time.Hour)
+	clock := &fakeClock{now: start}
+	g := newGenerator(t, 7, clock, Options{})
+
+	first, err := g.Next()
+	require.NoError(t, err)
+	second, err := g.Next()
+	require.NoError(t, err)
+
+	assert.Equal(t, first+1, second, "same tick increments the sequence")
+	assert.Equal(t, 7, Worker(first))
+	assert.Equal(t, start, Time(first))
+	assert.Equal(t, start.Add(Tick-1), g.Last())
+
+	// The largest possible ID fits in 10 base62 characters.
+	assert.Less(t, uint64(1)<<(TimeBits+WorkerBits+SequenceBits), uint64(839299365868340224))
+}
+
+func TestNextUniqueAndOrdered(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: Epoch.Add(time.Hour)}
+	g := newGenerator(t, 1, clock, Options{})
+
+	var previous uint64
+	for i := range 3 * (maxSequence + 1) {
+		id, err := g.Next()
+		require.NoError(t, err)
+		require.Greater(t, id, previous, "id %d", i)
+		previous = id
+	}
+	// The sequence ran out twice, so the generator waited for two more ticks.
+	assert.Equal(t, Epoch.Add(time.Hour+2*Tick), Time(previous))
+}
+
+func TestNextWorkersDontCollide(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: Epoch.Add(time.Hour)}
+	a := newGenerator(t, 1, clock, Options{})
+	b := newGenerator(t, 2, clock, Options{})
+
+	idA, err := a.Next()
+	require.NoError(t, err)
+	idB, err := b.Next()
+	require.NoError(t, err)
+	assert.NotEqual(t, idA, idB)
+}
+
+func TestNextClockBackwards(t *testing.T) {
+	t.Parallel()
+	start := Epoch.Add(time.Hour)
+	clock := &fakeClock{now: start}
+	g := newGenerator(t, 1, clock, Options{MaxBackwards: 50 * time.Millisecond})
+
+	first, err := g.Next()
+	require.NoError(t, err)
+
+	// A small step back is waited out.
+	clock.Set(start.Add(-30 * time.Millisecond))
+	second, err := g.Next()
+	require.NoError(t, err)
+	assert.Greater(t, second, first)
+
+	// A large one fails.
+	clock.Set(start.Add(-time.Second))
+	_, err = g.Next()
+	assert.ErrorIs(t, err, ErrClockBackwards)
+}
+
+func TestNextNotBefore(t *testing.T) {
+	t.Parallel()
+	start := Epoch.Add(time.Hour)
+	clock := &fakeClock{now: start}
+
+	// A previous holder of the worker ID generated IDs up to now.
+	previous := newGenerator(t, 1, clock, Options{})
+	last, err := previous.Next()
+	require.NoError(t, err)
+
+	g := newGenerator(t, 1, clock, Options{NotBefore: previous.Last()})
+	id, err := g.Next()
+	require.NoError(t, err)
+	assert.Greater(t, id, last)
+
+	// A clock far behind the previous holder refuses to generate.
+	clock.Set(start.Add(-time.Minute))
+	behind := newGenerator(t, 1, clock, Options{NotBefore: previous.Last(), MaxBackwards: time.Second})
+	_, err = behind.Next()
+	assert.ErrorIs(t, err, ErrClockBackwards)
+}
+
+func TestNextTimeOverflow(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: Epoch.Add(-time.Hour)}
+	g := newGenerator(t, 1, clock, Options{})
+	_, err := g.Next()
+	assert.ErrorIs(t, err, ErrTimeOverflow)
+
+	clock.Set(Epoch.Add(time.Duration(maxTicks+1) * Tick))
+	_, err = g.Next()
+	assert.ErrorIs(t, err, ErrTimeOverflow)
+}
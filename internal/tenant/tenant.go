@@ -0,0 +1,21 @@
+// Package tenant carries the workspace a request acts for, so storage only touches that workspace's links.
+package tenant
+
+import "context"
+
+// Default is the workspace of requests without an API key and of links created before workspaces existed.
+const Default = ""
+
+type contextKey struct{}
+
+// With returns a context scoped to the workspace.
+func With(ctx context.Context, workspace string) context.Context {
+	return context.WithValue(ctx, contextKey{}, workspace)
+}
+
+// From returns the workspace the context is scoped to. Unscoped contexts, e.g. of redirects and background
+// jobs, see the links of every workspace.
+func From(ctx context.Context) (workspace string, scoped bool) {
+	workspace, scoped = ctx.Value(contextKey{}).(string)
+	return workspace, scoped
+}
@@ -0,0 +1,22 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+	_, scoped := From(context.Background())
+	assert.False(t, scoped)
+
+	workspace, scoped := From(With(context.Background(), Default))
+	assert.True(t, scoped)
+	assert.Equal(t, Default, workspace)
+
+	workspace, scoped = From(With(context.Background(), "marketing"))
+	assert.True(t, scoped)
+	assert.Equal(t, "marketing", workspace)
+}